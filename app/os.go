@@ -147,6 +147,17 @@ type context interface {
 	Unlock()
 }
 
+// damagePresenter is implemented by contexts that can limit
+// drawing and presentation to the damaged region of a frame.
+type damagePresenter interface {
+	// PresentDamage is like Present, but hints that only the
+	// damage rectangles changed since the previous frame.
+	PresentDamage(damage []image.Rectangle) error
+	// BufferAge returns the age in frames of the contents of the
+	// render target, or 0 if they are undefined.
+	BufferAge() int
+}
+
 // Driver is the interface for the platform implementation
 // of a window.
 type driver interface {
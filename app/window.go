@@ -41,6 +41,8 @@ type Option func(unit.Metric, *Config)
 type Window struct {
 	ctx context
 	gpu gpu.GPU
+	// presentTime is the duration of the most recent Present of ctx.
+	presentTime time.Duration
 
 	// driverFuncs is a channel of functions to run when
 	// the Window has a valid driver.
@@ -281,7 +283,15 @@ func (w *Window) validateAndProcess(d driver, size image.Point, sync bool, frame
 		signal()
 		var err error
 		if w.gpu != nil {
-			err = w.ctx.Present()
+			start := time.Now()
+			dp, ok := w.ctx.(damagePresenter)
+			dt, tracked := w.gpu.(gpu.DamageTracker)
+			if ok && tracked {
+				err = dp.PresentDamage(dt.Damage())
+			} else {
+				err = w.ctx.Present()
+			}
+			w.presentTime = time.Since(start)
 			w.ctx.Unlock()
 		}
 		return err
@@ -300,6 +310,11 @@ func (w *Window) frame(frame *op.Ops, viewport image.Point) error {
 	if err != nil {
 		return err
 	}
+	if dp, ok := w.ctx.(damagePresenter); ok {
+		if pr, ok := w.gpu.(gpu.PartialRedrawer); ok {
+			pr.SetBufferAge(dp.BufferAge())
+		}
+	}
 	return w.gpu.Frame(frame, target, viewport)
 }
 
@@ -332,11 +347,14 @@ func (w *Window) processFrame(d driver, frameStart time.Time) {
 		d.EditorStateChanged(oldState, newState)
 	}
 	if q.Profiling() && w.gpu != nil {
-		frameDur := time.Since(frameStart)
-		frameDur = frameDur.Truncate(100 * time.Microsecond)
-		quantum := 100 * time.Microsecond
-		timings := fmt.Sprintf("tot:%7s %s", frameDur.Round(quantum), w.gpu.Profile())
-		q.Queue(profile.Event{Timings: timings})
+		e := profile.Event{Timings: w.gpu.Profile()}
+		if fp, ok := w.gpu.(gpu.FrameProfiler); ok {
+			e.Frame = fp.FrameProfile()
+			e.Frame.Total = time.Since(frameStart)
+			e.Frame.Present = w.presentTime
+			e.Timings = e.Frame.String()
+		}
+		q.Queue(e)
 	}
 	if t, ok := q.WakeupTime(); ok {
 		w.setNextFrame(t)
@@ -417,6 +435,45 @@ func (w *Window) Run(f func()) {
 	}
 }
 
+// RenderImage renders the operations of call into an image of size
+// pixels, with the GPU of the window. The image is transparent where the
+// operations don't draw. RenderImage fails if the window has not yet
+// drawn a frame. Like Run, RenderImage must not be called from the
+// event loop of the window except during the handling of a
+// system.FrameEvent.
+//
+// Use paint.NewRenderedImageOp to draw rendered operations without
+// reading them back from the GPU.
+func (w *Window) RenderImage(call op.CallOp, size image.Point) (*image.RGBA, error) {
+	if size.X < 0 || size.Y < 0 {
+		return nil, errors.New("app: invalid image size")
+	}
+	o := new(op.Ops)
+	call.Add(o)
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	err := errors.New("app: window closed")
+	w.Run(func() {
+		if w.gpu == nil {
+			err = errors.New("app: no GPU context")
+			return
+		}
+		r, ok := w.gpu.(gpu.ImageRenderer)
+		if !ok {
+			err = errors.New("app: GPU can't render images")
+			return
+		}
+		if err = w.ctx.Lock(); err != nil {
+			return
+		}
+		defer w.ctx.Unlock()
+		err = r.RenderImage(o, img)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
 // driverDefer is like Run but can be run from any context. It doesn't wait
 // for f to return.
 func (w *Window) driverDefer(f func(d driver)) {
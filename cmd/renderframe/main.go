@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Command renderframe renders a frame of operations written by package
+// gioui.org/op/dump to a PNG image.
+//
+// Usage:
+//
+//	renderframe [-o frame.png] [-size 800x600] [-software] frame.dump
+//
+// The frame is rendered by a headless GPU window, or by the CPU renderer
+// of package gioui.org/gpu/software if -software is specified.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gioui.org/gpu/headless"
+	"gioui.org/gpu/software"
+	"gioui.org/internal/f32color"
+	"gioui.org/op"
+	"gioui.org/op/dump"
+)
+
+var (
+	output  = flag.String("o", "", "output PNG file. The default is the input file with the extension .png")
+	size    = flag.String("size", "", "image size, WIDTHxHEIGHT. The default is the window size of the frame")
+	useSoft = flag.Bool("software", false, "render with the CPU renderer instead of a GPU")
+)
+
+// window is implemented by headless and software windows.
+type window interface {
+	Frame(ops *op.Ops) error
+	Screenshot(img *image.RGBA) error
+	Release()
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: renderframe [flags] <frame file>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0)); err != nil {
+		fmt.Fprintf(os.Stderr, "renderframe: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	frame, err := dump.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	sz := frame.Size
+	if *size != "" {
+		if _, err := fmt.Sscanf(*size, "%dx%d", &sz.X, &sz.Y); err != nil {
+			return fmt.Errorf("invalid -size %q: %w", *size, err)
+		}
+	}
+	if sz.X <= 0 || sz.Y <= 0 {
+		return errors.New("the frame has no size; specify one with -size")
+	}
+	var w window
+	if *useSoft {
+		w, err = software.NewWindow(sz.X, sz.Y)
+	} else {
+		w, err = headless.NewWindow(sz.X, sz.Y)
+	}
+	if err != nil {
+		return err
+	}
+	defer w.Release()
+	if err := w.Frame(frame.Ops); err != nil {
+		return err
+	}
+	img := image.NewRGBA(image.Rectangle{Max: sz})
+	if err := w.Screenshot(img); err != nil {
+		return err
+	}
+	out := *output
+	if out == "" {
+		out = strings.TrimSuffix(path, filepath.Ext(path)) + ".png"
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, toNRGBA(img)); err != nil {
+		return err
+	}
+	return os.WriteFile(out, buf.Bytes(), 0644)
+}
+
+// toNRGBA converts the premultiplied pixels of img to non-premultiplied
+// sRGB colors.
+func toNRGBA(img *image.RGBA) *image.NRGBA {
+	b := img.Bounds()
+	nrgba := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			nrgba.SetNRGBA(x, y, f32color.RGBAToNRGBA(img.RGBAAt(x, y)))
+		}
+	}
+	return nrgba
+}
@@ -32,6 +32,20 @@ func Parse(src []byte) (Face, error) {
 	return Face{face: face}, nil
 }
 
+// ParseCollection constructs the Faces of a font collection (.ttc or .otc)
+// from source bytes. A single font yields a single Face.
+func ParseCollection(src []byte) ([]Face, error) {
+	faces, err := font.ParseTTC(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing font collection: %w", err)
+	}
+	out := make([]Face, len(faces))
+	for i, face := range faces {
+		out[i] = Face{face: face}
+	}
+	return out, nil
+}
+
 func (f Face) Face() font.Face {
 	return f.face
 }
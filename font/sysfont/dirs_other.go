@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build !((linux && !android) || freebsd || openbsd)
+// +build !linux android
+// +build !freebsd
+// +build !openbsd
+
+package sysfont
+
+// Dirs returns the font directories of the system. It returns none on
+// platforms where they are not known.
+func Dirs() []string {
+	return nil
+}
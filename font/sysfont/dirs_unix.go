@@ -0,0 +1,47 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build (linux && !android) || freebsd || openbsd
+// +build linux,!android freebsd openbsd
+
+package sysfont
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dirs returns the font directories of the system: the directories
+// configured by fontconfig followed by the font directories of the XDG
+// base directory specification.
+func Dirs() []string {
+	home, _ := os.UserHomeDir()
+	dataHome := getenv("XDG_DATA_HOME", filepath.Join(home, ".local", "share"))
+	configHome := getenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	root := getenv("FONTCONFIG_PATH", "/etc/fonts")
+	// FONTCONFIG_PATH may list several directories; the first is the
+	// configuration directory.
+	root = strings.Split(root, ":")[0]
+	fc := &fontconfig{
+		root:       root,
+		home:       home,
+		dataHome:   dataHome,
+		configHome: configHome,
+	}
+	fc.parse(getenv("FONTCONFIG_FILE", filepath.Join(root, "fonts.conf")))
+	dirs := fc.dirs
+	dirs = append(dirs, filepath.Join(dataHome, "fonts"), filepath.Join(home, ".fonts"))
+	for _, d := range strings.Split(getenv("XDG_DATA_DIRS", "/usr/local/share:/usr/share"), ":") {
+		if d != "" {
+			dirs = append(dirs, filepath.Join(d, "fonts"))
+		}
+	}
+	return dedup(dirs)
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
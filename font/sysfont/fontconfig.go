@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package sysfont
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// fontconfig reads the font directories from fontconfig configuration
+// files, without running fontconfig itself.
+type fontconfig struct {
+	// root is the configuration directory, which relative includes are
+	// relative to.
+	root string
+	// home, dataHome and configHome are the home directory and the XDG
+	// data and configuration directories of the user, which the "~" and
+	// "xdg" prefixes refer to.
+	home, dataHome, configHome string
+
+	dirs []string
+	// parsed records the configuration files already parsed, to break
+	// include cycles.
+	parsed map[string]bool
+}
+
+// parse the configuration file or directory at path and the files it
+// includes, adding the font directories they configure.
+func (c *fontconfig) parse(path string) {
+	if c.parsed[path] {
+		return
+	}
+	if c.parsed == nil {
+		c.parsed = make(map[string]bool)
+	}
+	c.parsed[path] = true
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if info.IsDir() {
+		c.parseDir(path)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	d := xml.NewDecoder(f)
+	var (
+		start xml.StartElement
+		data  []byte
+	)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			// Keep the directories configured before a malformed
+			// element, like fontconfig.
+			return
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			start = tok
+			data = data[:0]
+			if tok.Name.Local == "reset-dirs" {
+				c.dirs = nil
+			}
+		case xml.CharData:
+			data = append(data, tok...)
+		case xml.EndElement:
+			if tok.Name.Local != start.Name.Local {
+				break
+			}
+			value := strings.TrimSpace(string(data))
+			if value == "" {
+				break
+			}
+			switch tok.Name.Local {
+			case "dir":
+				c.dirs = append(c.dirs, c.resolve(value, attr(start, "prefix"), c.dataHome, filepath.Dir(path)))
+			case "include":
+				c.parse(c.resolve(value, attr(start, "prefix"), c.configHome, c.root))
+			}
+		}
+	}
+}
+
+// parseDir parses the configuration files of a directory, which are the
+// files whose names start with a digit and end with ".conf", in the order
+// of their names.
+func (c *fontconfig) parseDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if name[0] >= '0' && name[0] <= '9' && strings.HasSuffix(name, ".conf") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.parse(filepath.Join(dir, name))
+	}
+}
+
+// resolve the path of a dir or include element with the given prefix
+// attribute. Paths with the "xdg" prefix are relative to xdg, and other
+// relative paths are relative to base.
+func (c *fontconfig) resolve(path, prefix, xdg, base string) string {
+	switch {
+	case prefix == "xdg":
+		return filepath.Join(xdg, path)
+	case path == "~" || strings.HasPrefix(path, "~/"):
+		return filepath.Join(c.home, path[1:])
+	case filepath.IsAbs(path):
+		return filepath.Clean(path)
+	}
+	return filepath.Join(base, path)
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,372 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package sysfont finds the fonts installed on the system and supplies
+// them to text shapers as fallback faces, so that text in scripts not
+// covered by the fonts of a program is still legible.
+//
+// A Source indexes the font files of a set of directories by family,
+// style, weight and the runes they cover. Only the tables describing a
+// font are read while indexing; the faces themselves are parsed the first
+// time a shaper needs them:
+//
+//	src := sysfont.NewSystem()
+//	// Index the fonts while the program starts.
+//	go src.Scan()
+//	shaper := text.NewShaper(gofont.Collection())
+//	shaper.AddSource(src)
+package sysfont
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-text/typesetting/opentype/api"
+	"github.com/go-text/typesetting/opentype/api/metadata"
+	"github.com/go-text/typesetting/opentype/loader"
+	"github.com/go-text/typesetting/opentype/tables"
+
+	"gioui.org/font/opentype"
+	"gioui.org/text"
+)
+
+// Source is a text.FontSource of the fonts in a set of directories. It is
+// safe for concurrent use, and may be shared by several shapers.
+type Source struct {
+	dirs []string
+
+	scanOnce sync.Once
+	mu       sync.Mutex
+	faces    []faceInfo
+	// files caches the parsed font files by path.
+	files map[string][]opentype.Face
+}
+
+// faceInfo is the index entry of a face of a font file.
+type faceInfo struct {
+	path string
+	// index is the index of the face in its file, for collections.
+	index int
+	font  text.Font
+	// stretch is the width of the face relative to its normal width.
+	stretch  float32
+	coverage runeSet
+	// runes is the number of runes in coverage.
+	runes int
+}
+
+// runeSet is a set of runes, stored as sorted, disjoint ranges.
+type runeSet []runeRange
+
+type runeRange struct {
+	first, last rune
+}
+
+// fontExts are the extensions of the font files indexed by a Source.
+var fontExts = map[string]bool{
+	".ttf": true,
+	".otf": true,
+	".ttc": true,
+	".otc": true,
+}
+
+// New returns a source of the fonts in dirs and their subdirectories. The
+// directories are indexed by Scan, or the first time the source is used.
+func New(dirs ...string) *Source {
+	return &Source{dirs: dirs}
+}
+
+// NewSystem returns a source of the fonts in the font directories of the
+// system, as returned by Dirs.
+func NewSystem() *Source {
+	return New(Dirs()...)
+}
+
+// Families returns the sorted names of the font families of the source.
+func (s *Source) Families() []string {
+	s.Scan()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var families []string
+	for _, f := range s.faces {
+		families = append(families, string(f.font.Typeface))
+	}
+	sort.Strings(families)
+	return dedup(families)
+}
+
+// Faces loads the faces of a family, whose name is matched without regard
+// to case. It is useful for using a system font as the primary font of a
+// shaper.
+func (s *Source) Faces(family string) ([]text.FontFace, error) {
+	s.Scan()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var faces []text.FontFace
+	for i := range s.faces {
+		if !strings.EqualFold(string(s.faces[i].font.Typeface), family) {
+			continue
+		}
+		f, err := s.load(i)
+		if err != nil {
+			return nil, err
+		}
+		faces = append(faces, f)
+	}
+	return faces, nil
+}
+
+// Fallback implements text.FontSource. Of the faces covering r, it prefers
+// faces of the typeface of fnt, then faces whose family name contains its
+// variant, then faces closest to its style and weight, and finally the
+// faces covering the most runes.
+func (s *Source) Fallback(fnt text.Font, r rune) (text.FontFace, bool) {
+	s.Scan()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		best := -1
+		for i := range s.faces {
+			f := &s.faces[i]
+			if !f.coverage.contains(r) {
+				continue
+			}
+			if best == -1 || better(fnt, f, &s.faces[best]) {
+				best = i
+			}
+		}
+		if best == -1 {
+			return text.FontFace{}, false
+		}
+		f, err := s.load(best)
+		if err == nil {
+			return f, true
+		}
+		// Skip the face from now on.
+		s.faces[best].coverage = nil
+	}
+}
+
+// better reports whether a is a better match for fnt than b.
+func better(fnt text.Font, a, b *faceInfo) bool {
+	if ma, mb := strings.EqualFold(string(a.font.Typeface), string(fnt.Typeface)), strings.EqualFold(string(b.font.Typeface), string(fnt.Typeface)); ma != mb {
+		return ma
+	}
+	if v := strings.ToLower(string(fnt.Variant)); v != "" {
+		ma := strings.Contains(strings.ToLower(string(a.font.Typeface)), v)
+		mb := strings.Contains(strings.ToLower(string(b.font.Typeface)), v)
+		if ma != mb {
+			return ma
+		}
+	}
+	if ma, mb := a.font.Style == fnt.Style, b.font.Style == fnt.Style; ma != mb {
+		return ma
+	}
+	if da, db := weightDistance(a.font.Weight, fnt.Weight), weightDistance(b.font.Weight, fnt.Weight); da != db {
+		return da < db
+	}
+	if da, db := math.Abs(float64(a.stretch-1)), math.Abs(float64(b.stretch-1)); da != db {
+		return da < db
+	}
+	return a.runes > b.runes
+}
+
+func weightDistance(a, b text.Weight) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// load the face at index i of the index. It must be called with the
+// mutex held.
+func (s *Source) load(i int) (text.FontFace, error) {
+	f := &s.faces[i]
+	faces, ok := s.files[f.path]
+	if !ok {
+		src, err := os.ReadFile(f.path)
+		if err == nil {
+			faces, err = opentype.ParseCollection(src)
+		}
+		if err != nil {
+			return text.FontFace{}, fmt.Errorf("sysfont: %s: %w", f.path, err)
+		}
+		if s.files == nil {
+			s.files = make(map[string][]opentype.Face)
+		}
+		s.files[f.path] = faces
+	}
+	if f.index >= len(faces) {
+		return text.FontFace{}, fmt.Errorf("sysfont: %s: missing face %d", f.path, f.index)
+	}
+	return text.FontFace{Font: f.font, Face: faces[f.index]}, nil
+}
+
+// Scan indexes the font files of the directories of the source, unless
+// they are indexed already. Otherwise, the source indexes them the first
+// time it is used, which delays the layout of the text using it. Call
+// Scan in a goroutine to index the fonts in the background:
+//
+//	src := sysfont.NewSystem()
+//	go src.Scan()
+func (s *Source) Scan() {
+	s.scanOnce.Do(func() {
+		seen := make(map[string]bool)
+		var faces []faceInfo
+		for _, dir := range s.dirs {
+			filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+				if err != nil || d.IsDir() || seen[path] || !fontExts[strings.ToLower(filepath.Ext(path))] {
+					return nil
+				}
+				seen[path] = true
+				faces = append(faces, indexFile(path)...)
+				return nil
+			})
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.faces = uniqueFonts(faces)
+	})
+}
+
+// uniqueFonts removes the faces whose text.Font equals that of another
+// face, keeping the face of normal width or else the first face. The
+// shapers identify faces by their text.Font.
+func uniqueFonts(faces []faceInfo) []faceInfo {
+	sort.SliceStable(faces, func(i, j int) bool {
+		a, b := faces[i].font, faces[j].font
+		if a.Typeface != b.Typeface {
+			return a.Typeface < b.Typeface
+		}
+		if a.Style != b.Style {
+			return a.Style < b.Style
+		}
+		if a.Weight != b.Weight {
+			return a.Weight < b.Weight
+		}
+		return math.Abs(float64(faces[i].stretch-1)) < math.Abs(float64(faces[j].stretch-1))
+	})
+	unique := faces[:0]
+	for _, f := range faces {
+		if n := len(unique); n > 0 && unique[n-1].font == f.font {
+			continue
+		}
+		unique = append(unique, f)
+	}
+	return unique
+}
+
+// indexFile returns the index entries of the faces of a font file, or
+// none if the file can't be read.
+func indexFile(path string) []faceInfo {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	lds, err := loader.NewLoaders(f)
+	if err != nil {
+		return nil
+	}
+	var faces []faceInfo
+	for i, ld := range lds {
+		aspect, family := metadata.Metadata(ld)
+		coverage, err := readCoverage(ld)
+		if err != nil || family == "" || len(coverage) == 0 {
+			continue
+		}
+		fnt := text.Font{
+			Typeface: text.Typeface(family),
+			Weight:   weight(aspect.Weight),
+		}
+		if aspect.Style == metadata.StyleItalic {
+			fnt.Style = text.Italic
+		}
+		faces = append(faces, faceInfo{
+			path:     path,
+			index:    i,
+			font:     fnt,
+			stretch:  float32(aspect.Stretch),
+			coverage: coverage,
+			runes:    coverage.len(),
+		})
+	}
+	return faces
+}
+
+// weight converts a CSS font weight to the closest text.Weight.
+func weight(w metadata.Weight) text.Weight {
+	css := math.Round(float64(w)/100) * 100
+	css = math.Max(100, math.Min(css, 900))
+	return text.Weight(css) - 400
+}
+
+// readCoverage returns the runes mapped by the cmap table of a font.
+func readCoverage(ld *loader.Loader) (runeSet, error) {
+	raw, err := ld.RawTable(loader.MustNewTag("cmap"))
+	if err != nil {
+		return nil, err
+	}
+	table, _, err := tables.ParseCmap(raw)
+	if err != nil {
+		return nil, err
+	}
+	cmap, _, err := api.ProcessCmap(table)
+	if err != nil {
+		return nil, err
+	}
+	var runes []rune
+	for it := cmap.Iter(); it.Next(); {
+		r, _ := it.Char()
+		runes = append(runes, r)
+	}
+	return newRuneSet(runes), nil
+}
+
+// newRuneSet returns the set of runes, sorting them in place.
+func newRuneSet(runes []rune) runeSet {
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	var set runeSet
+	for _, r := range runes {
+		if n := len(set); n > 0 && r <= set[n-1].last+1 {
+			if r > set[n-1].last {
+				set[n-1].last = r
+			}
+			continue
+		}
+		set = append(set, runeRange{first: r, last: r})
+	}
+	return set
+}
+
+func (s runeSet) contains(r rune) bool {
+	i := sort.Search(len(s), func(i int) bool { return s[i].last >= r })
+	return i < len(s) && s[i].first <= r
+}
+
+// len returns the number of runes in the set.
+func (s runeSet) len() int {
+	n := 0
+	for _, r := range s {
+		n += int(r.last-r.first) + 1
+	}
+	return n
+}
+
+// dedup removes the duplicates of a list, keeping the first occurrences.
+func dedup(list []string) []string {
+	seen := make(map[string]bool)
+	out := list[:0]
+	for _, s := range list {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
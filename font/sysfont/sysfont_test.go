@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package sysfont
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	nsareg "eliasnaur.com/font/noto/sans/arabic/regular"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+
+	"gioui.org/text"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string][]byte) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string][]byte{
+		"go/Go-Regular.ttf":         goregular.TTF,
+		"go/Go-Bold.TTF":            gobold.TTF,
+		"go/Go-Italic.ttf":          goitalic.TTF,
+		"noto/NotoSansArabic.ttf":   nsareg.TTF,
+		"noto/NotoSansArabic.woff2": nsareg.TTF,
+		"broken/Broken.ttf":         []byte("not a font"),
+	})
+	// Nested directories must not index files twice.
+	src := New(dir, filepath.Join(dir, "go"))
+	if got, want := src.Families(), []string{"Go", "Noto Sans Arabic"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got families %q, want %q", got, want)
+	}
+	faces, err := src.Faces("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(faces) != 3 {
+		t.Errorf("got %d faces of Go, want 3", len(faces))
+	}
+
+	type testcase struct {
+		font text.Font
+		r    rune
+		want text.Font
+		ok   bool
+	}
+	for _, tc := range []testcase{
+		{font: text.Font{}, r: 'a', want: text.Font{Typeface: "Go"}, ok: true},
+		{font: text.Font{Weight: text.Bold}, r: 'a', want: text.Font{Typeface: "Go", Weight: text.Bold}, ok: true},
+		{font: text.Font{Weight: text.Black}, r: 'a', want: text.Font{Typeface: "Go", Weight: text.Bold}, ok: true},
+		{font: text.Font{Style: text.Italic, Weight: text.Bold}, r: 'a', want: text.Font{Typeface: "Go", Style: text.Italic}, ok: true},
+		{font: text.Font{Weight: text.Bold}, r: 'ب', want: text.Font{Typeface: "Noto Sans Arabic"}, ok: true},
+		// Prefer the requested typeface even if others cover the rune.
+		{font: text.Font{Typeface: "Noto Sans Arabic"}, r: ' ', want: text.Font{Typeface: "Noto Sans Arabic"}, ok: true},
+		{font: text.Font{}, r: '中', ok: false},
+	} {
+		f, ok := src.Fallback(tc.font, tc.r)
+		if ok != tc.ok {
+			t.Errorf("Fallback(%+v, %q): got ok %v, want %v", tc.font, tc.r, ok, tc.ok)
+			continue
+		}
+		if f.Font != tc.want {
+			t.Errorf("Fallback(%+v, %q): got %+v, want %+v", tc.font, tc.r, f.Font, tc.want)
+		}
+		if ok {
+			if _, covered := f.Face.Face().NominalGlyph(tc.r); !covered {
+				t.Errorf("Fallback(%+v, %q): face doesn't cover the rune", tc.font, tc.r)
+			}
+		}
+	}
+	// Faces are parsed once.
+	f1, _ := src.Fallback(text.Font{}, 'a')
+	f2, _ := src.Fallback(text.Font{}, 'b')
+	if f1.Face.Face() != f2.Face.Face() {
+		t.Error("a face was parsed twice")
+	}
+
+	// A source indexing in the background must wait for the index.
+	bg := New(dir)
+	go bg.Scan()
+	if got, want := bg.Families(), src.Families(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got families %q while scanning, want %q", got, want)
+	}
+}
+
+func TestRuneSet(t *testing.T) {
+	set := newRuneSet([]rune{'c', 'a', 'b', 'x', 'z', 'b'})
+	if want := (runeSet{{'a', 'c'}, {'x', 'x'}, {'z', 'z'}}); !reflect.DeepEqual(set, want) {
+		t.Errorf("got %v, want %v", set, want)
+	}
+	if n := set.len(); n != 5 {
+		t.Errorf("got %d runes, want 5", n)
+	}
+	for r, want := range map[rune]bool{'a': true, 'b': true, 'c': true, 'd': false, 'x': true, 'y': false, 'z': true, '0': false, '~': false} {
+		if got := set.contains(r); got != want {
+			t.Errorf("contains(%q) = %v, want %v", r, got, want)
+		}
+	}
+}
+
+func TestFontconfig(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fontconfig paths are Unix paths")
+	}
+	dir := t.TempDir()
+	root := filepath.Join(dir, "etc", "fonts")
+	home := filepath.Join(dir, "home")
+	writeFiles(t, root, map[string][]byte{
+		"fonts.conf": []byte(`<?xml version="1.0"?>
+<!DOCTYPE fontconfig SYSTEM "urn:fontconfig:fonts.dtd">
+<fontconfig>
+	<dir>/usr/share/fonts</dir>
+	<dir prefix="xdg">fonts</dir>
+	<dir>~/.fonts</dir>
+	<cachedir>/var/cache/fontconfig</cachedir>
+	<include ignore_missing="yes">conf.d</include>
+	<include ignore_missing="yes">missing.conf</include>
+</fontconfig>`),
+		"conf.d/20-extra.conf": []byte(`<fontconfig>
+	<dir prefix="relative">extra</dir>
+	<include>local.conf</include>
+</fontconfig>`),
+		"conf.d/10-first.conf": []byte(`<fontconfig><dir> /opt/fonts </dir></fontconfig>`),
+		"conf.d/README":        []byte(`<fontconfig><dir>/ignored</dir></fontconfig>`),
+		"conf.d/30-cycle.conf": []byte(`<fontconfig><include>fonts.conf</include></fontconfig>`),
+		"local.conf":           []byte(`<fontconfig><dir>/local/fonts</dir></fontconfig>`),
+	})
+	fc := &fontconfig{
+		root:       root,
+		home:       home,
+		dataHome:   filepath.Join(home, ".local", "share"),
+		configHome: filepath.Join(home, ".config"),
+	}
+	fc.parse(filepath.Join(root, "fonts.conf"))
+	want := []string{
+		"/usr/share/fonts",
+		filepath.Join(home, ".local", "share", "fonts"),
+		filepath.Join(home, ".fonts"),
+		"/opt/fonts",
+		filepath.Join(root, "conf.d", "extra"),
+		"/local/fonts",
+	}
+	if !reflect.DeepEqual(fc.dirs, want) {
+		t.Errorf("got directories\n%q\nwant\n%q", fc.dirs, want)
+	}
+}
@@ -0,0 +1,272 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package golden implements golden image tests for widgets.
+
+A golden image test lays out and renders a widget, and compares the
+result with a reference image, the golden image, stored in the
+package's testdata directory:
+
+	func TestButton(t *testing.T) {
+		golden.Check(t, "button", golden.Options{Size: image.Pt(200, 50)}, func(gtx layout.Context) layout.Dimensions {
+			return material.Button(th, &btn, "Click").Layout(gtx)
+		})
+	}
+
+Widgets are rendered with the software renderer of package
+gioui.org/gpu/software, so golden images don't depend on the GPU or
+drivers of the machine running the tests.
+
+When a widget differs from its golden image, the test fails and the
+rendered image and an image of the differences are written next to the
+golden image, with the suffixes ".actual.png" and ".diff.png".
+
+Run the tests with the -update flag to write the golden images instead
+of comparing against them:
+
+	go test -update
+*/
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gioui.org/gpu/software"
+	"gioui.org/internal/f32color"
+	"gioui.org/io/router"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/unit"
+)
+
+// Update is set by the -update flag. If true, Check and CheckImage
+// write golden images instead of comparing against them.
+var Update = flag.Bool("update", false, "update golden images")
+
+// Options control the rendering and comparison of golden image tests.
+type Options struct {
+	// Size is the size in pixels of the rendered image. The widget is
+	// laid out with exact constraints of the same size.
+	Size image.Point
+	// Metric is the metric of the layout context. The zero value maps
+	// both dp and sp to one pixel.
+	Metric unit.Metric
+	// Tolerance is the largest difference allowed between each color
+	// component of the rendered and golden pixels, in the premultiplied
+	// sRGB encoding of image.RGBA.
+	Tolerance uint8
+	// Dir is the directory of the golden images. The default is
+	// "testdata".
+	Dir string
+}
+
+// Render lays out the widget w and renders it to an image.
+func Render(opts Options, w layout.Widget) (*image.RGBA, error) {
+	if opts.Size.X <= 0 || opts.Size.Y <= 0 {
+		return nil, fmt.Errorf("golden: invalid size %v", opts.Size)
+	}
+	win, err := software.NewWindow(opts.Size.X, opts.Size.Y)
+	if err != nil {
+		return nil, err
+	}
+	defer win.Release()
+	ops := new(op.Ops)
+	gtx := layout.Context{
+		Ops:         ops,
+		Metric:      opts.metric(),
+		Queue:       new(router.Router),
+		Constraints: layout.Exact(opts.Size),
+	}
+	w(gtx)
+	if err := win.Frame(ops); err != nil {
+		return nil, err
+	}
+	img := image.NewRGBA(image.Rectangle{Max: opts.Size})
+	if err := win.Screenshot(img); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// Check renders the widget w and compares it with the golden image
+// called name, as described by CheckImage.
+func Check(t testing.TB, name string, opts Options, w layout.Widget) {
+	t.Helper()
+	img, err := Render(opts, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	CheckImage(t, name, opts, img)
+}
+
+// CheckImage compares img with the golden image called name and fails
+// the test if their sizes differ or a pixel differs by more than the
+// tolerance. On failure, img and an image of the differing pixels are
+// written next to the golden image.
+//
+// If Update is set, CheckImage writes img as the golden image instead.
+func CheckImage(t testing.TB, name string, opts Options, img *image.RGBA) {
+	t.Helper()
+	path := opts.path(name)
+	if *Update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveImage(path, img); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := loadImage(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("golden: missing golden image %s; run the test with -update to create it", path)
+		}
+		t.Fatal(err)
+	}
+	// Compare the image as it would be stored, because the conversion
+	// to non-premultiplied colors is lossy for translucent pixels.
+	diff, n := Diff(want, fromImage(toNRGBA(img)), opts.Tolerance)
+	if n == 0 {
+		return
+	}
+	base := path[:len(path)-len(filepath.Ext(path))]
+	actualPath, diffPath := base+".actual.png", base+".diff.png"
+	if err := saveImage(actualPath, img); err != nil {
+		t.Error(err)
+	}
+	if err := saveImage(diffPath, diff); err != nil {
+		t.Error(err)
+	}
+	if want.Bounds() != img.Bounds() {
+		t.Errorf("golden: image is %v, golden image %s is %v (wrote %s)", img.Bounds(), path, want.Bounds(), actualPath)
+		return
+	}
+	t.Errorf("golden: %d pixels differ from golden image %s (wrote %s and %s)", n, path, actualPath, diffPath)
+}
+
+// Diff compares the images got and want and returns the number of
+// pixels whose color components differ by more than tolerance, along
+// with an image that marks those pixels in red on a faded copy of want.
+// Pixels outside the intersection of the image bounds always differ.
+func Diff(want, got *image.RGBA, tolerance uint8) (*image.RGBA, int) {
+	bounds := want.Bounds().Union(got.Bounds())
+	diff := image.NewRGBA(bounds)
+	n := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			p := image.Pt(x, y)
+			if !p.In(want.Bounds()) || !p.In(got.Bounds()) {
+				diff.SetRGBA(x, y, color.RGBA{R: 0xff, A: 0xff})
+				n++
+				continue
+			}
+			c1, c2 := want.RGBAAt(x, y), got.RGBAAt(x, y)
+			if near(c1, c2, tolerance) {
+				diff.SetRGBA(x, y, fade(c1))
+				continue
+			}
+			diff.SetRGBA(x, y, color.RGBA{R: 0xff, A: 0xff})
+			n++
+		}
+	}
+	return diff, n
+}
+
+func (o Options) metric() unit.Metric {
+	m := o.Metric
+	if m == (unit.Metric{}) {
+		m = unit.Metric{PxPerDp: 1, PxPerSp: 1}
+	}
+	return m
+}
+
+func (o Options) path(name string) string {
+	dir := o.Dir
+	if dir == "" {
+		dir = "testdata"
+	}
+	return filepath.Join(dir, name+".png")
+}
+
+func near(c1, c2 color.RGBA, tolerance uint8) bool {
+	d := func(a, b uint8) bool {
+		if a < b {
+			a, b = b, a
+		}
+		return a-b <= tolerance
+	}
+	return d(c1.R, c2.R) && d(c1.G, c2.G) && d(c1.B, c2.B) && d(c1.A, c2.A)
+}
+
+// fade returns the opaque gray that represents a matching pixel in
+// diff images.
+func fade(c color.RGBA) color.RGBA {
+	// Luminance of the premultiplied color composited onto white.
+	y := (299*uint32(c.R) + 587*uint32(c.G) + 114*uint32(c.B)) / 1000
+	y += 0xff - uint32(c.A)
+	if y > 0xff {
+		y = 0xff
+	}
+	g := uint8(0xc0 + y/4)
+	return color.RGBA{R: g, G: g, B: g, A: 0xff}
+}
+
+// saveImage writes img as a PNG file.
+func saveImage(path string, img *image.RGBA) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, toNRGBA(img)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// loadImage reads a PNG file written by saveImage.
+func loadImage(path string) (*image.RGBA, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("golden: %s: %w", path, err)
+	}
+	return fromImage(img), nil
+}
+
+// toNRGBA converts img to non-premultiplied colors. Like the reference
+// images of the GPU renderers, image files store the linear
+// premultiplied pixels of renderers as non-premultiplied sRGB colors.
+func toNRGBA(img *image.RGBA) *image.NRGBA {
+	b := img.Bounds()
+	nrgba := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			nrgba.SetNRGBA(x, y, f32color.RGBAToNRGBA(img.RGBAAt(x, y)))
+		}
+	}
+	return nrgba
+}
+
+// fromImage is the inverse of toNRGBA.
+func fromImage(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	img := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			img.SetRGBA(x, y, f32color.NRGBAToRGBA(c))
+		}
+	}
+	return img
+}
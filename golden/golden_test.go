@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package golden
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gioui.org/layout"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+)
+
+// recorder records the failures of a test instead of failing it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Error(args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprint(args...))
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func square(col color.NRGBA) layout.Widget {
+	return func(gtx layout.Context) layout.Dimensions {
+		sz := gtx.Dp(10)
+		paint.FillShape(gtx.Ops, col, clip.Rect{Min: image.Pt(sz, sz), Max: image.Pt(2*sz, 2*sz)}.Op())
+		// A translucent shape, to exercise the lossy conversion of
+		// translucent pixels.
+		paint.FillShape(gtx.Ops, color.NRGBA{B: 0xff, A: 0x33}, clip.Ellipse{Max: image.Pt(sz, sz)}.Op(gtx.Ops))
+		return layout.Dimensions{Size: gtx.Constraints.Max}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	opts := Options{
+		Size:   image.Pt(40, 40),
+		Metric: unit.Metric{PxPerDp: 2},
+		Dir:    t.TempDir(),
+	}
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	setUpdate(t, true)
+	Check(t, "square", opts, square(red))
+	setUpdate(t, false)
+	Check(t, "square", opts, square(red))
+
+	r := &recorder{TB: t}
+	Check(r, "square", opts, square(color.NRGBA{G: 0xff, A: 0xff}))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "400 pixels differ") {
+		t.Errorf("got errors %q, expected 400 differing pixels", r.errors)
+	}
+	for _, name := range []string{"square.actual.png", "square.diff.png"} {
+		if _, err := os.Stat(filepath.Join(opts.Dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	r = &recorder{TB: t}
+	opts.Size = image.Pt(30, 30)
+	Check(r, "square", opts, square(red))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "golden image") {
+		t.Errorf("got errors %q, expected a size mismatch", r.errors)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	want := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	got := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	want.SetRGBA(0, 0, color.RGBA{R: 100, A: 0xff})
+	got.SetRGBA(0, 0, color.RGBA{R: 103, A: 0xff})
+	if _, n := Diff(want, got, 3); n != 0 {
+		t.Errorf("got %d differing pixels with tolerance, expected 0", n)
+	}
+	diff, n := Diff(want, got, 2)
+	if n != 1 {
+		t.Errorf("got %d differing pixels, expected 1", n)
+	}
+	if c := diff.RGBAAt(0, 0); c != (color.RGBA{R: 0xff, A: 0xff}) {
+		t.Errorf("got diff color %v, expected red", c)
+	}
+	if c := diff.RGBAAt(1, 0); c.A != 0xff || c.R != c.G || c.R != c.B {
+		t.Errorf("got diff color %v, expected gray", c)
+	}
+}
+
+func setUpdate(t *testing.T, v bool) {
+	old := *Update
+	*Update = v
+	t.Cleanup(func() { *Update = old })
+}
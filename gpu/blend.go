@@ -0,0 +1,503 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/gpu/internal/driver"
+	"gioui.org/internal/blend"
+	"gioui.org/internal/blur"
+	"gioui.org/shader"
+)
+
+// layerBlender blends layers with modes that have no fixed-function
+// blending equivalent, and blurs the backdrops of blur layers. It
+// copies the backdrop of a layer to a texture and composites the layer
+// onto the copy with a fragment program. Blurs are separable Gaussians
+// drawn in a horizontal and a vertical pass.
+//
+// The programs exist only for the OpenGL backend. Other backends read
+// back the layer and its backdrop, blend them on the CPU and replace
+// the backdrop with the result.
+type layerBlender struct {
+	// composite and gaussian are the programs, or progErr the error
+	// from creating them.
+	composite *pipeline
+	gaussian  *pipeline
+	progErr   error
+	// compUniforms and blurUniforms are the uniforms of composite and
+	// gaussian.
+	compUniforms *blendUniforms
+	blurUniforms *blurUniforms
+	// fbos holds the copies of the backdrop and the layer, and the
+	// passes of blurs.
+	fbos  fboSet
+	sizes []image.Point
+	// fbo and pix hold the blended pixels of the CPU fallback.
+	fbo fboSet
+	pix []byte
+}
+
+// blendUniforms are the uniforms of the composite program: the
+// uniforms of the blit vertex program followed by the transformation
+// from layer to backdrop texture coordinates and the blend mode.
+type blendUniforms struct {
+	blitUniforms
+	// backdrop is the scale (xy) and offset (zw) of the backdrop
+	// texture coordinates.
+	backdrop [4]float32
+	mode     float32
+	_        [3]float32
+}
+
+// blurUniforms are the uniforms of the gaussian program: the uniforms
+// of the blit vertex program followed by the distance between taps in
+// texture coordinates (xy), the standard deviation (z) and radius (w)
+// of the Gaussian in taps, and the bounds of the texture coordinates.
+type blurUniforms struct {
+	blitUniforms
+	step   [4]float32
+	bounds [4]float32
+}
+
+// maxBlurTaps is the largest radius of a blur pass. Wider blurs are
+// split into repeated passes.
+const maxBlurTaps = 64
+
+// blend blends the area src of the layer texture srcTex into the area
+// dst of the layer texture target with mode. If sigma is positive, the
+// layer is instead a mask of where to blur the target by a Gaussian
+// with standard deviation sigma. The current render pass must draw to
+// target with the viewport vp, and dst is relative to vp. The render
+// pass is ended and restarted.
+func (lb *layerBlender) blend(ctx driver.Device, b *blitter, mode blend.Mode, sigma float32, target driver.Texture, vp, dst image.Rectangle, srcTex driver.Texture, src image.Rectangle) error {
+	ctx.EndRenderPass()
+	// The blurred area includes the backdrop around dst that blurs
+	// into it.
+	back := dst
+	if sigma > 0 {
+		back = dst.Inset(-blur.Margin(sigma)).Intersect(image.Rectangle{Max: vp.Size()})
+	}
+	area := back
+	// off is the position of dst in the backdrop.
+	off := dst.Min.Sub(back.Min)
+	if ctx.Caps().BottomLeftOrigin {
+		// Layers are drawn upside down in bottom-left origin
+		// textures, see layerUVTransform.
+		area.Min.Y, area.Max.Y = vp.Dy()-back.Max.Y, vp.Dy()-back.Min.Y
+		off.Y = back.Max.Y - dst.Max.Y
+	}
+	area = area.Add(vp.Min)
+	onGPU := lb.init(ctx)
+	var err error
+	if onGPU {
+		lb.blendGPU(ctx, b, sigma, target, area, srcTex, src)
+	} else {
+		err = lb.blendCPU(ctx, mode, sigma, target, area, off, srcTex, src)
+	}
+	ctx.BeginRenderPass(target, driver.LoadDesc{Action: driver.LoadActionKeep})
+	ctx.Viewport(vp.Min.X, vp.Min.Y, vp.Dx(), vp.Dy())
+	if err != nil {
+		return err
+	}
+	scale, pos := clipSpaceTransform(dst, vp.Size())
+	if !onGPU {
+		// The blended pixels replace the backdrop.
+		res := lb.fbo.fbos[0]
+		ctx.BindTexture(0, res.tex)
+		b.replace(scale, pos, layerUVTransform(ctx.Caps(), image.Point{}, dst.Size(), res.size))
+		return nil
+	}
+	lb.draw(ctx, b, mode, sigma, scale, pos, off)
+	return nil
+}
+
+// init creates the programs, and reports whether they are available.
+func (lb *layerBlender) init(ctx driver.Device) bool {
+	if lb.composite != nil || lb.progErr != nil {
+		return lb.progErr == nil
+	}
+	lb.compUniforms = new(blendUniforms)
+	lb.blurUniforms = new(blurUniforms)
+	composite, err := createLayerPipeline(ctx, shader_composite_frag, driver.BlendDesc{}, lb.compUniforms)
+	if err != nil {
+		lb.progErr = err
+		return false
+	}
+	gaussian, err := createLayerPipeline(ctx, shader_gaussian_frag, driver.BlendDesc{}, lb.blurUniforms)
+	if err != nil {
+		composite.Release()
+		lb.progErr = err
+		return false
+	}
+	lb.composite, lb.gaussian = composite, gaussian
+	return true
+}
+
+// blendGPU copies the area of target and the area src of srcTex to
+// the first two textures of fbos. If sigma is positive, it blurs the
+// backdrop into the last texture of fbos.
+func (lb *layerBlender) blendGPU(ctx driver.Device, b *blitter, sigma float32, target driver.Texture, area image.Rectangle, srcTex driver.Texture, src image.Rectangle) {
+	bsz := area.Size()
+	// The layer is copied too, because it may share its texture with
+	// target.
+	lb.sizes = append(lb.sizes[:0], bsz, src.Size())
+	if sigma > 0 {
+		lb.sizes = append(lb.sizes, bsz, bsz)
+	}
+	lb.fbos.resize(ctx, pixelFormats[formatLayer], lb.sizes)
+	fbos := lb.fbos.fbos
+	ctx.CopyTexture(fbos[0].tex, image.Point{}, target, area)
+	ctx.CopyTexture(fbos[1].tex, image.Point{}, srcTex, src)
+	ctx.PrepareTexture(fbos[0].tex)
+	ctx.PrepareTexture(fbos[1].tex)
+	if sigma <= 0 {
+		return
+	}
+	// Split wide Gaussians into repeated narrower Gaussians, whose
+	// variances add up to the variance of the wide Gaussian.
+	passes := 1
+	for blur.Margin(sigma/float32(math.Sqrt(float64(passes)))) > maxBlurTaps {
+		passes++
+	}
+	s := sigma / float32(math.Sqrt(float64(passes)))
+	from := fbos[0]
+	for i := 0; i < passes; i++ {
+		lb.blurPass(ctx, b, from, fbos[2], bsz, s, image.Pt(1, 0))
+		lb.blurPass(ctx, b, fbos[2], fbos[3], bsz, s, image.Pt(0, 1))
+		from = fbos[3]
+	}
+}
+
+// blurPass blurs the area of the given size at the origin of src into
+// dst, along dir.
+func (lb *layerBlender) blurPass(ctx driver.Device, b *blitter, src, dst stencilFBO, size image.Point, sigma float32, dir image.Point) {
+	ctx.BeginRenderPass(dst.tex, driver.LoadDesc{Action: driver.LoadActionInvalidate})
+	ctx.Viewport(0, 0, size.X, size.Y)
+	p := lb.gaussian
+	ctx.BindPipeline(p.pipeline)
+	ctx.BindVertexBuffer(b.quadVerts, 0)
+	ctx.BindTexture(0, src.tex)
+	u := lb.blurUniforms
+	scale, off := clipSpaceTransform(image.Rectangle{Max: size}, size)
+	u.transform = [4]float32{scale.X, scale.Y, off.X, off.Y}
+	t1, t2, t3, t4, t5, t6 := layerUVTransform(ctx.Caps(), image.Point{}, size, src.size).Elems()
+	u.uvTransformR1 = [4]float32{t1, t2, t3, 0}
+	u.uvTransformR2 = [4]float32{t4, t5, t6, 0}
+	w, h := float32(src.size.X), float32(src.size.Y)
+	u.step = [4]float32{float32(dir.X) / w, float32(dir.Y) / h, sigma, float32(blur.Margin(sigma))}
+	// Clamp taps to the centers of the edge pixels of the area.
+	u.bounds = [4]float32{.5 / w, .5 / h, (float32(size.X) - .5) / w, (float32(size.Y) - .5) / h}
+	p.UploadUniforms(ctx)
+	ctx.DrawArrays(0, 4)
+	ctx.EndRenderPass()
+	ctx.PrepareTexture(dst.tex)
+}
+
+// draw composites the layer copy onto the backdrop copy, or mixes the
+// backdrop with its blurred copy where the layer covers it, and draws
+// the result to the area given by scale and pos of the current render
+// pass. The position of the layer in the backdrop is off.
+func (lb *layerBlender) draw(ctx driver.Device, b *blitter, mode blend.Mode, sigma float32, scale, pos f32.Point, off image.Point) {
+	fbos := lb.fbos.fbos
+	back, layer, blurred := fbos[0], fbos[1], fbos[0]
+	if sigma > 0 {
+		blurred = fbos[3]
+	}
+	p := lb.composite
+	ctx.BindPipeline(p.pipeline)
+	ctx.BindVertexBuffer(b.quadVerts, 0)
+	ctx.BindTexture(0, layer.tex)
+	ctx.BindTexture(1, back.tex)
+	ctx.BindTexture(2, blurred.tex)
+	u := lb.compUniforms
+	u.transform = [4]float32{scale.X, scale.Y, pos.X, pos.Y}
+	sz := lb.sizes[1]
+	uv := layerUVTransform(ctx.Caps(), image.Point{}, sz, layer.size)
+	t1, t2, t3, t4, t5, t6 := uv.Elems()
+	u.uvTransformR1 = [4]float32{t1, t2, t3, 0}
+	u.uvTransformR2 = [4]float32{t4, t5, t6, 0}
+	// Map layer texture coordinates to backdrop texture
+	// coordinates. Both transformations scale and offset only.
+	buv := layerUVTransform(ctx.Caps(), off, sz, back.size)
+	sx, _, ox, _, sy, oy := buv.Mul(uv.Invert()).Elems()
+	u.backdrop = [4]float32{sx, sy, ox, oy}
+	u.mode = float32(mode)
+	p.UploadUniforms(ctx)
+	ctx.DrawArrays(0, 4)
+}
+
+// blendCPU reads back the area src of srcTex and the area of target,
+// blends or blurs them on the CPU, and uploads the result to the first
+// texture of fbo.
+func (lb *layerBlender) blendCPU(ctx driver.Device, mode blend.Mode, sigma float32, target driver.Texture, area image.Rectangle, off image.Point, srcTex driver.Texture, src image.Rectangle) error {
+	size := src.Size()
+	n := size.X * size.Y * 4
+	bsz := area.Size()
+	bn := 0
+	if sigma > 0 {
+		bn = bsz.X * bsz.Y * 4
+	}
+	if cap(lb.pix) < 2*n+bn {
+		lb.pix = make([]byte, 2*n+bn)
+	}
+	dstPix, srcPix := lb.pix[:n], lb.pix[n:2*n]
+	backPix := dstPix
+	if sigma > 0 {
+		backPix = lb.pix[2*n : 2*n+bn]
+	}
+	if err := srcTex.ReadPixels(src, srcPix, size.X*4); err != nil {
+		return err
+	}
+	if err := target.ReadPixels(area, backPix, bsz.X*4); err != nil {
+		return err
+	}
+	if sigma > 0 {
+		img := &image.RGBA{Pix: backPix, Stride: bsz.X * 4, Rect: image.Rectangle{Max: bsz}}
+		blurred := image.NewRGBA(img.Rect)
+		copy(blurred.Pix, img.Pix)
+		blur.Gaussian(blurred, sigma)
+		// Crop the backdrop and its blurred copy to dst, upside down
+		// in bottom-left origin textures.
+		for y := 0; y < size.Y; y++ {
+			row := dstPix[y*size.X*4 : (y+1)*size.X*4]
+			mask := srcPix[y*size.X*4 : (y+1)*size.X*4]
+			copy(row, img.Pix[img.PixOffset(off.X, off.Y+y):])
+			blur.Mask(row, blurred.Pix[blurred.PixOffset(off.X, off.Y+y):][:len(row)], mask)
+		}
+	} else {
+		mode.Composite(dstPix, srcPix)
+	}
+	lb.fbo.resize(ctx, pixelFormats[formatLayer], []image.Point{size})
+	res := lb.fbo.fbos[0]
+	res.tex.Upload(image.Point{}, size, dstPix, size.X*4)
+	ctx.PrepareTexture(res.tex)
+	return nil
+}
+
+func (lb *layerBlender) release(ctx driver.Device) {
+	if lb.composite != nil {
+		lb.composite.Release()
+		lb.gaussian.Release()
+	}
+	lb.fbos.delete(ctx, 0)
+	lb.fbo.delete(ctx, 0)
+}
+
+// shader_composite_frag blends the layer tex onto backdrop with the
+// separable blend modes of package blend, in linear premultiplied
+// colors. Mode zero mixes backdrop with blurred by the alpha of tex.
+var shader_composite_frag = shader.Sources{
+	Name:   "composite.frag",
+	Inputs: []shader.InputLocation{{Name: "vUV", Location: 0, Semantic: "TEXCOORD", SemanticIndex: 0, Type: 0x0, Size: 2}},
+	Uniforms: shader.UniformsReflection{
+		Locations: []shader.UniformLocation{{Name: "_blend.backdrop", Type: 0x0, Size: 4, Offset: 48}, {Name: "_blend.mode", Type: 0x0, Size: 1, Offset: 64}},
+		Size:      32,
+	},
+	Textures: []shader.TextureBinding{{Name: "tex", Binding: 0}, {Name: "backdrop", Binding: 1}, {Name: "blurred", Binding: 2}},
+	GLSL100ES: `#version 100
+#ifdef GL_FRAGMENT_PRECISION_HIGH
+precision highp float;
+#else
+precision mediump float;
+#endif
+
+struct Blend
+{
+    vec4 backdrop;
+    float mode;
+};
+
+uniform Blend _blend;
+
+uniform sampler2D tex;
+uniform sampler2D backdrop;
+uniform sampler2D blurred;
+
+varying vec2 vUV;
+
+vec3 screen(vec3 cb, vec3 cs)
+{
+    return cb + cs - cb*cs;
+}
+
+vec3 mixColors(float mode, vec3 cb, vec3 cs)
+{
+    if (mode < 1.5) {
+        return cb*cs;
+    } else if (mode < 2.5) {
+        return screen(cb, cs);
+    } else if (mode < 3.5) {
+        return mix(2.0*cb*cs, screen(cs, 2.0*cb - 1.0), vec3(greaterThan(cb, vec3(0.5))));
+    } else if (mode < 4.5) {
+        return min(cb, cs);
+    } else if (mode < 5.5) {
+        return max(cb, cs);
+    }
+    return abs(cb - cs);
+}
+
+void main()
+{
+    vec2 uv = vUV*_blend.backdrop.xy + _blend.backdrop.zw;
+    vec4 b = texture2D(backdrop, uv);
+    vec4 s = texture2D(tex, vUV);
+    float mode = _blend.mode;
+    if (mode < 0.5) {
+        gl_FragData[0] = mix(b, texture2D(blurred, uv), s.a);
+    } else if (mode > 6.5) {
+        gl_FragData[0] = min(b + s, vec4(1.0));
+    } else {
+        vec3 c = s.rgb*(1.0 - b.a) + b.rgb*(1.0 - s.a);
+        if (b.a > 0.0 && s.a > 0.0) {
+            c += s.a*b.a*mixColors(mode, b.rgb/b.a, s.rgb/s.a);
+        }
+        gl_FragData[0] = vec4(c, s.a + b.a - s.a*b.a);
+    }
+}
+`,
+	GLSL150: `#version 150
+
+struct Blend
+{
+    vec4 backdrop;
+    float mode;
+};
+
+uniform Blend _blend;
+
+uniform sampler2D tex;
+uniform sampler2D backdrop;
+uniform sampler2D blurred;
+
+in vec2 vUV;
+out vec4 fragColor;
+
+vec3 screen(vec3 cb, vec3 cs)
+{
+    return cb + cs - cb*cs;
+}
+
+vec3 mixColors(float mode, vec3 cb, vec3 cs)
+{
+    if (mode < 1.5) {
+        return cb*cs;
+    } else if (mode < 2.5) {
+        return screen(cb, cs);
+    } else if (mode < 3.5) {
+        return mix(2.0*cb*cs, screen(cs, 2.0*cb - 1.0), vec3(greaterThan(cb, vec3(0.5))));
+    } else if (mode < 4.5) {
+        return min(cb, cs);
+    } else if (mode < 5.5) {
+        return max(cb, cs);
+    }
+    return abs(cb - cs);
+}
+
+void main()
+{
+    vec2 uv = vUV*_blend.backdrop.xy + _blend.backdrop.zw;
+    vec4 b = texture(backdrop, uv);
+    vec4 s = texture(tex, vUV);
+    float mode = _blend.mode;
+    if (mode < 0.5) {
+        fragColor = mix(b, texture(blurred, uv), s.a);
+    } else if (mode > 6.5) {
+        fragColor = min(b + s, vec4(1.0));
+    } else {
+        vec3 c = s.rgb*(1.0 - b.a) + b.rgb*(1.0 - s.a);
+        if (b.a > 0.0 && s.a > 0.0) {
+            c += s.a*b.a*mixColors(mode, b.rgb/b.a, s.rgb/s.a);
+        }
+        fragColor = vec4(c, s.a + b.a - s.a*b.a);
+    }
+}
+`,
+}
+
+// shader_gaussian_frag convolves tex with a Gaussian along the
+// direction of _blur.step, clamping taps to _blur.bounds.
+var shader_gaussian_frag = shader.Sources{
+	Name:   "gaussian.frag",
+	Inputs: []shader.InputLocation{{Name: "vUV", Location: 0, Semantic: "TEXCOORD", SemanticIndex: 0, Type: 0x0, Size: 2}},
+	Uniforms: shader.UniformsReflection{
+		Locations: []shader.UniformLocation{{Name: "_blur.step", Type: 0x0, Size: 4, Offset: 48}, {Name: "_blur.bounds", Type: 0x0, Size: 4, Offset: 64}},
+		Size:      32,
+	},
+	Textures: []shader.TextureBinding{{Name: "tex", Binding: 0}},
+	GLSL100ES: `#version 100
+#ifdef GL_FRAGMENT_PRECISION_HIGH
+precision highp float;
+#else
+precision mediump float;
+#endif
+
+struct Blur
+{
+    vec4 step;
+    vec4 bounds;
+};
+
+uniform Blur _blur;
+
+uniform sampler2D tex;
+
+varying vec2 vUV;
+
+void main()
+{
+    float sigma = _blur.step.z;
+    float n = _blur.step.w;
+    vec4 sum = vec4(0.0);
+    float total = 0.0;
+    for (int i = -64; i <= 64; i++) {
+        float d = float(i);
+        if (abs(d) > n) {
+            continue;
+        }
+        float w = exp(-d*d/(2.0*sigma*sigma));
+        vec2 uv = clamp(vUV + d*_blur.step.xy, _blur.bounds.xy, _blur.bounds.zw);
+        sum += w*texture2D(tex, uv);
+        total += w;
+    }
+    gl_FragData[0] = sum/total;
+}
+`,
+	GLSL150: `#version 150
+
+struct Blur
+{
+    vec4 step;
+    vec4 bounds;
+};
+
+uniform Blur _blur;
+
+uniform sampler2D tex;
+
+in vec2 vUV;
+out vec4 fragColor;
+
+void main()
+{
+    float sigma = _blur.step.z;
+    float n = _blur.step.w;
+    vec4 sum = vec4(0.0);
+    float total = 0.0;
+    for (int i = -64; i <= 64; i++) {
+        float d = float(i);
+        if (abs(d) > n) {
+            continue;
+        }
+        float w = exp(-d*d/(2.0*sigma*sigma));
+        vec2 uv = clamp(vUV + d*_blur.step.xy, _blur.bounds.xy, _blur.bounds.zw);
+        sum += w*texture(tex, uv);
+        total += w;
+    }
+    fragColor = sum/total;
+}
+`,
+}
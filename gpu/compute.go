@@ -22,13 +22,19 @@ import (
 
 	"gioui.org/cpu"
 	"gioui.org/gpu/internal/driver"
+	"gioui.org/internal/blend"
 	"gioui.org/internal/byteslice"
 	"gioui.org/internal/f32"
 	"gioui.org/internal/f32color"
+	"gioui.org/internal/gradient"
+	"gioui.org/internal/mipmap"
 	"gioui.org/internal/ops"
 	"gioui.org/internal/scene"
+	"gioui.org/internal/stroke"
+	"gioui.org/io/profile"
 	"gioui.org/layout"
 	"gioui.org/op"
+	"gioui.org/op/paint"
 	"gioui.org/shader"
 	"gioui.org/shader/gio"
 	"gioui.org/shader/piet"
@@ -63,7 +69,19 @@ type compute struct {
 		memory sizedBuffer
 	}
 	output struct {
-		blitPipeline driver.Pipeline
+		// blitPipelines copy layers to render targets of each
+		// targetFormat.
+		blitPipelines [len(pixelFormats)]driver.Pipeline
+		// blitter composites and fades opacity groups. It is
+		// created when the first group is drawn.
+		blitter *blitter
+		// groups packs the offscreen textures of opacity groups.
+		groups    packer
+		groupFBOs fboSet
+		// rootFBO is the offscreen target of the root group when
+		// it is the backdrop of a blend group.
+		rootFBO fboSet
+		blender layerBlender
 
 		buffer sizedBuffer
 
@@ -75,8 +93,15 @@ type compute struct {
 
 		nullMaterials driver.Texture
 	}
-	// imgAllocs maps imageOpData.handles to allocs.
-	imgAllocs map[interface{}]*atlasAlloc
+	// imgAllocs maps images and their filters to allocs.
+	imgAllocs map[imageKey]*atlasAlloc
+	// rendered maps the handles of images rendered from operations
+	// to their renderings.
+	rendered  map[interface{}]*renderedImage
+	offscreen offscreen
+	damage    damageTracker
+	// region is the damage region of the frame.
+	region []image.Rectangle
 	// materials contains the pre-processed materials (transformed images for
 	// now, gradients etc. later) packed in a texture atlas. The atlas is used
 	// as source in kernel4.
@@ -93,9 +118,8 @@ type compute struct {
 		}
 	}
 	timers struct {
-		profile string
+		profile profile.Frame
 		t       *timers
-		compact *timer
 		render  *timer
 		blit    *timer
 	}
@@ -120,6 +144,32 @@ type layer struct {
 	alloc     *atlasAlloc
 	ops       []paintOp
 	materials *textureAtlas
+	// group is the index+1 of the opacity group of the layer ops,
+	// or 0 for the root group.
+	group int
+}
+
+// opacityGroup is a group of operations composited offscreen and blended
+// into its parent group with opacity and a blend mode.
+type opacityGroup struct {
+	opacity float32
+	mode    blend.Mode
+	// blur is the standard deviation of the blur applied to the
+	// backdrop of the group, or zero. The content of a blur group is
+	// the mask of the blurred area.
+	blur float32
+	// parent is the index+1 of the parent group, or 0 for the root
+	// group.
+	parent int
+	// depth is the number of groups enclosing the group, including
+	// itself.
+	depth int
+	// opStart is the index of the first operation of the group.
+	opStart int
+	// rect is the union of the layers of the group and its
+	// sub-groups.
+	rect  image.Rectangle
+	place placement
 }
 
 type allocQuery struct {
@@ -128,6 +178,7 @@ type allocQuery struct {
 	empty     bool
 	format    driver.TextureFormat
 	bindings  driver.BufferBinding
+	filter    driver.TextureFilter
 	nocompact bool
 }
 
@@ -150,6 +201,7 @@ type textureAtlas struct {
 	image     driver.Texture
 	format    driver.TextureFormat
 	bindings  driver.BufferBinding
+	filter    driver.TextureFilter
 	hasCPU    bool
 	cpuImage  cpu.ImageDescriptor
 	size      image.Point
@@ -177,6 +229,7 @@ type materialUniforms struct {
 type collector struct {
 	hasher     maphash.Hash
 	profile    bool
+	stats      frameStats
 	reader     ops.Reader
 	states     []f32.Affine2D
 	clear      bool
@@ -186,6 +239,14 @@ type collector struct {
 	transStack []transEntry
 	prevFrame  opsCollector
 	frame      opsCollector
+	gradients  gradientCache
+	shaders    shaderCache
+	mipmaps    mipmap.Cache
+	groups     []opacityGroup
+	// group is the index+1 of the current group in groups, or 0
+	// for the root group.
+	group      int
+	groupStack []int
 }
 
 type transEntry struct {
@@ -213,6 +274,7 @@ type paintOp struct {
 	hash      uint64
 	layer     int
 	texOpIdx  int
+	group     int
 }
 
 // clipCmd describes a clipping command ready to be used for the compute
@@ -252,11 +314,12 @@ type paintKey struct {
 	// Current paint.ColorOp, if any.
 	color color.NRGBA
 
-	// Current paint.LinearGradientOp.
-	stop1  f32.Point
-	stop2  f32.Point
-	color1 color.NRGBA
-	color2 color.NRGBA
+	// Current paint.GradientOp, paint.LinearGradientOp,
+	// paint.RadialGradientOp, paint.ConicGradientOp or paint.ShadowOp.
+	gradient gradientOpData
+
+	// Current paint.ShaderOp.
+	shader shaderOpData
 }
 
 type clipState struct {
@@ -284,6 +347,7 @@ type materialVertex struct {
 // textureKey identifies textureOp.
 type textureKey struct {
 	handle    interface{}
+	filter    byte
 	transform f32.Affine2D
 	bounds    image.Rectangle
 }
@@ -404,6 +468,7 @@ func newCompute(ctx driver.Device) (*compute, error) {
 		conf:          new(config),
 		memHeader:     new(memoryHeader),
 	}
+	g.offscreen.dev = ctx
 	shaders := []struct {
 		prog *computeProgram
 		src  shader.Sources
@@ -426,7 +491,7 @@ func newCompute(ctx driver.Device) (*compute, error) {
 	if g.useCPU {
 		g.dispatcher = newDispatcher(runtime.NumCPU())
 	} else {
-		null, err := ctx.NewTexture(driver.TextureFormatRGBA8, 1, 1, driver.FilterNearest, driver.FilterNearest, driver.BufferBindingShaderStorageRead)
+		null, err := ctx.NewTexture(driver.TextureFormatRGBA8, 1, 1, driver.FilterNearest, driver.FilterNearest, driver.WrapClamp, driver.BufferBindingShaderStorageRead)
 		if err != nil {
 			g.Release()
 			return nil, err
@@ -441,29 +506,31 @@ func newCompute(ctx driver.Device) (*compute, error) {
 	}
 	defer copyVert.Release()
 	defer copyFrag.Release()
-	pipe, err := ctx.NewPipeline(driver.PipelineDesc{
-		VertexShader:   copyVert,
-		FragmentShader: copyFrag,
-		VertexLayout: driver.VertexLayout{
-			Inputs: []driver.InputDesc{
-				{Type: shader.DataTypeFloat, Size: 2, Offset: 0},
-				{Type: shader.DataTypeFloat, Size: 2, Offset: 4 * 2},
+	for f, format := range pixelFormats {
+		pipe, err := ctx.NewPipeline(driver.PipelineDesc{
+			VertexShader:   copyVert,
+			FragmentShader: copyFrag,
+			VertexLayout: driver.VertexLayout{
+				Inputs: []driver.InputDesc{
+					{Type: shader.DataTypeFloat, Size: 2, Offset: 0},
+					{Type: shader.DataTypeFloat, Size: 2, Offset: 4 * 2},
+				},
+				Stride: int(unsafe.Sizeof(g.output.layerVertices[0])),
 			},
-			Stride: int(unsafe.Sizeof(g.output.layerVertices[0])),
-		},
-		PixelFormat: driver.TextureFormatOutput,
-		BlendDesc: driver.BlendDesc{
-			Enable:    true,
-			SrcFactor: driver.BlendFactorOne,
-			DstFactor: driver.BlendFactorOneMinusSrcAlpha,
-		},
-		Topology: driver.TopologyTriangles,
-	})
-	if err != nil {
-		g.Release()
-		return nil, err
+			PixelFormat: format,
+			BlendDesc: driver.BlendDesc{
+				Enable:    true,
+				SrcFactor: driver.BlendFactorOne,
+				DstFactor: driver.BlendFactorOneMinusSrcAlpha,
+			},
+			Topology: driver.TopologyTriangles,
+		})
+		if err != nil {
+			g.Release()
+			return nil, err
+		}
+		g.output.blitPipelines[f] = pipe
 	}
-	g.output.blitPipeline = pipe
 	g.output.uniforms = new(copyUniforms)
 
 	buf, err := ctx.NewBuffer(driver.BufferBindingUniforms, int(unsafe.Sizeof(*g.output.uniforms)))
@@ -480,7 +547,7 @@ func newCompute(ctx driver.Device) (*compute, error) {
 	}
 	defer materialVert.Release()
 	defer materialFrag.Release()
-	pipe, err = ctx.NewPipeline(driver.PipelineDesc{
+	pipe, err := ctx.NewPipeline(driver.PipelineDesc{
 		VertexShader:   materialVert,
 		FragmentShader: materialFrag,
 		VertexLayout: driver.VertexLayout{
@@ -560,6 +627,13 @@ func newCompute(ctx driver.Device) (*compute, error) {
 	return g, nil
 }
 
+// renderedImage is the rendering of an image rendered from
+// operations.
+type renderedImage struct {
+	img  *image.RGBA
+	used bool
+}
+
 func newShaders(ctx driver.Device, vsrc, fsrc shader.Sources) (vert driver.VertexShader, frag driver.FragmentShader, err error) {
 	vert, err = ctx.NewVertexShader(vsrc)
 	if err != nil {
@@ -583,7 +657,52 @@ func (g *compute) collect(viewport image.Point, ops *op.Ops) {
 	g.collector.reset()
 
 	g.texOps = g.texOps[:0]
+	start := time.Now()
 	g.collector.collect(ops, viewport, &g.texOps)
+	g.trackDamage()
+	if g.collector.profile {
+		g.timers.profile.Collect = time.Since(start)
+	}
+}
+
+// trackDamage adds the operations of the frame to the damage tracker
+// and computes the damage region.
+func (g *compute) trackDamage() {
+	c := &g.collector
+	if !c.clear {
+		// The frame draws on top of the previous frame.
+		g.damage.invalidate()
+	} else {
+		k := c.clearColor
+		c.hasher.Reset()
+		c.hasher.Write((*[unsafe.Sizeof(k)]byte)(unsafe.Pointer(&k))[:])
+		g.damage.add(c.hasher.Sum64(), image.Rectangle{Max: g.viewport})
+	}
+	for _, op := range c.frame.ops {
+		k := struct {
+			hash    uint64
+			offset  image.Point
+			opacity [8]float32
+		}{hash: op.hash, offset: op.offset}
+		for i, l := op.group, 0; i > 0; i, l = c.groups[i-1].parent, l+1 {
+			grp := c.groups[i-1]
+			if grp.readsBackdrop() || l == len(k.opacity) {
+				// Blending and blurring affect pixels outside
+				// the operations.
+				g.damage.invalidate()
+				break
+			}
+			k.opacity[l] = grp.opacity
+		}
+		c.hasher.Reset()
+		c.hasher.Write((*[unsafe.Sizeof(k)]byte)(unsafe.Pointer(&k))[:])
+		g.damage.add(c.hasher.Sum64(), op.intersect.Round())
+	}
+	g.region = g.damage.frame(g.viewport)
+}
+
+func (g *compute) Damage() []image.Rectangle {
+	return g.region
 }
 
 func (g *compute) Clear(col color.NRGBA) {
@@ -593,13 +712,17 @@ func (g *compute) Clear(col color.NRGBA) {
 
 func (g *compute) frame(target RenderTarget) error {
 	viewport := g.viewport
+	// Render images before the frame begins, because their
+	// rendering is a frame of its own.
+	if err := g.renderImages(); err != nil {
+		return err
+	}
 	defFBO := g.ctx.BeginFrame(target, g.collector.clear, viewport)
 	defer g.ctx.EndFrame()
 
 	t := &g.timers
 	if g.collector.profile && t.t == nil && g.ctx.Caps().Features.Has(driver.FeatureTimers) {
 		t.t = newTimers(g.ctx)
-		t.compact = t.t.newTimer()
 		t.render = t.t.newTimer()
 		t.blit = t.t.newTimer()
 	}
@@ -624,20 +747,26 @@ func (g *compute) frame(target RenderTarget) error {
 		d.Action = driver.LoadActionClear
 	}
 	t.blit.begin()
-	g.blitLayers(d, defFBO, viewport)
+	if err := g.blitLayers(d, defFBO, viewport); err != nil {
+		return err
+	}
 	t.blit.end()
-	t.compact.begin()
 	if err := g.compactAllocs(); err != nil {
 		return err
 	}
-	t.compact.end()
-	if g.collector.profile && t.t.ready() {
-		com, ren, blit := t.compact.Elapsed, t.render.Elapsed, t.blit.Elapsed
-		ft := com + ren + blit
-		q := 100 * time.Microsecond
-		ft = ft.Round(q)
-		com, ren, blit = com.Round(q), ren.Round(q), blit.Round(q)
-		t.profile = fmt.Sprintf("ft:%7s com: %7s ren:%7s blit:%7s", ft, com, ren, blit)
+	g.collector.gradients.frame()
+	g.collector.shaders.frame()
+	g.collector.mipmaps.Frame()
+	if g.collector.profile {
+		stats := &g.collector.stats
+		p := &t.profile
+		p.Encode = stats.encode
+		p.Paths, p.Images = stats.paths, len(g.texOps)
+		p.Damage = append(p.Damage[:0], g.region...)
+		p.Uploads, p.CacheHits, p.CacheMisses = stats.uploads, stats.cacheHits, stats.cacheMisses
+		if t.t.ready() {
+			p.Compute, p.Blit = t.render.Elapsed, t.blit.Elapsed
+		}
 	}
 	return nil
 }
@@ -662,6 +791,10 @@ func (g *compute) dumpAtlases() {
 }
 
 func (g *compute) Profile() string {
+	return g.timers.profile.String()
+}
+
+func (g *compute) FrameProfile() profile.Frame {
 	return g.timers.profile
 }
 
@@ -681,6 +814,7 @@ func (g *compute) compactAllocs() error {
 			dstAtlas *textureAtlas
 			format   driver.TextureFormat
 			bindings driver.BufferBinding
+			filter   driver.TextureFilter
 		)
 		g.moves = g.moves[:0]
 		addedLayers := false
@@ -693,11 +827,12 @@ func (g *compute) compactAllocs() error {
 				atlases = atlases[1:]
 				continue
 			}
-			if addedLayers && (format != srcAtlas.format || srcAtlas.bindings&bindings != srcAtlas.bindings) {
+			if addedLayers && (format != srcAtlas.format || srcAtlas.bindings&bindings != srcAtlas.bindings || filter != srcAtlas.filter) {
 				break
 			}
 			format = srcAtlas.format
 			bindings = srcAtlas.bindings
+			filter = srcAtlas.filter
 			for len(srcAtlas.allocs) > 0 {
 				a := srcAtlas.allocs[0]
 				n := len(srcAtlas.allocs)
@@ -713,6 +848,7 @@ func (g *compute) compactAllocs() error {
 					size:      size,
 					format:    format,
 					bindings:  bindings,
+					filter:    filter,
 					nocompact: true,
 				})
 				if !fits {
@@ -792,6 +928,8 @@ func (g *compute) renderLayers(viewport image.Point) error {
 		g.enc.reset()
 		for len(layers) > 0 {
 			l := &layers[0]
+			// Layers allocated in previous frames are reused.
+			g.collector.stats.lookup(l.alloc != nil)
 			if l.alloc != nil {
 				layers = layers[1:]
 				continue
@@ -822,7 +960,9 @@ func (g *compute) renderLayers(viewport image.Point) error {
 			addedLayers = true
 			l.alloc = &alloc
 			dst.allocs = append(dst.allocs, l.alloc)
+			start := time.Now()
 			encodeLayer(*l, alloc.rect.Min, viewport, &g.enc, g.texOps)
+			g.collector.stats.encode += time.Since(start)
 			layers = layers[1:]
 		}
 		if !addedLayers {
@@ -844,7 +984,7 @@ func (g *compute) renderLayers(viewport image.Point) error {
 	return nil
 }
 
-func (g *compute) blitLayers(d driver.LoadDesc, fbo driver.Texture, viewport image.Point) {
+func (g *compute) blitLayers(d driver.LoadDesc, fbo driver.Texture, viewport image.Point) error {
 	layers := g.collector.frame.layers
 	g.output.layerVertices = g.output.layerVertices[:0]
 	for _, l := range layers {
@@ -865,41 +1005,196 @@ func (g *compute) blitLayers(d driver.LoadDesc, fbo driver.Texture, viewport ima
 		g.output.buffer.ensureCapacity(false, g.ctx, driver.BufferBindingVertices, len(vertexData))
 		g.output.buffer.buffer.Upload(vertexData)
 	}
+	if err := g.renderGroups(); err != nil {
+		return err
+	}
+	if g.collector.blendsRoot() {
+		// Blending reads back its backdrop, which is not possible for
+		// every output framebuffer. Draw the root group offscreen and
+		// copy it to the output.
+		g.output.rootFBO.resize(g.ctx, pixelFormats[formatLayer], []image.Point{viewport})
+		root := g.output.rootFBO.fbos[0]
+		var col f32color.RGBA
+		if d.Action == driver.LoadActionClear {
+			col = d.ClearColor
+		}
+		g.ctx.BeginRenderPass(root.tex, driver.LoadDesc{Action: driver.LoadActionClear, ClearColor: col})
+		g.ctx.Viewport(0, 0, viewport.X, viewport.Y)
+		err := g.blitGroup(0, formatLayer, image.Rectangle{Max: viewport})
+		g.ctx.EndRenderPass()
+		if err != nil {
+			return err
+		}
+		g.ctx.PrepareTexture(root.tex)
+		g.ctx.BeginRenderPass(fbo, d)
+		defer g.ctx.EndRenderPass()
+		g.ctx.Viewport(0, 0, viewport.X, viewport.Y)
+		b := g.output.blitter
+		g.ctx.BindTexture(0, root.tex)
+		g.ctx.BindVertexBuffer(b.quadVerts, 0)
+		scale, off := clipSpaceTransform(image.Rectangle{Max: viewport}, viewport)
+		uv := layerUVTransform(g.ctx.Caps(), image.Point{}, viewport, root.size)
+		b.blit(formatOutput, materialTexture, f32color.RGBA{}, f32color.RGBA{}, f32color.RGBA{}, scale, off, uv)
+		return nil
+	}
 	g.ctx.BeginRenderPass(fbo, d)
 	defer g.ctx.EndRenderPass()
 	if len(layers) == 0 {
-		return
+		return nil
 	}
 	g.ctx.Viewport(0, 0, viewport.X, viewport.Y)
-	g.ctx.BindPipeline(g.output.blitPipeline)
-	g.ctx.BindVertexBuffer(g.output.buffer.buffer, 0)
-	start := 0
-	for len(layers) > 0 {
-		count := 0
-		atlas := layers[0].alloc.atlas
-		for len(layers) > 0 {
-			l := layers[0]
-			if l.alloc.atlas != atlas {
-				break
+	return g.blitGroup(0, formatOutput, image.Rectangle{Max: viewport})
+}
+
+// renderGroups composites the opacity groups into offscreen textures and
+// applies their opacities, innermost groups first.
+func (g *compute) renderGroups() error {
+	groups := g.collector.groups
+	p := &g.output.groups
+	p.clear()
+	p.maxDims = image.Pt(g.maxTextureDim, g.maxTextureDim)
+	depth := 0
+	for _, grp := range groups {
+		if grp.depth > depth && !grp.rect.Empty() {
+			depth = grp.depth
+		}
+	}
+	// Pack the groups of each depth into separate atlases, such that no
+	// group is drawn into the atlas it is blended from.
+	for ; depth > 0; depth-- {
+		page := false
+		for i := range groups {
+			grp := &groups[i]
+			if grp.depth != depth || grp.rect.Empty() {
+				continue
+			}
+			if !page {
+				p.newPage()
+				page = true
+			}
+			place, ok := p.add(grp.rect.Size())
+			if !ok {
+				return fmt.Errorf("gpu: group area %v is larger than maximum texture size %v", grp.rect, p.maxDims)
+			}
+			grp.place = place
+		}
+	}
+	if len(p.sizes) > 0 && g.output.blitter == nil {
+		g.output.blitter = newBlitter(g.ctx)
+	}
+	g.output.groupFBOs.resize(g.ctx, pixelFormats[formatLayer], p.sizes)
+	for idx, fbo := range g.output.groupFBOs.fbos {
+		g.ctx.BeginRenderPass(fbo.tex, driver.LoadDesc{Action: driver.LoadActionClear})
+		for i, grp := range groups {
+			if grp.rect.Empty() || grp.place.Idx != idx {
+				continue
+			}
+			g.ctx.Viewport(grp.place.Pos.X, grp.place.Pos.Y, grp.rect.Dx(), grp.rect.Dy())
+			if err := g.blitGroup(i+1, formatLayer, grp.rect); err != nil {
+				g.ctx.EndRenderPass()
+				return err
+			}
+			if grp.opacity < 1 {
+				g.output.blitter.fade(grp.opacity)
 			}
-			layers = layers[1:]
-			const verticesPerQuad = 6
-			count += verticesPerQuad
 		}
+		g.ctx.EndRenderPass()
+		g.ctx.PrepareTexture(fbo.tex)
+	}
+	return nil
+}
 
-		// Transform positions to clip space: [-1, -1] - [1, 1], and texture
-		// coordinates to texture space: [0, 0] - [1, 1].
-		clip := f32.Affine2D{}.Scale(f32.Pt(0, 0), f32.Pt(2/float32(viewport.X), 2/float32(viewport.Y))).Offset(f32.Pt(-1, -1))
-		sx, _, ox, _, sy, oy := clip.Elems()
+// blitGroup draws the layers and sub-groups of an opacity group, where 0
+// denotes the root group. The viewport covers the area r of the window.
+func (g *compute) blitGroup(group int, format targetFormat, r image.Rectangle) error {
+	c := &g.collector
+	layers := c.frame.layers
+	// Transform positions to clip space: [-1, -1] - [1, 1].
+	clip := f32.Affine2D{}.
+		Offset(layout.FPt(r.Min.Mul(-1))).
+		Scale(f32.Pt(0, 0), f32.Pt(2/float32(r.Dx()), 2/float32(r.Dy()))).
+		Offset(f32.Pt(-1, -1))
+	sx, _, ox, _, sy, oy := clip.Elems()
+	// Draw runs of layers that share atlas.
+	var atlas *textureAtlas
+	start, count := 0, 0
+	flush := func() {
+		if count == 0 {
+			return
+		}
+		g.ctx.BindPipeline(g.output.blitPipelines[format])
+		g.ctx.BindVertexBuffer(g.output.buffer.buffer, 0)
 		g.output.uniforms.scale = [2]float32{sx, sy}
 		g.output.uniforms.pos = [2]float32{ox, oy}
+		// Transform texture coordinates to texture space: [0, 0] - [1, 1].
 		g.output.uniforms.uvScale = [2]float32{1 / float32(atlas.size.X), 1 / float32(atlas.size.Y)}
 		g.output.uniBuf.Upload(byteslice.Struct(g.output.uniforms))
 		g.ctx.BindUniforms(g.output.uniBuf)
 		g.ctx.BindTexture(0, atlas.image)
 		g.ctx.DrawArrays(start, count)
-		start += count
+		count = 0
+	}
+	const verticesPerQuad = 6
+	for i := 0; i < len(layers); {
+		l := layers[i]
+		child, ok := c.groupChild(l.group, group)
+		switch {
+		case !ok:
+			i++
+		case child == 0:
+			if l.alloc.atlas != atlas || start+count != i*verticesPerQuad {
+				flush()
+				atlas = l.alloc.atlas
+				start = i * verticesPerQuad
+			}
+			count += verticesPerQuad
+			i++
+		default:
+			flush()
+			grp := c.groups[child-1]
+			fbo := g.output.groupFBOs.fbos[grp.place.Idx]
+			if grp.readsBackdrop() {
+				if err := g.blendGroup(group, child, r); err != nil {
+					return err
+				}
+			} else {
+				b := g.output.blitter
+				g.ctx.BindTexture(0, fbo.tex)
+				g.ctx.BindVertexBuffer(b.quadVerts, 0)
+				scale, off := clipSpaceTransform(grp.rect.Sub(r.Min), r.Size())
+				uvTrans := layerUVTransform(g.ctx.Caps(), grp.place.Pos, grp.rect.Size(), fbo.size)
+				b.blit(format, materialTexture, f32color.RGBA{}, f32color.RGBA{}, f32color.RGBA{}, scale, off, uvTrans)
+			}
+			// Skip the layers of the child group.
+			for i < len(layers) {
+				if ch, ok := c.groupChild(layers[i].group, group); !ok || ch != child {
+					break
+				}
+				i++
+			}
+		}
+	}
+	flush()
+	return nil
+}
+
+// blendGroup blends the group src into the group dst, where 0 denotes
+// the root group drawn offscreen by blitLayers. The area r of the window
+// is the viewport of dst.
+func (g *compute) blendGroup(dst, src int, r image.Rectangle) error {
+	c := &g.collector
+	target := g.output.rootFBO.fbos[0].tex
+	var pos image.Point
+	if dst > 0 {
+		grp := c.groups[dst-1]
+		target = g.output.groupFBOs.fbos[grp.place.Idx].tex
+		pos = grp.place.Pos
 	}
+	vp := image.Rectangle{Min: pos, Max: pos.Add(r.Size())}
+	grp := c.groups[src-1]
+	srcTex := g.output.groupFBOs.fbos[grp.place.Idx].tex
+	area := image.Rectangle{Min: grp.place.Pos, Max: grp.place.Pos.Add(grp.rect.Size())}
+	return g.output.blender.blend(g.ctx, g.output.blitter, grp.mode, grp.blur, target, vp, grp.rect.Sub(r.Min), srcTex, area)
 }
 
 func (g *compute) renderMaterials() error {
@@ -1056,30 +1351,45 @@ func (g *compute) uploadImages() error {
 		var atlas *textureAtlas
 		for len(texOps) > 0 {
 			op := &texOps[0]
-			if a, exists := g.imgAllocs[op.img.handle]; exists {
+			key := imageKey{handle: op.img.handle, filter: op.img.filter}
+			a, exists := g.imgAllocs[key]
+			g.collector.stats.lookup(exists)
+			if exists {
 				g.touchAlloc(a)
 				op.imgAlloc = a
 				texOps = texOps[1:]
 				continue
 			}
+			// Mipmaps are generated by the collector, so the atlas
+			// needs no more than linear filtering.
+			filter := driver.FilterLinear
+			if paint.ImageFilter(op.img.filter) == paint.FilterNearest {
+				filter = driver.FilterNearest
+			}
+			if atlas != nil && atlas.filter != filter {
+				// Only one filter per atlas.
+				break
+			}
 			size := op.img.src.Bounds().Size().Add(image.Pt(padding, padding))
 			alloc, fits := g.atlasAlloc(allocQuery{
 				atlas:    atlas,
 				size:     size,
 				format:   format,
 				bindings: driver.BufferBindingTexture | driver.BufferBindingFramebuffer,
+				filter:   filter,
 			})
 			if !fits {
 				break
 			}
 			atlas = alloc.atlas
 			if g.imgAllocs == nil {
-				g.imgAllocs = make(map[interface{}]*atlasAlloc)
+				g.imgAllocs = make(map[imageKey]*atlasAlloc)
 			}
 			op.imgAlloc = &alloc
 			atlas.allocs = append(atlas.allocs, op.imgAlloc)
-			g.imgAllocs[op.img.handle] = op.imgAlloc
+			g.imgAllocs[key] = op.imgAlloc
 			uploads = append(uploads, upload{pos: alloc.rect.Min, img: op.img.src})
+			g.collector.stats.uploads++
 			texOps = texOps[1:]
 		}
 		if len(uploads) == 0 {
@@ -1395,7 +1705,7 @@ func (g *compute) atlasAlloc(q allocQuery) (atlasAlloc, bool) {
 			if q.nocompact && a.compact {
 				continue
 			}
-			if a.format != q.format || a.bindings&q.bindings != q.bindings {
+			if a.format != q.format || a.bindings&q.bindings != q.bindings || a.filter != q.filter {
 				continue
 			}
 			place, fits = a.packer.tryAdd(q.size)
@@ -1411,6 +1721,7 @@ func (g *compute) atlasAlloc(q allocQuery) (atlasAlloc, bool) {
 		atlas = &textureAtlas{
 			format:   q.format,
 			bindings: q.bindings,
+			filter:   q.filter,
 		}
 		atlas.packer.maxDims = image.Pt(g.maxTextureDim, g.maxTextureDim)
 		atlas.packer.newPage()
@@ -1453,8 +1764,9 @@ func (a *textureAtlas) resize(ctx driver.Device, size image.Point) error {
 	a.Release()
 
 	img, err := ctx.NewTexture(a.format, size.X, size.Y,
-		driver.FilterNearest,
-		driver.FilterNearest,
+		a.filter,
+		a.filter,
+		driver.WrapClamp,
 		a.bindings)
 	if err != nil {
 		return err
@@ -1472,7 +1784,44 @@ func (a *textureAtlas) ensureCPUImage(useCPU bool) {
 	a.cpuImage = cpu.NewImageRGBA(a.size.X, a.size.Y)
 }
 
+func (g *compute) RenderImage(frame *op.Ops, img *image.RGBA) error {
+	return g.offscreen.readback(&frame.Internal, img)
+}
+
+// renderImages replaces images rendered from operations with their
+// renderings, and discards renderings no longer in use.
+func (g *compute) renderImages() error {
+	for i := range g.texOps {
+		img := &g.texOps[i].img
+		if img.render == nil {
+			continue
+		}
+		r, exists := g.rendered[img.handle]
+		if !exists {
+			dst := image.NewRGBA(image.Rectangle{Max: img.size})
+			if err := g.offscreen.readback(img.render, dst); err != nil {
+				return err
+			}
+			r = &renderedImage{img: dst}
+			if g.rendered == nil {
+				g.rendered = make(map[interface{}]*renderedImage)
+			}
+			g.rendered[img.handle] = r
+		}
+		r.used = true
+		img.src = r.img
+	}
+	for k, r := range g.rendered {
+		if !r.used {
+			delete(g.rendered, k)
+		}
+		r.used = false
+	}
+	return nil
+}
+
 func (g *compute) Release() {
+	g.offscreen.release()
 	if g.useCPU {
 		g.dispatcher.Stop()
 	}
@@ -1488,7 +1837,8 @@ func (g *compute) Release() {
 		&g.programs.binning,
 		&g.programs.coarse,
 		&g.programs.kernel4,
-		g.output.blitPipeline,
+		g.output.blitPipelines[formatOutput],
+		g.output.blitPipelines[formatLayer],
 		&g.output.buffer,
 		g.output.uniBuf,
 		&g.buffers.scene,
@@ -1508,6 +1858,12 @@ func (g *compute) Release() {
 	for _, a := range g.atlases {
 		a.Release()
 	}
+	if g.output.blitter != nil {
+		g.output.groupFBOs.delete(g.ctx, 0)
+		g.output.rootFBO.delete(g.ctx, 0)
+		g.output.blender.release(g.ctx)
+		g.output.blitter.release()
+	}
 	g.ctx.Release()
 	*g = compute{}
 }
@@ -1657,8 +2013,12 @@ func (e *encoder) line(start, end f32.Point) {
 func (c *collector) reset() {
 	c.prevFrame, c.frame = c.frame, c.prevFrame
 	c.profile = false
+	c.stats = frameStats{}
 	c.clipStates = c.clipStates[:0]
 	c.transStack = c.transStack[:0]
+	c.groups = c.groups[:0]
+	c.group = 0
+	c.groupStack = c.groupStack[:0]
 	c.frame.reset()
 }
 
@@ -1670,6 +2030,9 @@ func (c *opsCollector) reset() {
 }
 
 func (c *collector) addClip(state *encoderState, viewport, bounds f32.Rectangle, path []byte, key ops.Key, hash uint64, strokeWidth float32, push bool) {
+	if len(path) > 0 {
+		c.stats.paths++
+	}
 	// Rectangle clip regions.
 	if len(path) == 0 && !push {
 		// If the rectangular clip region contains a previous path it can be discarded.
@@ -1708,6 +2071,34 @@ func (c *collector) addClip(state *encoderState, viewport, bounds f32.Rectangle,
 	state.relTrans = f32.Affine2D{}
 }
 
+// encodeStrokeQuads encodes quads in the format of path data.
+func encodeStrokeQuads(quads stroke.StrokeQuads) []byte {
+	data := make([]byte, len(quads)*(scene.CommandSize+4))
+	bo := binary.LittleEndian
+	for i, q := range quads {
+		d := data[i*(scene.CommandSize+4):]
+		bo.PutUint32(d, q.Contour)
+		ops.EncodeCommand(d[4:], scene.Quad(q.Quad.From, q.Quad.Ctrl, q.Quad.To))
+	}
+	return data
+}
+
+// strokeHash combines a path hash with the stroke style and dash pattern
+// applied to it.
+func (c *collector) strokeHash(hash uint64, str stroke.StrokeStyle, dashes stroke.DashOp) uint64 {
+	c.hasher.Reset()
+	var buf [8 + 4*2 + 2 + 8]byte
+	bo := binary.LittleEndian
+	bo.PutUint64(buf[0:], hash)
+	bo.PutUint32(buf[8:], math.Float32bits(str.Width))
+	bo.PutUint32(buf[12:], math.Float32bits(str.Miter))
+	buf[16] = byte(str.Cap)
+	buf[17] = byte(str.Join)
+	bo.PutUint64(buf[18:], dashHash(dashes))
+	c.hasher.Write(buf[:])
+	return c.hasher.Sum64()
+}
+
 func (c *collector) collect(root *op.Ops, viewport image.Point, texOps *[]textureOp) {
 	fview := f32.Rectangle{Max: layout.FPt(viewport)}
 	var intOps *ops.Ops
@@ -1731,7 +2122,8 @@ func (c *collector) collect(root *op.Ops, viewport image.Point, texOps *[]textur
 			key  ops.Key
 			hash uint64
 		}
-		strWidth float32
+		str    stroke.StrokeStyle
+		dashes stroke.DashOp
 	)
 	c.addClip(&state, fview, fview, nil, ops.Key{}, 0, 0, false)
 	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
@@ -1752,7 +2144,7 @@ func (c *collector) collect(root *op.Ops, viewport image.Point, texOps *[]textur
 			state.t = st.t
 			state.relTrans = st.relTrans
 		case ops.TypeStroke:
-			strWidth = decodeStrokeOp(encOp.Data)
+			str, dashes = decodeStrokeOp(encOp.Data, encOp.Refs)
 		case ops.TypePath:
 			hash := bo.Uint64(encOp.Data[1:])
 			encOp, ok = r.Decode()
@@ -1766,70 +2158,79 @@ func (c *collector) collect(root *op.Ops, viewport image.Point, texOps *[]textur
 			var op ops.ClipOp
 			op.Decode(encOp.Data)
 			bounds := f32.FRect(op.Bounds)
-			c.addClip(&state, fview, bounds, pathData.data, pathData.key, pathData.hash, strWidth, true)
+			if str.Width > 0 && (str.Cap != stroke.RoundCap || str.Join != stroke.RoundJoin || !stroke.IsSolidLine(dashes)) {
+				// The compute programs only support round caps and joins.
+				// Convert other strokes to outlines.
+				pathData.data = encodeStrokeQuads(stroke.StrokePathCommands(str, dashes, pathData.data))
+				pathData.hash = c.strokeHash(pathData.hash, str, dashes)
+				str = stroke.StrokeStyle{}
+			}
+			c.addClip(&state, fview, bounds, pathData.data, pathData.key, pathData.hash, str.Width, true)
 			pathData.data = nil
-			strWidth = 0
+			str, dashes = stroke.StrokeStyle{}, stroke.DashOp{}
 		case ops.TypePopClip:
 			state.relTrans = state.clip.relTrans.Mul(state.relTrans)
 			state.clip = state.clip.parent
+		case ops.TypeOpacity:
+			c.pushGroup(ops.DecodeOpacity(encOp.Data), blend.SourceOver, 0)
+		case ops.TypePopOpacity:
+			c.popGroup()
+		case ops.TypeBlend:
+			c.pushGroup(1, ops.DecodeBlend(encOp.Data), 0)
+		case ops.TypePopBlend:
+			c.popGroup()
 		case ops.TypeColor:
 			state.matType = materialColor
 			state.color = decodeColorOp(encOp.Data)
 		case ops.TypeLinearGradient:
-			state.matType = materialLinearGradient
+			// The compute renderer has no linear gradient material; rasterize
+			// it like the other gradients.
+			state.matType = materialGradient
 			op := decodeLinearGradientOp(encOp.Data)
-			state.stop1 = op.stop1
-			state.stop2 = op.stop2
-			state.color1 = op.color1
-			state.color2 = op.color2
+			state.gradient = gradientOpData{
+				kind:   gradient.Linear,
+				p1:     op.stop1,
+				p2:     op.stop2,
+				color1: op.color1,
+				color2: op.color2,
+			}
+		case ops.TypeRadialGradient:
+			state.matType = materialGradient
+			state.gradient = decodeRadialGradientOp(encOp.Data)
+		case ops.TypeConicGradient:
+			state.matType = materialGradient
+			state.gradient = decodeConicGradientOp(encOp.Data)
+		case ops.TypeGradient:
+			g := decodeGradientOp(encOp.Data, encOp.Refs)
+			if g.kind > gradient.Conic {
+				// A gradient of unknown kind paints nothing.
+				state.matType = materialColor
+				state.color = color.NRGBA{}
+				break
+			}
+			state.matType = materialGradient
+			state.gradient = g
 		case ops.TypeImage:
 			state.matType = materialTexture
 			state.image = decodeImageOp(encOp.Data, encOp.Refs)
+		case ops.TypeShadow:
+			state.matType = materialGradient
+			state.gradient = decodeShadowOp(encOp.Data)
+		case ops.TypeShader:
+			// The compute renderer doesn't run shader programs; rasterize
+			// shaders with their fallbacks.
+			state.matType = materialShader
+			state.shader = decodeShaderOp(encOp.Data, encOp.Refs)
 		case ops.TypePaint:
-			paintState := state
-			if paintState.matType == materialTexture {
-				// Clip to the bounds of the image, to hide other images in the atlas.
-				sz := state.image.src.Rect.Size()
-				bounds := f32.Rectangle{Max: layout.FPt(sz)}
-				c.addClip(&paintState, fview, bounds, nil, ops.Key{}, 0, 0, false)
-			}
-			intersect := paintState.clip.intersect
-			if intersect.Empty() {
-				break
-			}
-
-			// If the paint is a uniform opaque color that takes up the whole
-			// screen, it covers all previous paints and we can discard all
-			// rendering commands recorded so far.
-			if paintState.clip == nil && paintState.matType == materialColor && paintState.color.A == 255 {
-				c.clearColor = f32color.LinearFromSRGB(paintState.color).Opaque()
-				c.clear = true
-				c.frame.reset()
-				break
-			}
-
-			// Flatten clip stack.
-			p := paintState.clip
-			startIdx := len(c.frame.clipCmds)
-			for p != nil {
-				idx := len(c.frame.paths)
-				c.frame.paths = append(c.frame.paths, make([]byte, len(p.path))...)
-				path := c.frame.paths[idx:]
-				copy(path, p.path)
-				c.frame.clipCmds = append(c.frame.clipCmds, clipCmd{
-					state:     p.clipKey,
-					path:      path,
-					pathKey:   p.pathKey,
-					absBounds: p.absBounds,
-				})
-				p = p.parent
-			}
-			clipStack := c.frame.clipCmds[startIdx:]
-			c.frame.ops = append(c.frame.ops, paintOp{
-				clipStack: clipStack,
-				state:     paintState.paintKey,
-				intersect: intersect,
-			})
+			c.paint(state, fview)
+		case ops.TypeBlur:
+			// Blur through a group masked by the clip area.
+			c.pushGroup(1, blend.SourceOver, ops.DecodeBlur(encOp.Data))
+			mask := state
+			mask.matType = materialColor
+			mask.color = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			c.paint(mask, fview)
+			c.popGroup()
 		case ops.TypeSave:
 			id := ops.DecodeSave(encOp.Data)
 			c.save(id, state.t)
@@ -1840,6 +2241,10 @@ func (c *collector) collect(root *op.Ops, viewport image.Point, texOps *[]textur
 			state.relTrans = state.t
 		}
 	}
+	// End groups left open.
+	for len(c.groupStack) > 0 {
+		c.popGroup()
+	}
 	for i := range c.frame.ops {
 		op := &c.frame.ops[i]
 		// For each clip, cull rectangular clip regions that contain its
@@ -1880,19 +2285,185 @@ func (c *collector) collect(root *op.Ops, viewport image.Point, texOps *[]textur
 			t := op.state.t.Offset(layout.FPt(op.offset))
 			t, off := separateTransform(t)
 			bounds := op.intersect.Round().Sub(off)
+			img := op.state.image
+			// Images rendered from operations have no mipmaps.
+			if paint.ImageFilter(img.filter) == paint.FilterLinearMipmapLinear && img.render == nil {
+				img, t = c.mipmap(img, t)
+			}
 			*texOps = append(*texOps, textureOp{
-				img: op.state.image,
+				img: img,
 				off: off,
 				key: textureKey{
 					bounds:    bounds,
 					transform: t,
-					handle:    op.state.image.handle,
+					handle:    img.handle,
+					filter:    img.filter,
 				},
 			})
 		}
 	}
 }
 
+// mipmap returns the mipmap level of img for drawing it with the
+// transformation t, along with the transformation of the level.
+func (c *collector) mipmap(img imageOpData, t f32.Affine2D) (imageOpData, f32.Affine2D) {
+	level := mipmap.Level(t)
+	if level == 0 {
+		return img, t
+	}
+	src := c.mipmaps.Get(img.handle, img.src, level)
+	ssz, dsz := img.src.Rect.Size(), src.Rect.Size()
+	scale := f32.Pt(float32(ssz.X)/float32(dsz.X), float32(ssz.Y)/float32(dsz.Y))
+	img.src = src
+	img.size = dsz
+	// Levels are cached, so they identify themselves.
+	img.handle = src
+	return img, t.Mul(f32.Affine2D{}.Scale(f32.Point{}, scale))
+}
+
+// paint adds an operation that fills the clip area of state with its
+// brush.
+func (c *collector) paint(state encoderState, fview f32.Rectangle) {
+	paintState := state
+	if m := paintState.matType; m == materialGradient || m == materialShader {
+		// Rasterize the gradient or shader to an image that covers the
+		// clip area, and paint it untransformed.
+		bounds := paintState.clip.intersect.Round()
+		if bounds.Empty() {
+			return
+		}
+		if m == materialShader {
+			paintState.image = c.shaders.image(paintState.shader, paintState.t, bounds)
+		} else {
+			paintState.image = c.gradients.image(paintState.gradient, paintState.t, bounds)
+		}
+		paintState.matType = materialTexture
+		// Replace the transformation, keeping relTrans relative to the
+		// transformation of the current clip.
+		t := f32.Affine2D{}.Offset(layout.FPt(bounds.Min))
+		paintState.relTrans = state.relTrans.Mul(state.t.Invert()).Mul(t)
+		paintState.t = t
+	}
+	if paintState.matType == materialTexture {
+		// Clip to the bounds of the image, to hide other images in the atlas.
+		sz := paintState.image.size
+		bounds := f32.Rectangle{Max: layout.FPt(sz)}
+		c.addClip(&paintState, fview, bounds, nil, ops.Key{}, 0, 0, false)
+	}
+	intersect := paintState.clip.intersect
+	if intersect.Empty() {
+		return
+	}
+
+	// If the paint is a uniform opaque color that takes up the whole
+	// screen, it covers all previous paints and we can discard all
+	// rendering commands recorded so far.
+	if paintState.clip == nil && paintState.matType == materialColor && paintState.color.A == 255 && c.group == 0 {
+		c.clearColor = f32color.LinearFromSRGB(paintState.color).Opaque()
+		c.clear = true
+		c.frame.reset()
+		c.groups = c.groups[:0]
+		return
+	}
+
+	// Flatten clip stack.
+	p := paintState.clip
+	startIdx := len(c.frame.clipCmds)
+	for p != nil {
+		idx := len(c.frame.paths)
+		c.frame.paths = append(c.frame.paths, make([]byte, len(p.path))...)
+		path := c.frame.paths[idx:]
+		copy(path, p.path)
+		c.frame.clipCmds = append(c.frame.clipCmds, clipCmd{
+			state:     p.clipKey,
+			path:      path,
+			pathKey:   p.pathKey,
+			absBounds: p.absBounds,
+		})
+		p = p.parent
+	}
+	clipStack := c.frame.clipCmds[startIdx:]
+	c.frame.ops = append(c.frame.ops, paintOp{
+		clipStack: clipStack,
+		state:     paintState.paintKey,
+		intersect: intersect,
+		group:     c.group,
+	})
+}
+
+// pushGroup starts an opacity, blend or blur group.
+func (c *collector) pushGroup(opacity float32, mode blend.Mode, blur float32) {
+	c.groupStack = append(c.groupStack, c.group)
+	if opacity == 1 && mode == blend.SourceOver && blur <= 0 {
+		// An opaque group is equivalent to no group.
+		return
+	}
+	depth := 1
+	if c.group > 0 {
+		depth += c.groups[c.group-1].depth
+	}
+	c.groups = append(c.groups, opacityGroup{
+		opacity: opacity,
+		mode:    mode,
+		blur:    blur,
+		parent:  c.group,
+		depth:   depth,
+		opStart: len(c.frame.ops),
+	})
+	c.group = len(c.groups)
+}
+
+// readsBackdrop reports whether the group is blended or blurred by
+// reading back its backdrop.
+func (g opacityGroup) readsBackdrop() bool {
+	return g.mode != blend.SourceOver || g.blur > 0
+}
+
+// popGroup ends the current opacity group.
+func (c *collector) popGroup() {
+	n := len(c.groupStack)
+	parent := c.groupStack[n-1]
+	c.groupStack = c.groupStack[:n-1]
+	idx := c.group
+	c.group = parent
+	if idx == parent {
+		return
+	}
+	grp := c.groups[idx-1]
+	if grp.opacity == 0 || grp.opStart == len(c.frame.ops) {
+		// Discard the invisible group along with its operations
+		// and sub-groups.
+		c.frame.ops = c.frame.ops[:grp.opStart]
+		c.groups = c.groups[:idx-1]
+	}
+}
+
+// blendsRoot reports whether a group is blended into the root group
+// with a mode other than source-over or blurs the root group.
+func (c *collector) blendsRoot() bool {
+	for _, grp := range c.groups {
+		if grp.parent == 0 && grp.readsBackdrop() && !grp.rect.Empty() {
+			return true
+		}
+	}
+	return false
+}
+
+// groupChild returns the child of the group ancestor that contains the
+// group grp, or 0 if grp equals ancestor. It returns false if grp is not
+// contained in ancestor.
+func (c *collector) groupChild(grp, ancestor int) (int, bool) {
+	child := 0
+	for grp != ancestor {
+		if grp == 0 {
+			return 0, false
+		}
+		child = grp
+		grp = c.groups[grp-1].parent
+	}
+	return child, true
+}
+
 func (c *collector) hashOp(op paintOp) uint64 {
 	c.hasher.Reset()
 	for _, cl := range op.clipStack {
@@ -1927,6 +2498,10 @@ func (g *compute) layer(viewport image.Point, texOps []textureOp) {
 			var materials *textureAtlas
 			idx := 0
 			for idx < len(ops) {
+				// Layers are blended into their opacity group.
+				if ops[idx].group != ops[0].group {
+					break
+				}
 				if i := ops[idx].texOpIdx; i != -1 {
 					omats := texOps[i].matAlloc.alloc.atlas
 					if materials != nil && omats != nil && omats != materials {
@@ -1936,7 +2511,7 @@ func (g *compute) layer(viewport image.Point, texOps []textureOp) {
 				}
 				idx++
 			}
-			l := layer{ops: ops[:idx], materials: materials}
+			l := layer{ops: ops[:idx], materials: materials, group: ops[0].group}
 			if prevLayerIdx != -1 {
 				prev := c.prevFrame.layers[prevLayerIdx]
 				if !prev.alloc.dead && len(prev.ops) == len(l.ops) {
@@ -1977,6 +2552,12 @@ func (g *compute) layer(viewport image.Point, texOps []textureOp) {
 	if len(ops) > 0 {
 		splitLayer(ops, -1)
 	}
+	for _, l := range c.frame.layers {
+		for grp := l.group; grp > 0; grp = c.groups[grp-1].parent {
+			og := &c.groups[grp-1]
+			og.rect = og.rect.Union(l.rect)
+		}
+	}
 }
 
 func longestLayer(prev []paintOp, order []hashIndex, ops []paintOp) ([]paintOp, int) {
@@ -2117,9 +2698,6 @@ func encodeOp(viewport image.Point, absOff image.Point, enc *encoder, texOps []t
 		enc.fillImage(0, off)
 	case materialColor:
 		enc.fillColor(f32color.NRGBAToRGBA(op.state.color))
-	case materialLinearGradient:
-		// TODO: implement.
-		enc.fillColor(f32color.NRGBAToRGBA(op.state.color1))
 	default:
 		panic("not implemented")
 	}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+)
+
+// maxDamageRects is the maximum number of rectangles of a damage
+// region. Additional rectangles are merged with the others.
+const maxDamageRects = 4
+
+// maxBufferAge is the oldest age of the contents of a render target
+// that can be updated by drawing only the damaged areas.
+const maxBufferAge = 4
+
+// damageOp is an operation as seen by damage tracking. Equal
+// damageOps draw equal pixels within their bounds, given equal
+// pixels below them.
+type damageOp struct {
+	hash   uint64
+	bounds image.Rectangle
+}
+
+// damageTracker computes the areas that differ between consecutive
+// frames by comparing the operations drawn by them.
+type damageTracker struct {
+	size image.Point
+	// valid reports whether prev is the previous frame.
+	valid bool
+	// full is set when the current frame has effects not
+	// covered by its operations.
+	full      bool
+	prev, cur []damageOp
+	// first maps operations to the index of their first occurrence
+	// in prev, and next links the indices of equal operations.
+	first  map[damageOp]int
+	next   []int
+	region []image.Rectangle
+	// history holds the damage regions of the most recent frames,
+	// latest first, and frames counts the valid entries.
+	history [maxBufferAge][]image.Rectangle
+	frames  int
+	redrawn []image.Rectangle
+}
+
+// add an operation of the current frame, in drawing order.
+func (d *damageTracker) add(hash uint64, bounds image.Rectangle) {
+	if bounds.Empty() {
+		return
+	}
+	d.cur = append(d.cur, damageOp{hash: hash, bounds: bounds})
+}
+
+// invalidate damages the whole current frame.
+func (d *damageTracker) invalidate() {
+	d.full = true
+}
+
+// frame ends the current frame of size pixels and returns its damage
+// region. The region is valid until the next call to frame.
+func (d *damageTracker) frame(size image.Point) []image.Rectangle {
+	d.region = d.region[:0]
+	viewport := image.Rectangle{Max: size}
+	if !d.valid || d.full || size != d.size {
+		d.region = append(d.region, viewport)
+	} else {
+		d.diff(viewport)
+	}
+	d.prev, d.cur = d.cur, d.prev[:0]
+	d.size = size
+	d.valid = true
+	d.full = false
+	last := d.history[maxBufferAge-1]
+	copy(d.history[1:], d.history[:maxBufferAge-1])
+	d.history[0] = append(last[:0], d.region...)
+	if d.frames < maxBufferAge {
+		d.frames++
+	}
+	return d.region
+}
+
+// redraw returns the region to draw by the last frame into a target
+// whose contents are age frames old, where age 1 is the frame before
+// it. The whole frame is drawn if the age is 0, meaning the contents
+// are undefined, or older than the tracked frames.
+func (d *damageTracker) redraw(age int) []image.Rectangle {
+	viewport := image.Rectangle{Max: d.size}
+	d.redrawn = d.redrawn[:0]
+	if age <= 0 || age > d.frames {
+		d.redrawn = append(d.redrawn, viewport)
+		return d.redrawn
+	}
+	for _, region := range d.history[:age] {
+		for _, r := range region {
+			d.redrawn = addDamage(d.redrawn, r, viewport)
+		}
+	}
+	return d.redrawn
+}
+
+// diff damages the operations that are not in both frames, or not in
+// the same order.
+func (d *damageTracker) diff(viewport image.Rectangle) {
+	if d.first == nil {
+		d.first = make(map[damageOp]int)
+	}
+	for k := range d.first {
+		delete(d.first, k)
+	}
+	d.next = d.next[:0]
+	for range d.prev {
+		d.next = append(d.next, -1)
+	}
+	for i := len(d.prev) - 1; i >= 0; i-- {
+		op := d.prev[i]
+		if j, ok := d.first[op]; ok {
+			d.next[i] = j
+		}
+		d.first[op] = i
+	}
+	// last is the index of the last match in prev. Operations
+	// matching earlier operations have moved in front of others.
+	last := -1
+	for _, op := range d.cur {
+		i, ok := d.first[op]
+		if !ok || i == -1 {
+			d.damage(op.bounds, viewport)
+			continue
+		}
+		d.first[op] = d.next[i]
+		// Mark the previous operation as matched.
+		d.next[i] = -2
+		if i < last {
+			d.damage(op.bounds, viewport)
+			continue
+		}
+		last = i
+	}
+	for i, op := range d.prev {
+		if d.next[i] != -2 {
+			d.damage(op.bounds, viewport)
+		}
+	}
+}
+
+// damage adds r clipped to viewport to the damage region.
+func (d *damageTracker) damage(r, viewport image.Rectangle) {
+	d.region = addDamage(d.region, r, viewport)
+}
+
+// addDamage adds r clipped to viewport to region and returns the
+// result.
+func addDamage(region []image.Rectangle, r, viewport image.Rectangle) []image.Rectangle {
+	r = r.Intersect(viewport)
+	if r.Empty() {
+		return region
+	}
+	// Merge with overlapping rectangles, which may in turn overlap
+	// others.
+	for i := 0; i < len(region); i++ {
+		if o := region[i]; o.Overlaps(r) {
+			r = r.Union(o)
+			region = append(region[:i], region[i+1:]...)
+			i = -1
+		}
+	}
+	region = append(region, r)
+	if len(region) <= maxDamageRects {
+		return region
+	}
+	// Merge the pair of rectangles whose union adds the least area.
+	bi, bj, best := 0, 1, -1
+	for i := range region {
+		for j := i + 1; j < len(region); j++ {
+			a, b := region[i], region[j]
+			u := a.Union(b)
+			waste := area(u) - area(a) - area(b)
+			if best == -1 || waste < best {
+				bi, bj, best = i, j, waste
+			}
+		}
+	}
+	region[bi] = region[bi].Union(region[bj])
+	region = append(region[:bj], region[bj+1:]...)
+	// The merged rectangle may overlap others.
+	merged := region[bi]
+	region = append(region[:bi], region[bi+1:]...)
+	return addDamage(region, merged, viewport)
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDamageTracker(t *testing.T) {
+	var d damageTracker
+	size := image.Pt(100, 100)
+	a := image.Rect(0, 0, 10, 10)
+	b := image.Rect(50, 50, 60, 60)
+	frame := func(ops ...damageOp) []image.Rectangle {
+		for _, op := range ops {
+			d.add(op.hash, op.bounds)
+		}
+		return d.frame(size)
+	}
+	if got := frame(damageOp{1, a}, damageOp{2, b}); len(got) != 1 || got[0] != (image.Rectangle{Max: size}) {
+		t.Errorf("first frame: got damage %v, expected the whole frame", got)
+	}
+	if got := frame(damageOp{1, a}, damageOp{2, b}); len(got) != 0 {
+		t.Errorf("equal frame: got damage %v, expected none", got)
+	}
+	if got := frame(damageOp{1, a}, damageOp{3, b}); len(got) != 1 || got[0] != b {
+		t.Errorf("changed operation: got damage %v, expected %v", got, b)
+	}
+	if got := frame(damageOp{3, b}, damageOp{1, a}); len(got) != 1 {
+		t.Errorf("reordered operations: got damage %v, expected a single rectangle", got)
+	}
+	if got := frame(damageOp{1, a}); len(got) != 1 || got[0] != b {
+		t.Errorf("removed operation: got damage %v, expected %v", got, b)
+	}
+	d.invalidate()
+	if got := frame(damageOp{1, a}); len(got) != 1 || got[0] != (image.Rectangle{Max: size}) {
+		t.Errorf("invalidated frame: got damage %v, expected the whole frame", got)
+	}
+	// The number of rectangles is bounded.
+	frame()
+	var ops []damageOp
+	for i := 0; i < 2*maxDamageRects; i++ {
+		ops = append(ops, damageOp{uint64(i), image.Rect(i*10, i*10, i*10+5, i*10+5)})
+	}
+	if got := frame(ops...); len(got) > maxDamageRects {
+		t.Errorf("got %d damage rectangles, expected at most %d", len(got), maxDamageRects)
+	}
+}
+
+func TestDamageRedraw(t *testing.T) {
+	var d damageTracker
+	size := image.Pt(100, 100)
+	full := image.Rectangle{Max: size}
+	a := image.Rect(0, 0, 10, 10)
+	b := image.Rect(50, 50, 60, 60)
+	d.add(1, a)
+	d.frame(size)
+	if got := d.redraw(1); len(got) != 1 || got[0] != full {
+		t.Errorf("first frame: got redraw %v, expected the whole frame", got)
+	}
+	d.add(2, a)
+	d.frame(size)
+	d.add(2, a)
+	d.add(3, b)
+	d.frame(size)
+	if got := d.redraw(1); len(got) != 1 || got[0] != b {
+		t.Errorf("age 1: got redraw %v, expected %v", got, b)
+	}
+	var area image.Rectangle
+	for _, r := range d.redraw(2) {
+		area = area.Union(r)
+	}
+	if !a.In(area) || !b.In(area) || full.In(area) {
+		t.Errorf("age 2: got redraw %v, expected %v and %v", d.redraw(2), a, b)
+	}
+	if got := d.redraw(4); len(got) != 1 || got[0] != full {
+		t.Errorf("age beyond the tracked frames: got redraw %v, expected the whole frame", got)
+	}
+	if got := d.redraw(0); len(got) != 1 || got[0] != full {
+		t.Errorf("unknown age: got redraw %v, expected the whole frame", got)
+	}
+}
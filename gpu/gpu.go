@@ -10,6 +10,8 @@ package gpu
 import (
 	"encoding/binary"
 	"fmt"
+	"hash/fnv"
+	"hash/maphash"
 	"image"
 	"image/color"
 	"math"
@@ -19,14 +21,18 @@ import (
 	"unsafe"
 
 	"gioui.org/gpu/internal/driver"
+	"gioui.org/internal/blend"
 	"gioui.org/internal/byteslice"
 	"gioui.org/internal/f32"
 	"gioui.org/internal/f32color"
+	"gioui.org/internal/gradient"
 	"gioui.org/internal/ops"
 	"gioui.org/internal/scene"
 	"gioui.org/internal/stroke"
+	"gioui.org/io/profile"
 	"gioui.org/layout"
 	"gioui.org/op"
+	"gioui.org/op/paint"
 	"gioui.org/shader"
 	"gioui.org/shader/gio"
 
@@ -50,16 +56,60 @@ type GPU interface {
 	Profile() string
 }
 
+// ImageRenderer is implemented by GPUs that render operations into
+// images.
+type ImageRenderer interface {
+	// RenderImage draws the graphics operations from frame into img, with
+	// the origin at the minimum point of its bounds. The operations are
+	// rendered offscreen and do not affect the next Frame.
+	RenderImage(frame *op.Ops, img *image.RGBA) error
+}
+
+// FrameProfiler is implemented by GPUs that report the durations and
+// counters of their frames.
+type FrameProfiler interface {
+	// FrameProfile is like Profile, but returns the profile of the
+	// frame as a profile.Frame. The Total and Present durations are left
+	// for the caller to fill in.
+	FrameProfile() profile.Frame
+}
+
+// DamageTracker is implemented by GPUs that track the area of the
+// viewport changed by each frame.
+type DamageTracker interface {
+	// Damage returns the area of the viewport drawn by the last Frame
+	// that differs from the frame before it. The whole viewport is
+	// damaged if the GPU can't tell which parts changed. The result
+	// is valid until the next Frame.
+	Damage() []image.Rectangle
+}
+
+// PartialRedrawer is implemented by GPUs that can limit the drawing
+// of a frame to the areas that changed since its target was drawn.
+type PartialRedrawer interface {
+	// SetBufferAge declares the age of the contents of the target of
+	// the next Frame in frames, as defined by EGL_EXT_buffer_age: 1
+	// means the target holds the previous frame. Only the areas
+	// damaged since then are cleared and drawn. Age 0 means the
+	// contents are undefined and the whole frame is drawn, which is
+	// also the case if the device can't limit drawing.
+	SetBufferAge(age int)
+}
+
 type gpu struct {
 	cache *resourceCache
 
-	profile                                string
-	timers                                 *timers
-	frameStart                             time.Time
-	stencilTimer, coverTimer, cleanupTimer *timer
-	drawOps                                drawOps
-	ctx                                    driver.Device
-	renderer                               *renderer
+	profile                  profile.Frame
+	timers                   *timers
+	stencilTimer, coverTimer *timer
+	drawOps                  drawOps
+	ctx                      driver.Device
+	renderer                 *renderer
+	// offscreen renders images from operations.
+	offscreen offscreen
+	// bufferAge is the age of the contents of the target of the
+	// next frame.
+	bufferAge int
 }
 
 type renderer struct {
@@ -68,10 +118,20 @@ type renderer struct {
 	pather        *pather
 	packer        packer
 	intersections packer
+	layers        packer
+	layerFBOs     fboSet
+	// rootFBO is the offscreen target of the root layer when it
+	// is the backdrop of a blend layer.
+	rootFBO fboSet
+	blender layerBlender
+	shaders shaderPipelines
+	// stats counts the work of the frame.
+	stats *frameStats
 }
 
 type drawOps struct {
 	profile     bool
+	stats       frameStats
 	reader      ops.Reader
 	states      []f32.Affine2D
 	transStack  []f32.Affine2D
@@ -84,6 +144,27 @@ type drawOps struct {
 	pathOpCache []pathOp
 	qs          quadSplitter
 	pathCache   *opCache
+	gradients   gradientCache
+	shaders     shaderCache
+	layers      []opacityLayer
+	// layer is the index+1 of the current layer in layers, or
+	// 0 for the root layer.
+	layer      int
+	layerStack []int
+	// cacheStack tracks the op.CacheOps being collected.
+	cacheStack []cacheKey
+	damage     damageTracker
+	// region is the damage region of the frame.
+	region []image.Rectangle
+	hasher maphash.Hash
+}
+
+// cacheKey identifies a path by the key of its op.CacheOp and its
+// index among the paths of the CacheOp. Unlike ops.Key, it stays the
+// same across frames.
+type cacheKey struct {
+	key   interface{}
+	index int
 }
 
 type drawState struct {
@@ -101,6 +182,13 @@ type drawState struct {
 	stop2  f32.Point
 	color1 color.NRGBA
 	color2 color.NRGBA
+
+	// Current paint.GradientOp, paint.RadialGradientOp or
+	// paint.ConicGradientOp.
+	gradient gradientOpData
+
+	// Current paint.ShaderOp.
+	shader shaderOpData
 }
 
 type pathOp struct {
@@ -119,7 +207,10 @@ type pathOp struct {
 	path      bool
 	pathVerts []byte
 	parent    *pathOp
-	place     placement
+	// hash identifies the clip stack of the operation across frames,
+	// for damage tracking.
+	hash  uint64
+	place placement
 }
 
 type imageOp struct {
@@ -128,24 +219,83 @@ type imageOp struct {
 	material material
 	clipType clipType
 	place    placement
+	// layer is the index+1 of the opacity layer the operation
+	// draws into, or 0 for the root layer.
+	layer int
+}
+
+// opacityLayer is a group of operations drawn into an offscreen
+// texture, which is blended into its parent layer with opacity and
+// a blend mode.
+type opacityLayer struct {
+	opacity float32
+	mode    blend.Mode
+	// blur is the standard deviation of the blur applied to the
+	// backdrop of the layer, or zero. The content of a blur layer is
+	// the mask of the blurred area.
+	blur float32
+	// parent is the index+1 of the parent layer, or 0 for the root
+	// layer.
+	parent int
+	// depth is the number of layers enclosing the layer, including
+	// itself.
+	depth int
+	// opStart is the index of the first imageOp of the layer.
+	opStart int
+	// clip is the union of the clip areas of the layer operations.
+	clip  image.Rectangle
+	place placement
+}
+
+func decodeStrokeOp(data []byte, refs []interface{}) (stroke.StrokeStyle, stroke.DashOp) {
+	_ = data[14]
+	bo := binary.LittleEndian
+	style := stroke.StrokeStyle{
+		Width: math.Float32frombits(bo.Uint32(data[1:])),
+		Miter: math.Float32frombits(bo.Uint32(data[5:])),
+		Cap:   stroke.StrokeCap(data[9]),
+		Join:  stroke.StrokeJoin(data[10]),
+	}
+	dashes := stroke.DashOp{
+		Phase: math.Float32frombits(bo.Uint32(data[11:])),
+	}
+	dashes.Dashes, _ = refs[0].([]float32)
+	return style, dashes
 }
 
-func decodeStrokeOp(data []byte) float32 {
-	_ = data[4]
+// dashHash returns a hash of a dash pattern suitable for cache keys.
+func dashHash(d stroke.DashOp) uint64 {
+	if stroke.IsSolidLine(d) {
+		return 0
+	}
+	h := fnv.New64a()
+	var buf [4]byte
 	bo := binary.LittleEndian
-	return math.Float32frombits(bo.Uint32(data[1:]))
+	bo.PutUint32(buf[:], math.Float32bits(d.Phase))
+	h.Write(buf[:])
+	for _, l := range d.Dashes {
+		bo.PutUint32(buf[:], math.Float32bits(l))
+		h.Write(buf[:])
+	}
+	return h.Sum64()
 }
 
 type quadsOp struct {
 	key opKey
 	aux []byte
+	// hash is the hash of the path data in aux.
+	hash   uint64
+	dashes stroke.DashOp
 }
 
 type opKey struct {
 	outline        bool
-	strokeWidth    float32
+	stroke         stroke.StrokeStyle
+	dashes         uint64
 	sx, hx, sy, hy float32
 	ops.Key
+	// cache replaces Key for paths of an op.CacheOp.
+	cache cacheKey
 }
 
 type material struct {
@@ -159,12 +309,32 @@ type material struct {
 	// For materialTypeTexture.
 	data    imageOpData
 	uvTrans f32.Affine2D
+	// layer is the index+1 of the opacity layer to use as
+	// texture instead of data.
+	layer int
 }
 
 // imageOpData is the shadow of paint.ImageOp.
 type imageOpData struct {
-	src    *image.RGBA
+	src *image.RGBA
+	// render is the operation list of an image rendered from
+	// operations.
+	render *ops.Ops
+	// shader is the shader brush of an image rendered by a shader
+	// program.
+	shader *shaderImage
+	size   image.Point
 	handle interface{}
+	filter byte
+	// wrap is the texture wrap mode of gradient ramps.
+	wrap driver.TextureWrap
+}
+
+// imageKey identifies the texture of an image sampled with a filter.
+type imageKey struct {
+	handle interface{}
+	filter byte
+	wrap   driver.TextureWrap
 }
 
 type linearGradientOpData struct {
@@ -179,10 +349,21 @@ func decodeImageOp(data []byte, refs []interface{}) imageOpData {
 	if handle == nil {
 		return imageOpData{}
 	}
-	return imageOpData{
-		src:    refs[0].(*image.RGBA),
+	data = data[:ops.TypeImageLen]
+	img := imageOpData{
 		handle: handle,
+		filter: data[1],
+	}
+	switch src := refs[0].(type) {
+	case *image.RGBA:
+		img.src = src
+		img.size = src.Bounds().Size()
+	case *ops.Ops:
+		bo := binary.LittleEndian
+		img.render = src
+		img.size = image.Pt(int(bo.Uint32(data[2:])), int(bo.Uint32(data[6:])))
 	}
+	return img
 }
 
 func decodeColorOp(data []byte) color.NRGBA {
@@ -236,11 +417,18 @@ type texture struct {
 type blitter struct {
 	ctx                    driver.Device
 	viewport               image.Point
-	pipelines              [3]*pipeline
+	pipelines              [len(pixelFormats)][3]*pipeline
 	colUniforms            *blitColUniforms
 	texUniforms            *blitTexUniforms
 	linearGradientUniforms *blitLinearGradientUniforms
-	quadVerts              driver.Buffer
+	// fadePipeline multiplies the framebuffer by the
+	// complement of the alpha of fadeUniforms.
+	fadePipeline *pipeline
+	fadeUniforms *blitColUniforms
+	// replacePipeline overwrites the framebuffer with a texture.
+	replacePipeline *pipeline
+	replaceUniforms *blitTexUniforms
+	quadVerts       driver.Buffer
 }
 
 type blitColUniforms struct {
@@ -286,6 +474,22 @@ type gradientUniforms struct {
 
 type materialType uint8
 
+// targetFormat identifies the pixel format of a render target.
+type targetFormat uint8
+
+const (
+	// formatOutput is the format of the output framebuffer.
+	formatOutput targetFormat = iota
+	// formatLayer is the format of opacity layer textures.
+	formatLayer
+)
+
+// pixelFormats maps target formats to driver formats.
+var pixelFormats = [...]driver.TextureFormat{
+	formatOutput: driver.TextureFormatOutput,
+	formatLayer:  driver.TextureFormatSRGBA,
+}
+
 const (
 	clipTypeNone clipType = iota
 	clipTypePath
@@ -296,6 +500,10 @@ const (
 	materialColor materialType = iota
 	materialLinearGradient
 	materialTexture
+	// materialGradient is rasterized and drawn as a materialTexture.
+	materialGradient
+	// materialShader is rendered and drawn as a materialTexture.
+	materialShader
 )
 
 // New creates a GPU for the given API.
@@ -335,7 +543,9 @@ func newGPU(ctx driver.Device) (*gpu, error) {
 
 func (g *gpu) init(ctx driver.Device) error {
 	g.ctx = ctx
+	g.offscreen.dev = ctx
 	g.renderer = newRenderer(ctx)
+	g.renderer.stats = &g.drawOps.stats
 	return nil
 }
 
@@ -345,6 +555,7 @@ func (g *gpu) Clear(col color.NRGBA) {
 }
 
 func (g *gpu) Release() {
+	g.offscreen.release()
 	g.renderer.release()
 	g.drawOps.pathCache.release()
 	g.cache.release()
@@ -363,21 +574,35 @@ func (g *gpu) collect(viewport image.Point, frameOps *op.Ops) {
 	g.renderer.blitter.viewport = viewport
 	g.renderer.pather.viewport = viewport
 	g.drawOps.reset(viewport)
+	start := time.Now()
 	g.drawOps.collect(frameOps, viewport)
+	g.drawOps.trackDamage()
+	if g.drawOps.profile {
+		g.profile.Collect = time.Since(start) - g.drawOps.stats.encode
+	}
 	if g.drawOps.profile && g.timers == nil && g.ctx.Caps().Features.Has(driver.FeatureTimers) {
-		g.frameStart = time.Now()
 		g.timers = newTimers(g.ctx)
 		g.stencilTimer = g.timers.newTimer()
 		g.coverTimer = g.timers.newTimer()
-		g.cleanupTimer = g.timers.newTimer()
 	}
 }
 
 func (g *gpu) frame(target RenderTarget) error {
 	viewport := g.renderer.blitter.viewport
+	// Render images before the frame begins, because their
+	// rendering is a frame of its own.
+	if err := g.renderImages(); err != nil {
+		return err
+	}
 	defFBO := g.ctx.BeginFrame(target, g.drawOps.clear, viewport)
 	defer g.ctx.EndFrame()
+	if err := g.renderShaders(); err != nil {
+		return err
+	}
+	start := time.Now()
 	g.drawOps.buildPaths(g.ctx)
+	stats := &g.drawOps.stats
+	stats.encode += time.Since(start)
 	for _, img := range g.drawOps.imageOps {
 		expandPathOp(img.path, img.clip)
 	}
@@ -391,6 +616,12 @@ func (g *gpu) frame(target RenderTarget) error {
 	g.coverTimer.begin()
 	g.renderer.uploadImages(g.cache, g.drawOps.imageOps)
 	g.renderer.prepareDrawOps(g.cache, g.drawOps.imageOps)
+	if err := g.renderer.packLayers(g.drawOps.layers); err != nil {
+		return err
+	}
+	if err := g.renderer.drawLayers(g.cache, g.drawOps.imageOps, g.drawOps.layers); err != nil {
+		return err
+	}
 	d := driver.LoadDesc{
 		ClearColor: g.drawOps.clearColor,
 	}
@@ -398,45 +629,199 @@ func (g *gpu) frame(target RenderTarget) error {
 		g.drawOps.clear = false
 		d.Action = driver.LoadActionClear
 	}
-	g.ctx.BeginRenderPass(defFBO, d)
-	g.ctx.Viewport(0, 0, viewport.X, viewport.Y)
-	g.renderer.drawOps(g.cache, g.drawOps.imageOps)
+	var root *stencilFBO
+	if g.drawOps.blendsRoot() {
+		// Blending reads back its backdrop, which is not possible for
+		// every output framebuffer. Draw the root layer offscreen and
+		// copy it to the output.
+		var col f32color.RGBA
+		if d.Action == driver.LoadActionClear {
+			col = d.ClearColor
+		}
+		fbo, err := g.renderer.drawRootLayer(g.cache, g.drawOps.imageOps, g.drawOps.layers, col)
+		if err != nil {
+			return err
+		}
+		root = &fbo
+	}
+	// Draw the frame once for every rectangle of the redraw region,
+	// each clipped by a scissor.
+	redraw := g.drawOps.damage.redraw(g.bufferAge)
+	g.bufferAge = 0
+	partial := len(redraw) != 1 || redraw[0] != image.Rectangle{Max: viewport}
+	for _, r := range redraw {
+		if partial {
+			if g.ctx.Caps().BottomLeftOrigin {
+				r.Min.Y, r.Max.Y = viewport.Y-r.Max.Y, viewport.Y-r.Min.Y
+			}
+			d.Scissor = r
+		}
+		g.ctx.BeginRenderPass(defFBO, d)
+		g.ctx.Viewport(0, 0, viewport.X, viewport.Y)
+		if root != nil {
+			g.ctx.BindTexture(0, root.tex)
+			g.ctx.BindVertexBuffer(g.renderer.blitter.quadVerts, 0)
+			scale, off := clipSpaceTransform(image.Rectangle{Max: viewport}, viewport)
+			uv := layerUVTransform(g.ctx.Caps(), image.Point{}, viewport, root.size)
+			g.renderer.blitter.blit(formatOutput, materialTexture, f32color.RGBA{}, f32color.RGBA{}, f32color.RGBA{}, scale, off, uv)
+		} else if err := g.renderer.drawOps(g.cache, g.drawOps.imageOps, g.drawOps.layers, 0, formatOutput); err != nil {
+			g.ctx.EndRenderPass()
+			return err
+		}
+		g.ctx.EndRenderPass()
+	}
 	g.coverTimer.end()
-	g.ctx.EndRenderPass()
-	g.cleanupTimer.begin()
 	g.cache.frame()
 	g.drawOps.pathCache.frame()
-	g.cleanupTimer.end()
-	if g.drawOps.profile && g.timers.ready() {
-		st, covt, cleant := g.stencilTimer.Elapsed, g.coverTimer.Elapsed, g.cleanupTimer.Elapsed
-		ft := st + covt + cleant
-		q := 100 * time.Microsecond
-		st, covt = st.Round(q), covt.Round(q)
-		frameDur := time.Since(g.frameStart).Round(q)
-		ft = ft.Round(q)
-		g.profile = fmt.Sprintf("draw:%7s gpu:%7s st:%7s cov:%7s", frameDur, ft, st, covt)
+	g.drawOps.gradients.frame()
+	g.drawOps.shaders.frame()
+	if g.drawOps.profile {
+		p := &g.profile
+		p.Encode = stats.encode
+		p.Paths = stats.paths
+		p.Images = 0
+		for _, img := range g.drawOps.imageOps {
+			if m := img.material; m.material == materialTexture && m.layer == 0 {
+				p.Images++
+			}
+		}
+		p.Uploads, p.CacheHits, p.CacheMisses = stats.uploads, stats.cacheHits, stats.cacheMisses
+		p.Damage = append(p.Damage[:0], g.drawOps.region...)
+		if g.timers.ready() {
+			p.Stencil, p.Cover = g.stencilTimer.Elapsed, g.coverTimer.Elapsed
+		}
 	}
 	return nil
 }
 
 func (g *gpu) Profile() string {
+	return g.profile.String()
+}
+
+func (g *gpu) FrameProfile() profile.Frame {
 	return g.profile
 }
 
+func (g *gpu) Damage() []image.Rectangle {
+	return g.drawOps.region
+}
+
+func (g *gpu) SetBufferAge(age int) {
+	if !g.ctx.Caps().Features.Has(driver.FeatureScissor) {
+		age = 0
+	}
+	g.bufferAge = age
+}
+
+func (g *gpu) RenderImage(frame *op.Ops, img *image.RGBA) error {
+	return g.offscreen.readback(&frame.Internal, img)
+}
+
+// renderImages renders the images drawn by the frame that are
+// rendered from operations and are not in the cache.
+func (g *gpu) renderImages() error {
+	for _, img := range g.drawOps.imageOps {
+		m := img.material
+		if m.material != materialTexture || m.layer != 0 || m.data.render == nil {
+			continue
+		}
+		key := imageKey{handle: m.data.handle, filter: m.data.filter}
+		if _, exists := g.cache.get(key); exists {
+			continue
+		}
+		min, mag := textureFilters(m.data.filter, false)
+		tex, err := g.ctx.NewTexture(driver.TextureFormatSRGBA, m.data.size.X, m.data.size.Y, min, mag, driver.WrapClamp, driver.BufferBindingTexture|driver.BufferBindingFramebuffer)
+		if err != nil {
+			return err
+		}
+		// Renderings with a bottom-left origin are flipped when drawn,
+		// by renderedUVTransform.
+		if err := g.offscreen.render(m.data.render, tex, m.data.size); err != nil {
+			tex.Release()
+			return err
+		}
+		g.cache.put(key, &texture{tex: tex})
+	}
+	return nil
+}
+
+// renderShaders renders the shader brushes drawn by the frame that
+// are not in the cache or whose uniforms changed. Shaders whose
+// programs can't run on the device are rasterized with their
+// fallbacks.
+func (g *gpu) renderShaders() error {
+	for _, img := range g.drawOps.imageOps {
+		m := img.material
+		if m.material != materialTexture || m.layer != 0 || m.data.shader == nil {
+			continue
+		}
+		s := m.data.shader
+		key := imageKey{handle: m.data.handle, filter: m.data.filter}
+		var tex driver.Texture
+		if t, exists := g.cache.get(key); exists {
+			if !s.stale {
+				continue
+			}
+			// Only the uniforms changed; render into the existing
+			// texture.
+			tex = t.(*texture).tex
+		} else {
+			size := m.data.size
+			t, err := g.ctx.NewTexture(driver.TextureFormatSRGBA, size.X, size.Y, driver.FilterLinear, driver.FilterLinear, driver.WrapClamp, driver.BufferBindingTexture|driver.BufferBindingFramebuffer)
+			if err != nil {
+				return err
+			}
+			tex = t
+			g.cache.put(key, &texture{tex: tex})
+		}
+		s.stale = false
+		var pipe *pipeline
+		if prog := s.key.shader; prog != nil {
+			// Errors are handled by the fallback.
+			pipe, _ = g.renderer.shaders.pipeline(prog)
+		}
+		if pipe != nil {
+			g.renderer.shaders.render(pipe, g.renderer.blitter.quadVerts, s, tex)
+		} else {
+			driver.UploadImage(tex, image.Point{}, s.rasterize())
+		}
+	}
+	return nil
+}
+
+// textureFilters maps an image filter to texture filters. Mipmapped
+// filtering is replaced by linear filtering if mipmaps is false.
+func textureFilters(filter byte, mipmaps bool) (min, mag driver.TextureFilter) {
+	switch paint.ImageFilter(filter) {
+	case paint.FilterNearest:
+		return driver.FilterNearest, driver.FilterNearest
+	case paint.FilterLinearMipmapLinear:
+		if mipmaps {
+			// The driver generates the mipmaps when the image is uploaded.
+			return driver.FilterLinearMipmapLinear, driver.FilterLinear
+		}
+	}
+	return driver.FilterLinear, driver.FilterLinear
+}
+
 func (r *renderer) texHandle(cache *resourceCache, data imageOpData) driver.Texture {
 	var tex *texture
-	t, exists := cache.get(data.handle)
+	key := imageKey{handle: data.handle, filter: data.filter, wrap: data.wrap}
+	t, exists := cache.get(key)
 	if !exists {
 		t = &texture{
 			src: data.src,
 		}
-		cache.put(data.handle, t)
+		cache.put(key, t)
 	}
 	tex = t.(*texture)
+	r.stats.lookup(tex.tex != nil)
 	if tex.tex != nil {
 		return tex.tex
 	}
-	handle, err := r.ctx.NewTexture(driver.TextureFormatSRGBA, data.src.Bounds().Dx(), data.src.Bounds().Dy(), driver.FilterLinearMipmapLinear, driver.FilterLinear, driver.BufferBindingTexture)
+	r.stats.uploads++
+	minFilter, magFilter := textureFilters(data.filter, true)
+	handle, err := r.ctx.NewTexture(driver.TextureFormatSRGBA, data.size.X, data.size.Y, minFilter, magFilter, data.wrap, driver.BufferBindingTexture)
 	if err != nil {
 		panic(err)
 	}
@@ -457,6 +842,7 @@ func newRenderer(ctx driver.Device) *renderer {
 		blitter: newBlitter(ctx),
 		pather:  newPather(ctx),
 	}
+	r.shaders.ctx = ctx
 
 	maxDim := ctx.Caps().MaxTextureSize
 	// Large atlas textures cause artifacts due to precision loss in
@@ -467,10 +853,15 @@ func newRenderer(ctx driver.Device) *renderer {
 
 	r.packer.maxDims = image.Pt(maxDim, maxDim)
 	r.intersections.maxDims = image.Pt(maxDim, maxDim)
+	r.layers.maxDims = image.Pt(maxDim, maxDim)
 	return r
 }
 
 func (r *renderer) release() {
+	r.layerFBOs.delete(r.ctx, 0)
+	r.rootFBO.delete(r.ctx, 0)
+	r.blender.release(r.ctx)
+	r.shaders.release()
 	r.pather.release()
 	r.blitter.release()
 }
@@ -494,24 +885,79 @@ func newBlitter(ctx driver.Device) *blitter {
 	b.colUniforms = new(blitColUniforms)
 	b.texUniforms = new(blitTexUniforms)
 	b.linearGradientUniforms = new(blitLinearGradientUniforms)
-	pipelines, err := createColorPrograms(ctx, gio.Shader_blit_vert, gio.Shader_blit_frag,
-		[3]interface{}{b.colUniforms, b.linearGradientUniforms, b.texUniforms},
-	)
+	for f, format := range pixelFormats {
+		pipelines, err := createColorPrograms(ctx, gio.Shader_blit_vert, gio.Shader_blit_frag,
+			[3]interface{}{b.colUniforms, b.linearGradientUniforms, b.texUniforms}, format,
+		)
+		if err != nil {
+			panic(err)
+		}
+		b.pipelines[f] = pipelines
+	}
+	b.fadeUniforms = new(blitColUniforms)
+	fade, err := createLayerPipeline(ctx, gio.Shader_blit_frag[materialColor], driver.BlendDesc{
+		Enable:    true,
+		SrcFactor: driver.BlendFactorZero,
+		DstFactor: driver.BlendFactorOneMinusSrcAlpha,
+	}, b.fadeUniforms)
+	if err != nil {
+		panic(err)
+	}
+	b.fadePipeline = fade
+	b.replaceUniforms = new(blitTexUniforms)
+	replace, err := createLayerPipeline(ctx, gio.Shader_blit_frag[materialTexture], driver.BlendDesc{}, b.replaceUniforms)
 	if err != nil {
 		panic(err)
 	}
-	b.pipelines = pipelines
+	b.replacePipeline = replace
 	return b
 }
 
+// createLayerPipeline creates a pipeline for drawing quads to layer
+// textures with a fragment program and a particular blending.
+func createLayerPipeline(b driver.Device, frag shader.Sources, blend driver.BlendDesc, uniforms interface{}) (*pipeline, error) {
+	vsh, err := b.NewVertexShader(gio.Shader_blit_vert)
+	if err != nil {
+		return nil, err
+	}
+	defer vsh.Release()
+	fsh, err := b.NewFragmentShader(frag)
+	if err != nil {
+		return nil, err
+	}
+	defer fsh.Release()
+	pipe, err := b.NewPipeline(driver.PipelineDesc{
+		VertexShader:   vsh,
+		FragmentShader: fsh,
+		BlendDesc:      blend,
+		VertexLayout: driver.VertexLayout{
+			Inputs: []driver.InputDesc{
+				{Type: shader.DataTypeFloat, Size: 2, Offset: 0},
+				{Type: shader.DataTypeFloat, Size: 2, Offset: 4 * 2},
+			},
+			Stride: 4 * 4,
+		},
+		PixelFormat: pixelFormats[formatLayer],
+		Topology:    driver.TopologyTriangleStrip,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pipeline{pipe, newUniformBuffer(b, uniforms)}, nil
+}
+
 func (b *blitter) release() {
 	b.quadVerts.Release()
-	for _, p := range b.pipelines {
-		p.Release()
+	for _, pipes := range b.pipelines {
+		for _, p := range pipes {
+			p.Release()
+		}
 	}
+	b.fadePipeline.Release()
+	b.replacePipeline.Release()
 }
 
-func createColorPrograms(b driver.Device, vsSrc shader.Sources, fsSrc [3]shader.Sources, uniforms [3]interface{}) ([3]*pipeline, error) {
+func createColorPrograms(b driver.Device, vsSrc shader.Sources, fsSrc [3]shader.Sources, uniforms [3]interface{}, format driver.TextureFormat) ([3]*pipeline, error) {
 	var pipelines [3]*pipeline
 	blend := driver.BlendDesc{
 		Enable:    true,
@@ -541,7 +987,7 @@ func createColorPrograms(b driver.Device, vsSrc shader.Sources, fsSrc [3]shader.
 			FragmentShader: fsh,
 			BlendDesc:      blend,
 			VertexLayout:   layout,
-			PixelFormat:    driver.TextureFormatOutput,
+			PixelFormat:    format,
 			Topology:       driver.TopologyTriangleStrip,
 		})
 		if err != nil {
@@ -566,7 +1012,7 @@ func createColorPrograms(b driver.Device, vsSrc shader.Sources, fsSrc [3]shader.
 			FragmentShader: fsh,
 			BlendDesc:      blend,
 			VertexLayout:   layout,
-			PixelFormat:    driver.TextureFormatOutput,
+			PixelFormat:    format,
 			Topology:       driver.TopologyTriangleStrip,
 		})
 		if err != nil {
@@ -592,7 +1038,7 @@ func createColorPrograms(b driver.Device, vsSrc shader.Sources, fsSrc [3]shader.
 			FragmentShader: fsh,
 			BlendDesc:      blend,
 			VertexLayout:   layout,
-			PixelFormat:    driver.TextureFormatOutput,
+			PixelFormat:    format,
 			Topology:       driver.TopologyTriangleStrip,
 		})
 		if err != nil {
@@ -762,12 +1208,17 @@ func (r *renderer) packStencils(pops *[]*pathOp) {
 
 func (d *drawOps) reset(viewport image.Point) {
 	d.profile = false
+	d.stats = frameStats{}
 	d.viewport = viewport
 	d.imageOps = d.imageOps[:0]
 	d.pathOps = d.pathOps[:0]
 	d.pathOpCache = d.pathOpCache[:0]
 	d.vertCache = d.vertCache[:0]
 	d.transStack = d.transStack[:0]
+	d.layers = d.layers[:0]
+	d.layer = 0
+	d.layerStack = d.layerStack[:0]
+	d.cacheStack = d.cacheStack[:0]
 }
 
 func (d *drawOps) collect(root *op.Ops, viewport image.Point) {
@@ -780,6 +1231,10 @@ func (d *drawOps) collect(root *op.Ops, viewport image.Point) {
 	}
 	d.reader.Reset(ops)
 	d.collectOps(&d.reader, viewf)
+	// End layers left open.
+	for len(d.layerStack) > 0 {
+		d.popLayer()
+	}
 }
 
 func (d *drawOps) buildPaths(ctx driver.Device) {
@@ -800,7 +1255,9 @@ func (d *drawOps) newPathOp() *pathOp {
 	return &d.pathOpCache[len(d.pathOpCache)-1]
 }
 
-func (d *drawOps) addClipPath(state *drawState, aux []byte, auxKey opKey, bounds f32.Rectangle, off f32.Point, push bool) {
+// addClipPath adds a clip path to the clip stack of state. The hash
+// identifies the shape of the path apart from its bounds and offset.
+func (d *drawOps) addClipPath(state *drawState, aux []byte, auxKey opKey, bounds f32.Rectangle, off f32.Point, push bool, hash uint64) {
 	npath := d.newPathOp()
 	*npath = pathOp{
 		parent:    state.cpath,
@@ -809,16 +1266,26 @@ func (d *drawOps) addClipPath(state *drawState, aux []byte, auxKey opKey, bounds
 		intersect: bounds.Add(off),
 		rect:      true,
 	}
+	k := struct {
+		parent, hash uint64
+		bounds       f32.Rectangle
+		off          f32.Point
+	}{hash: hash, bounds: bounds, off: off}
 	if npath.parent != nil {
 		npath.rect = npath.parent.rect
 		npath.intersect = npath.parent.intersect.Intersect(npath.intersect)
+		k.parent = npath.parent.hash
 	}
+	d.hasher.Reset()
+	d.hasher.Write((*[unsafe.Sizeof(k)]byte)(unsafe.Pointer(&k))[:])
+	npath.hash = d.hasher.Sum64()
 	if len(aux) > 0 {
 		npath.rect = false
 		npath.pathKey = auxKey
 		npath.path = true
 		npath.pathVerts = aux
 		d.pathOps = append(d.pathOps, npath)
+		d.stats.paths++
 	}
 	state.cpath = npath
 }
@@ -830,6 +1297,72 @@ func (d *drawOps) save(id int, state f32.Affine2D) {
 	d.states[id] = state
 }
 
+// shapeHash returns the hash of the clip shape described by key, the
+// hash of its path data and its transformation t. Unlike key, the
+// hash is equal for equal shapes of different frames.
+func (d *drawOps) shapeHash(key opKey, pathHash uint64, t f32.Affine2D) uint64 {
+	key.Key = ops.Key{}
+	key.cache = cacheKey{}
+	k := struct {
+		key  opKey
+		hash uint64
+		t    f32.Affine2D
+	}{key, pathHash, t}
+	d.hasher.Reset()
+	d.hasher.Write((*[unsafe.Sizeof(k)]byte)(unsafe.Pointer(&k))[:])
+	return d.hasher.Sum64()
+}
+
+// trackDamage adds the operations of the frame to the damage
+// tracker and computes the damage region.
+func (d *drawOps) trackDamage() {
+	if !d.clear {
+		// The frame draws on top of the previous frame.
+		d.damage.invalidate()
+	} else {
+		k := d.clearColor
+		d.hasher.Reset()
+		d.hasher.Write((*[unsafe.Sizeof(k)]byte)(unsafe.Pointer(&k))[:])
+		d.damage.add(d.hasher.Sum64(), image.Rectangle{Max: d.viewport})
+	}
+	for _, img := range d.imageOps {
+		if img.material.layer != 0 {
+			// The blending of a layer is covered by the operations
+			// of the layer.
+			continue
+		}
+		k := struct {
+			path    uint64
+			clip    image.Rectangle
+			mat     material
+			gen     uint64
+			opacity [8]float32
+		}{clip: img.clip, mat: img.material}
+		if img.path != nil {
+			k.path = img.path.hash
+		}
+		if s := img.material.data.shader; s != nil {
+			// A shader image is re-rendered in place when its
+			// uniforms change.
+			k.gen = s.gen
+		}
+		for i, l := img.layer, 0; i > 0; i, l = d.layers[i-1].parent, l+1 {
+			layer := d.layers[i-1]
+			if layer.readsBackdrop() || l == len(k.opacity) {
+				// Blending and blurring affect pixels outside
+				// the operations.
+				d.damage.invalidate()
+				break
+			}
+			k.opacity[l] = layer.opacity
+		}
+		d.hasher.Reset()
+		d.hasher.Write((*[unsafe.Sizeof(k)]byte)(unsafe.Pointer(&k))[:])
+		d.damage.add(d.hasher.Sum64(), img.clip)
+	}
+	d.region = d.damage.frame(d.viewport)
+}
+
 func (k opKey) SetTransform(t f32.Affine2D) opKey {
 	sx, hx, _, hy, sy, _ := t.Elems()
 	k.sx = sx
@@ -855,6 +1388,10 @@ loop:
 		switch ops.OpType(encOp.Data[0]) {
 		case ops.TypeProfile:
 			d.profile = true
+		case ops.TypeCache:
+			d.cacheStack = append(d.cacheStack, cacheKey{key: encOp.Refs[0]})
+		case ops.TypePopCache:
+			d.cacheStack = d.cacheStack[:len(d.cacheStack)-1]
 		case ops.TypeTransform:
 			dop, push := ops.DecodeTransform(encOp.Data)
 			if push {
@@ -867,15 +1404,25 @@ loop:
 			d.transStack = d.transStack[:n-1]
 
 		case ops.TypeStroke:
-			quads.key.strokeWidth = decodeStrokeOp(encOp.Data)
+			quads.key.stroke, quads.dashes = decodeStrokeOp(encOp.Data, encOp.Refs)
+			quads.key.dashes = dashHash(quads.dashes)
 
 		case ops.TypePath:
 			encOp, ok = r.Decode()
 			if !ok {
 				break loop
 			}
+			quads.hash = binary.LittleEndian.Uint64(encOp.Data[1:])
 			quads.aux = encOp.Data[ops.TypeAuxLen:]
 			quads.key.Key = encOp.Key
+			if n := len(d.cacheStack); n > 0 {
+				// Identify the path by its cache key, to reuse its
+				// encoding from previous frames.
+				c := &d.cacheStack[n-1]
+				c.index++
+				quads.key.Key = ops.Key{}
+				quads.key.cache = *c
+			}
 
 		case ops.TypeClip:
 			var op ops.ClipOp
@@ -888,15 +1435,19 @@ loop:
 				// cache key such that it will be equal only if the transform is the
 				// same also. Use cached data if we have it.
 				quads.key = quads.key.SetTransform(trans)
-				if v, ok := d.pathCache.get(quads.key); ok {
+				v, ok := d.pathCache.get(quads.key)
+				d.stats.lookup(ok)
+				if ok {
 					// Since the GPU data exists in the cache aux will not be used.
 					// Why is this not used for the offset shapes?
 					bounds = v.bounds
 				} else {
+					start := time.Now()
 					var pathData []byte
 					pathData, bounds = d.buildVerts(
-						quads.aux, trans, quads.key.outline, quads.key.strokeWidth,
+						quads.aux, trans, quads.key.outline, quads.key.stroke, quads.dashes,
 					)
+					d.stats.encode += time.Since(start)
 					quads.aux = pathData
 					// add it to the cache, without GPU data, so the transform can be
 					// reused.
@@ -906,11 +1457,21 @@ loop:
 				quads.aux, bounds, _ = d.boundsForTransformedRect(bounds, trans)
 				quads.key = opKey{Key: encOp.Key}
 			}
-			d.addClipPath(&state, quads.aux, quads.key, bounds, off, true)
+			hash := d.shapeHash(quads.key, quads.hash, trans)
+			d.addClipPath(&state, quads.aux, quads.key, bounds, off, true, hash)
 			quads = quadsOp{}
 		case ops.TypePopClip:
 			state.cpath = state.cpath.parent
 
+		case ops.TypeOpacity:
+			d.pushLayer(ops.DecodeOpacity(encOp.Data), blend.SourceOver, 0)
+		case ops.TypePopOpacity:
+			d.popLayer()
+		case ops.TypeBlend:
+			d.pushLayer(1, ops.DecodeBlend(encOp.Data), 0)
+		case ops.TypePopBlend:
+			d.popLayer()
+
 		case ops.TypeColor:
 			state.matType = materialColor
 			state.color = decodeColorOp(encOp.Data)
@@ -921,62 +1482,41 @@ loop:
 			state.stop2 = op.stop2
 			state.color1 = op.color1
 			state.color2 = op.color2
+		case ops.TypeRadialGradient:
+			state.matType = materialGradient
+			state.gradient = decodeRadialGradientOp(encOp.Data)
+		case ops.TypeConicGradient:
+			state.matType = materialGradient
+			state.gradient = decodeConicGradientOp(encOp.Data)
+		case ops.TypeGradient:
+			g := decodeGradientOp(encOp.Data, encOp.Refs)
+			if g.kind > gradient.Conic {
+				// A gradient of unknown kind paints nothing.
+				state.matType = materialColor
+				state.color = color.NRGBA{}
+				break
+			}
+			state.matType = materialGradient
+			state.gradient = g
 		case ops.TypeImage:
 			state.matType = materialTexture
 			state.image = decodeImageOp(encOp.Data, encOp.Refs)
+		case ops.TypeShadow:
+			state.matType = materialGradient
+			state.gradient = decodeShadowOp(encOp.Data)
+		case ops.TypeShader:
+			state.matType = materialShader
+			state.shader = decodeShaderOp(encOp.Data, encOp.Refs)
 		case ops.TypePaint:
-			// Transform (if needed) the painting rectangle and if so generate a clip path,
-			// for those cases also compute a partialTrans that maps texture coordinates between
-			// the new bounding rectangle and the transformed original paint rectangle.
-			t, off := state.t.Split()
-			// Fill the clip area, unless the material is a (bounded) image.
-			// TODO: Find a tighter bound.
-			inf := float32(1e6)
-			dst := f32.Rect(-inf, -inf, inf, inf)
-			if state.matType == materialTexture {
-				sz := state.image.src.Rect.Size()
-				dst = f32.Rectangle{Max: layout.FPt(sz)}
-			}
-			clipData, bnd, partialTrans := d.boundsForTransformedRect(dst, t)
-			cl := viewport.Intersect(bnd.Add(off))
-			if state.cpath != nil {
-				cl = state.cpath.intersect.Intersect(cl)
-			}
-			if cl.Empty() {
-				continue
-			}
-
-			if clipData != nil {
-				// The paint operation is sheared or rotated, add a clip path representing
-				// this transformed rectangle.
-				k := opKey{Key: encOp.Key}
-				k.SetTransform(t) // TODO: This call has no effect.
-				d.addClipPath(&state, clipData, k, bnd, off, false)
-			}
-
-			bounds := cl.Round()
-			mat := state.materialFor(bnd, off, partialTrans, bounds)
-
-			rect := state.cpath == nil || state.cpath.rect
-			if bounds.Min == (image.Point{}) && bounds.Max == d.viewport && rect && mat.opaque && (mat.material == materialColor) {
-				// The image is a uniform opaque color and takes up the whole screen.
-				// Scrap images up to and including this image and set clear color.
-				d.imageOps = d.imageOps[:0]
-				d.clearColor = mat.color.Opaque()
-				d.clear = true
-				continue
-			}
-			img := imageOp{
-				path:     state.cpath,
-				clip:     bounds,
-				material: mat,
-			}
-
-			d.imageOps = append(d.imageOps, img)
-			if clipData != nil {
-				// we added a clip path that should not remain
-				state.cpath = state.cpath.parent
-			}
+			d.paint(&state, viewport, encOp.Key)
+		case ops.TypeBlur:
+			// Blur through a layer masked by the clip area.
+			d.pushLayer(1, blend.SourceOver, ops.DecodeBlur(encOp.Data))
+			mask := state
+			mask.matType = materialColor
+			mask.color = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			d.paint(&mask, viewport, encOp.Key)
+			d.popLayer()
 		case ops.TypeSave:
 			id := ops.DecodeSave(encOp.Data)
 			d.save(id, state.t)
@@ -988,6 +1528,193 @@ loop:
 	}
 }
 
+// paint fills the current clip area with the current brush.
+func (d *drawOps) paint(state *drawState, viewport f32.Rectangle, key ops.Key) {
+	switch state.matType {
+	case materialGradient:
+		op, ok := state.gradient.linear(state.t)
+		if !ok {
+			d.paintBrushImage(state, viewport)
+			return
+		}
+		// Draw two-stop linear gradients with the linear gradient
+		// material, in pixel coordinates.
+		st := *state
+		st.matType = materialLinearGradient
+		st.t = f32.Affine2D{}
+		st.stop1, st.stop2 = op.stop1, op.stop2
+		st.color1, st.color2 = op.color1, op.color2
+		state = &st
+	case materialShader:
+		d.paintBrushImage(state, viewport)
+		return
+	}
+	// Transform (if needed) the painting rectangle and if so generate a clip path,
+	// for those cases also compute a partialTrans that maps texture coordinates between
+	// the new bounding rectangle and the transformed original paint rectangle.
+	t, off := state.t.Split()
+	// Fill the clip area, unless the material is a (bounded) image.
+	// TODO: Find a tighter bound.
+	inf := float32(1e6)
+	dst := f32.Rect(-inf, -inf, inf, inf)
+	if state.matType == materialTexture {
+		sz := state.image.size
+		dst = f32.Rectangle{Max: layout.FPt(sz)}
+	}
+	clipData, bnd, partialTrans := d.boundsForTransformedRect(dst, t)
+	cl := viewport.Intersect(bnd.Add(off))
+	if state.cpath != nil {
+		cl = state.cpath.intersect.Intersect(cl)
+	}
+	if cl.Empty() {
+		return
+	}
+
+	if clipData != nil {
+		// The paint operation is sheared or rotated, add a clip path representing
+		// this transformed rectangle.
+		k := opKey{Key: key}
+		k.SetTransform(t) // TODO: This call has no effect.
+		d.addClipPath(state, clipData, k, bnd, off, false, d.shapeHash(opKey{}, 0, t))
+	}
+
+	bounds := cl.Round()
+	mat := state.materialFor(bnd, off, partialTrans, bounds)
+
+	rect := state.cpath == nil || state.cpath.rect
+	if bounds.Min == (image.Point{}) && bounds.Max == d.viewport && rect && mat.opaque && (mat.material == materialColor) && d.layer == 0 {
+		// The image is a uniform opaque color and takes up the whole screen.
+		// Scrap images up to and including this image and set clear color.
+		d.imageOps = d.imageOps[:0]
+		d.layers = d.layers[:0]
+		d.clearColor = mat.color.Opaque()
+		d.clear = true
+		return
+	}
+	img := imageOp{
+		path:     state.cpath,
+		clip:     bounds,
+		material: mat,
+		layer:    d.layer,
+	}
+
+	d.imageOps = append(d.imageOps, img)
+	if clipData != nil {
+		// we added a clip path that should not remain
+		state.cpath = state.cpath.parent
+	}
+}
+
+// paintBrushImage paints the current gradient or shader brush over
+// the current clip area. Gradients are drawn from textures in their
+// canonical positions, while shaders are rendered to images that cover
+// the clip area.
+func (d *drawOps) paintBrushImage(state *drawState, viewport f32.Rectangle) {
+	cl := viewport
+	if state.cpath != nil {
+		cl = state.cpath.intersect.Intersect(cl)
+	}
+	bounds := cl.Round()
+	if bounds.Empty() {
+		return
+	}
+	var mat material
+	if state.matType == materialShader {
+		off := layout.FPt(bounds.Min)
+		rect := f32.Rectangle{Max: layout.FPt(bounds.Size())}
+		st := *state
+		st.matType = materialTexture
+		st.image = d.shaders.get(state.shader, state.t, bounds).imageOp()
+		mat = st.materialFor(rect, off, f32.Affine2D{}, bounds)
+	} else {
+		img, toTex := d.gradients.texture(state.gradient, state.t, bounds)
+		// Map the quad of bounds to pixels, and pixels to the texture.
+		toPixels := f32.Affine2D{}.Scale(f32.Point{}, layout.FPt(bounds.Size())).Offset(layout.FPt(bounds.Min))
+		mat = material{
+			material: materialTexture,
+			data:     img,
+			uvTrans:  toTex.Mul(toPixels),
+		}
+	}
+	d.imageOps = append(d.imageOps, imageOp{
+		path:     state.cpath,
+		clip:     bounds,
+		material: mat,
+		layer:    d.layer,
+	})
+}
+
+// pushLayer starts an opacity, blend or blur layer.
+func (d *drawOps) pushLayer(opacity float32, mode blend.Mode, blur float32) {
+	d.layerStack = append(d.layerStack, d.layer)
+	if opacity == 1 && mode == blend.SourceOver && blur <= 0 {
+		// An opaque layer is equivalent to no layer.
+		return
+	}
+	depth := 1
+	if d.layer > 0 {
+		depth += d.layers[d.layer-1].depth
+	}
+	d.layers = append(d.layers, opacityLayer{
+		opacity: opacity,
+		mode:    mode,
+		blur:    blur,
+		parent:  d.layer,
+		depth:   depth,
+		opStart: len(d.imageOps),
+	})
+	d.layer = len(d.layers)
+}
+
+// blendsRoot reports whether a layer is blended into the root layer
+// with a mode other than source-over or blurs the root layer.
+func (d *drawOps) blendsRoot() bool {
+	for _, l := range d.layers {
+		if l.parent == 0 && l.readsBackdrop() {
+			return true
+		}
+	}
+	return false
+}
+
+// readsBackdrop reports whether the layer is blended or blurred by
+// reading back its backdrop.
+func (l opacityLayer) readsBackdrop() bool {
+	return l.mode != blend.SourceOver || l.blur > 0
+}
+
+// popLayer ends the current opacity layer and adds the operation that
+// blends it into its parent.
+func (d *drawOps) popLayer() {
+	n := len(d.layerStack)
+	parent := d.layerStack[n-1]
+	d.layerStack = d.layerStack[:n-1]
+	idx := d.layer
+	d.layer = parent
+	if idx == parent {
+		return
+	}
+	l := &d.layers[idx-1]
+	for _, img := range d.imageOps[l.opStart:] {
+		l.clip = l.clip.Union(img.clip)
+	}
+	if l.opacity == 0 || l.clip.Empty() {
+		// Discard the invisible layer along with its operations
+		// and inner layers.
+		d.imageOps = d.imageOps[:l.opStart]
+		d.layers = d.layers[:idx-1]
+		return
+	}
+	d.imageOps = append(d.imageOps, imageOp{
+		clip: l.clip,
+		material: material{
+			material: materialTexture,
+			layer:    idx,
+		},
+		layer: parent,
+	})
+}
+
 func expandPathOp(p *pathOp, clip image.Rectangle) {
 	for p != nil {
 		pclip := p.clip
@@ -1016,22 +1743,27 @@ func (d *drawState) materialFor(rect f32.Rectangle, off f32.Point, partTrans f32
 		m.uvTrans = partTrans.Mul(gradientSpaceTransform(clip, off, d.stop1, d.stop2))
 	case materialTexture:
 		m.material = materialTexture
-		dr := rect.Add(off).Round()
-		sz := d.image.src.Bounds().Size()
+		// Map the clip area to the image through the exact image
+		// rectangle. Scaled images don't cover whole pixels, and
+		// stretching them to the rounded clip area misplaces their
+		// pixels.
+		dr := rect.Add(off)
+		fclip := f32.FRect(clip)
+		sz := d.image.size
 		sr := f32.Rectangle{
 			Max: f32.Point{
 				X: float32(sz.X),
 				Y: float32(sz.Y),
 			},
 		}
-		dx := float32(dr.Dx())
+		dx := dr.Dx()
 		sdx := sr.Dx()
-		sr.Min.X += float32(clip.Min.X-dr.Min.X) * sdx / dx
-		sr.Max.X -= float32(dr.Max.X-clip.Max.X) * sdx / dx
-		dy := float32(dr.Dy())
+		sr.Min.X += (fclip.Min.X - dr.Min.X) * sdx / dx
+		sr.Max.X -= (dr.Max.X - fclip.Max.X) * sdx / dx
+		dy := dr.Dy()
 		sdy := sr.Dy()
-		sr.Min.Y += float32(clip.Min.Y-dr.Min.Y) * sdy / dy
-		sr.Max.Y -= float32(dr.Max.Y-clip.Max.Y) * sdy / dy
+		sr.Min.Y += (fclip.Min.Y - dr.Min.Y) * sdy / dy
+		sr.Max.Y -= (dr.Max.Y - fclip.Max.Y) * sdy / dy
 		uvScale, uvOffset := texSpaceTransform(sr, sz)
 		m.uvTrans = partTrans.Mul(f32.Affine2D{}.Scale(f32.Point{}, uvScale).Offset(uvOffset))
 		m.data = d.image
@@ -1039,10 +1771,16 @@ func (d *drawState) materialFor(rect f32.Rectangle, off f32.Point, partTrans f32
 	return m
 }
 
+// renderedUVTransform flips the texture coordinates of images rendered
+// from operations on devices whose textures have their origin at the
+// bottom left. Uploaded images have their first row at the texture origin
+// instead.
+var renderedUVTransform = f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(1, -1)).Offset(f32.Pt(0, 1))
+
 func (r *renderer) uploadImages(cache *resourceCache, ops []imageOp) {
 	for _, img := range ops {
 		m := img.material
-		if m.material == materialTexture {
+		if m.material == materialTexture && m.layer == 0 {
 			r.texHandle(cache, m.data)
 		}
 	}
@@ -1051,8 +1789,8 @@ func (r *renderer) uploadImages(cache *resourceCache, ops []imageOp) {
 func (r *renderer) prepareDrawOps(cache *resourceCache, ops []imageOp) {
 	for _, img := range ops {
 		m := img.material
-		switch m.material {
-		case materialTexture:
+		switch {
+		case m.material == materialTexture && m.layer == 0:
 			r.ctx.PrepareTexture(r.texHandle(cache, m.data))
 		}
 
@@ -1069,24 +1807,136 @@ func (r *renderer) prepareDrawOps(cache *resourceCache, ops []imageOp) {
 	}
 }
 
-func (r *renderer) drawOps(cache *resourceCache, ops []imageOp) {
+// packLayers allocates atlas space for the opacity layers.
+func (r *renderer) packLayers(layers []opacityLayer) error {
+	r.layers.clear()
+	depth := 0
+	for _, l := range layers {
+		if l.depth > depth {
+			depth = l.depth
+		}
+	}
+	// Pack the layers of each depth into separate atlases, innermost
+	// layers first, such that no layer is drawn into the atlas it is
+	// blended from.
+	for ; depth > 0; depth-- {
+		page := false
+		for i := range layers {
+			l := &layers[i]
+			if l.depth != depth {
+				continue
+			}
+			if !page {
+				r.layers.newPage()
+				page = true
+			}
+			place, ok := r.layers.add(l.clip.Size())
+			if !ok {
+				return fmt.Errorf("gpu: layer area %v is larger than maximum texture size %v", l.clip, r.layers.maxDims)
+			}
+			l.place = place
+		}
+	}
+	return nil
+}
+
+// drawLayers draws the opacity layers to their atlases and applies
+// their opacities.
+func (r *renderer) drawLayers(cache *resourceCache, ops []imageOp, layers []opacityLayer) error {
+	r.layerFBOs.resize(r.ctx, pixelFormats[formatLayer], r.layers.sizes)
+	for idx, fbo := range r.layerFBOs.fbos {
+		r.ctx.BeginRenderPass(fbo.tex, driver.LoadDesc{Action: driver.LoadActionClear})
+		for i, l := range layers {
+			if l.place.Idx != idx {
+				continue
+			}
+			r.ctx.Viewport(l.place.Pos.X, l.place.Pos.Y, l.clip.Dx(), l.clip.Dy())
+			if err := r.drawOps(cache, ops, layers, i+1, formatLayer); err != nil {
+				r.ctx.EndRenderPass()
+				return err
+			}
+			if l.opacity < 1 {
+				r.blitter.fade(l.opacity)
+			}
+		}
+		r.ctx.EndRenderPass()
+		r.ctx.PrepareTexture(fbo.tex)
+	}
+	return nil
+}
+
+// layerUVTransform returns the transformation from quad texture coordinates
+// to the area of the given size at pos in a layer texture.
+func layerUVTransform(caps driver.Caps, pos, size, texSize image.Point) f32.Affine2D {
+	uv := f32.FRect(image.Rectangle{Min: pos, Max: pos.Add(size)})
+	if caps.BottomLeftOrigin {
+		// Layers are drawn with the window transformation, which flips
+		// them relative to the texture origin.
+		uv.Min.Y, uv.Max.Y = uv.Max.Y, uv.Min.Y
+	}
+	scale, off := texSpaceTransform(uv, texSize)
+	return f32.Affine2D{}.Scale(f32.Point{}, scale).Offset(off)
+}
+
+// drawRootLayer draws the root layer to an offscreen texture cleared
+// to col.
+func (r *renderer) drawRootLayer(cache *resourceCache, ops []imageOp, layers []opacityLayer, col f32color.RGBA) (stencilFBO, error) {
+	viewport := r.blitter.viewport
+	r.rootFBO.resize(r.ctx, pixelFormats[formatLayer], []image.Point{viewport})
+	fbo := r.rootFBO.fbos[0]
+	r.ctx.BeginRenderPass(fbo.tex, driver.LoadDesc{Action: driver.LoadActionClear, ClearColor: col})
+	r.ctx.Viewport(0, 0, viewport.X, viewport.Y)
+	err := r.drawOps(cache, ops, layers, 0, formatLayer)
+	r.ctx.EndRenderPass()
+	r.ctx.PrepareTexture(fbo.tex)
+	return fbo, err
+}
+
+// drawOps draws the operations of a layer to a target of the given
+// format, where 0 denotes the root layer.
+func (r *renderer) drawOps(cache *resourceCache, ops []imageOp, layers []opacityLayer, layer int, format targetFormat) error {
+	var origin image.Point
+	viewport := r.blitter.viewport
+	if layer > 0 {
+		l := layers[layer-1]
+		origin = l.clip.Min
+		viewport = l.clip.Size()
+	}
 	var coverTex driver.Texture
 	for _, img := range ops {
+		if img.layer != layer {
+			continue
+		}
 		m := img.material
-		switch m.material {
-		case materialTexture:
+		switch {
+		case m.material == materialTexture && m.layer > 0:
+			l := layers[m.layer-1]
+			if l.readsBackdrop() {
+				if err := r.blendLayer(layers, layer, m.layer); err != nil {
+					return err
+				}
+				coverTex = nil
+				continue
+			}
+			fbo := r.layerFBOs.fbos[l.place.Idx]
+			r.ctx.BindTexture(0, fbo.tex)
+			m.uvTrans = layerUVTransform(r.ctx.Caps(), l.place.Pos, l.clip.Size(), fbo.size)
+		case m.material == materialTexture:
 			r.ctx.BindTexture(0, r.texHandle(cache, m.data))
+			if m.data.render != nil && r.ctx.Caps().BottomLeftOrigin {
+				m.uvTrans = renderedUVTransform.Mul(m.uvTrans)
+			}
 		}
 		drc := img.clip
 
-		scale, off := clipSpaceTransform(drc, r.blitter.viewport)
+		scale, off := clipSpaceTransform(drc.Sub(origin), viewport)
 		var fbo stencilFBO
 		switch img.clipType {
 		case clipTypeNone:
-			p := r.blitter.pipelines[m.material]
+			p := r.blitter.pipelines[format][m.material]
 			r.ctx.BindPipeline(p.pipeline)
 			r.ctx.BindVertexBuffer(r.blitter.quadVerts, 0)
-			r.blitter.blit(m.material, m.color, m.color1, m.color2, scale, off, m.uvTrans)
+			r.blitter.blit(format, m.material, m.color, m.color1, m.color2, scale, off, m.uvTrans)
 			continue
 		case clipTypePath:
 			fbo = r.pather.stenciler.cover(img.place.Idx)
@@ -1102,15 +1952,16 @@ func (r *renderer) drawOps(cache *resourceCache, ops []imageOp) {
 			Max: img.place.Pos.Add(drc.Size()),
 		}
 		coverScale, coverOff := texSpaceTransform(f32.FRect(uv), fbo.size)
-		p := r.pather.coverer.pipelines[m.material]
+		p := r.pather.coverer.pipelines[format][m.material]
 		r.ctx.BindPipeline(p.pipeline)
 		r.ctx.BindVertexBuffer(r.blitter.quadVerts, 0)
-		r.pather.cover(m.material, m.color, m.color1, m.color2, scale, off, m.uvTrans, coverScale, coverOff)
+		r.pather.cover(format, m.material, m.color, m.color1, m.color2, scale, off, m.uvTrans, coverScale, coverOff)
 	}
+	return nil
 }
 
-func (b *blitter) blit(mat materialType, col f32color.RGBA, col1, col2 f32color.RGBA, scale, off f32.Point, uvTrans f32.Affine2D) {
-	p := b.pipelines[mat]
+func (b *blitter) blit(format targetFormat, mat materialType, col f32color.RGBA, col1, col2 f32color.RGBA, scale, off f32.Point, uvTrans f32.Affine2D) {
+	p := b.pipelines[format][mat]
 	b.ctx.BindPipeline(p.pipeline)
 	var uniforms *blitUniforms
 	switch mat {
@@ -1136,6 +1987,54 @@ func (b *blitter) blit(mat materialType, col f32color.RGBA, col1, col2 f32color.
 	b.ctx.DrawArrays(0, 4)
 }
 
+// blendLayer blends the layer src into the layer dst, where 0 denotes
+// the root layer drawn by drawRootLayer.
+func (r *renderer) blendLayer(layers []opacityLayer, dst, src int) error {
+	var (
+		target driver.Texture
+		origin image.Point
+		vp     image.Rectangle
+	)
+	if dst > 0 {
+		l := layers[dst-1]
+		target = r.layerFBOs.fbos[l.place.Idx].tex
+		origin = l.clip.Min
+		vp = image.Rectangle{Min: l.place.Pos, Max: l.place.Pos.Add(l.clip.Size())}
+	} else {
+		target = r.rootFBO.fbos[0].tex
+		vp = image.Rectangle{Max: r.blitter.viewport}
+	}
+	l := layers[src-1]
+	srcTex := r.layerFBOs.fbos[l.place.Idx].tex
+	area := image.Rectangle{Min: l.place.Pos, Max: l.place.Pos.Add(l.clip.Size())}
+	return r.blender.blend(r.ctx, r.blitter, l.mode, l.blur, target, vp, l.clip.Sub(origin), srcTex, area)
+}
+
+// replace overwrites the area given by scale and off with the
+// bound texture.
+func (b *blitter) replace(scale, off f32.Point, uvTrans f32.Affine2D) {
+	p := b.replacePipeline
+	b.ctx.BindPipeline(p.pipeline)
+	b.ctx.BindVertexBuffer(b.quadVerts, 0)
+	t1, t2, t3, t4, t5, t6 := uvTrans.Elems()
+	b.replaceUniforms.blitUniforms.uvTransformR1 = [4]float32{t1, t2, t3, 0}
+	b.replaceUniforms.blitUniforms.uvTransformR2 = [4]float32{t4, t5, t6, 0}
+	b.replaceUniforms.blitUniforms.transform = [4]float32{scale.X, scale.Y, off.X, off.Y}
+	p.UploadUniforms(b.ctx)
+	b.ctx.DrawArrays(0, 4)
+}
+
+// fade multiplies the current viewport by opacity.
+func (b *blitter) fade(opacity float32) {
+	p := b.fadePipeline
+	b.ctx.BindPipeline(p.pipeline)
+	b.ctx.BindVertexBuffer(b.quadVerts, 0)
+	b.fadeUniforms.color = f32color.RGBA{A: 1 - opacity}
+	b.fadeUniforms.transform = [4]float32{1, 1, 0, 0}
+	p.UploadUniforms(b.ctx)
+	b.ctx.DrawArrays(0, 4)
+}
+
 // newUniformBuffer creates a new GPU uniform buffer backed by the
 // structure uniformBlock points to.
 func newUniformBuffer(b driver.Device, uniformBlock interface{}) *uniformBuffer {
@@ -1271,7 +2170,7 @@ func (d *drawOps) writeVertCache(n int) []byte {
 }
 
 // transform, split paths as needed, calculate maxY, bounds and create GPU vertices.
-func (d *drawOps) buildVerts(pathData []byte, tr f32.Affine2D, outline bool, strWidth float32) (verts []byte, bounds f32.Rectangle) {
+func (d *drawOps) buildVerts(pathData []byte, tr f32.Affine2D, outline bool, str stroke.StrokeStyle, dashes stroke.DashOp) (verts []byte, bounds f32.Rectangle) {
 	inf := float32(math.Inf(+1))
 	d.qs.bounds = f32.Rectangle{
 		Min: f32.Point{X: inf, Y: inf},
@@ -1281,12 +2180,9 @@ func (d *drawOps) buildVerts(pathData []byte, tr f32.Affine2D, outline bool, str
 	startLength := len(d.vertCache)
 
 	switch {
-	case strWidth > 0:
+	case str.Width > 0:
 		// Stroke path.
-		ss := stroke.StrokeStyle{
-			Width: strWidth,
-		}
-		quads := stroke.StrokePathCommands(ss, pathData)
+		quads := stroke.StrokePathCommands(str, dashes, pathData)
 		for _, quad := range quads {
 			d.qs.contour = quad.Contour
 			quad.Quad = quad.Quad.Transform(tr)
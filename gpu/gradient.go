@@ -0,0 +1,434 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/gpu/internal/driver"
+	"gioui.org/internal/f32"
+	"gioui.org/internal/f32color"
+	"gioui.org/internal/gradient"
+	"gioui.org/internal/ops"
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+)
+
+// gradientOpData is the shadow of paint.GradientOp,
+// paint.RadialGradientOp, paint.ConicGradientOp and paint.ShadowOp.
+type gradientOpData struct {
+	kind   gradient.Kind
+	spread gradient.Spread
+	p1, p2 f32.Point
+	// radius and sigma are the corner radius and blur of shadows.
+	radius, sigma float32
+	// stops are the encoded stops of a paint.GradientOp. If empty,
+	// the gradient fades from color1 to color2.
+	stops  string
+	color1 color.NRGBA
+	color2 color.NRGBA
+}
+
+// gradientCache holds the images of gradients. The GPU renderer draws
+// gradients from textures that depend only on their colors and shapes,
+// mapped to their position by the texture coordinates. The compute
+// renderer draws gradients rasterized to images that cover their clip
+// areas.
+type gradientCache struct {
+	images   map[gradientKey]*gradientCacheValue
+	textures map[gradientTextureKey]*gradientCacheValue
+}
+
+type gradientCacheValue struct {
+	img  *image.RGBA
+	used bool
+}
+
+// gradientKey identifies a rasterized gradient. The transform is
+// relative to the origin of the image, so that gradients that
+// differ only by their integer offsets share an image.
+type gradientKey struct {
+	gradient  gradientOpData
+	transform f32.Affine2D
+	size      image.Point
+}
+
+// gradientTextureKey identifies a gradient texture. The gradient
+// is in the canonical position of its kind; see gradientCache.texture.
+type gradientTextureKey struct {
+	gradient gradientOpData
+	// extent is the distance from the center to the edges of a radial
+	// gradient texture, in units of the gradient radius.
+	extent int
+	// scale is the number of texels per unit of a shadow texture.
+	scale float32
+}
+
+const (
+	// rampWidth is the width of linear gradient textures.
+	rampWidth = 1024
+	// lookupSize is the size of the radial gradient textures that span
+	// the gradient radius, and of conic gradient textures.
+	lookupSize = 512
+	// maxGradientSize is the largest gradient texture size. Every
+	// device supports textures of that size.
+	maxGradientSize = 2048
+)
+
+func decodeGradientOp(data []byte, refs []interface{}) gradientOpData {
+	data = data[:ops.TypeGradientLen]
+	bo := binary.LittleEndian
+	g := gradientOpData{
+		kind:   gradient.Kind(data[1]),
+		spread: gradient.Spread(data[2]),
+		p1: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[3:])),
+			Y: math.Float32frombits(bo.Uint32(data[7:])),
+		},
+		p2: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[11:])),
+			Y: math.Float32frombits(bo.Uint32(data[15:])),
+		},
+		stops: refs[0].(string),
+	}
+	if g.spread > gradient.Reflect {
+		g.spread = gradient.Pad
+	}
+	return g
+}
+
+// decodeStops appends the stops of g to stops.
+func (g gradientOpData) decodeStops(stops []gradient.Stop) []gradient.Stop {
+	if g.stops == "" {
+		return append(stops,
+			gradient.Stop{Offset: 0, Color: f32color.LinearFromSRGB(g.color1)},
+			gradient.Stop{Offset: 1, Color: f32color.LinearFromSRGB(g.color2)},
+		)
+	}
+	bo := binary.LittleEndian
+	for enc := g.stops; len(enc) >= 8; enc = enc[8:] {
+		stop := []byte(enc[:8])
+		stops = append(stops, gradient.Stop{
+			Offset: math.Float32frombits(bo.Uint32(stop)),
+			Color:  f32color.LinearFromSRGB(decodeGradientColor(stop[4:])),
+		})
+	}
+	return stops
+}
+
+func decodeRadialGradientOp(data []byte) gradientOpData {
+	data = data[:ops.TypeRadialGradientLen]
+	bo := binary.LittleEndian
+	center := f32.Point{
+		X: math.Float32frombits(bo.Uint32(data[1:])),
+		Y: math.Float32frombits(bo.Uint32(data[5:])),
+	}
+	radius := math.Float32frombits(bo.Uint32(data[9:]))
+	return gradientOpData{
+		kind:   gradient.Radial,
+		p1:     center,
+		p2:     center.Add(f32.Pt(radius, 0)),
+		color1: decodeGradientColor(data[13:]),
+		color2: decodeGradientColor(data[17:]),
+	}
+}
+
+func decodeConicGradientOp(data []byte) gradientOpData {
+	data = data[:ops.TypeConicGradientLen]
+	bo := binary.LittleEndian
+	center := f32.Point{
+		X: math.Float32frombits(bo.Uint32(data[1:])),
+		Y: math.Float32frombits(bo.Uint32(data[5:])),
+	}
+	angle := float64(math.Float32frombits(bo.Uint32(data[9:])))
+	return gradientOpData{
+		kind:   gradient.Conic,
+		p1:     center,
+		p2:     center.Add(f32.Pt(float32(math.Cos(angle)), float32(math.Sin(angle)))),
+		color1: decodeGradientColor(data[13:]),
+		color2: decodeGradientColor(data[17:]),
+	}
+}
+
+func decodeShadowOp(data []byte) gradientOpData {
+	data = data[:ops.TypeShadowLen]
+	bo := binary.LittleEndian
+	return gradientOpData{
+		kind: gradient.Shadow,
+		p1: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[1:])),
+			Y: math.Float32frombits(bo.Uint32(data[5:])),
+		},
+		p2: f32.Point{
+			X: math.Float32frombits(bo.Uint32(data[9:])),
+			Y: math.Float32frombits(bo.Uint32(data[13:])),
+		},
+		radius: math.Float32frombits(bo.Uint32(data[17:])),
+		sigma:  math.Float32frombits(bo.Uint32(data[21:])),
+		color2: decodeGradientColor(data[25:]),
+	}
+}
+
+// linear returns the equivalent linear gradient in pixel coordinates of
+// g transformed by t, if g is a linear gradient with two stops and no
+// spread.
+func (g gradientOpData) linear(t f32.Affine2D) (linearGradientOpData, bool) {
+	if g.kind != gradient.Linear || g.spread != gradient.Pad || len(g.stops) != 2*8 {
+		return linearGradientOpData{}, false
+	}
+	bo := binary.LittleEndian
+	o1 := math.Float32frombits(bo.Uint32([]byte(g.stops[0:4])))
+	o2 := math.Float32frombits(bo.Uint32([]byte(g.stops[8:12])))
+	d := g.p2.Sub(g.p1)
+	l2 := d.X*d.X + d.Y*d.Y
+	if o1 >= o2 || l2 == 0 {
+		return linearGradientOpData{}, false
+	}
+	// The offset is an affine function of pixel coordinates that is
+	// zero at the transformed p1. Its gradient is a.
+	sx, hx, _, hy, sy, _ := t.Invert().Elems()
+	a := f32.Pt(sx*d.X+hy*d.Y, hx*d.X+sy*d.Y).Div(l2)
+	a2 := float64(a.X*a.X + a.Y*a.Y)
+	if !(a2 > 0) || math.IsInf(a2, 0) {
+		return linearGradientOpData{}, false
+	}
+	p1 := t.Transform(g.p1)
+	a = a.Div(float32(a2))
+	return linearGradientOpData{
+		stop1:  p1.Add(a.Mul(o1)),
+		color1: decodeGradientColor([]byte(g.stops[4:8])),
+		stop2:  p1.Add(a.Mul(o2)),
+		color2: decodeGradientColor([]byte(g.stops[12:16])),
+	}, true
+}
+
+// gradient returns the gradient described by g.
+func (g gradientOpData) gradient() gradient.Gradient {
+	return gradient.Gradient{
+		Kind:   g.kind,
+		Spread: g.spread,
+		P1:     g.p1,
+		P2:     g.p2,
+		Stops:  g.decodeStops(nil),
+		Radius: g.radius,
+		Sigma:  g.sigma,
+	}
+}
+
+func decodeGradientColor(data []byte) color.NRGBA {
+	return color.NRGBA{
+		R: data[0],
+		G: data[1],
+		B: data[2],
+		A: data[3],
+	}
+}
+
+// image returns the gradient g, transformed by t, rasterized to an image
+// that covers bounds. The image is meant to be drawn at the offset of
+// bounds without further transformation.
+func (c *gradientCache) image(g gradientOpData, t f32.Affine2D, bounds image.Rectangle) imageOpData {
+	key := gradientKey{
+		gradient:  g,
+		transform: t.Offset(layout.FPt(bounds.Min.Mul(-1))),
+		size:      bounds.Size(),
+	}
+	if v, ok := c.images[key]; ok {
+		v.used = true
+		return imageOpData{src: v.img, size: v.img.Rect.Size(), handle: v.img}
+	}
+	grad := g.gradient()
+	img := image.NewRGBA(image.Rectangle{Max: key.size})
+	grad.Rasterize(img, key.transform.Invert())
+	if c.images == nil {
+		c.images = make(map[gradientKey]*gradientCacheValue)
+	}
+	c.images[key] = &gradientCacheValue{img: img, used: true}
+	return imageOpData{src: img, size: img.Rect.Size(), handle: img}
+}
+
+// texture returns the texture of the gradient g, along with the
+// transformation from pixel coordinates to texture coordinates for
+// drawing g transformed by t over bounds.
+//
+// Gradient textures are rasterized in a canonical position and don't
+// depend on t, except for the extent of radial gradients that repeat
+// and for the scale of shadows. Linear gradients are 1-dimensional
+// ramps repeated or mirrored by the sampler. Radial gradients are
+// centered at the origin with radius 1, and conic gradients start at
+// the positive x axis. Shadows have their rectangle at the origin.
+func (c *gradientCache) texture(g gradientOpData, t f32.Affine2D, bounds image.Rectangle) (imageOpData, f32.Affine2D) {
+	toLocal := t.Invert()
+	// maxDist returns the largest distance from p to the corners of
+	// bounds, in the coordinates of g.
+	maxDist := func(p f32.Point) float32 {
+		var dist float32
+		for _, c := range []image.Point{bounds.Min, {X: bounds.Max.X, Y: bounds.Min.Y}, bounds.Max, {X: bounds.Min.X, Y: bounds.Max.Y}} {
+			if d := length(toLocal.Transform(layout.FPt(c)).Sub(p)); d > dist {
+				dist = d
+			}
+		}
+		return dist
+	}
+	key := gradientTextureKey{gradient: g}
+	key.gradient.p1 = f32.Point{}
+	key.gradient.p2 = f32.Pt(1, 0)
+	wrap := driver.WrapClamp
+	var (
+		size image.Point
+		// raster maps texel centers to the gradient.
+		raster f32.Affine2D
+		// toTex maps the coordinates of g to texture coordinates.
+		toTex f32.Affine2D
+	)
+	d := g.p2.Sub(g.p1)
+	switch l := length(d); {
+	case g.kind == gradient.Linear, g.kind == gradient.Radial && l == 0:
+		const n = rampWidth
+		size = image.Pt(n, 1)
+		// Offsets are projected to the x axis, and the degenerate
+		// radial gradient is the color at offset 1.
+		var proj f32.Affine2D
+		switch {
+		case g.kind == gradient.Radial:
+			proj = f32.NewAffine2D(0, 0, 1, 0, 0, 0)
+		case l == 0:
+			proj = f32.NewAffine2D(0, 0, 0, 0, 0, 0)
+		default:
+			l2 := l * l
+			proj = f32.NewAffine2D(d.X/l2, d.Y/l2, -(g.p1.X*d.X+g.p1.Y*d.Y)/l2, 0, 0, 0)
+		}
+		key.gradient.kind = gradient.Linear
+		if g.spread == gradient.Pad {
+			// Place offset 0 and 1 at the centers of the end texels.
+			raster = f32.NewAffine2D(1/float32(n-1), 0, -.5/float32(n-1), 0, 1, 0)
+			toTex = f32.NewAffine2D(float32(n-1)/n, 0, .5/n, 0, 0, .5).Mul(proj)
+		} else {
+			// Place whole offsets at the center of the first texel.
+			raster = f32.NewAffine2D(1/float32(n), 0, -.5/float32(n), 0, 1, 0)
+			toTex = f32.NewAffine2D(1, 0, .5/n, 0, 0, .5).Mul(proj)
+			wrap = driver.WrapRepeat
+			if g.spread == gradient.Reflect {
+				wrap = driver.WrapMirror
+			}
+		}
+	case g.kind == gradient.Radial:
+		// Padded gradients are constant outside the radius, and clamped
+		// textures repeat their edges. Other gradients are rasterized out
+		// to the farthest corner of bounds, in powers of two.
+		key.extent = 1
+		if g.spread != gradient.Pad {
+			for dist := maxDist(g.p1) / l; float32(key.extent) < dist && key.extent < 1<<16; {
+				key.extent *= 2
+			}
+		}
+		e := float32(key.extent)
+		n := lookupSize * key.extent
+		if n > maxGradientSize {
+			n = maxGradientSize
+		}
+		// An odd size places a texel at the center.
+		n--
+		size = image.Pt(n, n)
+		raster = f32.NewAffine2D(2*e/float32(n), 0, -e, 0, 2*e/float32(n), -e)
+		s := 1 / (2 * e * l)
+		toTex = f32.NewAffine2D(s, 0, .5-g.p1.X*s, 0, s, .5-g.p1.Y*s)
+	case g.kind == gradient.Conic:
+		// Conic gradients depend only on the angle, so the texture is
+		// scaled to the farthest corner of bounds.
+		key.gradient.spread = gradient.Pad
+		dist := maxDist(g.p1)
+		if dist == 0 {
+			dist = 1
+		}
+		const n = lookupSize - 1
+		size = image.Pt(n, n)
+		raster = f32.NewAffine2D(2/float32(n), 0, -1, 0, 2/float32(n), -1)
+		angle := float32(math.Atan2(float64(d.Y), float64(d.X)))
+		toTex = f32.Affine2D{}.
+			Offset(g.p1.Mul(-1)).
+			Rotate(f32.Point{}, -angle).
+			Scale(f32.Point{}, f32.Pt(.5/dist, .5/dist)).
+			Offset(f32.Pt(.5, .5))
+	case g.kind == gradient.Shadow:
+		rect := f32.Rectangle{Min: g.p1, Max: g.p2}.Canon()
+		key.gradient.p1 = f32.Point{}
+		key.gradient.p2 = rect.Size()
+		key.gradient.spread = gradient.Pad
+		// Rasterize at the largest scale of t, with a border of
+		// transparent texels for clamping.
+		sx, hx, _, hy, sy, _ := t.Elems()
+		scale := float32(math.Max(math.Hypot(float64(sx), float64(hy)), math.Hypot(float64(hx), float64(sy))))
+		if !(scale > 0) {
+			scale = 1
+		}
+		border := 3 * g.sigma
+		if border < 0 {
+			border = 0
+		}
+		texSize := func() image.Point {
+			pad := 2 * (border + 1/scale)
+			return image.Pt(
+				int(math.Ceil(float64((key.gradient.p2.X+pad)*scale))),
+				int(math.Ceil(float64((key.gradient.p2.Y+pad)*scale))),
+			)
+		}
+		size = texSize()
+		if l := math.Max(float64(size.X), float64(size.Y)); l > maxGradientSize {
+			scale *= float32(maxGradientSize / l)
+			size = texSize()
+		}
+		key.scale = scale
+		pad := border + 1/scale
+		raster = f32.NewAffine2D(1/scale, 0, -pad, 0, 1/scale, -pad)
+		w, h := float32(size.X), float32(size.Y)
+		toTex = f32.NewAffine2D(scale/w, 0, (pad-rect.Min.X)*scale/w, 0, scale/h, (pad-rect.Min.Y)*scale/h)
+	default:
+		panic("invalid gradient kind")
+	}
+	if c.textures == nil {
+		c.textures = make(map[gradientTextureKey]*gradientCacheValue)
+	}
+	v, ok := c.textures[key]
+	if !ok {
+		img := image.NewRGBA(image.Rectangle{Max: size})
+		grad := key.gradient.gradient()
+		grad.Rasterize(img, raster)
+		v = &gradientCacheValue{img: img}
+		c.textures[key] = v
+	}
+	v.used = true
+	img := imageOpData{
+		src:    v.img,
+		size:   size,
+		handle: v.img,
+		filter: byte(paint.FilterLinear),
+		wrap:   wrap,
+	}
+	return img, toTex.Mul(toLocal)
+}
+
+// frame discards the images not used since the previous call to frame.
+func (c *gradientCache) frame() {
+	for k, v := range c.images {
+		if !v.used {
+			delete(c.images, k)
+			continue
+		}
+		v.used = false
+	}
+	for k, v := range c.textures {
+		if !v.used {
+			delete(c.textures, k)
+			continue
+		}
+		v.used = false
+	}
+}
+
+func length(p f32.Point) float32 {
+	return float32(math.Hypot(float64(p.X), float64(p.Y)))
+}
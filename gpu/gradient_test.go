@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"encoding/binary"
+	"image"
+	"math"
+	"testing"
+
+	"gioui.org/internal/f32"
+	"gioui.org/internal/gradient"
+)
+
+func TestLinearGradientEquivalent(t *testing.T) {
+	stops := make([]byte, 2*8)
+	for i, o := range []float32{.25, .75} {
+		stop := stops[i*8:]
+		binary.LittleEndian.PutUint32(stop, math.Float32bits(o))
+		copy(stop[4:], []byte{0xff, 0, 0, 0xff})
+	}
+	g := gradientOpData{
+		kind:  gradient.Linear,
+		p1:    f32.Pt(10, 20),
+		p2:    f32.Pt(50, 30),
+		stops: string(stops),
+	}
+	grad := g.gradient()
+	transforms := []f32.Affine2D{
+		{},
+		f32.Affine2D{}.Offset(f32.Pt(7, -3)),
+		f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(2, .5)).Rotate(f32.Pt(5, 5), 1),
+		f32.Affine2D{}.Shear(f32.Point{}, .5, 0).Offset(f32.Pt(1, 2)),
+	}
+	for _, tr := range transforms {
+		lin, ok := g.linear(tr)
+		if !ok {
+			t.Fatalf("%v: no linear equivalent", tr)
+		}
+		// Map the stops of the equivalent to [0, 1].
+		eq := gradient.Gradient{Kind: gradient.Linear, P1: lin.stop1, P2: lin.stop2}
+		for _, p := range []f32.Point{{}, {X: 100, Y: 3}, {X: -20, Y: 60}} {
+			want := (grad.Offset(tr.Invert().Transform(p)) - .25) / .5
+			if got := eq.Offset(p); math.Abs(float64(got-want)) > 1e-4 {
+				t.Errorf("%v: offset at %v is %v, want %v", tr, p, got, want)
+			}
+		}
+	}
+}
+
+func TestGradientTextureTranslation(t *testing.T) {
+	gradients := []gradientOpData{
+		{kind: gradient.Linear, spread: gradient.Repeat, p1: f32.Pt(10, 10), p2: f32.Pt(20, 30)},
+		{kind: gradient.Radial, p1: f32.Pt(10, 10), p2: f32.Pt(20, 30)},
+		{kind: gradient.Radial, spread: gradient.Reflect, p1: f32.Pt(10, 10), p2: f32.Pt(20, 30)},
+		{kind: gradient.Conic, p1: f32.Pt(10, 10), p2: f32.Pt(20, 30)},
+		{kind: gradient.Shadow, p1: f32.Pt(10, 10), p2: f32.Pt(20, 30), radius: 4, sigma: 8},
+	}
+	for _, g := range gradients {
+		var c gradientCache
+		bounds := image.Rect(0, 0, 100, 50)
+		img1, _ := c.texture(g, f32.Affine2D{}, bounds)
+		tr := f32.Affine2D{}.Offset(f32.Pt(13.5, -40))
+		img2, _ := c.texture(g, tr, bounds.Add(image.Pt(13, -40)))
+		if img1.handle != img2.handle {
+			t.Errorf("kind %d: translated gradient has a new texture", g.kind)
+		}
+		if n := len(c.textures); n != 1 {
+			t.Errorf("kind %d: got %d textures, want 1", g.kind, n)
+		}
+	}
+}
+
+func TestShadowTextureRotation(t *testing.T) {
+	g := gradientOpData{kind: gradient.Shadow, p1: f32.Pt(10, 10), p2: f32.Pt(60, 30), radius: 4, sigma: 8}
+	var c gradientCache
+	bounds := image.Rect(0, 0, 100, 100)
+	img1, _ := c.texture(g, f32.Affine2D{}, bounds)
+	img2, _ := c.texture(g, f32.Affine2D{}.Rotate(f32.Pt(50, 50), math.Pi/2), bounds)
+	if img1.handle != img2.handle {
+		t.Error("rotated shadow has a new texture")
+	}
+}
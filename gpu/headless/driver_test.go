@@ -149,6 +149,7 @@ func newFBO(t *testing.T, b driver.Device, size image.Point) driver.Texture {
 		driver.TextureFormatSRGBA,
 		size.X, size.Y,
 		driver.FilterNearest, driver.FilterNearest,
+		driver.WrapClamp,
 		driver.BufferBindingFramebuffer,
 	)
 	if err != nil {
@@ -2,6 +2,9 @@
 
 // Package headless implements headless windows for rendering
 // an operation list to an image.
+//
+// Headless windows need a working GPU backend. Package
+// gioui.org/gpu/software renders without one.
 package headless
 
 import (
@@ -11,6 +14,7 @@ import (
 
 	"gioui.org/gpu"
 	"gioui.org/gpu/internal/driver"
+	"gioui.org/io/profile"
 	"gioui.org/op"
 )
 
@@ -76,6 +80,7 @@ func NewWindow(width, height int) (*Window, error) {
 			driver.TextureFormatSRGBA,
 			width, height,
 			driver.FilterNearest, driver.FilterNearest,
+			driver.WrapClamp,
 			driver.BufferBindingFramebuffer,
 		)
 		if err != nil {
@@ -135,6 +140,40 @@ func (w *Window) Frame(frame *op.Ops) error {
 	})
 }
 
+// Profile returns the last available profile of the frames drawn
+// by Frame. Frames are profiled when they contain a profile.Op. The
+// Total and Present durations are zero, because headless windows
+// don't present their frames.
+func (w *Window) Profile() profile.Frame {
+	if fp, ok := w.gpu.(gpu.FrameProfiler); ok {
+		return fp.FrameProfile()
+	}
+	return profile.Frame{}
+}
+
+// RenderImage renders the operations of call into an image of size
+// pixels. The image is transparent where the operations don't draw.
+// Rendering does not affect the window content.
+func (w *Window) RenderImage(call op.CallOp, size image.Point) (*image.RGBA, error) {
+	if size.X < 0 || size.Y < 0 {
+		return nil, errors.New("headless: invalid image size")
+	}
+	r, ok := w.gpu.(gpu.ImageRenderer)
+	if !ok {
+		return nil, errors.New("headless: GPU can't render images")
+	}
+	o := new(op.Ops)
+	call.Add(o)
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	err := contextDo(w.ctx, func() error {
+		return r.RenderImage(o, img)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
 // Screenshot transfers the Window content at origin img.Rect.Min to img.
 func (w *Window) Screenshot(img *image.RGBA) error {
 	return contextDo(w.ctx, func() error {
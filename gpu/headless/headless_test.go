@@ -3,14 +3,21 @@
 package headless
 
 import (
+	"encoding/binary"
 	"image"
 	"image/color"
+	"math"
 	"testing"
 
+	"gioui.org/f32"
+	"gioui.org/gpu"
+	"gioui.org/gpu/internal/driver"
 	"gioui.org/internal/f32color"
+	"gioui.org/io/profile"
 	"gioui.org/op"
 	"gioui.org/op/clip"
 	"gioui.org/op/paint"
+	"gioui.org/shader/gio"
 )
 
 func TestHeadless(t *testing.T) {
@@ -136,6 +143,152 @@ func TestNoOps(t *testing.T) {
 	}
 }
 
+func TestLargeLayer(t *testing.T) {
+	// A window wider than the largest atlas of opacity layers.
+	w, err := NewWindow(8200, 4)
+	if err != nil {
+		t.Skipf("large headless windows not supported: %v", err)
+	}
+	defer w.Release()
+	var ops op.Ops
+	opacity := paint.OpacityOp{Opacity: .5}.Push(&ops)
+	paint.Fill(&ops, color.NRGBA{R: 0xff, A: 0xff})
+	opacity.Pop()
+	if err := w.Frame(&ops); err == nil {
+		t.Error("drawing a layer larger than the maximum texture size succeeded")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	w, release := newTestWindow(t)
+	defer release()
+
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	imgOp := paint.NewImageOp(img)
+	var ops op.Ops
+	frame := func() profile.Frame {
+		ops.Reset()
+		profile.Op{Tag: w}.Add(&ops)
+		paint.FillShape(&ops, color.NRGBA{R: 0xff, A: 0xff}, clip.Ellipse(image.Rect(10, 10, 110, 60)).Op(&ops))
+		off := op.Offset(image.Pt(200, 200)).Push(&ops)
+		imgOp.Add(&ops)
+		paint.PaintOp{}.Add(&ops)
+		off.Pop()
+		if err := w.Frame(&ops); err != nil {
+			t.Fatal(err)
+		}
+		return w.Profile()
+	}
+	p := frame()
+	if p.Paths != 1 || p.Images != 1 {
+		t.Errorf("got %d paths and %d images, expected 1 of each", p.Paths, p.Images)
+	}
+	if p.Uploads != 1 || p.CacheMisses == 0 {
+		t.Errorf("got %d uploads and %d cache misses in the first frame, expected an upload and misses", p.Uploads, p.CacheMisses)
+	}
+	p = frame()
+	if p.Uploads != 0 || p.CacheHits == 0 {
+		t.Errorf("got %d uploads and %d cache hits in the second frame, expected no uploads and hits", p.Uploads, p.CacheHits)
+	}
+}
+
+func TestRenderImage(t *testing.T) {
+	w, release := newTestWindow(t)
+	defer release()
+
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	green := color.NRGBA{G: 0xff, A: 0xff}
+	src := new(op.Ops)
+	m := op.Record(src)
+	paint.FillShape(src, red, clip.Rect(image.Rect(0, 0, 10, 10)).Op())
+	paint.FillShape(src, green, clip.Rect(image.Rect(0, 0, 5, 5)).Op())
+	call := m.Stop()
+
+	img, err := w.RenderImage(call, image.Pt(20, 20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var bg color.NRGBA
+	tests := []struct {
+		x, y  int
+		color color.NRGBA
+	}{
+		{2, 2, green}, {7, 7, red}, {15, 15, bg},
+	}
+	for _, test := range tests {
+		if got, exp := img.RGBAAt(test.x, test.y), f32color.NRGBAToRGBA(test.color); got != exp {
+			t.Errorf("(%d,%d): got color %v, expected %v", test.x, test.y, got, exp)
+		}
+	}
+
+	// Draw the rendering as an image, twice to reuse it.
+	var ops op.Ops
+	im := paint.NewRenderedImageOp(call, image.Pt(20, 20))
+	im.Filter = paint.FilterNearest
+	for i := 0; i < 2; i++ {
+		ops.Reset()
+		off := op.Offset(image.Pt(100, 100)).Push(&ops)
+		im.Add(&ops)
+		paint.PaintOp{}.Add(&ops)
+		off.Pop()
+		if err := w.Frame(&ops); err != nil {
+			t.Fatal(err)
+		}
+	}
+	img = image.NewRGBA(image.Rectangle{Max: w.Size()})
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		x, y := test.x+100, test.y+100
+		if got, exp := img.RGBAAt(x, y), f32color.NRGBAToRGBA(test.color); got != exp {
+			t.Errorf("(%d,%d): got color %v, expected %v", x, y, got, exp)
+		}
+	}
+}
+
+func TestCacheOp(t *testing.T) {
+	w, release := newTestWindow(t)
+	defer release()
+
+	col := color.NRGBA{R: 0xff, A: 0xff}
+	frame := func(off int) profile.Frame {
+		// Record the cached operations anew every frame, like a
+		// program would.
+		var ops op.Ops
+		profile.Op{Tag: w}.Add(&ops)
+		m := op.Record(&ops)
+		paint.FillShape(&ops, col, clip.Stroke{
+			Path:  clip.Ellipse(image.Rect(10, 10, 110, 60)).Path(&ops),
+			Width: 4,
+		}.Op())
+		call := m.Stop()
+		st := op.Offset(image.Pt(off, 0)).Push(&ops)
+		op.CacheOp{Key: "ellipse", Call: call}.Add(&ops)
+		st.Pop()
+		if err := w.Frame(&ops); err != nil {
+			t.Fatal(err)
+		}
+		return w.Profile()
+	}
+	p := frame(0)
+	if p.CacheHits != 0 {
+		t.Errorf("got %d cache hits in the first frame, expected none", p.CacheHits)
+	}
+	// Moving the cached operations doesn't invalidate them.
+	p = frame(100)
+	if p.CacheHits == 0 {
+		t.Errorf("got no cache hits in the second frame")
+	}
+	img := image.NewRGBA(image.Rectangle{Max: w.Size()})
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := img.RGBAAt(110, 35), f32color.NRGBAToRGBA(col); got != exp {
+		t.Errorf("got color %v, expected %v", got, exp)
+	}
+}
+
 func newTestWindow(t *testing.T) (*Window, func()) {
 	t.Helper()
 	sz := image.Point{X: 800, Y: 600}
@@ -147,3 +300,192 @@ func newTestWindow(t *testing.T) (*Window, func()) {
 		w.Release()
 	}
 }
+
+func TestDamage(t *testing.T) {
+	w, release := newTestWindow(t)
+	defer release()
+
+	frame := func(r image.Rectangle) []image.Rectangle {
+		var ops op.Ops
+		profile.Op{Tag: w}.Add(&ops)
+		paint.Fill(&ops, color.NRGBA{A: 0xff})
+		paint.FillShape(&ops, color.NRGBA{R: 0xff, A: 0xff}, clip.Rect(r).Op())
+		if err := w.Frame(&ops); err != nil {
+			t.Fatal(err)
+		}
+		return w.Profile().Damage
+	}
+	full := image.Rectangle{Max: w.Size()}
+	r1 := image.Rect(10, 10, 50, 50)
+	if d := frame(r1); len(d) != 1 || d[0] != full {
+		t.Errorf("got damage %v in the first frame, expected %v", d, full)
+	}
+	if d := frame(r1); len(d) != 0 {
+		t.Errorf("got damage %v for an unchanged frame, expected none", d)
+	}
+	r2 := image.Rect(200, 200, 240, 240)
+	d := frame(r2)
+	var area image.Rectangle
+	for _, r := range d {
+		area = area.Union(r)
+	}
+	if !r1.In(area) || !r2.In(area) {
+		t.Errorf("got damage %v for a moved rectangle, expected %v and %v", d, r1, r2)
+	}
+	if full.In(area) {
+		t.Errorf("got damage %v for a moved rectangle, expected less than the frame", d)
+	}
+
+	// Animate the uniforms of a shader.
+	s := paint.NewShader(gio.Shader_blit_frag[0], func(p f32.Point, uniforms []byte) color.NRGBA {
+		return color.NRGBA{R: uniforms[len(uniforms)-1], A: 0xff}
+	})
+	shade := func(v float32) []image.Rectangle {
+		uniforms := make([]byte, 112-paint.ShaderUniformsOffset+16)
+		for i, v := range []float32{v, 0, 0, 1} {
+			binary.LittleEndian.PutUint32(uniforms[112-paint.ShaderUniformsOffset+i*4:], math.Float32bits(v))
+		}
+		var ops op.Ops
+		profile.Op{Tag: w}.Add(&ops)
+		paint.Fill(&ops, color.NRGBA{A: 0xff})
+		cl := clip.Rect(r1).Push(&ops)
+		paint.ShaderOp{Shader: s, Uniforms: uniforms}.Add(&ops)
+		paint.PaintOp{}.Add(&ops)
+		cl.Pop()
+		if err := w.Frame(&ops); err != nil {
+			t.Fatal(err)
+		}
+		return w.Profile().Damage
+	}
+	shade(1)
+	if d := shade(1); len(d) != 0 {
+		t.Errorf("got damage %v for unchanged uniforms, expected none", d)
+	}
+	d = shade(.5)
+	area = image.Rectangle{}
+	for _, r := range d {
+		area = area.Union(r)
+	}
+	if !r1.In(area) {
+		t.Errorf("got damage %v for changed uniforms, expected %v", d, r1)
+	}
+}
+
+func TestPartialRedraw(t *testing.T) {
+	w, release := newTestWindow(t)
+	defer release()
+	pr, ok := w.gpu.(gpu.PartialRedrawer)
+	if !ok {
+		t.Skip("GPU doesn't support partial redraws")
+	}
+
+	red := color.NRGBA{R: 0xff, A: 0xff}
+	frame := func(r image.Rectangle, age int) {
+		var ops op.Ops
+		paint.FillShape(&ops, red, clip.Rect(r).Op())
+		pr.SetBufferAge(age)
+		if err := w.Frame(&ops); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Fill the target with green behind the back of the GPU, to
+	// detect the pixels it draws.
+	mark := func() {
+		green := image.NewRGBA(image.Rectangle{Max: w.Size()})
+		for i := 0; i < len(green.Pix); i += 4 {
+			green.Pix[i+1], green.Pix[i+3] = 0xff, 0xff
+		}
+		err := contextDo(w.ctx, func() error {
+			driver.UploadImage(w.fboTex, image.Point{}, green)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	r1 := image.Rect(10, 10, 50, 50)
+	r2 := image.Rect(100, 20, 140, 60)
+	frame(r1, 0)
+	mark()
+	// Move the rectangle, and draw into the target holding the
+	// previous frame.
+	frame(r2, 1)
+	img := image.NewRGBA(image.Rectangle{Max: w.Size()})
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	green := color.RGBA{G: 0xff, A: 0xff}
+	for _, p := range []struct {
+		pos image.Point
+		exp color.RGBA
+	}{
+		{image.Pt(20, 20), color.RGBA{}},
+		{image.Pt(120, 40), f32color.NRGBAToRGBA(red)},
+		{image.Pt(200, 100), green},
+		{image.Pt(120, 580), green},
+	} {
+		if got := img.RGBAAt(p.pos.X, p.pos.Y); got != p.exp {
+			t.Errorf("partial redraw: got color %v at %v, expected %v", got, p.pos, p.exp)
+		}
+	}
+	// An unknown age redraws everything.
+	mark()
+	frame(r2, 0)
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	if got := img.RGBAAt(200, 100); got != (color.RGBA{}) {
+		t.Errorf("full redraw: got color %v outside the damage, expected transparent", got)
+	}
+}
+
+func TestShader(t *testing.T) {
+	w, release := newTestWindow(t)
+	defer release()
+
+	// Use the color program of Gio as the shader. Its color uniform is
+	// at offset 112 of the uniform block, and linear and premultiplied.
+	s := paint.NewShader(gio.Shader_blit_frag[0], func(p f32.Point, uniforms []byte) color.NRGBA {
+		return color.NRGBA{R: 0xff, A: 0xff}
+	})
+	uniforms := make([]byte, 112-paint.ShaderUniformsOffset+16)
+	for i, v := range []float32{1, 0, 0, 1} {
+		binary.LittleEndian.PutUint32(uniforms[112-paint.ShaderUniformsOffset+i*4:], math.Float32bits(v))
+	}
+	var ops op.Ops
+	cl := clip.Rect(image.Rect(10, 10, 50, 50)).Push(&ops)
+	paint.ShaderOp{Shader: s, Uniforms: uniforms}.Add(&ops)
+	paint.PaintOp{}.Add(&ops)
+	cl.Pop()
+	if err := w.Frame(&ops); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rectangle{Max: w.Size()})
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := img.RGBAAt(20, 20), f32color.NRGBAToRGBA(color.NRGBA{R: 0xff, A: 0xff}); got != exp {
+		t.Errorf("got color %v inside the clip, expected %v", got, exp)
+	}
+	if got := img.RGBAAt(60, 20); got != (color.RGBA{}) {
+		t.Errorf("got color %v outside the clip, expected transparent", got)
+	}
+	// Animate the uniforms.
+	for i, v := range []float32{0, 0, 1, 1} {
+		binary.LittleEndian.PutUint32(uniforms[112-paint.ShaderUniformsOffset+i*4:], math.Float32bits(v))
+	}
+	ops.Reset()
+	cl = clip.Rect(image.Rect(10, 10, 50, 50)).Push(&ops)
+	paint.ShaderOp{Shader: s, Uniforms: uniforms}.Add(&ops)
+	paint.PaintOp{}.Add(&ops)
+	cl.Pop()
+	if err := w.Frame(&ops); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := img.RGBAAt(20, 20), f32color.NRGBAToRGBA(color.NRGBA{B: 0xff, A: 0xff}); got != exp {
+		t.Errorf("got color %v after changing uniforms, expected %v", got, exp)
+	}
+}
@@ -209,7 +209,7 @@ func (b *Backend) Release() {
 	*b = Backend{}
 }
 
-func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, bindings driver.BufferBinding) (driver.Texture, error) {
+func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, wrap driver.TextureWrap, bindings driver.BufferBinding) (driver.Texture, error) {
 	var d3dfmt uint32
 	switch format {
 	case driver.TextureFormatFloat:
@@ -274,8 +274,8 @@ func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, min
 		var err error
 		sampler, err = b.dev.CreateSamplerState(&d3d11.SAMPLER_DESC{
 			Filter:        filter,
-			AddressU:      d3d11.TEXTURE_ADDRESS_CLAMP,
-			AddressV:      d3d11.TEXTURE_ADDRESS_CLAMP,
+			AddressU:      convTextureWrap(wrap),
+			AddressV:      convTextureWrap(wrap),
 			AddressW:      d3d11.TEXTURE_ADDRESS_CLAMP,
 			MaxAnisotropy: 1,
 			MinLOD:        -math.MaxFloat32,
@@ -548,6 +548,9 @@ func (b *Backend) NewVertexShader(src shader.Sources) (driver.VertexShader, erro
 }
 
 func (b *Backend) NewFragmentShader(src shader.Sources) (driver.FragmentShader, error) {
+	if len(src.DXBC) == 0 {
+		return nil, fmt.Errorf("d3d11: no bytecode for shader %q", src.Name)
+	}
 	fs, err := b.dev.CreatePixelShader([]byte(src.DXBC))
 	if err != nil {
 		return nil, err
@@ -830,6 +833,19 @@ func (b *Backend) EndRenderPass() {
 
 func (f *Texture) ImplementsRenderTarget() {}
 
+func convTextureWrap(w driver.TextureWrap) uint32 {
+	switch w {
+	case driver.WrapClamp:
+		return d3d11.TEXTURE_ADDRESS_CLAMP
+	case driver.WrapRepeat:
+		return d3d11.TEXTURE_ADDRESS_WRAP
+	case driver.WrapMirror:
+		return d3d11.TEXTURE_ADDRESS_MIRROR
+	default:
+		panic("unsupported texture wrap")
+	}
+}
+
 func convBufferBinding(typ driver.BufferBinding) uint32 {
 	var bindings uint32
 	if typ&driver.BufferBindingVertices != 0 {
@@ -22,7 +22,7 @@ type Device interface {
 	// IsContinuousTime reports whether all timer measurements
 	// are valid at the point of call.
 	IsTimeContinuous() bool
-	NewTexture(format TextureFormat, width, height int, minFilter, magFilter TextureFilter, bindings BufferBinding) (Texture, error)
+	NewTexture(format TextureFormat, width, height int, minFilter, magFilter TextureFilter, wrap TextureWrap, bindings BufferBinding) (Texture, error)
 	NewImmutableBuffer(typ BufferBinding, data []byte) (Buffer, error)
 	NewBuffer(typ BufferBinding, size int) (Buffer, error)
 	NewComputeProgram(shader shader.Sources) (Program, error)
@@ -59,6 +59,10 @@ var ErrDeviceLost = errors.New("GPU device lost")
 type LoadDesc struct {
 	Action     LoadAction
 	ClearColor f32color.RGBA
+	// Scissor restricts the clear and the draws of the render pass
+	// to a rectangle in the coordinates of Viewport, unless it is
+	// empty. It requires FeatureScissor.
+	Scissor image.Rectangle
 }
 
 type Pipeline interface {
@@ -97,6 +101,9 @@ type BlendFactor uint8
 type Topology uint8
 
 type TextureFilter uint8
+
+type TextureWrap uint8
+
 type TextureFormat uint8
 
 type BufferBinding uint8
@@ -169,11 +176,21 @@ const (
 	FilterLinearMipmapLinear
 )
 
+const (
+	// WrapClamp clamps texture coordinates to the edge texels.
+	WrapClamp TextureWrap = iota
+	// WrapRepeat repeats the texture.
+	WrapRepeat
+	// WrapMirror repeats the texture, mirrored at every other repetition.
+	WrapMirror
+)
+
 const (
 	FeatureTimers Features = 1 << iota
 	FeatureFloatRenderTargets
 	FeatureCompute
 	FeatureSRGB
+	FeatureScissor
 )
 
 const (
@@ -290,13 +290,15 @@ static CFTypeRef newTexture(CFTypeRef devRef, NSUInteger width, NSUInteger heigh
 	}
 }
 
-static CFTypeRef newSampler(CFTypeRef devRef, MTLSamplerMinMagFilter minFilter, MTLSamplerMinMagFilter magFilter, MTLSamplerMipFilter mipFilter) {
+static CFTypeRef newSampler(CFTypeRef devRef, MTLSamplerMinMagFilter minFilter, MTLSamplerMinMagFilter magFilter, MTLSamplerMipFilter mipFilter, MTLSamplerAddressMode addressMode) {
 	@autoreleasepool {
 		id<MTLDevice> dev = (__bridge id<MTLDevice>)devRef;
 		MTLSamplerDescriptor *desc = [MTLSamplerDescriptor new];
 		desc.minFilter = minFilter;
 		desc.magFilter = magFilter;
 		desc.mipFilter = mipFilter;
+		desc.sAddressMode = addressMode;
+		desc.tAddressMode = addressMode;
 		return CFBridgingRetain([dev newSamplerStateWithDescriptor:desc]);
 	}
 }
@@ -579,7 +581,7 @@ func (b *Backend) Release() {
 	*b = Backend{}
 }
 
-func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, bindings driver.BufferBinding) (driver.Texture, error) {
+func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, wrap driver.TextureWrap, bindings driver.BufferBinding) (driver.Texture, error) {
 	mformat := pixelFormatFor(format)
 	var usage C.MTLTextureUsage
 	if bindings&(driver.BufferBindingTexture|driver.BufferBindingShaderStorageRead) != 0 {
@@ -602,7 +604,7 @@ func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, min
 	if tex == 0 {
 		return nil, errors.New("metal: [MTLDevice newTextureWithDescriptor:] failed")
 	}
-	s := C.newSampler(b.dev, min, max, mip)
+	s := C.newSampler(b.dev, min, max, mip, samplerAddressModeFor(wrap))
 	if s == 0 {
 		C.CFRelease(tex)
 		return nil, errors.New("metal: [MTLDevice newSamplerStateWithDescriptor:] failed")
@@ -610,6 +612,19 @@ func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, min
 	return &Texture{backend: b, texture: tex, sampler: s, width: width, height: height, mipmap: mipmap}, nil
 }
 
+func samplerAddressModeFor(w driver.TextureWrap) C.MTLSamplerAddressMode {
+	switch w {
+	case driver.WrapClamp:
+		return C.MTLSamplerAddressModeClampToEdge
+	case driver.WrapRepeat:
+		return C.MTLSamplerAddressModeRepeat
+	case driver.WrapMirror:
+		return C.MTLSamplerAddressModeMirrorRepeat
+	default:
+		panic("invalid texture wrap")
+	}
+}
+
 func samplerFilterFor(f driver.TextureFilter) (C.MTLSamplerMinMagFilter, C.MTLSamplerMipFilter) {
 	switch f {
 	case driver.FilterNearest:
@@ -770,6 +785,9 @@ func (b *Backend) NewFragmentShader(src shader.Sources) (driver.FragmentShader,
 }
 
 func (b *Backend) newShader(src shader.Sources) (*Shader, error) {
+	if len(src.MetalLib) == 0 {
+		return nil, fmt.Errorf("metal: no library for shader %q", src.Name)
+	}
 	vsrc := []byte(src.MetalLib)
 	cname := C.CString(src.Name)
 	defer C.free(unsafe.Pointer(cname))
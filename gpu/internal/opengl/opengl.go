@@ -62,7 +62,7 @@ type glState struct {
 	prog     gl.Program
 	texUnits struct {
 		active gl.Enum
-		binds  [2]gl.Texture
+		binds  [3]gl.Texture
 	}
 	arrayBuf  gl.Buffer
 	elemBuf   gl.Buffer
@@ -72,6 +72,7 @@ type glState struct {
 	storeBufs [4]gl.Buffer
 	vertArray gl.VertexArray
 	srgb      bool
+	scissor   bool
 	blend     struct {
 		enable         bool
 		srcRGB, dstRGB gl.Enum
@@ -202,6 +203,7 @@ func newOpenGLDevice(api driver.OpenGL) (driver.Device, error) {
 		sharedCtx:   api.Shared,
 	}
 	b.feats.BottomLeftOrigin = true
+	b.feats.Features |= driver.FeatureScissor
 	if srgbErr == nil {
 		b.feats.Features |= driver.FeatureSRGB
 	}
@@ -319,6 +321,7 @@ func (b *Backend) queryState() glState {
 		s.pack_row_length = b.funcs.GetInteger(gl.PACK_ROW_LENGTH)
 	}
 	s.blend.enable = b.funcs.IsEnabled(gl.BLEND)
+	s.scissor = b.funcs.IsEnabled(gl.SCISSOR_TEST)
 	s.blend.srcRGB = gl.Enum(b.funcs.GetInteger(gl.BLEND_SRC_RGB))
 	s.blend.dstRGB = gl.Enum(b.funcs.GetInteger(gl.BLEND_DST_RGB))
 	s.blend.srcA = gl.Enum(b.funcs.GetInteger(gl.BLEND_SRC_ALPHA))
@@ -373,6 +376,7 @@ func (b *Backend) restoreState(dst glState) {
 	bf := dst.blend
 	src.setBlendFuncSeparate(f, bf.srcRGB, bf.dstRGB, bf.srcA, bf.dstA)
 	src.set(f, gl.FRAMEBUFFER_SRGB, dst.srgb)
+	src.set(f, gl.SCISSOR_TEST, dst.scissor)
 	src.bindVertexArray(f, dst.vertArray)
 	src.useProgram(f, dst.prog)
 	src.bindBuffer(f, gl.ELEMENT_ARRAY_BUFFER, dst.elemBuf)
@@ -638,6 +642,11 @@ func (s *glState) set(f *gl.Functions, target gl.Enum, enable bool) {
 			return
 		}
 		s.blend.enable = enable
+	case gl.SCISSOR_TEST:
+		if s.scissor == enable {
+			return
+		}
+		s.scissor = enable
 	default:
 		panic("unknown enable")
 	}
@@ -689,7 +698,7 @@ func (t *texture) ensureFBO() gl.Framebuffer {
 	return fb
 }
 
-func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, binding driver.BufferBinding) (driver.Texture, error) {
+func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, wrap driver.TextureWrap, binding driver.BufferBinding) (driver.Texture, error) {
 	glErr(b.funcs)
 	tex := &texture{backend: b, obj: b.funcs.CreateTexture(), width: width, height: height, bindings: binding}
 	switch format {
@@ -712,8 +721,9 @@ func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, min
 	tex.mipmap = mipmap
 	b.funcs.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, mag)
 	b.funcs.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, min)
-	b.funcs.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, gl.CLAMP_TO_EDGE)
-	b.funcs.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, gl.CLAMP_TO_EDGE)
+	w := toTexWrap(wrap)
+	b.funcs.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_S, w)
+	b.funcs.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_WRAP_T, w)
 	if mipmap {
 		nmipmaps := 1
 		if mipmap {
@@ -1200,6 +1210,13 @@ func (b *Backend) EndCompute() {
 func (b *Backend) BeginRenderPass(tex driver.Texture, desc driver.LoadDesc) {
 	fbo := tex.(*texture).ensureFBO()
 	b.glstate.bindFramebuffer(b.funcs, gl.FRAMEBUFFER, fbo)
+	// The scissor test affects clears as well as draws.
+	if r := desc.Scissor; !r.Empty() {
+		b.glstate.set(b.funcs, gl.SCISSOR_TEST, true)
+		b.funcs.Scissor(int32(r.Min.X), int32(r.Min.Y), int32(r.Dx()), int32(r.Dy()))
+	} else {
+		b.glstate.set(b.funcs, gl.SCISSOR_TEST, false)
+	}
 	switch desc.Action {
 	case driver.LoadActionClear:
 		c := desc.ClearColor
@@ -1210,6 +1227,7 @@ func (b *Backend) BeginRenderPass(tex driver.Texture, desc driver.LoadDesc) {
 }
 
 func (b *Backend) EndRenderPass() {
+	b.glstate.set(b.funcs, gl.SCISSOR_TEST, false)
 }
 
 func (f *texture) ImplementsRenderTarget() {}
@@ -1232,6 +1250,19 @@ func toTexFilter(f driver.TextureFilter) (int, bool) {
 	}
 }
 
+func toTexWrap(w driver.TextureWrap) int {
+	switch w {
+	case driver.WrapClamp:
+		return gl.CLAMP_TO_EDGE
+	case driver.WrapRepeat:
+		return gl.REPEAT
+	case driver.WrapMirror:
+		return gl.MIRRORED_REPEAT
+	default:
+		panic("unsupported texture wrap")
+	}
+}
+
 func (b *Backend) PrepareTexture(tex driver.Texture) {}
 
 func (b *Backend) BindTexture(unit int, t driver.Texture) {
@@ -293,3 +293,100 @@ func TestStrokedRect(t *testing.T) {
 	}, func(r result) {
 	})
 }
+
+func TestStrokedCaps(t *testing.T) {
+	run(t, func(o *op.Ops) {
+		caps := []clip.StrokeCap{clip.ButtCap, clip.SquareCap, clip.RoundCap}
+		for i, c := range caps {
+			y := float32(20 + i*30)
+			var p clip.Path
+			p.Begin(o)
+			p.MoveTo(f32.Pt(30, y))
+			p.LineTo(f32.Pt(90, y))
+			paint.FillShape(o, black, clip.Stroke{
+				Path:  p.End(),
+				Width: 10,
+				Cap:   c,
+			}.Op())
+		}
+	}, func(r result) {
+		r.expect(27, 20, transparent)
+		r.expect(31, 20, colornames.Black)
+		r.expect(26, 50, colornames.Black)
+		r.expect(25, 45, colornames.Black)
+		r.expect(26, 80, colornames.Black)
+		r.expect(25, 75, transparent)
+	})
+}
+
+func TestStrokedJoins(t *testing.T) {
+	run(t, func(o *op.Ops) {
+		joins := []clip.StrokeJoin{clip.MiterJoin, clip.BevelJoin, clip.RoundJoin}
+		for i, j := range joins {
+			x := float32(20 + i*40)
+			var p clip.Path
+			p.Begin(o)
+			p.MoveTo(f32.Pt(x, 100))
+			p.LineTo(f32.Pt(x, 20))
+			p.LineTo(f32.Pt(x+25, 20))
+			paint.FillShape(o, black, clip.Stroke{
+				Path:  p.End(),
+				Width: 10,
+				Cap:   clip.ButtCap,
+				Join:  j,
+			}.Op())
+		}
+	}, func(r result) {
+		r.expect(15, 15, colornames.Black)
+		r.expect(55, 15, transparent)
+		r.expect(57, 18, colornames.Black)
+		r.expect(95, 15, transparent)
+		r.expect(97, 18, colornames.Black)
+	})
+}
+
+func TestStrokedDashes(t *testing.T) {
+	run(t, func(o *op.Ops) {
+		line := func(y float32) clip.PathSpec {
+			var p clip.Path
+			p.Begin(o)
+			p.MoveTo(f32.Pt(10, y))
+			p.LineTo(f32.Pt(118, y))
+			return p.End()
+		}
+		paint.FillShape(o, black, clip.Stroke{
+			Path:   line(20),
+			Width:  6,
+			Cap:    clip.ButtCap,
+			Dashes: []float32{10, 5},
+		}.Op())
+		paint.FillShape(o, black, clip.Stroke{
+			Path:       line(50),
+			Width:      6,
+			Cap:        clip.ButtCap,
+			Dashes:     []float32{10, 10},
+			DashOffset: 5,
+		}.Op())
+		paint.FillShape(o, black, clip.Stroke{
+			Path:   line(80),
+			Width:  6,
+			Dashes: []float32{1, 9},
+		}.Op())
+		paint.FillShape(o, black, clip.Stroke{
+			Path:   clip.Rect{Min: image.Pt(20, 95), Max: image.Pt(108, 120)}.Path(),
+			Width:  2,
+			Cap:    clip.ButtCap,
+			Dashes: []float32{8, 4},
+		}.Op())
+	}, func(r result) {
+		r.expect(15, 20, colornames.Black)
+		r.expect(22, 20, transparent)
+		r.expect(27, 20, colornames.Black)
+		r.expect(12, 50, colornames.Black)
+		r.expect(20, 50, transparent)
+		r.expect(30, 50, colornames.Black)
+		r.expect(10, 80, colornames.Black)
+		r.expect(15, 80, transparent)
+		r.expect(20, 80, colornames.Black)
+	})
+}
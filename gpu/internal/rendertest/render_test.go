@@ -326,6 +326,236 @@ func TestLinearGradientAngled(t *testing.T) {
 	}, func(r result) {})
 }
 
+func TestRadialGradient(t *testing.T) {
+	run(t, func(ops *op.Ops) {
+		paint.RadialGradientOp{
+			Center: f32.Pt(32, 64),
+			Radius: 32,
+			Color1: white,
+			Color2: red,
+		}.Add(ops)
+		cl := clip.Rect(image.Rect(0, 0, 64, 128)).Push(ops)
+		paint.PaintOp{}.Add(ops)
+		cl.Pop()
+
+		// Stretch the gradient to an ellipse. Brushes are
+		// transformed by the transformation of the PaintOp.
+		cl = clip.Rect(image.Rect(64, 0, 128, 128)).Push(ops)
+		t := op.Affine(f32.Affine2D{}.Scale(f32.Pt(96, 64), f32.Pt(1, 2))).Push(ops)
+		paint.RadialGradientOp{
+			Center: f32.Pt(96, 64),
+			Radius: 24,
+			Color1: black,
+			Color2: blue,
+		}.Add(ops)
+		paint.PaintOp{}.Add(ops)
+		t.Pop()
+		cl.Pop()
+	}, func(r result) {
+		r.expect(32, 64, colornames.White)
+		r.expect(0, 0, colornames.Red)
+		r.expect(96, 64, colornames.Black)
+		r.expect(96, 126, colornames.Blue)
+		r.expect(127, 64, colornames.Blue)
+		// The point is outside the unstretched circle, and inside the
+		// ellipse. The comparison of expect is too coarse for shades
+		// of blue.
+		if c := r.img.RGBAAt(96, 88); c.B > 220 {
+			r.t.Errorf("got %v at (96,88), expected the gradient stretched to an ellipse", c)
+		}
+	})
+}
+
+func TestConicGradient(t *testing.T) {
+	run(t, func(ops *op.Ops) {
+		paint.ConicGradientOp{
+			Center: f32.Pt(64, 64),
+			Angle:  0,
+			Color1: black,
+			Color2: green,
+		}.Add(ops)
+		cl := clip.Rect(image.Rect(0, 0, 128, 128)).Push(ops)
+		paint.PaintOp{}.Add(ops)
+		cl.Pop()
+
+		defer op.Offset(image.Pt(0, 64)).Push(ops).Pop()
+		paint.ConicGradientOp{
+			Center: f32.Pt(32, 32),
+			Angle:  math.Pi / 2,
+			Color1: white,
+			Color2: magenta,
+		}.Add(ops)
+		cl = clip.Rect(image.Rect(0, 0, 64, 64)).Push(ops)
+		paint.PaintOp{}.Add(ops)
+		cl.Pop()
+	}, func(r result) {
+		r.expect(127, 65, colornames.Black)
+		r.expect(127, 62, colornames.Green)
+		r.expect(30, 127, colornames.White)
+		r.expect(33, 127, colornames.Magenta)
+	})
+}
+
+func TestGradientSpread(t *testing.T) {
+	stops := []paint.GradientStop{
+		{Offset: 0, Color: red},
+		{Offset: .5, Color: white},
+		{Offset: 1, Color: blue},
+	}
+	run(t, func(ops *op.Ops) {
+		spreads := []paint.Spread{paint.SpreadPad, paint.SpreadRepeat, paint.SpreadReflect}
+		for i, spread := range spreads {
+			paint.GradientOp{
+				Kind:   paint.LinearGradient,
+				Start:  f32.Pt(.5, 0),
+				End:    f32.Pt(32.5, 0),
+				Stops:  stops,
+				Spread: spread,
+			}.Add(ops)
+			cl := clip.Rect(image.Rect(0, i*32, 128, i*32+32)).Push(ops)
+			paint.PaintOp{}.Add(ops)
+			cl.Pop()
+		}
+		paint.GradientOp{
+			Kind:   paint.RadialGradient,
+			Start:  f32.Pt(64.5, 112.5),
+			End:    f32.Pt(72.5, 112.5),
+			Stops:  stops,
+			Spread: paint.SpreadRepeat,
+		}.Add(ops)
+		cl := clip.Rect(image.Rect(0, 96, 128, 128)).Push(ops)
+		paint.PaintOp{}.Add(ops)
+		cl.Pop()
+	}, func(r result) {
+		// Pad.
+		r.expect(0, 16, colornames.Red)
+		r.expect(16, 16, colornames.White)
+		r.expect(100, 16, colornames.Blue)
+		// Repeat.
+		r.expect(48, 48, colornames.White)
+		r.expect(64, 48, colornames.Red)
+		r.expect(96, 48, colornames.Red)
+		// Reflect.
+		r.expect(32, 80, colornames.Blue)
+		r.expect(48, 80, colornames.White)
+		r.expect(64, 80, colornames.Red)
+		r.expect(96, 80, colornames.Blue)
+		// Radial repeat.
+		r.expect(64, 112, colornames.Red)
+		r.expect(68, 112, colornames.White)
+		r.expect(76, 112, colornames.White)
+	})
+}
+
+func TestOpacity(t *testing.T) {
+	run(t, func(ops *op.Ops) {
+		paint.Fill(ops, white)
+		// Overlapping shapes must not show through each other.
+		opacity := paint.OpacityOp{Opacity: .5}.Push(ops)
+		paint.FillShape(ops, red, clip.Rect(image.Rect(16, 16, 80, 80)).Op())
+		paint.FillShape(ops, blue, clip.Rect(image.Rect(48, 48, 112, 112)).Op())
+		opacity.Pop()
+	}, func(r result) {
+		r.expect(8, 8, colornames.White)
+		r.expect(32, 32, color.RGBA{R: 255, G: 188, B: 188, A: 255})
+		r.expect(64, 64, color.RGBA{R: 188, G: 188, B: 255, A: 255})
+		r.expect(96, 96, color.RGBA{R: 188, G: 188, B: 255, A: 255})
+	})
+}
+
+func TestOpacityNested(t *testing.T) {
+	run(t, func(ops *op.Ops) {
+		paint.Fill(ops, white)
+		outer := paint.OpacityOp{Opacity: .5}.Push(ops)
+		paint.FillShape(ops, red, clip.Rect(image.Rect(0, 0, 64, 128)).Op())
+		inner := paint.OpacityOp{Opacity: .5}.Push(ops)
+		paint.FillShape(ops, blue, clip.Rect(image.Rect(32, 0, 96, 64)).Op())
+		paint.FillShape(ops, blue, clip.Rect(image.Rect(32, 32, 96, 96)).Op())
+		inner.Pop()
+		// Opaque layers have no effect.
+		opaque := paint.OpacityOp{Opacity: 1}.Push(ops)
+		paint.FillShape(ops, black, clip.Rect(image.Rect(0, 112, 16, 128)).Op())
+		opaque.Pop()
+		// Invisible layers are not drawn.
+		invisible := paint.OpacityOp{Opacity: 0}.Push(ops)
+		paint.FillShape(ops, black, clip.Rect(image.Rect(96, 96, 128, 128)).Op())
+		invisible.Pop()
+		outer.Pop()
+	}, func(r result) {
+		r.expect(16, 16, color.RGBA{R: 255, G: 188, B: 188, A: 255})
+		r.expect(48, 16, color.RGBA{R: 225, G: 188, B: 225, A: 255})
+		r.expect(80, 80, color.RGBA{R: 225, G: 225, B: 255, A: 255})
+		r.expect(8, 120, color.RGBA{R: 188, G: 188, B: 188, A: 255})
+		r.expect(112, 112, colornames.White)
+	})
+}
+
+func TestBlendModes(t *testing.T) {
+	modes := []paint.BlendMode{
+		paint.BlendSourceOver,
+		paint.BlendMultiply,
+		paint.BlendScreen,
+		paint.BlendOverlay,
+		paint.BlendDarken,
+		paint.BlendLighten,
+		paint.BlendDifference,
+		paint.BlendPlus,
+	}
+	run(t, func(ops *op.Ops) {
+		paint.FillShape(ops, color.NRGBA{R: 0xc0, G: 0x80, B: 0x40, A: 0xff}, clip.Rect(image.Rect(0, 0, 128, 64)).Op())
+		paint.FillShape(ops, color.NRGBA{R: 0x30, G: 0x60, B: 0xe0, A: 0xff}, clip.Rect(image.Rect(0, 64, 128, 128)).Op())
+		for i, m := range modes {
+			stack := paint.BlendOp{Mode: m}.Push(ops)
+			paint.FillShape(ops, color.NRGBA{R: 0x40, G: 0x80, B: 0xff, A: 0xff}, clip.Rect(image.Rect(i*16, 0, i*16+16, 128)).Op())
+			stack.Pop()
+		}
+	}, func(r result) {
+		top := []color.RGBA{
+			{R: 64, G: 128, B: 255, A: 255},
+			{R: 46, G: 61, B: 64, A: 255},
+			{R: 196, G: 167, B: 255, A: 255},
+			{R: 90, G: 86, B: 90, A: 255},
+			{R: 64, G: 128, B: 64, A: 255},
+			{R: 192, G: 128, B: 255, A: 255},
+			{R: 183, G: 0, B: 249, A: 255},
+			{R: 200, G: 176, B: 255, A: 255},
+		}
+		bottom := []color.RGBA{
+			{R: 64, G: 128, B: 255, A: 255},
+			{R: 5, G: 44, B: 224, A: 255},
+			{R: 80, G: 151, B: 255, A: 255},
+			{R: 10, G: 64, B: 255, A: 255},
+			{R: 48, G: 96, B: 224, A: 255},
+			{R: 64, G: 128, B: 255, A: 255},
+			{R: 41, G: 89, B: 138, A: 255},
+			{R: 80, G: 156, B: 255, A: 255},
+		}
+		for i := range modes {
+			r.expect(i*16+8, 32, top[i])
+			r.expect(i*16+8, 96, bottom[i])
+		}
+	})
+}
+
+func TestBlendNested(t *testing.T) {
+	run(t, func(ops *op.Ops) {
+		paint.Fill(ops, white)
+		opacity := paint.OpacityOp{Opacity: .5}.Push(ops)
+		paint.FillShape(ops, blue, clip.Rect(image.Rect(0, 0, 64, 128)).Op())
+		// The transparent parts of the group act as a transparent
+		// backdrop.
+		diff := paint.BlendOp{Mode: paint.BlendDifference}.Push(ops)
+		paint.FillShape(ops, white, clip.Rect(image.Rect(32, 32, 96, 96)).Op())
+		diff.Pop()
+		opacity.Pop()
+	}, func(r result) {
+		r.expect(16, 16, color.RGBA{R: 188, G: 188, B: 255, A: 255})
+		r.expect(48, 48, color.RGBA{R: 255, G: 255, B: 188, A: 255})
+		r.expect(80, 48, colornames.White)
+		r.expect(112, 112, colornames.White)
+	})
+}
+
 func TestZeroImage(t *testing.T) {
 	ops := new(op.Ops)
 	w := newWindow(t, 10, 10)
@@ -359,6 +589,111 @@ func TestImageRGBA(t *testing.T) {
 	})
 }
 
+func TestImageFilter(t *testing.T) {
+	// A checkerboard of single black and white pixels.
+	checkers := image.NewNRGBA(image.Rect(0, 0, 120, 120))
+	for y := 0; y < 120; y++ {
+		for x := 0; x < 120; x++ {
+			c := colornames.Black
+			if (x+y)%2 == 0 {
+				c = colornames.White
+			}
+			checkers.Set(x, y, c)
+		}
+	}
+	pixels := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	pixels.Set(0, 0, colornames.Red)
+	pixels.Set(1, 0, colornames.Blue)
+	pixels.Set(0, 1, colornames.Green)
+	pixels.Set(1, 1, colornames.White)
+	filters := []paint.ImageFilter{paint.FilterNearest, paint.FilterLinear, paint.FilterLinearMipmapLinear}
+	run(t, func(o *op.Ops) {
+		for i, f := range filters {
+			off := op.Offset(image.Pt(i*44, 0)).Push(o)
+			im := paint.NewImageOp(pixels)
+			im.Filter = f
+			im.Add(o)
+			s := scale(20, 20).Push(o)
+			paint.PaintOp{}.Add(o)
+			s.Pop()
+			off.Pop()
+
+			off = op.Offset(image.Pt(i*44, 64)).Push(o)
+			im = paint.NewImageOp(checkers)
+			im.Filter = f
+			im.Add(o)
+			s = scale(1.0/3, 1.0/3).Push(o)
+			paint.PaintOp{}.Add(o)
+			s.Pop()
+			off.Pop()
+		}
+	}, func(r result) {
+		// Nearest filtering keeps the edges of upscaled pixels.
+		r.expect(19, 10, colornames.Red)
+		r.expect(20, 10, colornames.Blue)
+		// Mipmaps average downscaled checkers to gray.
+		r.expect(88+20, 64+20, color.RGBA{R: 188, G: 188, B: 188, A: 255})
+	})
+}
+
+func TestShadow(t *testing.T) {
+	run(t, func(o *op.Ops) {
+		paint.Fill(o, white)
+		paint.FillShadow(o, paint.ShadowOp{
+			Rect:   image.Rect(16, 16, 64, 64),
+			Radius: 8,
+			Offset: image.Pt(4, 8),
+			Blur:   16,
+			Color:  color.NRGBA{A: 0x80},
+		})
+		paint.FillShape(o, red, clip.UniformRRect(image.Rect(16, 16, 64, 64), 8).Op(o))
+		// A sharp, spread shadow.
+		paint.FillShadow(o, paint.ShadowOp{
+			Rect:   image.Rect(80, 80, 112, 112),
+			Radius: 4,
+			Spread: 4,
+			Color:  black,
+		})
+		paint.FillShape(o, blue, clip.UniformRRect(image.Rect(80, 80, 112, 112), 4).Op(o))
+	}, func(r result) {
+		r.expect(40, 40, colornames.Red)
+		r.expect(2, 2, colornames.White)
+		r.expect(77, 96, colornames.Black)
+		r.expect(96, 96, colornames.Blue)
+	})
+}
+
+func TestBlur(t *testing.T) {
+	run(t, func(o *op.Ops) {
+		paint.FillShape(o, red, clip.Rect(image.Rect(0, 0, 64, 128)).Op())
+		paint.FillShape(o, blue, clip.Rect(image.Rect(64, 0, 128, 128)).Op())
+		paint.FillShape(o, white, clip.Rect(image.Rect(56, 0, 72, 128)).Op())
+		cl := clip.UniformRRect(image.Rect(16, 32, 112, 96), 16).Push(o)
+		paint.BlurOp{Radius: 8}.Add(o)
+		cl.Pop()
+	}, func(r result) {
+		// The content outside the clip area is unchanged.
+		r.expect(20, 20, colornames.Red)
+		r.expect(64, 20, colornames.White)
+		r.expect(108, 100, colornames.Blue)
+		r.expect(20, 64, colornames.Red)
+	})
+}
+
+func TestBlurWide(t *testing.T) {
+	// A radius wide enough to blur in several passes.
+	run(t, func(o *op.Ops) {
+		paint.FillShape(o, black, clip.Rect(image.Rect(0, 0, 128, 128)).Op())
+		paint.FillShape(o, white, clip.Rect(image.Rect(56, 0, 72, 128)).Op())
+		cl := clip.Rect(image.Rect(0, 32, 128, 96)).Push(o)
+		paint.BlurOp{Radius: 96}.Add(o)
+		cl.Pop()
+	}, func(r result) {
+		r.expect(64, 16, colornames.White)
+		r.expect(64, 64, color.RGBA{R: 0x66, G: 0x66, B: 0x66, A: 0xff})
+	})
+}
+
 func TestGapsInPath(t *testing.T) {
 	ops := new(op.Ops)
 	var p clip.Path
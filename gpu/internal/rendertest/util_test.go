@@ -19,6 +19,7 @@ import (
 
 	"gioui.org/f32"
 	"gioui.org/gpu/headless"
+	"gioui.org/gpu/software"
 	"gioui.org/internal/f32color"
 	"gioui.org/op"
 	"gioui.org/op/paint"
@@ -73,6 +74,7 @@ func drawImage(t *testing.T, size int, ops *op.Ops, draw func(o *op.Ops)) (*imag
 }
 
 func run(t *testing.T, f func(o *op.Ops), c func(r result)) {
+	runSoftware(t, frame(f, c))
 	// Draw a few times and check that it is correct each time, to
 	// ensure any caching effects still generate the correct images.
 	var img *image.RGBA
@@ -106,6 +108,7 @@ type frameT struct {
 // multiRun is used to run test cases over multiple frames, typically
 // to test caching interactions.
 func multiRun(t *testing.T, frames ...frameT) {
+	runSoftware(t, frames...)
 	// draw a few times and check that it is correct each time, to
 	// ensure any caching effects still generate the correct images.
 	var err error
@@ -141,38 +144,119 @@ func multiRun(t *testing.T, frames ...frameT) {
 	}
 }
 
-func verifyRef(t *testing.T, img *image.RGBA, frame int) (ok bool) {
-	// ensure identical to ref data
-	var path string
-	if frame == 0 {
-		path = t.Name()
-	} else {
-		path = t.Name() + "_" + strconv.Itoa(frame)
-	}
-	path = filepath.Join("refs", path+".png")
+// runSoftware draws the frames with the software renderer and
+// compares them with the reference images of the GPU renderers.
+func runSoftware(t *testing.T, frames ...frameT) {
 	if *dumpImages {
-		if err := os.MkdirAll(filepath.Dir(path), 0766); err != nil {
-			if !os.IsExist(err) {
-				t.Error(err)
+		// The reference images are rendered by the GPU.
+		return
+	}
+	name := t.Name()
+	t.Run("software", func(t *testing.T) {
+		sz := image.Point{X: 128, Y: 128}
+		w, err := software.NewWindow(sz.X, sz.Y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Release()
+		ops := new(op.Ops)
+		for i, f := range frames {
+			ops.Reset()
+			f.f(ops)
+			if err := w.Frame(ops); err != nil {
+				t.Fatalf("rendering failed: %v", err)
+			}
+			img := image.NewRGBA(image.Rectangle{Max: sz})
+			if err := w.Screenshot(img); err != nil {
+				t.Fatalf("screenshot failed: %v", err)
+			}
+			ref := loadRef(t, refPath(name, i), img.Bounds())
+			if ref == nil {
 				return
 			}
+			if n := softwareMismatches(ref, img); n > maxSoftwareMismatches {
+				t.Errorf("frame %d: %d pixels differ from the reference, expected at most %d", i, n, maxSoftwareMismatches)
+			}
+			if f.c != nil {
+				f.c(result{t: t, img: img})
+			}
+		}
+	})
+}
+
+// maxSoftwareMismatches is the number of pixels of the software
+// renderer allowed to differ from the reference images, mostly
+// because of the filtering of transformed images and the placement
+// of the discontinuities of repeated gradients.
+const maxSoftwareMismatches = 128
+
+// softwareMismatches counts the pixels of img that differ from every
+// pixel of ref in the 3x3 neighborhood of their position. Neighbors
+// are allowed because the edges of shapes are anti-aliased
+// differently.
+func softwareMismatches(ref, img *image.RGBA) int {
+	n := 0
+	bnd := img.Bounds()
+	for y := bnd.Min.Y; y < bnd.Max.Y; y++ {
+		for x := bnd.Min.X; x < bnd.Max.X; x++ {
+			got := img.RGBAAt(x, y)
+			match := false
+			for dy := -1; dy <= 1 && !match; dy++ {
+				for dx := -1; dx <= 1 && !match; dx++ {
+					p := image.Pt(x+dx, y+dy)
+					if !p.In(bnd) {
+						continue
+					}
+					exp := ref.RGBAAt(p.X, p.Y)
+					match = colorsClose(exp, got) && alphaClose(exp, got) || channelsClose(exp, got, 40)
+				}
+			}
+			if !match {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// channelsClose reports whether every channel of c1 and c2 differ by
+// at most d.
+func channelsClose(c1, c2 color.RGBA, d int) bool {
+	for _, v := range [...]int{
+		int(c1.R) - int(c2.R), int(c1.G) - int(c2.G),
+		int(c1.B) - int(c2.B), int(c1.A) - int(c2.A),
+	} {
+		if v < -d || v > d {
+			return false
 		}
-		saveImage(t, path, img)
-		return true
 	}
+	return true
+}
+
+func refPath(name string, frame int) string {
+	if frame != 0 {
+		name += "_" + strconv.Itoa(frame)
+	}
+	return filepath.Join("refs", name+".png")
+}
+
+// loadRef loads the reference image at path, which must have the
+// bounds bnd. It returns nil after reporting an error if the image
+// can't be loaded.
+func loadRef(t *testing.T, path string, bnd image.Rectangle) *image.RGBA {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		t.Error("could not open ref:", err)
-		return
+		return nil
 	}
 	r, err := png.Decode(bytes.NewReader(b))
 	if err != nil {
 		t.Error("could not decode ref:", err)
-		return
+		return nil
 	}
-	if img.Bounds() != r.Bounds() {
-		t.Errorf("reference image is %v, expected %v", r.Bounds(), img.Bounds())
-		return false
+	if bnd != r.Bounds() {
+		t.Errorf("reference image is %v, expected %v", r.Bounds(), bnd)
+		return nil
 	}
 	var ref *image.RGBA
 	switch r := r.(type) {
@@ -189,6 +273,26 @@ func verifyRef(t *testing.T, img *image.RGBA, frame int) (ok bool) {
 	default:
 		t.Fatalf("reference image is a %T, expected *image.NRGBA or *image.RGBA", r)
 	}
+	return ref
+}
+
+func verifyRef(t *testing.T, img *image.RGBA, frame int) (ok bool) {
+	// ensure identical to ref data
+	path := refPath(t.Name(), frame)
+	if *dumpImages {
+		if err := os.MkdirAll(filepath.Dir(path), 0766); err != nil {
+			if !os.IsExist(err) {
+				t.Error(err)
+				return
+			}
+		}
+		saveImage(t, path, img)
+		return true
+	}
+	ref := loadRef(t, path, img.Bounds())
+	if ref == nil {
+		return false
+	}
 	bnd := img.Bounds()
 	for x := bnd.Min.X; x < bnd.Max.X; x++ {
 		for y := bnd.Min.Y; y < bnd.Max.Y; y++ {
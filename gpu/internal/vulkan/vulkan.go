@@ -279,7 +279,7 @@ func (b *Backend) Release() {
 	*b = Backend{}
 }
 
-func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, bindings driver.BufferBinding) (driver.Texture, error) {
+func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, minFilter, magFilter driver.TextureFilter, wrap driver.TextureWrap, bindings driver.BufferBinding) (driver.Texture, error) {
 	vkfmt := formatFor(format)
 	usage := vk.IMAGE_USAGE_TRANSFER_DST_BIT | vk.IMAGE_USAGE_TRANSFER_SRC_BIT
 	passLayout := vk.IMAGE_LAYOUT_COLOR_ATTACHMENT_OPTIMAL
@@ -302,6 +302,17 @@ func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, min
 		}
 		panic("unknown filter")
 	}
+	addressModeFor := func(w driver.TextureWrap) vk.SamplerAddressMode {
+		switch w {
+		case driver.WrapClamp:
+			return vk.SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE
+		case driver.WrapRepeat:
+			return vk.SAMPLER_ADDRESS_MODE_REPEAT
+		case driver.WrapMirror:
+			return vk.SAMPLER_ADDRESS_MODE_MIRRORED_REPEAT
+		}
+		panic("unknown wrap")
+	}
 	mipmapMode := vk.SAMPLER_MIPMAP_MODE_NEAREST
 	mipmap := minFilter == driver.FilterLinearMipmapLinear
 	nmipmaps := 1
@@ -314,7 +325,7 @@ func (b *Backend) NewTexture(format driver.TextureFormat, width, height int, min
 		log2 := 32 - bits.LeadingZeros32(uint32(dim)) - 1
 		nmipmaps = log2 + 1
 	}
-	sampler, err := vk.CreateSampler(b.dev, filterFor(minFilter), filterFor(magFilter), mipmapMode)
+	sampler, err := vk.CreateSampler(b.dev, filterFor(minFilter), filterFor(magFilter), mipmapMode, addressModeFor(wrap))
 	if err != nil {
 		return nil, mapErr(err)
 	}
@@ -557,6 +568,9 @@ func createPipelineLayout(d vk.Device, src shader.Sources, ranges []vk.PushConst
 }
 
 func (b *Backend) newShader(src shader.Sources, stage vk.ShaderStageFlags) (*Shader, error) {
+	if len(src.SPIRV) == 0 {
+		return nil, fmt.Errorf("vulkan: no SPIR-V for shader %q", src.Name)
+	}
 	mod, err := vk.CreateShaderModule(b.dev, src.SPIRV)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"gioui.org/gpu/internal/driver"
+	"gioui.org/internal/ops"
+	"gioui.org/op"
+)
+
+// offscreen renders operations to textures and images, with a GPU of
+// its own on the device of its parent.
+type offscreen struct {
+	dev driver.Device
+	gpu GPU
+	ops op.Ops
+}
+
+// sharedDevice is a device shared with a parent GPU, which releases
+// it.
+type sharedDevice struct {
+	driver.Device
+}
+
+func (sharedDevice) Release() {}
+
+// render draws src into target.
+func (o *offscreen) render(src *ops.Ops, target driver.Texture, size image.Point) error {
+	if o.gpu == nil {
+		g, err := NewWithDevice(sharedDevice{o.dev})
+		if err != nil {
+			return err
+		}
+		o.gpu = g
+	}
+	o.ops.Reset()
+	ops.AddCall(&o.ops.Internal, src, ops.PC{}, ops.PCFor(src))
+	o.gpu.Clear(color.NRGBA{})
+	return o.gpu.Frame(&o.ops, target, size)
+}
+
+// readback renders src into img.
+func (o *offscreen) readback(src *ops.Ops, img *image.RGBA) error {
+	size := img.Bounds().Size()
+	if size.X == 0 || size.Y == 0 {
+		return nil
+	}
+	if max := o.dev.Caps().MaxTextureSize; size.X > max || size.Y > max {
+		return fmt.Errorf("gpu: image size %v exceeds the maximum texture size %d", size, max)
+	}
+	tex, err := o.dev.NewTexture(driver.TextureFormatSRGBA, size.X, size.Y, driver.FilterNearest, driver.FilterNearest, driver.WrapClamp, driver.BufferBindingTexture|driver.BufferBindingFramebuffer)
+	if err != nil {
+		return err
+	}
+	defer tex.Release()
+	if err := o.render(src, tex, size); err != nil {
+		return err
+	}
+	dst := img
+	if img.Rect.Min != (image.Point{}) || img.Stride != size.X*4 {
+		// Download tightly packed pixels at the texture origin.
+		dst = image.NewRGBA(image.Rectangle{Max: size})
+	}
+	o.dev.BeginFrame(nil, false, image.Point{})
+	err = driver.DownloadImage(o.dev, tex, dst)
+	o.dev.EndFrame()
+	if err != nil {
+		return err
+	}
+	if dst != img {
+		draw.Draw(img, img.Bounds(), dst, image.Point{}, draw.Src)
+	}
+	return nil
+}
+
+func (o *offscreen) release() {
+	if o.gpu != nil {
+		o.gpu.Release()
+		o.gpu = nil
+	}
+}
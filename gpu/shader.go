@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+
+	"gioui.org/gpu/internal/driver"
+	"gioui.org/internal/f32"
+	"gioui.org/internal/f32color"
+	"gioui.org/internal/ops"
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+	"gioui.org/shader"
+	"gioui.org/shader/gio"
+)
+
+// shaderOpData is the shadow of paint.ShaderOp.
+type shaderOpData struct {
+	// shader is nil if the operation refers to something else than a
+	// paint.Shader, such as a placeholder of a decoded dump.
+	shader   *paint.Shader
+	uniforms string
+}
+
+// shaderImage is a shader brush rendered to an image that covers the
+// clip area of a paint.
+type shaderImage struct {
+	key      shaderKey
+	uniforms string
+	// img is the rendering of the fallback of the shader, for
+	// renderers that don't run its program.
+	img *image.RGBA
+	// stale is set when the image must be rendered, because it is new
+	// or its uniforms changed since it was last rendered.
+	stale bool
+	// gen counts the renderings of the image, to tell them apart in
+	// the damage tracker.
+	gen  uint64
+	used bool
+}
+
+// shaderKey identifies a rendered shader. Like gradientKey, the
+// transform is relative to the origin of the image. The uniforms are
+// not part of the key, so that animating them renders into the same
+// image.
+type shaderKey struct {
+	shader    *paint.Shader
+	transform f32.Affine2D
+	size      image.Point
+}
+
+// shaderCache holds shader brushes rendered to images, for drawing
+// them as textures.
+type shaderCache struct {
+	// images holds the images of a key, one for every set of uniforms
+	// drawn by a frame.
+	images map[shaderKey][]*shaderImage
+}
+
+// shaderPipelines holds the pipelines of shader programs, which render
+// them to textures.
+type shaderPipelines struct {
+	ctx   driver.Device
+	pipes map[*paint.Shader]*shaderPipeline
+	// uniforms is shared by all pipelines.
+	uniforms *shaderUniforms
+}
+
+type shaderPipeline struct {
+	pipe *pipeline
+	// err is the error from creating the pipeline, in which case
+	// the shader is rasterized with its fallback.
+	err error
+}
+
+// shaderUniforms is the uniform block of shader programs: the uniforms
+// of the blit vertex program followed by the uniforms of the
+// ShaderOp.
+type shaderUniforms struct {
+	blitUniforms
+	uniforms [paint.MaxShaderUniforms]byte
+}
+
+func decodeShaderOp(data []byte, refs []interface{}) shaderOpData {
+	_ = data[:ops.TypeShaderLen]
+	s, _ := refs[0].(*paint.Shader)
+	u, _ := refs[1].(string)
+	return shaderOpData{shader: s, uniforms: u}
+}
+
+// get returns the shader s, transformed by t, rendered to an image that
+// covers bounds. The image is meant to be drawn at the offset of bounds
+// without further transformation.
+func (c *shaderCache) get(s shaderOpData, t f32.Affine2D, bounds image.Rectangle) *shaderImage {
+	key := shaderKey{
+		shader:    s.shader,
+		transform: t.Offset(layout.FPt(bounds.Min.Mul(-1))),
+		size:      bounds.Size(),
+	}
+	imgs := c.images[key]
+	for _, img := range imgs {
+		if img.uniforms == s.uniforms {
+			img.used = true
+			return img
+		}
+	}
+	// Re-render an image not yet used by this frame with the new
+	// uniforms.
+	for _, img := range imgs {
+		if !img.used {
+			img.uniforms = s.uniforms
+			img.img = nil
+			img.stale = true
+			img.gen++
+			img.used = true
+			return img
+		}
+	}
+	img := &shaderImage{key: key, uniforms: s.uniforms, stale: true, used: true}
+	if c.images == nil {
+		c.images = make(map[shaderKey][]*shaderImage)
+	}
+	c.images[key] = append(imgs, img)
+	return img
+}
+
+// image is like get, but rasterizes the shader with its fallback.
+func (c *shaderCache) image(s shaderOpData, t f32.Affine2D, bounds image.Rectangle) imageOpData {
+	img := c.get(s, t, bounds)
+	src := img.rasterize()
+	return imageOpData{src: src, size: src.Rect.Size(), handle: src}
+}
+
+// frame discards the images not used since the previous call to frame.
+func (c *shaderCache) frame() {
+	for k, imgs := range c.images {
+		used := imgs[:0]
+		for _, img := range imgs {
+			if img.used {
+				img.used = false
+				used = append(used, img)
+			}
+		}
+		if len(used) == 0 {
+			delete(c.images, k)
+			continue
+		}
+		for i := len(used); i < len(imgs); i++ {
+			imgs[i] = nil
+		}
+		c.images[k] = used
+	}
+}
+
+// imageOp returns the image for drawing s with a texture rendered by
+// its program.
+func (s *shaderImage) imageOp() imageOpData {
+	return imageOpData{shader: s, size: s.key.size, handle: s}
+}
+
+// rasterize renders the shader with its fallback.
+func (s *shaderImage) rasterize() *image.RGBA {
+	if s.img != nil {
+		return s.img
+	}
+	k := s.key
+	s.img = image.NewRGBA(image.Rectangle{Max: k.size})
+	if k.shader == nil {
+		return s.img
+	}
+	inv := k.transform.Invert()
+	uniforms := []byte(s.uniforms)
+	for y := 0; y < k.size.Y; y++ {
+		row := s.img.Pix[s.img.PixOffset(0, y):]
+		for x := 0; x < k.size.X; x++ {
+			p := inv.Transform(f32.Pt(float32(x)+.5, float32(y)+.5))
+			c := f32color.LinearFromSRGB(k.shader.Fallback(p, uniforms)).SRGBPremul()
+			row[0], row[1], row[2], row[3] = c.R, c.G, c.B, c.A
+			row = row[4:]
+		}
+	}
+	return s.img
+}
+
+// pipeline returns the pipeline of the program of s, or an error if
+// the device can't run it.
+func (p *shaderPipelines) pipeline(s *paint.Shader) (*pipeline, error) {
+	if sp, ok := p.pipes[s]; ok {
+		return sp.pipe, sp.err
+	}
+	if p.uniforms == nil {
+		p.uniforms = new(shaderUniforms)
+	}
+	pipe, err := p.newPipeline(s)
+	if p.pipes == nil {
+		p.pipes = make(map[*paint.Shader]*shaderPipeline)
+	}
+	p.pipes[s] = &shaderPipeline{pipe: pipe, err: err}
+	return pipe, err
+}
+
+func (p *shaderPipelines) newPipeline(s *paint.Shader) (*pipeline, error) {
+	vsh, err := p.ctx.NewVertexShader(gio.Shader_blit_vert)
+	if err != nil {
+		return nil, err
+	}
+	defer vsh.Release()
+	fsh, err := p.ctx.NewFragmentShader(s.Sources())
+	if err != nil {
+		return nil, err
+	}
+	defer fsh.Release()
+	pipe, err := p.ctx.NewPipeline(driver.PipelineDesc{
+		VertexShader:   vsh,
+		FragmentShader: fsh,
+		VertexLayout: driver.VertexLayout{
+			Inputs: []driver.InputDesc{
+				{Type: shader.DataTypeFloat, Size: 2, Offset: 0},
+				{Type: shader.DataTypeFloat, Size: 2, Offset: 4 * 2},
+			},
+			Stride: 4 * 4,
+		},
+		PixelFormat: pixelFormats[formatLayer],
+		Topology:    driver.TopologyTriangleStrip,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &pipeline{pipe, newUniformBuffer(p.ctx, p.uniforms)}, nil
+}
+
+// render draws the program of img into tex, which must be the size of
+// img.
+func (p *shaderPipelines) render(pipe *pipeline, quadVerts driver.Buffer, img *shaderImage, tex driver.Texture) {
+	k := img.key
+	p.ctx.BeginRenderPass(tex, driver.LoadDesc{Action: driver.LoadActionClear})
+	p.ctx.Viewport(0, 0, k.size.X, k.size.Y)
+	p.ctx.BindPipeline(pipe.pipeline)
+	p.ctx.BindVertexBuffer(quadVerts, 0)
+	// Map the texture coordinates of the quad to pixels, flipped to
+	// match the orientation of uploaded images, and then to the
+	// coordinates of the shader.
+	var uv f32.Affine2D
+	if p.ctx.Caps().BottomLeftOrigin {
+		uv = uv.Scale(f32.Point{}, f32.Pt(1, -1)).Offset(f32.Pt(0, 1))
+	}
+	uv = uv.Scale(f32.Point{}, layout.FPt(k.size))
+	uv = k.transform.Invert().Mul(uv)
+	t1, t2, t3, t4, t5, t6 := uv.Elems()
+	u := p.uniforms
+	u.transform = [4]float32{1, 1, 0, 0}
+	u.uvTransformR1 = [4]float32{t1, t2, t3, 0}
+	u.uvTransformR2 = [4]float32{t4, t5, t6, 0}
+	n := copy(u.uniforms[:], img.uniforms)
+	for i := range u.uniforms[n:] {
+		u.uniforms[n+i] = 0
+	}
+	pipe.UploadUniforms(p.ctx)
+	p.ctx.DrawArrays(0, 4)
+	p.ctx.EndRenderPass()
+}
+
+func (p *shaderPipelines) release() {
+	for _, sp := range p.pipes {
+		if sp.pipe != nil {
+			sp.pipe.Release()
+		}
+	}
+	p.pipes = nil
+}
@@ -0,0 +1,43 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/internal/f32"
+	"gioui.org/op/paint"
+	"gioui.org/shader"
+)
+
+func TestShaderCacheUniforms(t *testing.T) {
+	prog := paint.NewShader(shader.Sources{}, nil)
+	bounds := image.Rect(10, 10, 50, 50)
+	var c shaderCache
+	img := c.get(shaderOpData{shader: prog, uniforms: "a"}, f32.Affine2D{}, bounds)
+	img.stale = false
+	c.frame()
+
+	// Changing only the uniforms must reuse the image.
+	if got := c.get(shaderOpData{shader: prog, uniforms: "b"}, f32.Affine2D{}, bounds); got != img {
+		t.Fatal("new image for changed uniforms")
+	}
+	if !img.stale || img.uniforms != "b" {
+		t.Errorf("image not marked for rendering with the new uniforms")
+	}
+	img.stale = false
+	// Drawing other uniforms in the same frame needs another image.
+	other := c.get(shaderOpData{shader: prog, uniforms: "c"}, f32.Affine2D{}, bounds.Add(image.Pt(100, 0)))
+	if other == img {
+		t.Fatal("image shared by different uniforms in a frame")
+	}
+	if got := c.get(shaderOpData{shader: prog, uniforms: "b"}, f32.Affine2D{}, bounds); got != img || img.stale {
+		t.Error("image with unchanged uniforms not reused")
+	}
+	c.frame()
+	c.frame()
+	if len(c.images) != 0 {
+		t.Errorf("%d unused images not discarded", len(c.images))
+	}
+}
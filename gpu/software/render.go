@@ -0,0 +1,865 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package software
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/vector"
+
+	"gioui.org/internal/blend"
+	"gioui.org/internal/blur"
+	"gioui.org/internal/f32"
+	"gioui.org/internal/f32color"
+	"gioui.org/internal/gradient"
+	"gioui.org/internal/mipmap"
+	"gioui.org/internal/ops"
+	"gioui.org/internal/scene"
+	"gioui.org/internal/stroke"
+	"gioui.org/op"
+	"gioui.org/op/paint"
+)
+
+// renderer draws operation lists to images.
+type renderer struct {
+	reader     ops.Reader
+	dst        *image.RGBA
+	layers     []layer
+	transStack []f32.Affine2D
+	states     []f32.Affine2D
+	rast       vector.Rasterizer
+	mipmaps    mipmap.Cache
+	// rendered maps the handles of images rendered from operations
+	// to their renderings, and child renders them.
+	rendered map[interface{}]*renderedImage
+	child    *renderer
+}
+
+// renderedImage is the rendering of an image rendered from
+// operations.
+type renderedImage struct {
+	img  *image.RGBA
+	used bool
+}
+
+// layer is an opacity, blend or blur layer. Its content is composited
+// onto the layer below when it is popped.
+type layer struct {
+	img     *image.RGBA
+	opacity float32
+	mode    blend.Mode
+	// blur is the standard deviation of the blur applied to the layer
+	// below, or zero. The content of a blur layer is the mask of the
+	// blurred area.
+	blur float32
+}
+
+// clipState is the intersection of the clip operations on the
+// clip stack.
+type clipState struct {
+	parent *clipState
+	// bounds is the pixel area of the clip.
+	bounds image.Rectangle
+	// mask is the coverage of the pixels in bounds, or nil if
+	// bounds is fully covered.
+	mask *image.Alpha
+}
+
+type brushKind uint8
+
+const (
+	brushColor brushKind = iota
+	brushGradient
+	brushImage
+	brushShader
+)
+
+type drawState struct {
+	t    f32.Affine2D
+	clip *clipState
+
+	brush brushKind
+	// color is the linear, premultiplied color of brushColor.
+	color f32color.RGBA
+	grad  gradient.Gradient
+	image imageOp
+	// shader and uniforms are the program and uniforms of
+	// brushShader.
+	shader   *paint.Shader
+	uniforms []byte
+}
+
+// imageOp is the shadow of paint.ImageOp.
+type imageOp struct {
+	src *image.RGBA
+	// render is the operation list of an image rendered from
+	// operations, and size is its size.
+	render *ops.Ops
+	size   image.Point
+	handle interface{}
+	filter paint.ImageFilter
+}
+
+// path is an outline in pixel coordinates, in the form of subpaths
+// of lines and curves.
+type path struct {
+	cmds   []pathCmd
+	bounds f32.Rectangle
+	pen    f32.Point
+}
+
+type pathCmd struct {
+	// n is the number of points of the segment: 1 for lines, 2 for
+	// quadratic and 3 for cubic curves. Zero starts a new subpath.
+	n   int
+	pts [3]f32.Point
+}
+
+const (
+	pathCmdMove = iota
+	pathCmdLine
+	pathCmdQuad
+	pathCmdCubic
+)
+
+// linear maps sRGB encoded components to linear components.
+var linear [256]float32
+
+func init() {
+	for i := range linear {
+		linear[i] = f32color.LinearFromSRGB(color.NRGBA{R: uint8(i), A: 0xff}).R
+	}
+}
+
+// render draws frame onto dst.
+func (r *renderer) render(dst *image.RGBA, frame *op.Ops) {
+	var o *ops.Ops
+	if frame != nil {
+		o = &frame.Internal
+	}
+	r.renderOps(dst, o)
+}
+
+func (r *renderer) renderOps(dst *image.RGBA, o *ops.Ops) {
+	r.dst = dst
+	r.reader.Reset(o)
+	r.drawOps()
+	// End layers left open.
+	for len(r.layers) > 0 {
+		r.popLayer()
+	}
+	r.transStack = r.transStack[:0]
+	r.mipmaps.Frame()
+	for k, img := range r.rendered {
+		if !img.used {
+			delete(r.rendered, k)
+		}
+		img.used = false
+	}
+	r.dst = nil
+}
+
+// renderImage returns the rendering of an image rendered from
+// operations.
+func (r *renderer) renderImage(img imageOp) *image.RGBA {
+	if ri, exists := r.rendered[img.handle]; exists {
+		ri.used = true
+		return ri.img
+	}
+	if r.child == nil {
+		r.child = new(renderer)
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: img.size})
+	r.child.renderOps(dst, img.render)
+	if r.rendered == nil {
+		r.rendered = make(map[interface{}]*renderedImage)
+	}
+	r.rendered[img.handle] = &renderedImage{img: dst, used: true}
+	return dst
+}
+
+func (r *renderer) drawOps() {
+	var (
+		state  drawState
+		aux    []byte
+		style  stroke.StrokeStyle
+		dashes stroke.DashOp
+	)
+	reset := func() {
+		state = drawState{
+			color: f32color.RGBA{A: 1},
+		}
+	}
+	reset()
+loop:
+	for encOp, ok := r.reader.Decode(); ok; encOp, ok = r.reader.Decode() {
+		switch ops.OpType(encOp.Data[0]) {
+		case ops.TypeTransform:
+			dop, push := ops.DecodeTransform(encOp.Data)
+			if push {
+				r.transStack = append(r.transStack, state.t)
+			}
+			state.t = state.t.Mul(dop)
+		case ops.TypePopTransform:
+			n := len(r.transStack)
+			state.t = r.transStack[n-1]
+			r.transStack = r.transStack[:n-1]
+
+		case ops.TypeStroke:
+			style, dashes = decodeStrokeOp(encOp.Data, encOp.Refs)
+
+		case ops.TypePath:
+			encOp, ok = r.reader.Decode()
+			if !ok {
+				break loop
+			}
+			aux = encOp.Data[ops.TypeAuxLen:]
+
+		case ops.TypeClip:
+			var op ops.ClipOp
+			op.Decode(encOp.Data)
+			state.clip = r.clip(state.clip, state.t, op.Bounds, aux, style, dashes)
+			aux = nil
+			style, dashes = stroke.StrokeStyle{}, stroke.DashOp{}
+		case ops.TypePopClip:
+			state.clip = state.clip.parent
+
+		case ops.TypeOpacity:
+			r.pushLayer(ops.DecodeOpacity(encOp.Data), blend.SourceOver, 0)
+		case ops.TypePopOpacity:
+			r.popLayer()
+		case ops.TypeBlend:
+			r.pushLayer(1, ops.DecodeBlend(encOp.Data), 0)
+		case ops.TypePopBlend:
+			r.popLayer()
+		case ops.TypeBlur:
+			// Blur through a layer masked by the clip area.
+			r.pushLayer(1, blend.SourceOver, ops.DecodeBlur(encOp.Data))
+			mask := state
+			mask.brush = brushColor
+			mask.color = f32color.RGBA{R: 1, G: 1, B: 1, A: 1}
+			r.paint(&mask)
+			r.popLayer()
+
+		case ops.TypeColor:
+			state.brush = brushColor
+			state.color = f32color.LinearFromSRGB(decodeColorOp(encOp.Data))
+		case ops.TypeLinearGradient:
+			state.brush = brushGradient
+			state.grad = decodeLinearGradientOp(encOp.Data)
+		case ops.TypeRadialGradient:
+			state.brush = brushGradient
+			state.grad = decodeRadialGradientOp(encOp.Data)
+		case ops.TypeConicGradient:
+			state.brush = brushGradient
+			state.grad = decodeConicGradientOp(encOp.Data)
+		case ops.TypeGradient:
+			g := decodeGradientOp(encOp.Data, encOp.Refs)
+			if g.Kind > gradient.Conic {
+				// A gradient of unknown kind paints nothing.
+				state.brush = brushColor
+				state.color = f32color.RGBA{}
+				break
+			}
+			state.brush = brushGradient
+			state.grad = g
+		case ops.TypeShadow:
+			state.brush = brushGradient
+			state.grad = decodeShadowOp(encOp.Data)
+		case ops.TypeImage:
+			state.brush = brushImage
+			state.image = decodeImageOp(encOp.Data, encOp.Refs)
+			if state.image.render != nil {
+				state.image.src = r.renderImage(state.image)
+			}
+		case ops.TypeShader:
+			state.brush = brushShader
+			state.shader, state.uniforms = decodeShaderOp(encOp.Refs)
+		case ops.TypePaint:
+			r.paint(&state)
+
+		case ops.TypeSave:
+			id := ops.DecodeSave(encOp.Data)
+			if extra := id - len(r.states) + 1; extra > 0 {
+				r.states = append(r.states, make([]f32.Affine2D, extra)...)
+			}
+			r.states[id] = state.t
+		case ops.TypeLoad:
+			reset()
+			id := ops.DecodeLoad(encOp.Data)
+			state.t = r.states[id]
+		}
+	}
+}
+
+// clip returns the intersection of parent with the clip area described
+// by bounds and the path data aux, transformed by t.
+func (r *renderer) clip(parent *clipState, t f32.Affine2D, bounds image.Rectangle, aux []byte, style stroke.StrokeStyle, dashes stroke.DashOp) *clipState {
+	cl := &clipState{
+		parent: parent,
+		bounds: r.dst.Rect,
+	}
+	if parent != nil {
+		cl.bounds = parent.bounds
+		cl.mask = parent.mask
+	}
+	var p path
+	switch {
+	case style.Width > 0:
+		for _, q := range stroke.StrokePathCommands(style, dashes, aux) {
+			q.Quad = q.Quad.Transform(t)
+			p.segment(q.Quad.From, q.Quad.Ctrl, q.Quad.To)
+		}
+	case len(aux) > 0:
+		p.decode(aux, t)
+	default:
+		b := f32.FRect(bounds)
+		if _, hx, _, hy, _, _ := t.Elems(); hx == 0 && hy == 0 {
+			// Like the GPU renderers, rectangles that remain aligned
+			// to the axes cover whole pixels.
+			b = f32.Rectangle{Min: t.Transform(b.Min), Max: t.Transform(b.Max)}.Canon()
+			cl.bounds = cl.bounds.Intersect(b.Round())
+			return cl
+		}
+		corners := [4]f32.Point{b.Min, {X: b.Max.X, Y: b.Min.Y}, b.Max, {X: b.Min.X, Y: b.Max.Y}}
+		for i := range corners {
+			p.segment(t.Transform(corners[i]), t.Transform(corners[(i+1)%len(corners)]))
+		}
+	}
+	cl.bounds = cl.bounds.Intersect(p.bounds.Round())
+	if len(p.cmds) == 0 || cl.bounds.Empty() {
+		cl.bounds = image.Rectangle{}
+		cl.mask = nil
+		return cl
+	}
+	mask := image.NewAlpha(cl.bounds)
+	r.rast.Reset(cl.bounds.Dx(), cl.bounds.Dy())
+	p.rasterize(&r.rast, f32.FPt(cl.bounds.Min))
+	r.rast.Draw(mask, cl.bounds, image.Opaque, image.Point{})
+	if pmask := cl.mask; pmask != nil {
+		for y := cl.bounds.Min.Y; y < cl.bounds.Max.Y; y++ {
+			row := mask.Pix[mask.PixOffset(cl.bounds.Min.X, y):]
+			prow := pmask.Pix[pmask.PixOffset(cl.bounds.Min.X, y):]
+			for x := 0; x < cl.bounds.Dx(); x++ {
+				row[x] = uint8((uint32(row[x])*uint32(prow[x]) + 0x7f) / 0xff)
+			}
+		}
+	}
+	cl.mask = mask
+	return cl
+}
+
+// paint fills the current clip area with the brush of s.
+func (r *renderer) paint(s *drawState) {
+	dst := r.target()
+	area := dst.Rect
+	var mask *image.Alpha
+	if s.clip != nil {
+		area = area.Intersect(s.clip.bounds)
+		mask = s.clip.mask
+	}
+	var ramp *gradient.Ramp
+	switch s.brush {
+	case brushGradient:
+		ramp = new(gradient.Ramp)
+		s.grad.Ramp(ramp)
+	case brushShader:
+		if s.shader == nil {
+			return
+		}
+	case brushImage:
+		if s.image.src == nil {
+			return
+		}
+		// Images are bounded.
+		sz := f32.FPt(s.image.src.Rect.Size())
+		corners := [4]f32.Point{{}, {X: sz.X}, sz, {Y: sz.Y}}
+		var b f32.Rectangle
+		for i, c := range corners {
+			c = s.t.Transform(c)
+			if i == 0 {
+				b = f32.Rectangle{Min: c, Max: c}
+			}
+			b = extend(b, c)
+		}
+		area = area.Intersect(b.Round())
+	}
+	if area.Empty() {
+		return
+	}
+	inv := s.t.Invert()
+	src := s.image.src
+	// scale maps image coordinates to the coordinates of src.
+	scale := f32.Pt(1, 1)
+	if s.brush == brushImage && s.image.filter == paint.FilterLinearMipmapLinear {
+		if level := mipmap.Level(s.t); level > 0 {
+			src = r.mipmaps.Get(s.image.handle, src, level)
+			ssz, dsz := s.image.src.Rect.Size(), src.Rect.Size()
+			scale = f32.Pt(float32(dsz.X)/float32(ssz.X), float32(dsz.Y)/float32(ssz.Y))
+		}
+	}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			cov := float32(1)
+			if mask != nil {
+				a := mask.Pix[mask.PixOffset(x, y)]
+				if a == 0 {
+					continue
+				}
+				cov = float32(a) / 0xff
+			}
+			c := s.color
+			if s.brush != brushColor {
+				p := inv.Transform(f32.Pt(float32(x)+.5, float32(y)+.5))
+				switch s.brush {
+				case brushGradient:
+					c = decode(ramp.At(s.grad.Spread.Extend(s.grad.Offset(p))))
+				case brushShader:
+					// Run the fallback of the shader, which the software
+					// renderer uses in place of its program.
+					c = f32color.LinearFromSRGB(s.shader.Fallback(p, s.uniforms))
+				case brushImage:
+					var inside bool
+					c, inside = sample(src, f32.Pt(p.X*scale.X, p.Y*scale.Y), s.image.filter == paint.FilterNearest)
+					if !inside {
+						continue
+					}
+				}
+			}
+			c.R *= cov
+			c.G *= cov
+			c.B *= cov
+			c.A *= cov
+			if c.A == 0 {
+				continue
+			}
+			px := dst.Pix[dst.PixOffset(x, y):]
+			if c.A < 1 {
+				c = blend.SourceOver.Blend(decodePix(px), c)
+			}
+			enc := c.SRGBPremul()
+			px[0], px[1], px[2], px[3] = enc.R, enc.G, enc.B, enc.A
+		}
+	}
+}
+
+// target returns the image of the current layer.
+func (r *renderer) target() *image.RGBA {
+	if n := len(r.layers); n > 0 {
+		return r.layers[n-1].img
+	}
+	return r.dst
+}
+
+// pushLayer starts an opacity, blend or blur layer.
+func (r *renderer) pushLayer(opacity float32, mode blend.Mode, blur float32) {
+	r.layers = append(r.layers, layer{
+		img:     image.NewRGBA(r.dst.Rect),
+		opacity: opacity,
+		mode:    mode,
+		blur:    blur,
+	})
+}
+
+// popLayer ends the current layer and composites it onto the layer
+// below.
+func (r *renderer) popLayer() {
+	n := len(r.layers) - 1
+	l := r.layers[n]
+	r.layers = r.layers[:n]
+	if l.blur > 0 {
+		blurLayer(r.target(), l.img, l.blur)
+		return
+	}
+	if l.opacity < 1 {
+		pix := l.img.Pix
+		for i := 0; i < len(pix); i += 4 {
+			if pix[i+3] == 0 {
+				continue
+			}
+			c := decodePix(pix[i:])
+			c.R *= l.opacity
+			c.G *= l.opacity
+			c.B *= l.opacity
+			c.A *= l.opacity
+			enc := c.SRGBPremul()
+			pix[i+0], pix[i+1], pix[i+2], pix[i+3] = enc.R, enc.G, enc.B, enc.A
+		}
+	}
+	l.mode.Composite(r.target().Pix, l.img.Pix)
+}
+
+// blurLayer blurs dst by a Gaussian with standard deviation sigma
+// where mask covers it.
+func blurLayer(dst, mask *image.RGBA, sigma float32) {
+	// Find the masked area.
+	area := image.Rectangle{}
+	for y := mask.Rect.Min.Y; y < mask.Rect.Max.Y; y++ {
+		row := mask.Pix[mask.PixOffset(mask.Rect.Min.X, y):][:mask.Rect.Dx()*4]
+		x0, x1 := -1, -1
+		for x := 0; x < len(row)/4; x++ {
+			if row[x*4+3] != 0 {
+				if x0 == -1 {
+					x0 = x
+				}
+				x1 = x + 1
+			}
+		}
+		if x0 != -1 {
+			area = area.Union(image.Rect(mask.Rect.Min.X+x0, y, mask.Rect.Min.X+x1, y+1))
+		}
+	}
+	if area.Empty() {
+		return
+	}
+	// Blur the area with the backdrop around it that blurs into it.
+	back := area.Inset(-blur.Margin(sigma)).Intersect(dst.Rect)
+	blurred := image.NewRGBA(back)
+	for y := back.Min.Y; y < back.Max.Y; y++ {
+		copy(blurred.Pix[blurred.PixOffset(back.Min.X, y):], dst.Pix[dst.PixOffset(back.Min.X, y):][:back.Dx()*4])
+	}
+	blur.Gaussian(blurred, sigma)
+	w := area.Dx() * 4
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		row := dst.Pix[dst.PixOffset(area.Min.X, y):][:w]
+		blur.Mask(row, blurred.Pix[blurred.PixOffset(area.Min.X, y):][:w], mask.Pix[mask.PixOffset(area.Min.X, y):][:w])
+	}
+}
+
+// sample returns the color of img at p, relative to the origin of img,
+// interpolated between the nearest pixels or, if nearest is set, of
+// the nearest pixel. It reports false if p is outside img.
+func sample(img *image.RGBA, p f32.Point, nearest bool) (f32color.RGBA, bool) {
+	sz := img.Rect.Size()
+	if p.X < 0 || p.Y < 0 || p.X >= float32(sz.X) || p.Y >= float32(sz.Y) {
+		return f32color.RGBA{}, false
+	}
+	if nearest {
+		return decodePix(img.Pix[img.PixOffset(img.Rect.Min.X+int(p.X), img.Rect.Min.Y+int(p.Y)):]), true
+	}
+	u, v := p.X-.5, p.Y-.5
+	x0, y0 := int(math.Floor(float64(u))), int(math.Floor(float64(v)))
+	fx, fy := u-float32(x0), v-float32(y0)
+	texel := func(x, y int) f32color.RGBA {
+		x = clamp(x, 0, sz.X-1)
+		y = clamp(y, 0, sz.Y-1)
+		return decodePix(img.Pix[img.PixOffset(img.Rect.Min.X+x, img.Rect.Min.Y+y):])
+	}
+	top := lerp(texel(x0, y0), texel(x0+1, y0), fx)
+	bottom := lerp(texel(x0, y0+1), texel(x0+1, y0+1), fx)
+	return lerp(top, bottom, fy), true
+}
+
+// decode parses the path data of a clip path and transforms it by t.
+func (p *path) decode(data []byte, t f32.Affine2D) {
+	for len(data) >= scene.CommandSize+4 {
+		cmd := ops.DecodeCommand(data[4:])
+		switch cmd.Op() {
+		case scene.OpLine:
+			from, to := scene.DecodeLine(cmd)
+			p.segment(t.Transform(from), t.Transform(to))
+		case scene.OpGap:
+			from, to := scene.DecodeGap(cmd)
+			p.segment(t.Transform(from), t.Transform(to))
+		case scene.OpQuad:
+			from, ctrl, to := scene.DecodeQuad(cmd)
+			p.segment(t.Transform(from), t.Transform(ctrl), t.Transform(to))
+		case scene.OpCubic:
+			from, ctrl0, ctrl1, to := scene.DecodeCubic(cmd)
+			p.segment(t.Transform(from), t.Transform(ctrl0), t.Transform(ctrl1), t.Transform(to))
+		default:
+			panic("unsupported scene command")
+		}
+		data = data[scene.CommandSize+4:]
+	}
+}
+
+// segment adds a line or curve through pts. A new subpath is
+// started if the segment doesn't start at the pen.
+func (p *path) segment(pts ...f32.Point) {
+	if len(p.cmds) == 0 {
+		p.bounds = f32.Rectangle{Min: pts[0], Max: pts[0]}
+	}
+	if len(p.cmds) == 0 || pts[0] != p.pen {
+		p.cmds = append(p.cmds, pathCmd{n: pathCmdMove, pts: [3]f32.Point{pts[0]}})
+	}
+	cmd := pathCmd{n: len(pts) - 1}
+	copy(cmd.pts[:], pts[1:])
+	p.cmds = append(p.cmds, cmd)
+	for _, pt := range pts[1:] {
+		p.bounds = extend(p.bounds, pt)
+	}
+	p.pen = pts[len(pts)-1]
+}
+
+// flatness is the maximum distance in pixels between curves and the
+// lines that approximate them. It is lower than the tolerance of the
+// rasterizer's own curve flattening, to match the precision of the GPU
+// renderers.
+const flatness = 1.0 / 64
+
+// rasterize adds the subpaths of p, offset by -off, to z.
+func (p *path) rasterize(z *vector.Rasterizer, off f32.Point) {
+	open := false
+	var pen f32.Point
+	for _, c := range p.cmds {
+		pts := c.pts
+		for i := range pts {
+			pts[i] = pts[i].Sub(off)
+		}
+		switch c.n {
+		case pathCmdMove:
+			if open {
+				z.ClosePath()
+			}
+			z.MoveTo(pts[0].X, pts[0].Y)
+			open = true
+		case pathCmdLine:
+			z.LineTo(pts[0].X, pts[0].Y)
+		case pathCmdQuad:
+			// The second derivative of a quadratic Bézier curve is
+			// constant.
+			dd := length(pen.Sub(pts[0].Mul(2)).Add(pts[1])) * 2
+			n := segments(dd)
+			for i := 1; i <= n; i++ {
+				t := float32(i) / float32(n)
+				q := quadAt(pen, pts[0], pts[1], t)
+				z.LineTo(q.X, q.Y)
+			}
+		case pathCmdCubic:
+			dd := 6 * float32(math.Max(
+				float64(length(pen.Sub(pts[0].Mul(2)).Add(pts[1]))),
+				float64(length(pts[0].Sub(pts[1].Mul(2)).Add(pts[2]))),
+			))
+			n := segments(dd)
+			for i := 1; i <= n; i++ {
+				t := float32(i) / float32(n)
+				q := lerpPt(quadAt(pen, pts[0], pts[1], t), quadAt(pts[0], pts[1], pts[2], t), t)
+				z.LineTo(q.X, q.Y)
+			}
+		}
+		if c.n == pathCmdMove {
+			pen = pts[0]
+		} else {
+			pen = pts[c.n-1]
+		}
+	}
+	if open {
+		z.ClosePath()
+	}
+}
+
+// segments returns the number of lines needed to approximate a curve
+// whose second derivative is bounded by dd.
+func segments(dd float32) int {
+	// The distance between a curve and a chord over a parameter
+	// interval h is at most dd*h²/8.
+	n := int(math.Ceil(math.Sqrt(float64(dd / (8 * flatness)))))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func quadAt(p0, p1, p2 f32.Point, t float32) f32.Point {
+	return lerpPt(lerpPt(p0, p1, t), lerpPt(p1, p2, t), t)
+}
+
+func lerpPt(a, b f32.Point, t float32) f32.Point {
+	return a.Add(b.Sub(a).Mul(t))
+}
+
+func length(p f32.Point) float32 {
+	return float32(math.Hypot(float64(p.X), float64(p.Y)))
+}
+
+func decodeStrokeOp(data []byte, refs []interface{}) (stroke.StrokeStyle, stroke.DashOp) {
+	_ = data[14]
+	bo := binary.LittleEndian
+	style := stroke.StrokeStyle{
+		Width: math.Float32frombits(bo.Uint32(data[1:])),
+		Miter: math.Float32frombits(bo.Uint32(data[5:])),
+		Cap:   stroke.StrokeCap(data[9]),
+		Join:  stroke.StrokeJoin(data[10]),
+	}
+	dashes := stroke.DashOp{
+		Phase: math.Float32frombits(bo.Uint32(data[11:])),
+	}
+	dashes.Dashes, _ = refs[0].([]float32)
+	return style, dashes
+}
+
+func decodeImageOp(data []byte, refs []interface{}) imageOp {
+	if refs[1] == nil {
+		return imageOp{}
+	}
+	data = data[:ops.TypeImageLen]
+	img := imageOp{
+		handle: refs[1],
+		filter: paint.ImageFilter(data[1]),
+	}
+	switch src := refs[0].(type) {
+	case *image.RGBA:
+		img.src = src
+		img.size = src.Bounds().Size()
+	case *ops.Ops:
+		bo := binary.LittleEndian
+		img.render = src
+		img.size = image.Pt(int(bo.Uint32(data[2:])), int(bo.Uint32(data[6:])))
+		// Images rendered from operations have no mipmaps.
+		if img.filter == paint.FilterLinearMipmapLinear {
+			img.filter = paint.FilterLinear
+		}
+	}
+	return img
+}
+
+// decodeShaderOp decodes a paint.ShaderOp. The shader is nil if the
+// operation refers to something else, such as a placeholder of a
+// decoded dump.
+func decodeShaderOp(refs []interface{}) (*paint.Shader, []byte) {
+	s, _ := refs[0].(*paint.Shader)
+	u, _ := refs[1].(string)
+	return s, []byte(u)
+}
+
+func decodeColorOp(data []byte) color.NRGBA {
+	data = data[:ops.TypeColorLen]
+	return decodeNRGBA(data[1:])
+}
+
+func decodeLinearGradientOp(data []byte) gradient.Gradient {
+	data = data[:ops.TypeLinearGradientLen]
+	return gradient.Gradient{
+		Kind:  gradient.Linear,
+		P1:    decodePoint(data[1:]),
+		P2:    decodePoint(data[9:]),
+		Stops: twoStops(decodeNRGBA(data[17:]), decodeNRGBA(data[21:])),
+	}
+}
+
+func decodeRadialGradientOp(data []byte) gradient.Gradient {
+	data = data[:ops.TypeRadialGradientLen]
+	bo := binary.LittleEndian
+	center := decodePoint(data[1:])
+	radius := math.Float32frombits(bo.Uint32(data[9:]))
+	return gradient.Gradient{
+		Kind:  gradient.Radial,
+		P1:    center,
+		P2:    center.Add(f32.Pt(radius, 0)),
+		Stops: twoStops(decodeNRGBA(data[13:]), decodeNRGBA(data[17:])),
+	}
+}
+
+func decodeConicGradientOp(data []byte) gradient.Gradient {
+	data = data[:ops.TypeConicGradientLen]
+	bo := binary.LittleEndian
+	center := decodePoint(data[1:])
+	angle := float64(math.Float32frombits(bo.Uint32(data[9:])))
+	return gradient.Gradient{
+		Kind:  gradient.Conic,
+		P1:    center,
+		P2:    center.Add(f32.Pt(float32(math.Cos(angle)), float32(math.Sin(angle)))),
+		Stops: twoStops(decodeNRGBA(data[13:]), decodeNRGBA(data[17:])),
+	}
+}
+
+func decodeGradientOp(data []byte, refs []interface{}) gradient.Gradient {
+	data = data[:ops.TypeGradientLen]
+	g := gradient.Gradient{
+		Kind:   gradient.Kind(data[1]),
+		Spread: gradient.Spread(data[2]),
+		P1:     decodePoint(data[3:]),
+		P2:     decodePoint(data[11:]),
+	}
+	bo := binary.LittleEndian
+	for enc := refs[0].(string); len(enc) >= 8; enc = enc[8:] {
+		stop := []byte(enc[:8])
+		g.Stops = append(g.Stops, gradient.Stop{
+			Offset: math.Float32frombits(bo.Uint32(stop)),
+			Color:  f32color.LinearFromSRGB(decodeNRGBA(stop[4:])),
+		})
+	}
+	if g.Spread > gradient.Reflect {
+		g.Spread = gradient.Pad
+	}
+	return g
+}
+
+func decodeShadowOp(data []byte) gradient.Gradient {
+	data = data[:ops.TypeShadowLen]
+	bo := binary.LittleEndian
+	return gradient.Gradient{
+		Kind:   gradient.Shadow,
+		P1:     decodePoint(data[1:]),
+		P2:     decodePoint(data[9:]),
+		Radius: math.Float32frombits(bo.Uint32(data[17:])),
+		Sigma:  math.Float32frombits(bo.Uint32(data[21:])),
+		Stops:  twoStops(color.NRGBA{}, decodeNRGBA(data[25:])),
+	}
+}
+
+func twoStops(c1, c2 color.NRGBA) []gradient.Stop {
+	return []gradient.Stop{
+		{Offset: 0, Color: f32color.LinearFromSRGB(c1)},
+		{Offset: 1, Color: f32color.LinearFromSRGB(c2)},
+	}
+}
+
+func decodePoint(data []byte) f32.Point {
+	bo := binary.LittleEndian
+	return f32.Point{
+		X: math.Float32frombits(bo.Uint32(data[0:])),
+		Y: math.Float32frombits(bo.Uint32(data[4:])),
+	}
+}
+
+func decodeNRGBA(data []byte) color.NRGBA {
+	return color.NRGBA{R: data[0], G: data[1], B: data[2], A: data[3]}
+}
+
+// decode converts a premultiplied sRGB color to the linear color space.
+func decode(c color.RGBA) f32color.RGBA {
+	return f32color.RGBA{
+		R: linear[c.R],
+		G: linear[c.G],
+		B: linear[c.B],
+		A: float32(c.A) / 0xff,
+	}
+}
+
+// decodePix decodes the image.RGBA pixel at the start of p.
+func decodePix(p []byte) f32color.RGBA {
+	return decode(color.RGBA{R: p[0], G: p[1], B: p[2], A: p[3]})
+}
+
+func lerp(a, b f32color.RGBA, t float32) f32color.RGBA {
+	return f32color.RGBA{
+		R: a.R + (b.R-a.R)*t,
+		G: a.G + (b.G-a.G)*t,
+		B: a.B + (b.B-a.B)*t,
+		A: a.A + (b.A-a.A)*t,
+	}
+}
+
+// extend returns the smallest rectangle that contains r and p.
+func extend(r f32.Rectangle, p f32.Point) f32.Rectangle {
+	r.Min.X = float32(math.Min(float64(r.Min.X), float64(p.X)))
+	r.Min.Y = float32(math.Min(float64(r.Min.Y), float64(p.Y)))
+	r.Max.X = float32(math.Max(float64(r.Max.X), float64(p.X)))
+	r.Max.Y = float32(math.Max(float64(r.Max.Y), float64(p.Y)))
+	return r
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package software implements headless windows that render operation
+lists to images entirely on the CPU.
+
+Unlike package headless, software needs no GPU, drivers or cgo and
+works everywhere Go runs, at the cost of speed. It is intended for
+screenshots and tests of user interfaces in environments such as
+continuous integration servers.
+
+The output closely follows the GPU renderers: colors are blended in the
+linear sRGB color space, and the window content is stored as
+premultiplied sRGB pixels like the headless windows. Small differences
+remain in the anti-aliasing of shape edges.
+
+The renderer doesn't share the CPU backend of the compute renderer,
+gioui.org/cpu, because that backend needs cgo and is available for few
+architectures. Instead, the tests in gpu/internal/rendertest check its
+output against the reference images of the GPU renderers.
+*/
+package software
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+
+	"gioui.org/op"
+)
+
+// Window is a headless window backed by a software renderer.
+type Window struct {
+	size image.Point
+	img  *image.RGBA
+	r    renderer
+}
+
+// NewWindow creates a new software window.
+func NewWindow(width, height int) (*Window, error) {
+	if width <= 0 || height <= 0 {
+		return nil, errors.New("software: invalid window size")
+	}
+	w := &Window{
+		size: image.Point{X: width, Y: height},
+		img:  image.NewRGBA(image.Rect(0, 0, width, height)),
+	}
+	return w, nil
+}
+
+// Release resources associated with the window.
+func (w *Window) Release() {
+	w.img = nil
+	w.r = renderer{}
+}
+
+// Size returns the window size.
+func (w *Window) Size() image.Point {
+	return w.size
+}
+
+// Frame replaces the window content and state with the
+// operation list.
+func (w *Window) Frame(frame *op.Ops) error {
+	if w.img == nil {
+		return errors.New("software: window released")
+	}
+	for i := range w.img.Pix {
+		w.img.Pix[i] = 0
+	}
+	w.r.render(w.img, frame)
+	return nil
+}
+
+// RenderImage renders the operations of call into an image of size
+// pixels. The image is transparent where the operations don't draw.
+// Rendering does not affect the window content.
+func (w *Window) RenderImage(call op.CallOp, size image.Point) (*image.RGBA, error) {
+	if w.img == nil {
+		return nil, errors.New("software: window released")
+	}
+	if size.X < 0 || size.Y < 0 {
+		return nil, errors.New("software: invalid image size")
+	}
+	o := new(op.Ops)
+	call.Add(o)
+	img := image.NewRGBA(image.Rectangle{Max: size})
+	var r renderer
+	r.render(img, o)
+	return img, nil
+}
+
+// Screenshot transfers the Window content at origin img.Rect.Min to img.
+func (w *Window) Screenshot(img *image.RGBA) error {
+	if w.img == nil {
+		return errors.New("software: window released")
+	}
+	if !img.Rect.In(w.img.Rect) {
+		return errors.New("software: screenshot outside the window")
+	}
+	draw.Draw(img, img.Rect, w.img, img.Rect.Min, draw.Src)
+	return nil
+}
@@ -0,0 +1,434 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package software
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/internal/f32color"
+	"gioui.org/internal/ops"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/shader"
+)
+
+var (
+	red   = color.NRGBA{R: 0xff, A: 0xff}
+	green = color.NRGBA{G: 0xff, A: 0xff}
+	blue  = color.NRGBA{B: 0xff, A: 0xff}
+	bg    = color.NRGBA{}
+)
+
+type pixel struct {
+	x, y  int
+	color color.NRGBA
+}
+
+func TestSoftware(t *testing.T) {
+	col := color.NRGBA{A: 0xff, R: 0xca, G: 0xfe}
+	ops := new(op.Ops)
+	paint.FillShape(ops, col, clip.Rect(image.Rect(0, 0, 50, 50)).Op())
+	img := render(t, ops)
+	if sz := img.Bounds().Size(); sz != image.Pt(100, 100) {
+		t.Errorf("got %v screenshot, expected (100,100)", sz)
+	}
+	checkPixels(t, img, []pixel{{0, 0, col}, {49, 49, col}, {50, 50, bg}})
+}
+
+func TestNoOps(t *testing.T) {
+	w, err := NewWindow(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Release()
+	if err := w.Frame(nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInvalidSize(t *testing.T) {
+	if _, err := NewWindow(0, 10); err == nil {
+		t.Error("NewWindow succeeded for an empty window")
+	}
+}
+
+func TestScreenshotOffset(t *testing.T) {
+	ops := new(op.Ops)
+	paint.FillShape(ops, red, clip.Rect(image.Rect(20, 20, 30, 30)).Op())
+	w, err := NewWindow(100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Release()
+	if err := w.Frame(ops); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(20, 20, 40, 40))
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	checkPixels(t, img, []pixel{{20, 20, red}, {30, 30, bg}})
+	if err := w.Screenshot(image.NewRGBA(image.Rect(90, 90, 110, 110))); err == nil {
+		t.Error("Screenshot succeeded outside the window")
+	}
+}
+
+func TestClipping(t *testing.T) {
+	ops := new(op.Ops)
+	paint.ColorOp{Color: red}.Add(ops)
+	cl := clip.RRect{Rect: image.Rect(10, 10, 60, 60), SE: 20}.Push(ops)
+	paint.PaintOp{}.Add(ops)
+	paint.ColorOp{Color: blue}.Add(ops)
+	// The ellipse is intersected with the rounded rectangle.
+	el := clip.Ellipse{Min: image.Pt(30, 30), Max: image.Pt(90, 90)}.Push(ops)
+	paint.PaintOp{}.Add(ops)
+	el.Pop()
+	cl.Pop()
+	img := render(t, ops)
+	checkPixels(t, img, []pixel{
+		{15, 15, red},
+		{50, 50, blue},
+		// Cut by the rounded corner.
+		{58, 58, bg},
+		// Outside the rounded rectangle.
+		{70, 70, bg},
+	})
+}
+
+func TestTransform(t *testing.T) {
+	ops := new(op.Ops)
+	op.Offset(image.Pt(20, 10)).Add(ops)
+	op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(2, 2))).Add(ops)
+	paint.FillShape(ops, red, clip.Rect(image.Rect(0, 0, 10, 10)).Op())
+	// A square rotated 45 degrees around its center.
+	op.Affine(f32.Affine2D{}.Rotate(f32.Pt(25, 25), math.Pi/4)).Add(ops)
+	paint.FillShape(ops, blue, clip.Rect(image.Rect(20, 20, 30, 30)).Op())
+	img := render(t, ops)
+	checkPixels(t, img, []pixel{
+		{20, 10, red},
+		{39, 29, red},
+		{40, 30, bg},
+		// The center of the rotated square.
+		{70, 60, blue},
+		// A corner of the unrotated square is outside the rotated
+		// square.
+		{61, 51, bg},
+	})
+}
+
+func TestStroke(t *testing.T) {
+	ops := new(op.Ops)
+	var p clip.Path
+	p.Begin(ops)
+	p.MoveTo(f32.Pt(10, 50))
+	p.LineTo(f32.Pt(90, 50))
+	paint.FillShape(ops, red, clip.Stroke{Path: p.End(), Width: 10}.Op())
+	img := render(t, ops)
+	checkPixels(t, img, []pixel{
+		{50, 46, red},
+		{50, 53, red},
+		{50, 56, bg},
+		// Round caps extend beyond the end points.
+		{7, 50, red},
+		{4, 50, bg},
+	})
+}
+
+func TestLinearGradient(t *testing.T) {
+	ops := new(op.Ops)
+	paint.LinearGradientOp{
+		// Place the stops at the centers of the first and last pixels.
+		Stop1:  f32.Pt(.5, 0),
+		Stop2:  f32.Pt(99.5, 0),
+		Color1: red,
+		Color2: blue,
+	}.Add(ops)
+	paint.PaintOp{}.Add(ops)
+	img := render(t, ops)
+	checkPixels(t, img, []pixel{{0, 50, red}, {99, 50, blue}})
+	mid := img.RGBAAt(50, 50)
+	if mid.R == 0 || mid.B == 0 || mid.A != 0xff {
+		t.Errorf("got color %v, expected a mix of red and blue", mid)
+	}
+}
+
+func TestGradientNaN(t *testing.T) {
+	ops := new(op.Ops)
+	paint.GradientOp{
+		Kind:  paint.LinearGradient,
+		Start: f32.Pt(.5, 0),
+		End:   f32.Pt(99.5, 0),
+		Stops: []paint.GradientStop{
+			{Offset: 1, Color: blue},
+			{Offset: float32(math.NaN()), Color: red},
+		},
+	}.Add(ops)
+	paint.PaintOp{}.Add(ops)
+	img := render(t, ops)
+	// The NaN offset is clamped to 0.
+	checkPixels(t, img, []pixel{{0, 50, red}, {99, 50, blue}})
+}
+
+func TestUnknownGradient(t *testing.T) {
+	g := paint.GradientOp{
+		Kind:  9,
+		Stops: []paint.GradientStop{{Offset: 0, Color: red}},
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("GradientOp.Add accepted an unknown kind")
+			}
+		}()
+		g.Add(new(op.Ops))
+	}()
+	// Encode a gradient of unknown kind behind the back of
+	// GradientOp.Add, such as a decoded dump could.
+	g.Kind = paint.LinearGradient
+	o := new(op.Ops)
+	g.Add(o)
+	paint.PaintOp{}.Add(o)
+	data, refs := ops.Contents(&o.Internal)
+	data = append([]byte(nil), data...)
+	refs = append([]interface{}(nil), refs...)
+	for pc := 0; pc < len(data); pc += ops.OpType(data[pc]).Size() {
+		if ops.OpType(data[pc]) == ops.TypeGradient {
+			data[pc+1] = 9
+		}
+	}
+	ops.SetContents(&o.Internal, data, refs)
+	img := render(t, o)
+	checkPixels(t, img, []pixel{{50, 50, bg}})
+}
+
+func TestImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	src.SetNRGBA(0, 0, red)
+	src.SetNRGBA(1, 0, green)
+	src.SetNRGBA(0, 1, blue)
+	ops := new(op.Ops)
+	op.Offset(image.Pt(10, 10)).Add(ops)
+	op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(20, 20))).Add(ops)
+	paint.NewImageOp(src).Add(ops)
+	paint.PaintOp{}.Add(ops)
+	img := render(t, ops)
+	checkPixels(t, img, []pixel{
+		{12, 12, red},
+		{48, 12, green},
+		{12, 48, blue},
+		{48, 48, bg},
+		// Images don't extend beyond their bounds.
+		{5, 5, bg},
+		{55, 12, bg},
+	})
+}
+
+func TestImageFilter(t *testing.T) {
+	// A checkerboard of single black and white pixels.
+	checkers := image.NewNRGBA(image.Rect(0, 0, 60, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 60; x++ {
+			c := color.NRGBA{A: 0xff}
+			if (x+y)%2 == 0 {
+				c = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			checkers.SetNRGBA(x, y, c)
+		}
+	}
+	draw := func(filter paint.ImageFilter) *image.RGBA {
+		ops := new(op.Ops)
+		im := paint.NewImageOp(checkers)
+		im.Filter = filter
+		im.Add(ops)
+		op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(1.0/3, 1.0/3))).Add(ops)
+		paint.PaintOp{}.Add(ops)
+		return render(t, ops)
+	}
+	// Without mipmaps, every pixel samples a single black or white
+	// pixel.
+	img := draw(paint.FilterLinear)
+	if c := img.RGBAAt(5, 5); c.R != 0 && c.R != 0xff {
+		t.Errorf("got color %v, expected black or white", c)
+	}
+	// With mipmaps, the checkers average to gray.
+	img = draw(paint.FilterLinearMipmapLinear)
+	gray := f32color.RGBA{R: .5, G: .5, B: .5, A: 1}.SRGBPremul()
+	for _, p := range []image.Point{{5, 5}, {6, 5}, {19, 19}} {
+		if c := img.RGBAAt(p.X, p.Y); !near(c, gray) {
+			t.Errorf("(%d,%d): got color %v, expected %v", p.X, p.Y, c, gray)
+		}
+	}
+
+	// Nearest filtering keeps the edges of upscaled pixels.
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.SetNRGBA(0, 0, red)
+	src.SetNRGBA(1, 0, blue)
+	ops := new(op.Ops)
+	im := paint.NewImageOp(src)
+	im.Filter = paint.FilterNearest
+	im.Add(ops)
+	op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(50, 50))).Add(ops)
+	paint.PaintOp{}.Add(ops)
+	checkPixels(t, render(t, ops), []pixel{{49, 10, red}, {50, 10, blue}})
+}
+
+func TestRenderedImage(t *testing.T) {
+	// A red square with a green corner.
+	src := new(op.Ops)
+	m := op.Record(src)
+	paint.FillShape(src, red, clip.Rect(image.Rect(0, 0, 10, 10)).Op())
+	paint.FillShape(src, green, clip.Rect(image.Rect(0, 0, 5, 5)).Op())
+	call := m.Stop()
+
+	ops := new(op.Ops)
+	im := paint.NewRenderedImageOp(call, image.Pt(20, 20))
+	if sz := im.Size(); sz != image.Pt(20, 20) {
+		t.Errorf("got size %v, expected (20,20)", sz)
+	}
+	im.Filter = paint.FilterNearest
+	im.Add(ops)
+	op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(2, 2)).Offset(f32.Pt(10, 10))).Add(ops)
+	paint.PaintOp{}.Add(ops)
+	checkPixels(t, render(t, ops), []pixel{
+		{12, 12, green},
+		{22, 22, red},
+		// The rendered image is transparent outside the operations.
+		{32, 32, bg},
+		{9, 9, bg},
+	})
+
+	w, err := NewWindow(10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Release()
+	img, err := w.RenderImage(call, image.Pt(15, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sz := img.Bounds().Size(); sz != image.Pt(15, 15) {
+		t.Errorf("got image size %v, expected (15,15)", sz)
+	}
+	checkPixels(t, img, []pixel{{2, 2, green}, {7, 7, red}, {12, 12, bg}})
+}
+
+func TestOpacity(t *testing.T) {
+	ops := new(op.Ops)
+	paint.Fill(ops, color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	opc := paint.OpacityOp{Opacity: .5}.Push(ops)
+	// Overlapping shapes in a layer don't show through each other.
+	paint.FillShape(ops, red, clip.Rect(image.Rect(0, 0, 60, 60)).Op())
+	paint.FillShape(ops, blue, clip.Rect(image.Rect(40, 40, 100, 100)).Op())
+	opc.Pop()
+	img := render(t, ops)
+	// Half of the layer color blended with white.
+	half := func(c color.NRGBA) color.RGBA {
+		l := f32color.LinearFromSRGB(c)
+		return f32color.RGBA{R: l.R*.5 + .5, G: l.G*.5 + .5, B: l.B*.5 + .5, A: 1}.SRGBPremul()
+	}
+	for _, p := range []pixel{{10, 10, red}, {50, 50, blue}, {90, 90, blue}} {
+		// Allow for the rounding of the layer pixels.
+		if got, exp := img.RGBAAt(p.x, p.y), half(p.color); !near(got, exp) {
+			t.Errorf("(%d,%d): got color %v, expected %v", p.x, p.y, got, exp)
+		}
+	}
+}
+
+func TestShadow(t *testing.T) {
+	ops := new(op.Ops)
+	paint.FillShadow(ops, paint.ShadowOp{
+		Rect:   image.Rect(20, 20, 60, 60),
+		Radius: 10,
+		Offset: image.Pt(10, 10),
+		Blur:   10,
+		Color:  color.NRGBA{A: 0xff},
+	})
+	img := render(t, ops)
+	checkPixels(t, img, []pixel{{50, 50, color.NRGBA{A: 0xff}}, {10, 50, bg}, {50, 95, bg}})
+	// The edges are half covered.
+	if a := (int(img.RGBAAt(29, 50).A) + int(img.RGBAAt(30, 50).A)) / 2; a < 0x7a || a > 0x85 {
+		t.Errorf("got alpha %#x at the edge, expected about %#x", a, 0x80)
+	}
+	// The rounded corners cover less than the sharp corners.
+	if a := img.RGBAAt(30, 30).A; a >= 0x40 {
+		t.Errorf("got alpha %#x at the corner, expected less than %#x", a, 0x40)
+	}
+}
+
+func TestBlur(t *testing.T) {
+	ops := new(op.Ops)
+	paint.FillShape(ops, red, clip.Rect(image.Rect(0, 0, 50, 100)).Op())
+	paint.FillShape(ops, blue, clip.Rect(image.Rect(50, 0, 100, 100)).Op())
+	cl := clip.Rect(image.Rect(30, 30, 70, 70)).Push(ops)
+	paint.BlurOp{Radius: 10}.Add(ops)
+	cl.Pop()
+	img := render(t, ops)
+	// Outside the clip area, the content is unchanged.
+	checkPixels(t, img, []pixel{{49, 29, red}, {50, 70, blue}, {29, 50, red}})
+	// The colors spread across the edge inside the clip area.
+	if c := img.RGBAAt(49, 50); c.R == 0xff || c.B == 0 || c.A != 0xff {
+		t.Errorf("got %v at the blurred edge, expected a mix of red and blue", c)
+	}
+	if c, exp := img.RGBAAt(31, 50), f32color.NRGBAToRGBA(red); !near(c, exp) {
+		t.Errorf("got %v far from the edge, expected %v", c, exp)
+	}
+}
+
+func near(c1, c2 color.RGBA) bool {
+	d := func(a, b uint8) bool { return a-b <= 1 || b-a <= 1 }
+	return d(c1.R, c2.R) && d(c1.G, c2.G) && d(c1.B, c2.B) && d(c1.A, c2.A)
+}
+
+func render(t *testing.T, ops *op.Ops) *image.RGBA {
+	t.Helper()
+	w, err := NewWindow(100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Release()
+	if err := w.Frame(ops); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rectangle{Max: w.Size()})
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func checkPixels(t *testing.T, img *image.RGBA, pixels []pixel) {
+	t.Helper()
+	for _, p := range pixels {
+		if got, exp := img.RGBAAt(p.x, p.y), f32color.NRGBAToRGBA(p.color); got != exp {
+			t.Errorf("(%d,%d): got color %v, expected %v", p.x, p.y, got, exp)
+		}
+	}
+}
+
+func TestShader(t *testing.T) {
+	// The fallback paints the uniform color right of x = 10.
+	s := paint.NewShader(shader.Sources{}, func(p f32.Point, uniforms []byte) color.NRGBA {
+		if p.X < 10 {
+			return red
+		}
+		return color.NRGBA{R: uniforms[0], G: uniforms[1], B: uniforms[2], A: uniforms[3]}
+	})
+	ops := new(op.Ops)
+	op.Offset(image.Pt(10, 10)).Add(ops)
+	defer clip.Rect(image.Rect(0, 0, 20, 20)).Push(ops).Pop()
+	paint.ShaderOp{Shader: s, Uniforms: []byte{0, 0, 0xff, 0xff}}.Add(ops)
+	paint.PaintOp{}.Add(ops)
+	img := render(t, ops)
+	checkPixels(t, img, []pixel{
+		{15, 15, red},
+		{25, 15, blue},
+		// Shaders are clipped.
+		{35, 15, bg},
+		{5, 5, bg},
+	})
+}
@@ -92,3 +92,22 @@ func (t *timers) Release() {
 	}
 	t.timers = nil
 }
+
+// frameStats counts the work of a frame, for profiling.
+type frameStats struct {
+	paths       int
+	uploads     int
+	cacheHits   int
+	cacheMisses int
+	// encode is the time spent encoding paths.
+	encode time.Duration
+}
+
+// lookup counts a cache lookup.
+func (s *frameStats) lookup(hit bool) {
+	if hit {
+		s.cacheHits++
+	} else {
+		s.cacheMisses++
+	}
+}
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package blend implements the blend modes of paint.BlendOp. The
+formulas follow the separable blend modes of the W3C Compositing and
+Blending specification, applied to linear, premultiplied colors.
+
+Renderers that cannot express a mode with fixed-function blending use
+Composite to blend layers on the CPU.
+*/
+package blend
+
+import (
+	"image/color"
+
+	"gioui.org/internal/f32color"
+)
+
+// Mode is a blend mode. The zero Mode is SourceOver.
+type Mode uint8
+
+const (
+	SourceOver Mode = iota
+	Multiply
+	Screen
+	Overlay
+	Darken
+	Lighten
+	Difference
+	Plus
+)
+
+// linear maps sRGB encoded components to linear components.
+var linear [256]float32
+
+func init() {
+	for i := range linear {
+		linear[i] = f32color.LinearFromSRGB(color.NRGBA{R: uint8(i), A: 0xff}).R
+	}
+}
+
+// Blend returns the result of blending the source color src onto the
+// backdrop dst.
+func (m Mode) Blend(dst, src f32color.RGBA) f32color.RGBA {
+	switch m {
+	case SourceOver:
+		return f32color.RGBA{
+			R: src.R + dst.R*(1-src.A),
+			G: src.G + dst.G*(1-src.A),
+			B: src.B + dst.B*(1-src.A),
+			A: src.A + dst.A*(1-src.A),
+		}
+	case Plus:
+		return f32color.RGBA{
+			R: min(src.R+dst.R, 1),
+			G: min(src.G+dst.G, 1),
+			B: min(src.B+dst.B, 1),
+			A: min(src.A+dst.A, 1),
+		}
+	}
+	return f32color.RGBA{
+		R: m.channel(dst.R, src.R, dst.A, src.A),
+		G: m.channel(dst.G, src.G, dst.A, src.A),
+		B: m.channel(dst.B, src.B, dst.A, src.A),
+		A: src.A + dst.A - src.A*dst.A,
+	}
+}
+
+// Composite blends the pixels of src onto the pixels of dst, both in
+// the premultiplied sRGB layout of image.RGBA.Pix.
+func (m Mode) Composite(dst, src []byte) {
+	for i := 0; i+3 < len(dst) && i+3 < len(src); i += 4 {
+		if src[i+3] == 0 {
+			// Every mode leaves the backdrop unchanged where the
+			// source is transparent.
+			continue
+		}
+		c := m.Blend(decode(dst[i:]), decode(src[i:])).SRGBPremul()
+		dst[i+0] = c.R
+		dst[i+1] = c.G
+		dst[i+2] = c.B
+		dst[i+3] = c.A
+	}
+}
+
+// channel blends a premultiplied color component cs with alpha as
+// onto the component cb with alpha ab.
+func (m Mode) channel(cb, cs, ab, as float32) float32 {
+	c := cs*(1-ab) + cb*(1-as)
+	if ab == 0 || as == 0 {
+		return c
+	}
+	return c + as*ab*m.mix(cb/ab, cs/as)
+}
+
+// mix applies the mode to the unpremultiplied backdrop
+// component cb and source component cs.
+func (m Mode) mix(cb, cs float32) float32 {
+	switch m {
+	case Multiply:
+		return cb * cs
+	case Screen:
+		return screen(cb, cs)
+	case Overlay:
+		if cb <= .5 {
+			return 2 * cb * cs
+		}
+		return screen(cs, 2*cb-1)
+	case Darken:
+		return min(cb, cs)
+	case Lighten:
+		return max(cb, cs)
+	case Difference:
+		if cb > cs {
+			return cb - cs
+		}
+		return cs - cb
+	}
+	return cs
+}
+
+func screen(cb, cs float32) float32 {
+	return cb + cs - cb*cs
+}
+
+func decode(p []byte) f32color.RGBA {
+	return f32color.RGBA{
+		R: linear[p[0]],
+		G: linear[p[1]],
+		B: linear[p[2]],
+		A: float32(p[3]) / 0xff,
+	}
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
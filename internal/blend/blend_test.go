@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package blend
+
+import (
+	"testing"
+
+	"gioui.org/internal/f32color"
+)
+
+func TestBlend(t *testing.T) {
+	dst := f32color.RGBA{R: .8, G: .4, B: .2, A: 1}
+	src := f32color.RGBA{R: .5, G: .5, B: 0, A: 1}
+	tests := []struct {
+		mode Mode
+		want f32color.RGBA
+	}{
+		{SourceOver, f32color.RGBA{R: .5, G: .5, B: 0, A: 1}},
+		{Multiply, f32color.RGBA{R: .4, G: .2, B: 0, A: 1}},
+		{Screen, f32color.RGBA{R: .9, G: .7, B: .2, A: 1}},
+		{Overlay, f32color.RGBA{R: .8, G: .4, B: 0, A: 1}},
+		{Darken, f32color.RGBA{R: .5, G: .4, B: 0, A: 1}},
+		{Lighten, f32color.RGBA{R: .8, G: .5, B: .2, A: 1}},
+		{Difference, f32color.RGBA{R: .3, G: .1, B: .2, A: 1}},
+		{Plus, f32color.RGBA{R: 1, G: .9, B: .2, A: 1}},
+	}
+	for _, test := range tests {
+		if got := test.mode.Blend(dst, src); !equal(got, test.want) {
+			t.Errorf("mode %d: got %v, expected %v", test.mode, got, test.want)
+		}
+	}
+}
+
+func TestBlendTransparent(t *testing.T) {
+	dst := f32color.RGBA{R: .8, G: .4, B: .2, A: 1}
+	for m := SourceOver; m <= Plus; m++ {
+		if got := m.Blend(dst, f32color.RGBA{}); !equal(got, dst) {
+			t.Errorf("mode %d: transparent source changed backdrop to %v", m, got)
+		}
+		src := f32color.RGBA{R: .25, G: .5, A: .5}
+		if got := m.Blend(f32color.RGBA{}, src); m != Plus && !equal(got, src) {
+			t.Errorf("mode %d: transparent backdrop changed source to %v", m, got)
+		}
+	}
+}
+
+func TestComposite(t *testing.T) {
+	dst := []byte{0xff, 0xff, 0xff, 0xff, 0x10, 0x20, 0x30, 0xff}
+	src := []byte{0xff, 0x00, 0x00, 0xff, 0x00, 0x00, 0x00, 0x00}
+	Multiply.Composite(dst, src)
+	want := []byte{0xff, 0x00, 0x00, 0xff, 0x10, 0x20, 0x30, 0xff}
+	for i := range want {
+		if dst[i] != want[i] {
+			t.Fatalf("got %v, expected %v", dst, want)
+		}
+	}
+}
+
+func equal(c1, c2 f32color.RGBA) bool {
+	const eps = 1e-5
+	return abs(c1.R-c2.R) < eps && abs(c1.G-c2.G) < eps && abs(c1.B-c2.B) < eps && abs(c1.A-c2.A) < eps
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
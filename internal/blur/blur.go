@@ -0,0 +1,132 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package blur implements Gaussian blurs of images. It is used by the
+renderers to blur the content below paint.BlurOp areas on the CPU.
+*/
+package blur
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/internal/f32color"
+)
+
+// linear maps sRGB encoded components to linear components.
+var linear [256]float32
+
+func init() {
+	for i := range linear {
+		linear[i] = f32color.LinearFromSRGB(color.NRGBA{R: uint8(i), A: 0xff}).R
+	}
+}
+
+// Margin returns the distance in pixels beyond which a Gaussian with
+// standard deviation sigma has a negligible effect.
+func Margin(sigma float32) int {
+	if sigma <= 0 {
+		return 0
+	}
+	return int(math.Ceil(float64(3 * sigma)))
+}
+
+// Gaussian blurs img, an image of premultiplied sRGB encoded colors,
+// by a Gaussian with standard deviation sigma. The blur is computed in
+// the linear color space, and pixels beyond the edges of img are taken
+// to be their nearest edge pixels.
+func Gaussian(img *image.RGBA, sigma float32) {
+	n := Margin(sigma)
+	sz := img.Rect.Size()
+	if n == 0 || sz.X == 0 || sz.Y == 0 {
+		return
+	}
+	kernel := make([]float32, 2*n+1)
+	var sum float32
+	for i := range kernel {
+		d := float64(i - n)
+		w := float32(math.Exp(-d * d / (2 * float64(sigma) * float64(sigma))))
+		kernel[i] = w
+		sum += w
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	pix := make([]f32color.RGBA, sz.X*sz.Y)
+	for y := 0; y < sz.Y; y++ {
+		row := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y):]
+		for x := 0; x < sz.X; x++ {
+			p := row[x*4:]
+			pix[y*sz.X+x] = f32color.RGBA{R: linear[p[0]], G: linear[p[1]], B: linear[p[2]], A: float32(p[3]) / 0xff}
+		}
+	}
+	tmp := make([]f32color.RGBA, len(pix))
+	// Blur the rows, then the columns.
+	convolve(tmp, pix, kernel, sz.X, sz.Y, 1, sz.X)
+	convolve(pix, tmp, kernel, sz.Y, sz.X, sz.X, 1)
+	for y := 0; y < sz.Y; y++ {
+		row := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y):]
+		for x := 0; x < sz.X; x++ {
+			c := pix[y*sz.X+x].SRGBPremul()
+			p := row[x*4:]
+			p[0], p[1], p[2], p[3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+// convolve convolves the lines of src with kernel and stores the
+// result in dst. There are count lines of length pixels, where step
+// is the distance between the pixels of a line and stride the
+// distance between lines.
+func convolve(dst, src []f32color.RGBA, kernel []float32, length, count, step, stride int) {
+	n := len(kernel) / 2
+	for l := 0; l < count; l++ {
+		line := l * stride
+		for i := 0; i < length; i++ {
+			var c f32color.RGBA
+			for k, w := range kernel {
+				j := i + k - n
+				switch {
+				case j < 0:
+					j = 0
+				case j >= length:
+					j = length - 1
+				}
+				p := src[line+j*step]
+				c.R += w * p.R
+				c.G += w * p.G
+				c.B += w * p.B
+				c.A += w * p.A
+			}
+			dst[line+i*step] = c
+		}
+	}
+}
+
+// Mask replaces the backdrop pixels of dst with the blurred pixels of
+// blurred where the pixels of mask cover them. The pixels are
+// interpolated in the linear color space by the alpha of the mask.
+// The slices contain premultiplied sRGB encoded pixels in the format
+// of image.RGBA and must have equal lengths.
+func Mask(dst, blurred, mask []byte) {
+	for i := 0; i+3 < len(dst); i += 4 {
+		a := mask[i+3]
+		switch a {
+		case 0:
+			continue
+		case 0xff:
+			copy(dst[i:i+4], blurred[i:i+4])
+			continue
+		}
+		t := float32(a) / 0xff
+		d, b := dst[i:i+4], blurred[i:i+4]
+		c := f32color.RGBA{
+			R: linear[d[0]] + (linear[b[0]]-linear[d[0]])*t,
+			G: linear[d[1]] + (linear[b[1]]-linear[d[1]])*t,
+			B: linear[d[2]] + (linear[b[2]]-linear[d[2]])*t,
+			A: (float32(d[3]) + (float32(b[3])-float32(d[3]))*t) / 0xff,
+		}.SRGBPremul()
+		d[0], d[1], d[2], d[3] = c.R, c.G, c.B, c.A
+	}
+}
@@ -0,0 +1,50 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package blur
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGaussian(t *testing.T) {
+	white := color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	img := image.NewRGBA(image.Rect(10, 10, 50, 20))
+	for y := 10; y < 20; y++ {
+		for x := 10; x < 30; x++ {
+			img.SetRGBA(x, y, white)
+		}
+	}
+	Gaussian(img, 3)
+	// The edge is half covered, and the pixels far from the edge are
+	// unchanged.
+	if got := img.RGBAAt(10, 15); got != white {
+		t.Errorf("got %v far inside the edge, expected %v", got, white)
+	}
+	if got := img.RGBAAt(49, 15); got != (color.RGBA{}) {
+		t.Errorf("got %v far outside the edge, expected transparent", got)
+	}
+	// The blurred pixels at the edge are half transparent.
+	left, right := img.RGBAAt(29, 15), img.RGBAAt(30, 15)
+	if a := (int(left.A) + int(right.A)) / 2; a < 0x7c || a > 0x83 {
+		t.Errorf("got alpha %#x at the edge, expected about %#x", a, 0x7f)
+	}
+	// Rows are blurred alike.
+	if got, exp := img.RGBAAt(29, 10), left; got != exp {
+		t.Errorf("got %v at the top row, expected %v", got, exp)
+	}
+}
+
+func TestMask(t *testing.T) {
+	dst := []byte{0xff, 0, 0, 0xff, 0xff, 0, 0, 0xff, 0xff, 0, 0, 0xff}
+	blurred := []byte{0, 0, 0xff, 0xff, 0, 0, 0xff, 0xff, 0, 0, 0xff, 0xff}
+	mask := []byte{0, 0, 0, 0, 0, 0, 0, 0xff, 0, 0, 0, 0x80}
+	Mask(dst, blurred, mask)
+	exp := []byte{0xff, 0, 0, 0xff, 0, 0, 0xff, 0xff, 188, 0, 188, 0xff}
+	for i := range exp {
+		if d := int(dst[i]) - int(exp[i]); d < -1 || d > 1 {
+			t.Fatalf("got %v, expected %v", dst, exp)
+		}
+	}
+}
@@ -8,6 +8,7 @@ package egl
 import (
 	"errors"
 	"fmt"
+	"image"
 	"runtime"
 	"strings"
 
@@ -19,6 +20,9 @@ type Context struct {
 	eglCtx        *eglContext
 	eglSurf       _EGLSurface
 	width, height int
+	// rects is the damage region in the form expected by
+	// eglSwapBuffersWithDamage.
+	rects []_EGLint
 }
 
 type eglContext struct {
@@ -27,6 +31,12 @@ type eglContext struct {
 	visualID    int
 	srgb        bool
 	surfaceless bool
+	// swapDamage reports whether eglSwapBuffersWithDamage is
+	// supported.
+	swapDamage bool
+	// bufferAge reports whether the age of the back buffer can be
+	// queried.
+	bufferAge bool
 }
 
 var (
@@ -40,6 +50,7 @@ var (
 const (
 	_EGL_ALPHA_SIZE             = 0x3021
 	_EGL_BLUE_SIZE              = 0x3022
+	_EGL_BUFFER_AGE_EXT         = 0x313d
 	_EGL_CONFIG_CAVEAT          = 0x3027
 	_EGL_CONTEXT_CLIENT_VERSION = 0x3098
 	_EGL_DEPTH_SIZE             = 0x3025
@@ -72,6 +83,38 @@ func (c *Context) Present() error {
 	return nil
 }
 
+// PresentDamage is like Present, but hints that only the rectangles
+// of damage differ from the previous frame. The rectangles are in
+// pixels with the origin in the upper left corner of the surface.
+func (c *Context) PresentDamage(damage []image.Rectangle) error {
+	if !c.eglCtx.swapDamage || len(damage) == 0 {
+		return c.Present()
+	}
+	c.rects = c.rects[:0]
+	for _, r := range damage {
+		// EGL has the origin in the lower left corner.
+		c.rects = append(c.rects, _EGLint(r.Min.X), _EGLint(c.height-r.Max.Y), _EGLint(r.Dx()), _EGLint(r.Dy()))
+	}
+	if !eglSwapBuffersWithDamage(c.disp, c.eglSurf, c.rects) {
+		return fmt.Errorf("eglSwapBuffersWithDamage failed (%x)", eglGetError())
+	}
+	return nil
+}
+
+// BufferAge returns the age of the contents of the back buffer in
+// frames, or 0 if they are undefined or the age is unknown. It must
+// be called while the context is current.
+func (c *Context) BufferAge() int {
+	if !c.eglCtx.bufferAge || c.eglSurf == nilEGLSurface {
+		return 0
+	}
+	age, ok := eglQuerySurface(c.disp, c.eglSurf, _EGL_BUFFER_AGE_EXT)
+	if !ok {
+		return 0
+	}
+	return int(age)
+}
+
 func NewContext(disp NativeDisplayType) (*Context, error) {
 	if err := loadEGL(); err != nil {
 		return nil, err
@@ -224,12 +267,21 @@ func createContext(disp _EGLDisplay) (*eglContext, error) {
 			return nil, fmt.Errorf("eglCreateContext failed: 0x%x", eglGetError())
 		}
 	}
+	swapDamage := false
+	for _, ext := range []string{"KHR", "EXT"} {
+		if hasExtension(exts, "EGL_"+ext+"_swap_buffers_with_damage") && loadSwapBuffersWithDamage("eglSwapBuffersWithDamage"+ext) {
+			swapDamage = true
+			break
+		}
+	}
 	return &eglContext{
 		config:      _EGLConfig(eglCfg),
 		ctx:         _EGLContext(eglCtx),
 		visualID:    int(visID),
 		srgb:        srgb,
 		surfaceless: hasExtension(exts, "EGL_KHR_surfaceless_context"),
+		swapDamage:  swapDamage,
+		bufferAge:   hasExtension(exts, "EGL_EXT_buffer_age"),
 	}, nil
 }
 
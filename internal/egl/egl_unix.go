@@ -14,11 +14,20 @@ package egl
 #cgo openbsd LDFLAGS: -L/usr/X11R6/lib
 #cgo CFLAGS: -DEGL_NO_X11
 
+#include <stdlib.h>
 #include <EGL/egl.h>
 #include <EGL/eglext.h>
+
+typedef EGLBoolean (*_eglSwapBuffersWithDamageProc)(EGLDisplay, EGLSurface, const EGLint *, EGLint);
+
+static EGLBoolean _eglSwapBuffersWithDamage(void *f, EGLDisplay disp, EGLSurface surf, const EGLint *rects, EGLint n) {
+	return ((_eglSwapBuffersWithDamageProc)f)(disp, surf, rects, n);
+}
 */
 import "C"
 
+import "unsafe"
+
 type (
 	_EGLint           = C.EGLint
 	_EGLDisplay       = C.EGLDisplay
@@ -61,6 +70,12 @@ func eglGetConfigAttrib(disp _EGLDisplay, cfg _EGLConfig, attr _EGLint) (_EGLint
 	return val, ret == C.EGL_TRUE
 }
 
+func eglQuerySurface(disp _EGLDisplay, surf _EGLSurface, attr _EGLint) (_EGLint, bool) {
+	var val _EGLint
+	ret := C.eglQuerySurface(disp, surf, attr, &val)
+	return val, ret == C.EGL_TRUE
+}
+
 func eglGetError() _EGLint {
 	return C.eglGetError()
 }
@@ -83,6 +98,27 @@ func eglSwapBuffers(disp _EGLDisplay, surf _EGLSurface) bool {
 	return C.eglSwapBuffers(disp, surf) == C.EGL_TRUE
 }
 
+// swapBuffersWithDamage is the swap with damage function of an
+// extension.
+var swapBuffersWithDamage unsafe.Pointer
+
+// loadSwapBuffersWithDamage loads the swap with damage function of
+// the given name from an extension.
+func loadSwapBuffersWithDamage(name string) bool {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	f := unsafe.Pointer(C.eglGetProcAddress(cname))
+	if f == nil {
+		return false
+	}
+	swapBuffersWithDamage = f
+	return true
+}
+
+func eglSwapBuffersWithDamage(disp _EGLDisplay, surf _EGLSurface, rects []_EGLint) bool {
+	return C._eglSwapBuffersWithDamage(swapBuffersWithDamage, disp, surf, &rects[0], _EGLint(len(rects)/4)) == C.EGL_TRUE
+}
+
 func eglSwapInterval(disp _EGLDisplay, interval _EGLint) bool {
 	return C.eglSwapInterval(disp, interval) == C.EGL_TRUE
 }
@@ -40,7 +40,10 @@ var (
 	_eglSwapBuffers         = libEGL.NewProc("eglSwapBuffers")
 	_eglTerminate           = libEGL.NewProc("eglTerminate")
 	_eglQueryString         = libEGL.NewProc("eglQueryString")
+	_eglQuerySurface        = libEGL.NewProc("eglQuerySurface")
 	_eglWaitClient          = libEGL.NewProc("eglWaitClient")
+
+	_eglSwapBuffersWithDamage *syscall.LazyProc
 )
 
 var loadOnce sync.Once
@@ -111,6 +114,12 @@ func eglGetConfigAttrib(disp _EGLDisplay, cfg _EGLConfig, attr _EGLint) (_EGLint
 	return _EGLint(val), r != 0
 }
 
+func eglQuerySurface(disp _EGLDisplay, surf _EGLSurface, attr _EGLint) (_EGLint, bool) {
+	var val uintptr
+	r, _, _ := _eglQuerySurface.Call(uintptr(disp), uintptr(surf), uintptr(attr), uintptr(unsafe.Pointer(&val)))
+	return _EGLint(val), r != 0
+}
+
 func eglGetDisplay(disp NativeDisplayType) _EGLDisplay {
 	d, _, _ := _eglGetDisplay.Call(uintptr(disp))
 	return _EGLDisplay(d)
@@ -147,6 +156,24 @@ func eglSwapBuffers(disp _EGLDisplay, surf _EGLSurface) bool {
 	return r != 0
 }
 
+// loadSwapBuffersWithDamage loads the swap with damage function of
+// the given name from an extension.
+func loadSwapBuffersWithDamage(name string) bool {
+	p := libEGL.NewProc(name)
+	if p.Find() != nil {
+		return false
+	}
+	_eglSwapBuffersWithDamage = p
+	return true
+}
+
+func eglSwapBuffersWithDamage(disp _EGLDisplay, surf _EGLSurface, rects []_EGLint) bool {
+	a := &rects[0]
+	r, _, _ := _eglSwapBuffersWithDamage.Call(uintptr(disp), uintptr(surf), uintptr(unsafe.Pointer(a)), uintptr(len(rects)/4))
+	issue34474KeepAlive(a)
+	return r != 0
+}
+
 func eglTerminate(disp _EGLDisplay) bool {
 	r, _, _ := _eglTerminate.Call(uintptr(disp))
 	return r != 0
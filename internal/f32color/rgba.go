@@ -37,6 +37,20 @@ func (col RGBA) SRGB() color.NRGBA {
 	}
 }
 
+// SRGBPremul converts from linear to premultiplied sRGB color space,
+// the format of image.RGBA pixels.
+//
+// Each component in the result is `sRGB(c)`, where `c` is the
+// premultiplied linear component.
+func (col RGBA) SRGBPremul() color.RGBA {
+	return color.RGBA{
+		R: uint8(linearTosRGB(col.R)*255 + .5),
+		G: uint8(linearTosRGB(col.G)*255 + .5),
+		B: uint8(linearTosRGB(col.B)*255 + .5),
+		A: uint8(col.A*255 + .5),
+	}
+}
+
 // Luminance calculates the relative luminance of a linear RGBA color.
 // Normalized to 0 for black and 1 for white.
 //
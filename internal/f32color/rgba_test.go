@@ -37,6 +37,18 @@ func TestLinearToRGBARoundtrip(t *testing.T) {
 	}
 }
 
+func TestSRGBPremul(t *testing.T) {
+	for col := 0; col <= 0xFF; col++ {
+		for alpha := 0; alpha <= 0xFF; alpha++ {
+			in := color.NRGBA{R: uint8(col), G: uint8(col), B: uint8(col), A: uint8(alpha)}
+			want := NRGBAToRGBA(in)
+			if got := LinearFromSRGB(in).SRGBPremul(); got != want {
+				t.Errorf("%v: got %v expected %v", in, got, want)
+			}
+		}
+	}
+}
+
 var sink RGBA
 
 func BenchmarkLinearFromSRGB(b *testing.B) {
@@ -61,6 +61,7 @@ const (
 	LUMINANCE                             = 0x1909
 	MAP_READ_BIT                          = 0x0001
 	MAX_TEXTURE_SIZE                      = 0xd33
+	MIRRORED_REPEAT                       = 0x8370
 	NEAREST                               = 0x2600
 	NO_ERROR                              = 0x0
 	NUM_EXTENSIONS                        = 0x821D
@@ -78,6 +79,7 @@ const (
 	READ_WRITE                            = 0x88BA
 	RED                                   = 0x1903
 	RENDERER                              = 0x1F01
+	REPEAT                                = 0x2901
 	RENDERBUFFER                          = 0x8d41
 	RENDERBUFFER_BINDING                  = 0x8ca7
 	RENDERBUFFER_HEIGHT                   = 0x8d43
@@ -85,6 +87,7 @@ const (
 	RGB                                   = 0x1907
 	RGBA                                  = 0x1908
 	RGBA8                                 = 0x8058
+	SCISSOR_TEST                          = 0x0C11
 	SHADER_STORAGE_BUFFER                 = 0x90D2
 	SHADER_STORAGE_BUFFER_BINDING         = 0x90D3
 	SHORT                                 = 0x1402
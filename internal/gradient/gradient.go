@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package gradient evaluates gradient brushes. It is used by the
+renderers to rasterize gradients that have no dedicated shader
+program.
+*/
+package gradient
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/internal/f32"
+	"gioui.org/internal/f32color"
+)
+
+// Kind is the geometry of a gradient.
+type Kind uint8
+
+// Spread determines the color of offsets outside the [0, 1] range.
+type Spread uint8
+
+// Stop is a color stop of a gradient. Offset is in the range [0, 1]
+// and Color is in the linear, premultiplied color space.
+type Stop struct {
+	Offset float32
+	Color  f32color.RGBA
+}
+
+// Gradient describes a gradient in its local coordinate space. The
+// meaning of P1 and P2 depends on Kind:
+//
+// For Linear gradients, offset 0 is at P1 and offset 1 at P2.
+//
+// For Radial gradients, P1 is the center and the distance from P1
+// to P2 is the radius.
+//
+// For Conic gradients, P1 is the center and the direction from P1 to
+// P2 is the angle of offset 0. Offsets increase clockwise.
+//
+// For Shadow gradients, P1 and P2 are the corners of a rectangle with
+// rounded corners of radius Radius, and the offset is the coverage of
+// the rectangle blurred by a Gaussian with standard deviation Sigma.
+//
+// Stops must be sorted by their offsets.
+type Gradient struct {
+	Kind   Kind
+	Spread Spread
+	P1, P2 f32.Point
+	Stops  []Stop
+	Radius float32
+	Sigma  float32
+}
+
+// Ramp is a table of colors sampled at evenly spaced offsets
+// along a gradient.
+type Ramp [rampSize]color.RGBA
+
+const (
+	Linear Kind = iota
+	Radial
+	Conic
+	Shadow
+)
+
+const (
+	Pad Spread = iota
+	Repeat
+	Reflect
+)
+
+// rampSize is the number of entries in a Ramp. It is larger than the
+// 256 values of a color channel to avoid banding of gradients with
+// many stops.
+const rampSize = 1024
+
+// Offset returns the gradient offset at p. The result is not
+// clamped to [0, 1].
+func (g *Gradient) Offset(p f32.Point) float32 {
+	d := g.P2.Sub(g.P1)
+	v := p.Sub(g.P1)
+	switch g.Kind {
+	case Linear:
+		l := d.X*d.X + d.Y*d.Y
+		if l == 0 {
+			return 0
+		}
+		return (v.X*d.X + v.Y*d.Y) / l
+	case Radial:
+		r := length(d)
+		if r == 0 {
+			return 1
+		}
+		return length(v) / r
+	case Conic:
+		a := math.Atan2(float64(v.Y), float64(v.X)) - math.Atan2(float64(d.Y), float64(d.X))
+		a /= 2 * math.Pi
+		return float32(a - math.Floor(a))
+	case Shadow:
+		return g.shadow(p)
+	default:
+		panic("invalid gradient kind")
+	}
+}
+
+// shadow returns the coverage of the blurred rounded rectangle of a
+// Shadow gradient at p. The Gaussian is separable, so the coverage of
+// each row of the rectangle is computed exactly, and the rows are
+// integrated numerically.
+func (g *Gradient) shadow(p f32.Point) float32 {
+	rect := f32.Rectangle{Min: g.P1, Max: g.P2}.Canon()
+	minX, minY := float64(rect.Min.X), float64(rect.Min.Y)
+	maxX, maxY := float64(rect.Max.X), float64(rect.Max.Y)
+	r := math.Min(float64(g.Radius), math.Min(maxX-minX, maxY-minY)/2)
+	r = math.Max(r, 0)
+	// inset returns the horizontal extent of the rectangle at y.
+	inset := func(y float64) (float64, float64) {
+		x0, x1 := minX, maxX
+		d := 0.0
+		switch {
+		case y < minY+r:
+			d = minY + r - y
+		case y > maxY-r:
+			d = y - (maxY - r)
+		}
+		if d > 0 {
+			in := r - math.Sqrt(math.Max(0, r*r-d*d))
+			x0, x1 = x0+in, x1-in
+		}
+		return x0, x1
+	}
+	px, py := float64(p.X), float64(p.Y)
+	s := float64(g.Sigma)
+	if s <= 0 {
+		if py < minY || py >= maxY {
+			return 0
+		}
+		if x0, x1 := inset(py); px < x0 || px >= x1 {
+			return 0
+		}
+		return 1
+	}
+	// Rows farther than 3 standard deviations contribute little and
+	// are skipped.
+	lo := math.Max(minY, py-3*s)
+	hi := math.Min(maxY, py+3*s)
+	if lo >= hi {
+		return 0
+	}
+	const n = 16
+	step := (hi - lo) / n
+	k := 1 / (s * math.Sqrt2)
+	var sum float64
+	for i := 0; i < n; i++ {
+		y := lo + (float64(i)+.5)*step
+		x0, x1 := inset(y)
+		dy := (y - py) / s
+		w := math.Exp(-dy*dy/2) / (s * math.Sqrt(2*math.Pi))
+		sum += w * step * (math.Erf((x1-px)*k) - math.Erf((x0-px)*k)) / 2
+	}
+	// Normalize for the truncated rows.
+	return float32(sum / math.Erf(3/math.Sqrt2))
+}
+
+// Ramp samples the colors of the gradient stops.
+func (g *Gradient) Ramp(r *Ramp) {
+	stops := g.Stops
+	if len(stops) == 0 {
+		*r = Ramp{}
+		return
+	}
+	j := 0
+	for i := range r {
+		t := float32(i) / (rampSize - 1)
+		for j < len(stops) && stops[j].Offset <= t {
+			j++
+		}
+		var c f32color.RGBA
+		switch {
+		case j == 0:
+			c = stops[0].Color
+		case j == len(stops):
+			c = stops[len(stops)-1].Color
+		default:
+			s1, s2 := stops[j-1], stops[j]
+			f := (t - s1.Offset) / (s2.Offset - s1.Offset)
+			c = lerp(s1.Color, s2.Color, f)
+		}
+		r[i] = c.SRGBPremul()
+	}
+}
+
+// Extend maps the offset t to the [0, 1] range according to s.
+func (s Spread) Extend(t float32) float32 {
+	switch s {
+	case Repeat:
+		return t - float32(math.Floor(float64(t)))
+	case Reflect:
+		t = float32(math.Abs(float64(t)))
+		t -= 2 * float32(math.Floor(float64(t/2)))
+		if t > 1 {
+			t = 2 - t
+		}
+		return t
+	default:
+		return t
+	}
+}
+
+// At returns the ramp color at offset t, clamped to [0, 1].
+func (r *Ramp) At(t float32) color.RGBA {
+	switch {
+	case t <= 0 || t != t:
+		return r[0]
+	case t >= 1:
+		return r[rampSize-1]
+	}
+	return r[int(t*(rampSize-1)+.5)]
+}
+
+// Rasterize fills dst with the gradient. The transformation t maps the
+// centers of dst pixels to the local coordinate space of g.
+func (g *Gradient) Rasterize(dst *image.RGBA, t f32.Affine2D) {
+	var ramp Ramp
+	g.Ramp(&ramp)
+	b := dst.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := dst.Pix[dst.PixOffset(b.Min.X, y):]
+		for x := b.Min.X; x < b.Max.X; x++ {
+			p := t.Transform(f32.Pt(float32(x)+.5, float32(y)+.5))
+			c := ramp.At(g.Spread.Extend(g.Offset(p)))
+			row[0], row[1], row[2], row[3] = c.R, c.G, c.B, c.A
+			row = row[4:]
+		}
+	}
+}
+
+func lerp(a, b f32color.RGBA, t float32) f32color.RGBA {
+	return f32color.RGBA{
+		R: a.R + (b.R-a.R)*t,
+		G: a.G + (b.G-a.G)*t,
+		B: a.B + (b.B-a.B)*t,
+		A: a.A + (b.A-a.A)*t,
+	}
+}
+
+func length(p f32.Point) float32 {
+	return float32(math.Hypot(float64(p.X), float64(p.Y)))
+}
@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gradient
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"gioui.org/internal/f32"
+	"gioui.org/internal/f32color"
+)
+
+func TestOffset(t *testing.T) {
+	tests := []struct {
+		kind Kind
+		p    f32.Point
+		want float32
+	}{
+		{Linear, f32.Pt(10, 10), 0},
+		{Linear, f32.Pt(15, 30), .5},
+		{Linear, f32.Pt(20, -5), 1},
+		{Radial, f32.Pt(10, 10), 0},
+		{Radial, f32.Pt(10, 15), .5},
+		{Radial, f32.Pt(10, 30), 2},
+		{Conic, f32.Pt(20, 10), 0},
+		{Conic, f32.Pt(10, 20), .25},
+		{Conic, f32.Pt(0, 10), .5},
+		{Conic, f32.Pt(10, 0), .75},
+	}
+	for _, test := range tests {
+		g := Gradient{Kind: test.kind, P1: f32.Pt(10, 10), P2: f32.Pt(20, 10)}
+		if got := g.Offset(test.p); abs(got-test.want) > 1e-5 {
+			t.Errorf("kind %d: offset at %v is %v, expected %v", test.kind, test.p, got, test.want)
+		}
+	}
+}
+
+func TestShadow(t *testing.T) {
+	g := Gradient{Kind: Shadow, P1: f32.Pt(0, 0), P2: f32.Pt(100, 50), Radius: 10, Sigma: 4}
+	tests := []struct {
+		p    f32.Point
+		want float32
+	}{
+		// Inside, far from the edges.
+		{f32.Pt(50, 25), 1},
+		// Half covered at an edge.
+		{f32.Pt(0, 25), .5},
+		{f32.Pt(50, 50), .5},
+		// Outside, beyond the blur.
+		{f32.Pt(-20, 25), 0},
+	}
+	for _, test := range tests {
+		if got := g.Offset(test.p); abs(got-test.want) > 1e-3 {
+			t.Errorf("shadow offset at %v is %v, expected %v", test.p, got, test.want)
+		}
+	}
+	// The rounded corner covers less than the sharp corner.
+	sharp := g
+	sharp.Radius = 0
+	if c, s := g.Offset(f32.Pt(0, 0)), sharp.Offset(f32.Pt(0, 0)); c >= s || abs(s-.25) > 1e-3 {
+		t.Errorf("corner coverage is %v, sharp corner coverage is %v", c, s)
+	}
+	// Without blur, the shadow is the rounded rectangle.
+	g.Sigma = 0
+	if got := g.Offset(f32.Pt(1, 1)); got != 0 {
+		t.Errorf("unblurred shadow covers the rounded corner")
+	}
+	if got := g.Offset(f32.Pt(10, 1)); got != 1 {
+		t.Errorf("unblurred shadow doesn't cover the inside")
+	}
+}
+
+func TestRasterize(t *testing.T) {
+	g := Gradient{
+		Kind: Linear,
+		P1:   f32.Pt(0, 0),
+		P2:   f32.Pt(4, 0),
+		Stops: []Stop{
+			{Offset: 0, Color: f32color.LinearFromSRGB(color.NRGBA{R: 0xff, A: 0xff})},
+			{Offset: 1, Color: f32color.LinearFromSRGB(color.NRGBA{B: 0xff, A: 0xff})},
+		},
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 8, 1))
+	// Sample pixel centers.
+	g.Rasterize(img, f32.Affine2D{}.Offset(f32.Pt(-.5, -.5)))
+	if got, want := img.RGBAAt(0, 0), (color.RGBA{R: 0xff, A: 0xff}); got != want {
+		t.Errorf("got %v at first stop, expected %v", got, want)
+	}
+	for x := 4; x < 8; x++ {
+		if got, want := img.RGBAAt(x, 0), (color.RGBA{B: 0xff, A: 0xff}); got != want {
+			t.Errorf("got %v at %d past the last stop, expected %v", got, x, want)
+		}
+	}
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		spread Spread
+		t      float32
+		want   float32
+	}{
+		{Pad, -.5, -.5},
+		{Pad, 1.5, 1.5},
+		{Repeat, 1.25, .25},
+		{Repeat, -.25, .75},
+		{Reflect, 1.25, .75},
+		{Reflect, 2.25, .25},
+		{Reflect, -.25, .25},
+	}
+	for _, test := range tests {
+		if got := test.spread.Extend(test.t); abs(got-test.want) > 1e-5 {
+			t.Errorf("spread %d: offset %v extended to %v, expected %v", test.spread, test.t, got, test.want)
+		}
+	}
+}
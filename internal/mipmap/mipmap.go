@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package mipmap computes mipmaps, the successively halved copies of an
+image that renderers sample from to draw the image smaller than its
+size without aliasing. It is used by the renderers that can't rely on
+mipmaps generated by the GPU.
+*/
+package mipmap
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"gioui.org/internal/f32"
+	"gioui.org/internal/f32color"
+)
+
+// Cache holds the mipmap levels of images, keyed by their handles.
+type Cache struct {
+	images map[interface{}]*cacheValue
+}
+
+type cacheValue struct {
+	// levels are the levels generated so far, starting with the
+	// image itself.
+	levels []*image.RGBA
+	used   bool
+}
+
+// linear maps sRGB encoded components to linear components.
+var linear [256]float32
+
+func init() {
+	for i := range linear {
+		linear[i] = f32color.LinearFromSRGB(color.NRGBA{R: uint8(i), A: 0xff}).R
+	}
+}
+
+// Level returns the mipmap level for sampling an image drawn with the
+// transformation t: the largest level whose pixels are no larger than
+// the pixels they are drawn to.
+func Level(t f32.Affine2D) int {
+	sx, hx, _, hy, sy, _ := t.Elems()
+	// The scale of the image is the length of the shortest of the
+	// transformed unit vectors.
+	scale := math.Min(math.Hypot(float64(sx), float64(hy)), math.Hypot(float64(hx), float64(sy)))
+	if scale >= 1 || scale <= 0 {
+		return 0
+	}
+	// Allow for rounding errors in the transformation.
+	const eps = 1e-3
+	return int(math.Log2(1/scale) + eps)
+}
+
+// Get returns the mipmap level of the image src identified by handle,
+// generating the levels up to it if necessary. Levels beyond the
+// smallest, single pixel level are clamped to the smallest level.
+// Level 0 is src itself.
+func (c *Cache) Get(handle interface{}, src *image.RGBA, level int) *image.RGBA {
+	v, ok := c.images[handle]
+	if !ok || v.levels[0] != src {
+		v = &cacheValue{levels: []*image.RGBA{src}}
+		if c.images == nil {
+			c.images = make(map[interface{}]*cacheValue)
+		}
+		c.images[handle] = v
+	}
+	v.used = true
+	for len(v.levels) <= level {
+		last := v.levels[len(v.levels)-1]
+		if sz := last.Rect.Size(); sz.X <= 1 && sz.Y <= 1 {
+			break
+		}
+		v.levels = append(v.levels, Downscale(last))
+	}
+	if level >= len(v.levels) {
+		level = len(v.levels) - 1
+	}
+	return v.levels[level]
+}
+
+// Frame discards the levels of images not used since the previous
+// call to Frame.
+func (c *Cache) Frame() {
+	for k, v := range c.images {
+		if !v.used {
+			delete(c.images, k)
+			continue
+		}
+		v.used = false
+	}
+}
+
+// Downscale returns the mipmap level following img. Its size is half
+// the size of img, rounded up, and each of its pixels is the average
+// of the up to four pixels of img it covers. Like GPUs, Downscale
+// averages in the linear color space.
+func Downscale(img *image.RGBA) *image.RGBA {
+	sz := img.Rect.Size()
+	dsz := image.Pt((sz.X+1)/2, (sz.Y+1)/2)
+	dst := image.NewRGBA(image.Rectangle{Max: dsz})
+	for y := 0; y < dsz.Y; y++ {
+		for x := 0; x < dsz.X; x++ {
+			var sum f32color.RGBA
+			n := 0
+			for sy := 2 * y; sy < 2*y+2 && sy < sz.Y; sy++ {
+				for sx := 2 * x; sx < 2*x+2 && sx < sz.X; sx++ {
+					p := img.Pix[img.PixOffset(img.Rect.Min.X+sx, img.Rect.Min.Y+sy):]
+					sum.R += linear[p[0]]
+					sum.G += linear[p[1]]
+					sum.B += linear[p[2]]
+					sum.A += float32(p[3]) / 0xff
+					n++
+				}
+			}
+			w := 1 / float32(n)
+			sum.R *= w
+			sum.G *= w
+			sum.B *= w
+			sum.A *= w
+			c := sum.SRGBPremul()
+			d := dst.Pix[dst.PixOffset(x, y):]
+			d[0], d[1], d[2], d[3] = c.R, c.G, c.B, c.A
+		}
+	}
+	return dst
+}
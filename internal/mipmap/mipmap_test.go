@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package mipmap
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"gioui.org/internal/f32"
+)
+
+func TestLevel(t *testing.T) {
+	tests := []struct {
+		t     f32.Affine2D
+		level int
+	}{
+		{f32.Affine2D{}, 0},
+		{f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(4, 4)), 0},
+		{f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(.5, .5)), 1},
+		{f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(.3, .3)), 1},
+		{f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(1, .25)), 2},
+		{f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(.25, .25)).Rotate(f32.Point{}, math.Pi/3), 2},
+		{f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(0, 0)), 0},
+	}
+	for _, test := range tests {
+		if got := Level(test.t); got != test.level {
+			t.Errorf("Level(%v) = %d, expected %d", test.t, got, test.level)
+		}
+	}
+}
+
+func TestDownscale(t *testing.T) {
+	img := image.NewRGBA(image.Rect(10, 10, 13, 11))
+	img.SetRGBA(10, 10, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff})
+	img.SetRGBA(11, 10, color.RGBA{A: 0xff})
+	img.SetRGBA(12, 10, color.RGBA{R: 0xff, A: 0xff})
+	d := Downscale(img)
+	if got, exp := d.Rect, image.Rect(0, 0, 2, 1); got != exp {
+		t.Fatalf("got bounds %v, expected %v", got, exp)
+	}
+	// Black and white average to the sRGB encoding of linear 0.5.
+	if got, exp := d.RGBAAt(0, 0), (color.RGBA{R: 188, G: 188, B: 188, A: 0xff}); got != exp {
+		t.Errorf("got color %v, expected %v", got, exp)
+	}
+	// Odd columns are averaged with themselves.
+	if got, exp := d.RGBAAt(1, 0), (color.RGBA{R: 0xff, A: 0xff}); got != exp {
+		t.Errorf("got color %v, expected %v", got, exp)
+	}
+}
+
+func TestCache(t *testing.T) {
+	var c Cache
+	src := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	handle := new(int)
+	l2 := c.Get(handle, src, 2)
+	if got, exp := l2.Rect.Size(), image.Pt(2, 1); got != exp {
+		t.Errorf("got level 2 size %v, expected %v", got, exp)
+	}
+	if c.Get(handle, src, 0) != src {
+		t.Error("level 0 is not the source image")
+	}
+	if c.Get(handle, src, 2) != l2 {
+		t.Error("level 2 was not cached")
+	}
+	if got, exp := c.Get(handle, src, 10).Rect.Size(), image.Pt(1, 1); got != exp {
+		t.Errorf("got clamped level size %v, expected %v", got, exp)
+	}
+	c.Frame()
+	c.Frame()
+	if c.Get(handle, src, 2) == l2 {
+		t.Error("unused levels were not discarded")
+	}
+}
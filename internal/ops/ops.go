@@ -8,6 +8,7 @@ import (
 	"math"
 
 	"gioui.org/f32"
+	"gioui.org/internal/blend"
 	"gioui.org/internal/byteslice"
 	"gioui.org/internal/scene"
 )
@@ -48,6 +49,9 @@ const (
 	TypePaint
 	TypeColor
 	TypeLinearGradient
+	TypeRadialGradient
+	TypeConicGradient
+	TypeGradient
 	TypePass
 	TypePopPass
 	TypePointerInput
@@ -76,6 +80,15 @@ const (
 	TypeSnippet
 	TypeSelection
 	TypeActionInput
+	TypeOpacity
+	TypePopOpacity
+	TypeBlend
+	TypePopBlend
+	TypeShadow
+	TypeBlur
+	TypeCache
+	TypePopCache
+	TypeShader
 )
 
 type StackID struct {
@@ -111,6 +124,8 @@ const (
 	ClipStack StackKind = iota
 	TransStack
 	PassStack
+	OpacityStack
+	BlendStack
 	_StackKind
 )
 
@@ -128,10 +143,13 @@ const (
 	TypeTransformLen        = 1 + 1 + 4*6
 	TypePopTransformLen     = 1
 	TypeRedrawLen           = 1 + 8
-	TypeImageLen            = 1
+	TypeImageLen            = 1 + 1 + 4 + 4
 	TypePaintLen            = 1
 	TypeColorLen            = 1 + 4
 	TypeLinearGradientLen   = 1 + 8*2 + 4*2
+	TypeRadialGradientLen   = 1 + 8 + 4 + 4*2
+	TypeConicGradientLen    = 1 + 8 + 4 + 4*2
+	TypeGradientLen         = 1 + 1 + 1 + 8*2
 	TypePassLen             = 1
 	TypePopPassLen          = 1
 	TypePointerInputLen     = 1 + 1 + 1*2 + 2*4 + 2*4
@@ -151,7 +169,7 @@ const (
 	TypeProfileLen          = 1
 	TypeCursorLen           = 2
 	TypePathLen             = 8 + 1
-	TypeStrokeLen           = 1 + 4
+	TypeStrokeLen           = 1 + 4 + 4 + 1 + 1 + 4
 	TypeSemanticLabelLen    = 1
 	TypeSemanticDescLen     = 1
 	TypeSemanticClassLen    = 2
@@ -160,6 +178,15 @@ const (
 	TypeSnippetLen          = 1 + 4 + 4
 	TypeSelectionLen        = 1 + 2*4 + 2*4 + 4 + 4
 	TypeActionInputLen      = 1 + 1
+	TypeOpacityLen          = 1 + 4
+	TypePopOpacityLen       = 1
+	TypeBlendLen            = 1 + 1
+	TypePopBlendLen         = 1
+	TypeShadowLen           = 1 + 4*4 + 4 + 4 + 4
+	TypeBlurLen             = 1 + 4
+	TypeCacheLen            = 1
+	TypePopCacheLen         = 1
+	TypeShaderLen           = 1
 )
 
 func (op *ClipOp) Decode(data []byte) {
@@ -189,6 +216,19 @@ func Reset(o *Ops) {
 	o.version++
 }
 
+// Contents returns the encoded operations and their references.
+func Contents(o *Ops) (data []byte, refs []interface{}) {
+	return o.data, o.refs
+}
+
+// SetContents replaces the operations of o with data and refs, as
+// returned by Contents.
+func SetContents(o *Ops, data []byte, refs []interface{}) {
+	Reset(o)
+	o.data = append(o.data, data...)
+	o.refs = append(o.refs, refs...)
+}
+
 func Write(o *Ops, n int) []byte {
 	if o.multipOp {
 		panic("cannot mix multi ops with single ones")
@@ -354,6 +394,33 @@ func DecodeTransform(data []byte) (t f32.Affine2D, push bool) {
 	return f32.NewAffine2D(a, b, c, d, e, f), push
 }
 
+// DecodeOpacity decodes the opacity of an opacity op.
+func DecodeOpacity(data []byte) float32 {
+	if OpType(data[0]) != TypeOpacity {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	return math.Float32frombits(bo.Uint32(data[1:]))
+}
+
+// DecodeBlend decodes the mode of a blend op.
+func DecodeBlend(data []byte) blend.Mode {
+	if OpType(data[0]) != TypeBlend {
+		panic("invalid op")
+	}
+	return blend.Mode(data[1])
+}
+
+// DecodeBlur decodes the standard deviation of the Gaussian of a blur
+// op.
+func DecodeBlur(data []byte) float32 {
+	if OpType(data[0]) != TypeBlur {
+		panic("invalid op")
+	}
+	bo := binary.LittleEndian
+	return math.Float32frombits(bo.Uint32(data[1:]))
+}
+
 // DecodeSave decodes the state id of a save op.
 func DecodeSave(data []byte) int {
 	if OpType(data[0]) != TypeSave {
@@ -389,6 +456,9 @@ var opProps = [0x100]opProp{
 	TypePaint:            {Size: TypePaintLen, NumRefs: 0},
 	TypeColor:            {Size: TypeColorLen, NumRefs: 0},
 	TypeLinearGradient:   {Size: TypeLinearGradientLen, NumRefs: 0},
+	TypeRadialGradient:   {Size: TypeRadialGradientLen, NumRefs: 0},
+	TypeConicGradient:    {Size: TypeConicGradientLen, NumRefs: 0},
+	TypeGradient:         {Size: TypeGradientLen, NumRefs: 1},
 	TypePass:             {Size: TypePassLen, NumRefs: 0},
 	TypePopPass:          {Size: TypePopPassLen, NumRefs: 0},
 	TypePointerInput:     {Size: TypePointerInputLen, NumRefs: 1},
@@ -408,7 +478,7 @@ var opProps = [0x100]opProp{
 	TypeProfile:          {Size: TypeProfileLen, NumRefs: 1},
 	TypeCursor:           {Size: TypeCursorLen, NumRefs: 0},
 	TypePath:             {Size: TypePathLen, NumRefs: 0},
-	TypeStroke:           {Size: TypeStrokeLen, NumRefs: 0},
+	TypeStroke:           {Size: TypeStrokeLen, NumRefs: 1},
 	TypeSemanticLabel:    {Size: TypeSemanticLabelLen, NumRefs: 1},
 	TypeSemanticDesc:     {Size: TypeSemanticDescLen, NumRefs: 1},
 	TypeSemanticClass:    {Size: TypeSemanticClassLen, NumRefs: 0},
@@ -417,6 +487,15 @@ var opProps = [0x100]opProp{
 	TypeSnippet:          {Size: TypeSnippetLen, NumRefs: 2},
 	TypeSelection:        {Size: TypeSelectionLen, NumRefs: 1},
 	TypeActionInput:      {Size: TypeActionInputLen, NumRefs: 0},
+	TypeOpacity:          {Size: TypeOpacityLen, NumRefs: 0},
+	TypePopOpacity:       {Size: TypePopOpacityLen, NumRefs: 0},
+	TypeBlend:            {Size: TypeBlendLen, NumRefs: 0},
+	TypePopBlend:         {Size: TypePopBlendLen, NumRefs: 0},
+	TypeShadow:           {Size: TypeShadowLen, NumRefs: 0},
+	TypeBlur:             {Size: TypeBlurLen, NumRefs: 0},
+	TypeCache:            {Size: TypeCacheLen, NumRefs: 1},
+	TypePopCache:         {Size: TypePopCacheLen, NumRefs: 0},
+	TypeShader:           {Size: TypeShaderLen, NumRefs: 2},
 }
 
 func (t OpType) props() (size, numRefs int) {
@@ -456,6 +535,12 @@ func (t OpType) String() string {
 		return "Color"
 	case TypeLinearGradient:
 		return "LinearGradient"
+	case TypeRadialGradient:
+		return "RadialGradient"
+	case TypeConicGradient:
+		return "ConicGradient"
+	case TypeGradient:
+		return "Gradient"
 	case TypePass:
 		return "Pass"
 	case TypePopPass:
@@ -498,6 +583,24 @@ func (t OpType) String() string {
 		return "Stroke"
 	case TypeSemanticLabel:
 		return "SemanticDescription"
+	case TypeOpacity:
+		return "Opacity"
+	case TypePopOpacity:
+		return "PopOpacity"
+	case TypeBlend:
+		return "Blend"
+	case TypePopBlend:
+		return "PopBlend"
+	case TypeShadow:
+		return "Shadow"
+	case TypeBlur:
+		return "Blur"
+	case TypeCache:
+		return "Cache"
+	case TypePopCache:
+		return "PopCache"
+	case TypeShader:
+		return "Shader"
 	default:
 		panic("unknown OpType")
 	}
@@ -40,6 +40,35 @@ import (
 // op/clip, eliminating the duplicate types.
 type StrokeStyle struct {
 	Width float32
+	// Miter is the miter limit of MiterJoin.
+	Miter float32
+	Cap   StrokeCap
+	Join  StrokeJoin
+}
+
+// StrokeCap describes the head or tail of a stroked path.
+type StrokeCap uint8
+
+// StrokeJoin describes how stroked paths are collated.
+type StrokeJoin uint8
+
+const (
+	RoundCap StrokeCap = iota
+	ButtCap
+	SquareCap
+)
+
+const (
+	RoundJoin StrokeJoin = iota
+	MiterJoin
+	BevelJoin
+)
+
+// DashOp describes a dash pattern of alternating dash and gap
+// lengths, starting Phase units into the pattern.
+type DashOp struct {
+	Phase  float32
+	Dashes []float32
 }
 
 // strokeTolerance is used to reconcile rounding errors arising
@@ -124,7 +153,162 @@ func (qs StrokeQuads) split() []StrokeQuads {
 	return o
 }
 
-func (qs StrokeQuads) stroke(stroke StrokeStyle) StrokeQuads {
+// IsSolidLine reports whether the dash pattern d describes a solid line,
+// that is, whether it is empty or invalid.
+func IsSolidLine(d DashOp) bool {
+	if len(d.Dashes) == 0 {
+		return true
+	}
+	var period float32
+	for _, l := range d.Dashes {
+		if l < 0 || l != l {
+			return true
+		}
+		period += l
+	}
+	return !(period > 0) || math.IsInf(float64(period), 1)
+}
+
+// dash splits the contours of qs into dashes according to d. Each dash
+// is a separate contour, except that the first and last dashes of a
+// closed contour are joined.
+func (qs StrokeQuads) dash(d DashOp) StrokeQuads {
+	pattern := d.Dashes
+	if len(pattern)%2 == 1 {
+		// An odd number of lengths is repeated to yield an even number.
+		pattern = append(pattern[:len(pattern):len(pattern)], pattern...)
+	}
+	var period float32
+	for _, l := range pattern {
+		period += l
+	}
+	start := float32(math.Mod(float64(d.Phase), float64(period)))
+	if start < 0 {
+		start += period
+	}
+	var (
+		o       StrokeQuads
+		contour uint32
+		dashes  []StrokeQuads
+	)
+	for _, ps := range qs.split() {
+		// Find the dash or gap at the start of the contour. Like SVG, the
+		// pattern restarts for every contour.
+		i := 0
+		phase := start
+		for phase >= pattern[i] {
+			phase -= pattern[i]
+			i = (i + 1) % len(pattern)
+		}
+		var (
+			rem    = pattern[i] - phase
+			on     = i%2 == 0
+			onBeg  = on
+			cur    StrokeQuads
+			closed = ps[0].Quad.From == ps[len(ps)-1].Quad.To
+		)
+		dashes = dashes[:0]
+		for _, q := range ps {
+			var lens dashLengths
+			lens.measure(q.Quad)
+			qlen := lens[len(lens)-1]
+			var pos float32
+			for rem <= qlen-pos {
+				end := pos + rem
+				if on {
+					cur = cur.appendSegment(q.Quad, lens.param(pos), lens.param(end))
+					if len(cur) > 0 {
+						dashes = append(dashes, cur)
+						cur = nil
+					}
+				}
+				pos = end
+				i = (i + 1) % len(pattern)
+				rem = pattern[i]
+				on = !on
+			}
+			rem -= qlen - pos
+			if on {
+				cur = cur.appendSegment(q.Quad, lens.param(pos), 1)
+			}
+		}
+		if len(cur) > 0 {
+			if closed && onBeg && len(dashes) > 0 {
+				// Join the last dash with the first dash.
+				dashes[0] = append(cur, dashes[0]...)
+			} else {
+				dashes = append(dashes, cur)
+			}
+		}
+		for _, dash := range dashes {
+			// Contours are numbered from 1, see split.
+			contour++
+			for _, q := range dash {
+				q.Contour = contour
+				o = append(o, q)
+			}
+		}
+	}
+	return o
+}
+
+// dashLengths are the approximate arc lengths of a quadratic Bézier
+// curve at evenly spaced parameter values.
+type dashLengths [17]float32
+
+func (l *dashLengths) measure(q QuadSegment) {
+	n := float32(len(l) - 1)
+	prev := q.From
+	for i := 1; i < len(l); i++ {
+		p := quadBezierSample(q.From, q.Ctrl, q.To, float32(i)/n)
+		l[i] = l[i-1] + lenPt(p.Sub(prev))
+		prev = p
+	}
+}
+
+// param returns the parameter value at the arc length s.
+func (l *dashLengths) param(s float32) float32 {
+	n := len(l) - 1
+	for i := 1; i <= n; i++ {
+		if s <= l[i] {
+			seg := l[i] - l[i-1]
+			if seg == 0 {
+				return float32(i) / float32(n)
+			}
+			return (float32(i-1) + (s-l[i-1])/seg) / float32(n)
+		}
+	}
+	return 1
+}
+
+// appendSegment appends the part of q between the parameter values t0 and
+// t1 to qs.
+func (qs StrokeQuads) appendSegment(q QuadSegment, t0, t1 float32) StrokeQuads {
+	if t1 <= t0 {
+		return qs
+	}
+	if t1 < 1 {
+		q.From, q.Ctrl, q.To, _, _, _ = quadBezierSplit(q.From, q.Ctrl, q.To, t1)
+		t0 /= t1
+	}
+	if t0 > 0 {
+		_, _, _, q.From, q.Ctrl, q.To = quadBezierSplit(q.From, q.Ctrl, q.To, t0)
+	}
+	if q.From == q.To {
+		return qs
+	}
+	if n := len(qs); n > 0 {
+		// Avoid gaps from rounding errors.
+		q.From = qs[n-1].Quad.To
+	}
+	return append(qs, StrokeQuad{Quad: q})
+}
+
+func (qs StrokeQuads) stroke(stroke StrokeStyle, dashes DashOp) StrokeQuads {
+	if !IsSolidLine(dashes) {
+		qs = qs.dash(dashes)
+	}
+
 	var (
 		o  StrokeQuads
 		hw = 0.5 * stroke.Width
@@ -198,7 +382,7 @@ func (qs StrokeQuads) offset(hw float32, stroke StrokeStyle) (rhs, lhs StrokeQua
 				next = states[0]
 			}
 			if state.n1 != next.n0 {
-				strokePathRoundJoin(&rhs, &lhs, hw, state.p1, state.n1, next.n0, state.r1, next.r0)
+				strokePathJoin(stroke, &rhs, &lhs, hw, state.p1, state.n1, next.n0, state.r1, next.r0)
 			}
 		}
 	}
@@ -484,6 +668,52 @@ func quadBezierSplit(p0, p1, p2 f32.Point, t float32) (f32.Point, f32.Point, f32
 	return b0, b1, b2, a0, a1, a2
 }
 
+// strokePathJoin joins the two paths rhs and lhs, according to the provided
+// stroke operation.
+func strokePathJoin(stroke StrokeStyle, rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
+	switch stroke.Join {
+	case MiterJoin:
+		strokePathMiterJoin(rhs, lhs, hw, stroke.Miter, pivot, n0, n1)
+	case BevelJoin:
+		strokePathBevelJoin(rhs, lhs, hw, pivot, n0, n1)
+	default:
+		strokePathRoundJoin(rhs, lhs, hw, pivot, n0, n1, r0, r1)
+	}
+}
+
+// strokePathBevelJoin joins the two paths rhs and lhs, connecting their
+// outer corners with a straight line.
+func strokePathBevelJoin(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point) {
+	rhs.lineTo(pivot.Add(n1))
+	lhs.lineTo(pivot.Sub(n1))
+}
+
+// strokePathMiterJoin joins the two paths rhs and lhs, extending their
+// outer edges until they meet. Joins with a ratio of miter length to
+// stroke width larger than limit are beveled.
+func strokePathMiterJoin(rhs, lhs *StrokeQuads, hw, limit float32, pivot, n0, n1 f32.Point) {
+	// The miter extends along the bisector of the normals to a distance
+	// of hw/cos(θ/2), where θ is the angle between the normals and
+	//
+	//	|n0 + n1| = 2·hw·cos(θ/2)
+	bisect := n0.Add(n1)
+	l2 := bisect.X*bisect.X + bisect.Y*bisect.Y
+	// The ratio of miter length to stroke width is 1/cos(θ/2).
+	if l2 == 0 || 4*hw*hw > limit*limit*l2 {
+		strokePathBevelJoin(rhs, lhs, hw, pivot, n0, n1)
+		return
+	}
+	miter := bisect.Mul(2 * hw * hw / l2)
+	if perpDot(n0, n1) > 0 {
+		// Path bends to the left, ie. CCW; the outer edge is rhs.
+		rhs.lineTo(pivot.Add(miter))
+	} else {
+		lhs.lineTo(pivot.Sub(miter))
+	}
+	rhs.lineTo(pivot.Add(n1))
+	lhs.lineTo(pivot.Sub(n1))
+}
+
 // strokePathRoundJoin joins the two paths rhs and lhs, creating an arc.
 func strokePathRoundJoin(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Point, r0, r1 float32) {
 	rp := pivot.Add(n1)
@@ -507,7 +737,30 @@ func strokePathRoundJoin(rhs, lhs *StrokeQuads, hw float32, pivot, n0, n1 f32.Po
 
 // strokePathCap caps the provided path qs, according to the provided stroke operation.
 func strokePathCap(stroke StrokeStyle, qs *StrokeQuads, hw float32, pivot, n0 f32.Point) {
-	strokePathRoundCap(qs, hw, pivot, n0)
+	switch stroke.Cap {
+	case ButtCap:
+		strokePathButtCap(qs, hw, pivot, n0)
+	case SquareCap:
+		strokePathSquareCap(qs, hw, pivot, n0)
+	default:
+		strokePathRoundCap(qs, hw, pivot, n0)
+	}
+}
+
+// strokePathButtCap caps the start or end of a path with a flat cap
+// ending at the path endpoint.
+func strokePathButtCap(qs *StrokeQuads, hw float32, pivot, n0 f32.Point) {
+	qs.lineTo(pivot.Sub(n0))
+}
+
+// strokePathSquareCap caps the start or end of a path with a flat cap
+// extending half the stroke width beyond the path endpoint.
+func strokePathSquareCap(qs *StrokeQuads, hw float32, pivot, n0 f32.Point) {
+	// The normal rotated counter-clockwise points away from the path.
+	e := f32.Pt(-n0.Y, n0.X)
+	qs.lineTo(pivot.Add(n0).Add(e))
+	qs.lineTo(pivot.Sub(n0).Add(e))
+	qs.lineTo(pivot.Sub(n0))
 }
 
 // strokePathRoundCap caps the start or end of a path with a round cap.
@@ -613,9 +866,9 @@ func dist(p1, p2 f32.Point) float64 {
 	return math.Hypot(dx, dy)
 }
 
-func StrokePathCommands(style StrokeStyle, scene []byte) StrokeQuads {
+func StrokePathCommands(style StrokeStyle, dashes DashOp, scene []byte) StrokeQuads {
 	quads := decodeToStrokeQuads(scene)
-	return quads.stroke(style)
+	return quads.stroke(style, dashes)
 }
 
 // decodeToStrokeQuads decodes scene commands to quads ready to stroke.
@@ -0,0 +1,187 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package stroke
+
+import (
+	"math"
+	"testing"
+
+	"gioui.org/internal/f32"
+)
+
+func line(contour uint32, from, to f32.Point) StrokeQuad {
+	return StrokeQuad{
+		Contour: contour,
+		Quad:    QuadSegment{From: from, Ctrl: from.Add(to).Mul(.5), To: to},
+	}
+}
+
+func polyline(pts ...f32.Point) StrokeQuads {
+	var qs StrokeQuads
+	for i := 1; i < len(pts); i++ {
+		qs = append(qs, line(1, pts[i-1], pts[i]))
+	}
+	return qs
+}
+
+func bounds(qs StrokeQuads) f32.Rectangle {
+	inf := float32(math.Inf(+1))
+	b := f32.Rectangle{Min: f32.Pt(inf, inf), Max: f32.Pt(-inf, -inf)}
+	for _, q := range qs {
+		for _, p := range []f32.Point{q.Quad.From, q.Quad.To} {
+			b.Min.X = min(b.Min.X, p.X)
+			b.Min.Y = min(b.Min.Y, p.Y)
+			b.Max.X = max(b.Max.X, p.X)
+			b.Max.Y = max(b.Max.Y, p.Y)
+		}
+	}
+	return b
+}
+
+func dashLens(qs StrokeQuads) []float32 {
+	var lens []float32
+	for _, c := range qs.split() {
+		var l float32
+		for _, q := range c {
+			l += lenPt(q.Quad.To.Sub(q.Quad.From))
+		}
+		lens = append(lens, l)
+	}
+	return lens
+}
+
+func TestDash(t *testing.T) {
+	tests := []struct {
+		dashes DashOp
+		want   []float32
+	}{
+		{DashOp{Dashes: []float32{10, 5}}, []float32{10, 10, 10, 10, 10, 10, 10}},
+		{DashOp{Phase: 5, Dashes: []float32{10, 5}}, []float32{5, 10, 10, 10, 10, 10, 10}},
+		{DashOp{Phase: -5, Dashes: []float32{10, 5}}, []float32{10, 10, 10, 10, 10, 10, 5}},
+		// Odd patterns repeat with dashes and gaps swapped.
+		{DashOp{Dashes: []float32{20}}, []float32{20, 20, 20}},
+	}
+	qs := polyline(f32.Pt(0, 0), f32.Pt(40, 0), f32.Pt(100, 0))
+	for _, test := range tests {
+		got := dashLens(qs.dash(test.dashes))
+		if len(got) != len(test.want) {
+			t.Errorf("%v: got dashes %v, expected %v", test.dashes, got, test.want)
+			continue
+		}
+		for i := range got {
+			if abs(got[i]-test.want[i]) > 1e-3 {
+				t.Errorf("%v: got dashes %v, expected %v", test.dashes, got, test.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDashClosed(t *testing.T) {
+	sq := polyline(f32.Pt(0, 0), f32.Pt(40, 0), f32.Pt(40, 40), f32.Pt(0, 40), f32.Pt(0, 0))
+	// The last dash continues into the first dash across the closing
+	// point.
+	got := dashLens(sq.dash(DashOp{Phase: 15, Dashes: []float32{30, 10}}))
+	want := []float32{30, 30, 30, 30}
+	if len(got) != len(want) {
+		t.Fatalf("got dashes %v, expected %v", got, want)
+	}
+	for i := range got {
+		if abs(got[i]-want[i]) > 1e-3 {
+			t.Fatalf("got dashes %v, expected %v", got, want)
+		}
+	}
+}
+
+func TestIsSolidLine(t *testing.T) {
+	tests := []struct {
+		dashes []float32
+		solid  bool
+	}{
+		{nil, true},
+		{[]float32{0, 0}, true},
+		{[]float32{1, -1}, true},
+		{[]float32{0, 1}, false},
+		{[]float32{1}, false},
+	}
+	for _, test := range tests {
+		if got := IsSolidLine(DashOp{Dashes: test.dashes}); got != test.solid {
+			t.Errorf("IsSolidLine(%v) = %v, expected %v", test.dashes, got, test.solid)
+		}
+	}
+}
+
+func TestCaps(t *testing.T) {
+	qs := polyline(f32.Pt(10, 10), f32.Pt(90, 10))
+	tests := []struct {
+		cap  StrokeCap
+		want f32.Rectangle
+	}{
+		{ButtCap, f32.Rect(10, 5, 90, 15)},
+		{SquareCap, f32.Rect(5, 5, 95, 15)},
+		{RoundCap, f32.Rect(5, 5, 95, 15)},
+	}
+	for _, test := range tests {
+		got := bounds(qs.stroke(StrokeStyle{Width: 10, Cap: test.cap}, DashOp{}))
+		if !rectEq(got, test.want) {
+			t.Errorf("cap %d: got bounds %v, expected %v", test.cap, got, test.want)
+		}
+	}
+}
+
+func TestJoins(t *testing.T) {
+	// A right angle corner at (90, 10).
+	qs := polyline(f32.Pt(10, 10), f32.Pt(90, 10), f32.Pt(90, 90))
+	tests := []struct {
+		join  StrokeJoin
+		miter float32
+		// corner is whether the outer corner point (95, 5) is part of
+		// the outline.
+		corner bool
+	}{
+		{MiterJoin, 4, true},
+		// The miter ratio of a right angle is √2.
+		{MiterJoin, 1.4, false},
+		{BevelJoin, 4, false},
+		{RoundJoin, 4, false},
+	}
+	for _, test := range tests {
+		out := qs.stroke(StrokeStyle{Width: 10, Cap: ButtCap, Join: test.join, Miter: test.miter}, DashOp{})
+		got := false
+		for _, q := range out {
+			if lenPt(q.Quad.To.Sub(f32.Pt(95, 5))) < 1e-3 {
+				got = true
+			}
+		}
+		if got != test.corner {
+			t.Errorf("join %d, miter %v: corner point found: %v, expected %v", test.join, test.miter, got, test.corner)
+		}
+	}
+}
+
+func rectEq(r1, r2 f32.Rectangle) bool {
+	const eps = 1e-3
+	return abs(r1.Min.X-r2.Min.X) < eps && abs(r1.Min.Y-r2.Min.Y) < eps &&
+		abs(r1.Max.X-r2.Max.X) < eps && abs(r1.Max.Y-r2.Max.Y) < eps
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func min(a, b float32) float32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b float32) float32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,401 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package svgpath parses the path data of SVG path elements.
+package svgpath
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"gioui.org/f32"
+)
+
+// Builder receives the segments of parsed path data.
+type Builder interface {
+	// Pos returns the pen position.
+	Pos() f32.Point
+	MoveTo(to f32.Point)
+	LineTo(to f32.Point)
+	QuadTo(ctrl, to f32.Point)
+	CubeTo(ctrl0, ctrl1, to f32.Point)
+	// Close closes the current contour and moves the pen to its
+	// start.
+	Close()
+}
+
+// Error describes a syntax error in path data.
+type Error struct {
+	// Offset is the byte offset of the error in the path data.
+	Offset int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid SVG path data at offset %d: %s", e.Offset, e.Msg)
+}
+
+// Parse parses the path data d and passes its segments to p. Elliptical
+// arcs are converted to cubic Bézier curves. Like SVG renderers, Parse
+// passes the segments up to the first error, which is returned as an
+// *Error.
+func Parse(p Builder, d string) error {
+	s := scanner{data: d}
+	var (
+		cmd, last byte
+		// ctrl is the last control point of the previous segment,
+		// for reflection by smooth curves.
+		ctrl f32.Point
+	)
+	s.skipSpace()
+	for !s.done() {
+		c := d[s.pos]
+		switch {
+		case cmd == 0 && c != 'M' && c != 'm':
+			return s.errorf("expected moveto command, found %q", c)
+		case isCommand(c):
+			cmd = c
+			s.pos++
+			s.skipSpace()
+		case cmd == 'Z' || cmd == 'z':
+			return s.errorf("unexpected %q after closepath", c)
+		}
+		// Points are relative to the pen position at the start of each
+		// segment.
+		rel := cmd >= 'a'
+		pen := p.Pos()
+		point := func() (f32.Point, error) {
+			pt, err := s.point()
+			if rel {
+				pt = pt.Add(pen)
+			}
+			return pt, err
+		}
+		prev := ctrl
+		ctrl = f32.Point{}
+		var err error
+		switch cmd {
+		case 'M', 'm':
+			var to f32.Point
+			if to, err = point(); err != nil {
+				break
+			}
+			p.MoveTo(to)
+			// Subsequent pairs are implicit lineto commands.
+			cmd = 'L' | cmd&0x20
+		case 'L', 'l':
+			var to f32.Point
+			if to, err = point(); err != nil {
+				break
+			}
+			p.LineTo(to)
+		case 'H', 'h':
+			var x float32
+			if x, err = s.number(); err != nil {
+				break
+			}
+			if rel {
+				x += pen.X
+			}
+			p.LineTo(f32.Pt(x, pen.Y))
+		case 'V', 'v':
+			var y float32
+			if y, err = s.number(); err != nil {
+				break
+			}
+			if rel {
+				y += pen.Y
+			}
+			p.LineTo(f32.Pt(pen.X, y))
+		case 'C', 'c', 'S', 's':
+			var c0, c1, to f32.Point
+			if cmd == 'C' || cmd == 'c' {
+				if c0, err = point(); err != nil {
+					break
+				}
+			} else {
+				c0 = pen
+				if last == 'C' || last == 'S' {
+					c0 = pen.Mul(2).Sub(prev)
+				}
+			}
+			if c1, err = point(); err != nil {
+				break
+			}
+			if to, err = point(); err != nil {
+				break
+			}
+			p.CubeTo(c0, c1, to)
+			ctrl = c1
+		case 'Q', 'q', 'T', 't':
+			var c, to f32.Point
+			if cmd == 'Q' || cmd == 'q' {
+				if c, err = point(); err != nil {
+					break
+				}
+			} else {
+				c = pen
+				if last == 'Q' || last == 'T' {
+					c = pen.Mul(2).Sub(prev)
+				}
+			}
+			if to, err = point(); err != nil {
+				break
+			}
+			p.QuadTo(c, to)
+			ctrl = c
+		case 'A', 'a':
+			var (
+				rx, ry, rot  float32
+				large, sweep bool
+				to           f32.Point
+			)
+			if rx, err = s.number(); err != nil {
+				break
+			}
+			if ry, err = s.number(); err != nil {
+				break
+			}
+			if rot, err = s.number(); err != nil {
+				break
+			}
+			if large, err = s.flag(); err != nil {
+				break
+			}
+			if sweep, err = s.flag(); err != nil {
+				break
+			}
+			if to, err = point(); err != nil {
+				break
+			}
+			ArcTo(p, rx, ry, rot, large, sweep, to)
+		case 'Z', 'z':
+			// Close leaves the pen at the start of the subpath.
+			p.Close()
+		}
+		if err != nil {
+			return err
+		}
+		last = cmd &^ 0x20
+		s.skipSeparator()
+	}
+	return nil
+}
+
+// ArcTo adds an elliptical arc in the endpoint parameterization of SVG to
+// p, with the rotation of the ellipse in degrees. The arc is converted to
+// its center parameterization and approximated by cubic Bézier curves. See
+// the implementation notes of the SVG specification, sections F.6.5 and
+// F.6.6.
+func ArcTo(p Builder, rx, ry, rotation float32, large, sweep bool, to f32.Point) {
+	from := p.Pos()
+	if from == to {
+		return
+	}
+	if rx == 0 || ry == 0 {
+		p.LineTo(to)
+		return
+	}
+	var (
+		x1, y1 = float64(from.X), float64(from.Y)
+		x2, y2 = float64(to.X), float64(to.Y)
+		rX     = math.Abs(float64(rx))
+		rY     = math.Abs(float64(ry))
+		phi    = float64(rotation) * math.Pi / 180
+	)
+	sin, cos := math.Sincos(phi)
+	// Step 1: compute the transformed start point.
+	dx, dy := (x1-x2)/2, (y1-y2)/2
+	x1p := cos*dx + sin*dy
+	y1p := -sin*dx + cos*dy
+	// Scale up radii too small to reach the end point.
+	if l := x1p*x1p/(rX*rX) + y1p*y1p/(rY*rY); l > 1 {
+		l = math.Sqrt(l)
+		rX *= l
+		rY *= l
+	}
+	// Step 2: compute the transformed center.
+	num := rX*rX*rY*rY - rX*rX*y1p*y1p - rY*rY*x1p*x1p
+	den := rX*rX*y1p*y1p + rY*rY*x1p*x1p
+	coef := 0.0
+	if num > 0 && den > 0 {
+		coef = math.Sqrt(num / den)
+	}
+	if large == sweep {
+		coef = -coef
+	}
+	cxp := coef * rX * y1p / rY
+	cyp := -coef * rY * x1p / rX
+	// Step 3: compute the center.
+	cx := cos*cxp - sin*cyp + (x1+x2)/2
+	cy := sin*cxp + cos*cyp + (y1+y2)/2
+	// Step 4: compute the start angle and the sweep.
+	theta := math.Atan2((y1p-cyp)/rY, (x1p-cxp)/rX)
+	delta := math.Atan2((-y1p-cyp)/rY, (-x1p-cxp)/rX) - theta
+	if sweep && delta < 0 {
+		delta += 2 * math.Pi
+	} else if !sweep && delta > 0 {
+		delta -= 2 * math.Pi
+	}
+
+	// Approximate the arc with cubic Béziers of at most a quarter circle
+	// each.
+	segments := int(math.Ceil(math.Abs(delta) / (math.Pi / 2)))
+	step := delta / float64(segments)
+	k := 4.0 / 3 * math.Tan(step/4)
+	point := func(a float64) (pt, deriv f32.Point) {
+		s, c := math.Sincos(a)
+		ex, ey := rX*c, rY*s
+		dx, dy := -rX*s, rY*c
+		pt = f32.Pt(float32(cx+cos*ex-sin*ey), float32(cy+sin*ex+cos*ey))
+		deriv = f32.Pt(float32(cos*dx-sin*dy), float32(sin*dx+cos*dy))
+		return pt, deriv
+	}
+	p0, d0 := point(theta)
+	for i := 1; i <= segments; i++ {
+		p1, d1 := point(theta + float64(i)*step)
+		if i == segments {
+			// Avoid rounding errors at the end point.
+			p1 = to
+		}
+		c0 := p0.Add(d0.Mul(float32(k)))
+		c1 := p1.Sub(d1.Mul(float32(k)))
+		p.CubeTo(c0, c1, p1)
+		p0, d0 = p1, d1
+	}
+}
+
+// Numbers parses a list of numbers separated by white space or commas,
+// such as the points of an SVG polygon.
+func Numbers(d string) ([]float32, error) {
+	s := scanner{data: d}
+	var nums []float32
+	s.skipSpace()
+	for !s.done() {
+		n, err := s.number()
+		if err != nil {
+			return nil, err
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+func isCommand(c byte) bool {
+	switch c {
+	case 'M', 'm', 'L', 'l', 'H', 'h', 'V', 'v', 'C', 'c', 'S', 's',
+		'Q', 'q', 'T', 't', 'A', 'a', 'Z', 'z':
+		return true
+	}
+	return false
+}
+
+// scanner scans the numbers and flags of path data.
+type scanner struct {
+	data string
+	pos  int
+}
+
+func (s *scanner) done() bool {
+	return s.pos >= len(s.data)
+}
+
+func (s *scanner) errorf(format string, args ...interface{}) error {
+	return &Error{Offset: s.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (s *scanner) skipSpace() {
+	for !s.done() {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r', '\f':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// skipSeparator skips white space with at most one comma.
+func (s *scanner) skipSeparator() {
+	s.skipSpace()
+	if !s.done() && s.data[s.pos] == ',' {
+		s.pos++
+		s.skipSpace()
+	}
+}
+
+func (s *scanner) point() (f32.Point, error) {
+	x, err := s.number()
+	if err != nil {
+		return f32.Point{}, err
+	}
+	y, err := s.number()
+	return f32.Pt(x, y), err
+}
+
+// number scans a number and the separator following it.
+func (s *scanner) number() (float32, error) {
+	start := s.pos
+	digits := func() int {
+		n := 0
+		for !s.done() && '0' <= s.data[s.pos] && s.data[s.pos] <= '9' {
+			s.pos++
+			n++
+		}
+		return n
+	}
+	sign := func() {
+		if !s.done() && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+	}
+	sign()
+	n := digits()
+	if !s.done() && s.data[s.pos] == '.' {
+		s.pos++
+		n += digits()
+	}
+	if n == 0 {
+		s.pos = start
+		if s.done() {
+			return 0, s.errorf("expected number, found end of data")
+		}
+		return 0, s.errorf("expected number, found %q", s.data[s.pos])
+	}
+	if !s.done() && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		// Only consume the exponent if it is well-formed.
+		end := s.pos
+		s.pos++
+		sign()
+		if digits() == 0 {
+			s.pos = end
+		}
+	}
+	str := s.data[start:s.pos]
+	v, err := strconv.ParseFloat(str, 32)
+	if err != nil {
+		s.pos = start
+		return 0, s.errorf("invalid number %q", str)
+	}
+	s.skipSeparator()
+	return float32(v), nil
+}
+
+// flag scans an arc flag and the separator following it. Flags need not
+// be separated from the following value.
+func (s *scanner) flag() (bool, error) {
+	if s.done() {
+		return false, s.errorf("expected flag, found end of data")
+	}
+	var f bool
+	switch c := s.data[s.pos]; c {
+	case '0':
+	case '1':
+		f = true
+	default:
+		return false, s.errorf("expected flag, found %q", c)
+	}
+	s.pos++
+	s.skipSeparator()
+	return f, nil
+}
@@ -443,6 +443,7 @@ type (
 	QueueFlags            = C.VkQueueFlags
 	RenderPass            = C.VkRenderPass
 	Sampler               = C.VkSampler
+	SamplerAddressMode    = C.VkSamplerAddressMode
 	SamplerMipmapMode     = C.VkSamplerMipmapMode
 	Semaphore             = C.VkSemaphore
 	ShaderModule          = C.VkShaderModule
@@ -584,6 +585,10 @@ const (
 	SAMPLER_MIPMAP_MODE_NEAREST SamplerMipmapMode = C.VK_SAMPLER_MIPMAP_MODE_NEAREST
 	SAMPLER_MIPMAP_MODE_LINEAR  SamplerMipmapMode = C.VK_SAMPLER_MIPMAP_MODE_LINEAR
 
+	SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE   SamplerAddressMode = C.VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE
+	SAMPLER_ADDRESS_MODE_REPEAT          SamplerAddressMode = C.VK_SAMPLER_ADDRESS_MODE_REPEAT
+	SAMPLER_ADDRESS_MODE_MIRRORED_REPEAT SamplerAddressMode = C.VK_SAMPLER_ADDRESS_MODE_MIRRORED_REPEAT
+
 	REMAINING_MIP_LEVELS = -1
 )
 
@@ -1471,15 +1476,15 @@ func FreeMemory(d Device, mem DeviceMemory) {
 	C.vkFreeMemory(funcs.vkFreeMemory, d, mem, nil)
 }
 
-func CreateSampler(d Device, minFilter, magFilter Filter, mipmapMode SamplerMipmapMode) (Sampler, error) {
+func CreateSampler(d Device, minFilter, magFilter Filter, mipmapMode SamplerMipmapMode, addressMode SamplerAddressMode) (Sampler, error) {
 	inf := C.VkSamplerCreateInfo{
 		sType:        C.VK_STRUCTURE_TYPE_SAMPLER_CREATE_INFO,
 		minFilter:    minFilter,
 		magFilter:    magFilter,
 		mipmapMode:   mipmapMode,
 		maxLod:       C.VK_LOD_CLAMP_NONE,
-		addressModeU: C.VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
-		addressModeV: C.VK_SAMPLER_ADDRESS_MODE_CLAMP_TO_EDGE,
+		addressModeU: addressMode,
+		addressModeV: addressMode,
 	}
 	var s C.VkSampler
 	if err := vkErr(C.vkCreateSampler(funcs.vkCreateSampler, d, &inf, nil, &s)); err != nil {
@@ -5,6 +5,10 @@
 package profile
 
 import (
+	"fmt"
+	"image"
+	"time"
+
 	"gioui.org/internal/ops"
 	"gioui.org/io/event"
 	"gioui.org/op"
@@ -19,8 +23,62 @@ type Op struct {
 // Event contains profile data from a single
 // rendered frame.
 type Event struct {
-	// Timings. Very likely to change.
+	// Timings is a summary of Frame for display. Very likely to
+	// change.
 	Timings string
+	// Frame contains the durations and counters of the frame.
+	Frame Frame
+}
+
+// Frame contains the durations of the stages of rendering a frame,
+// and counters of the work done.
+//
+// The durations of stages that the renderer doesn't perform are zero.
+// The GPU durations are zero for devices that don't support timer
+// queries, and lag a few frames behind the other fields, because the
+// GPU reports them asynchronously.
+type Frame struct {
+	// Total is the time from the start of the frame until the
+	// frame is presented.
+	Total time.Duration
+	// Collect is the CPU time spent collecting the operations of the
+	// frame.
+	Collect time.Duration
+	// Encode is the CPU time spent encoding paths for the GPU.
+	Encode time.Duration
+	// Stencil is the GPU time spent rendering the coverage of paths.
+	Stencil time.Duration
+	// Cover is the GPU time spent filling the paths with their
+	// materials.
+	Cover time.Duration
+	// Compute is the GPU time spent in the dispatches of compute
+	// renderers.
+	Compute time.Duration
+	// Blit is the GPU time spent copying rendered layers to the
+	// output.
+	Blit time.Duration
+	// Present is the time spent presenting the frame to the window,
+	// including waiting for the display.
+	Present time.Duration
+
+	// Paths is the number of paths rendered.
+	Paths int
+	// Images is the number of images drawn.
+	Images int
+	// Uploads is the number of images uploaded to the GPU.
+	Uploads int
+	// CacheHits is the number of paths and images found in the
+	// caches of the renderer.
+	CacheHits int
+	// CacheMisses is the number of paths and images that were
+	// missing from the caches of the renderer.
+	CacheMisses int
+
+	// Damage is the area of the frame, in pixels, that differs
+	// from the previous frame. It is empty if the frames are equal,
+	// and covers the whole frame if the renderer can't tell which
+	// parts changed, such as for the first frame.
+	Damage []image.Rectangle
 }
 
 func (p Op) Add(o *op.Ops) {
@@ -28,4 +86,17 @@ func (p Op) Add(o *op.Ops) {
 	data[0] = byte(ops.TypeProfile)
 }
 
+// String summarizes the durations and counters of f.
+func (f Frame) String() string {
+	q := 100 * time.Microsecond
+	area := 0
+	for _, r := range f.Damage {
+		area += r.Dx() * r.Dy()
+	}
+	return fmt.Sprintf("tot:%7s col:%7s enc:%7s st:%7s cov:%7s comp:%7s blit:%7s pres:%7s paths:%d imgs:%d upl:%d hit:%d miss:%d dmg:%dpx",
+		f.Total.Round(q), f.Collect.Round(q), f.Encode.Round(q), f.Stencil.Round(q), f.Cover.Round(q),
+		f.Compute.Round(q), f.Blit.Round(q), f.Present.Round(q),
+		f.Paths, f.Images, f.Uploads, f.CacheHits, f.CacheMisses, area)
+}
+
 func (p Event) ImplementsEvent() {}
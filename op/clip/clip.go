@@ -21,8 +21,14 @@ import (
 type Op struct {
 	path PathSpec
 
-	outline bool
-	width   float32
+	outline  bool
+	fillRule FillRule
+	width    float32
+	cap      StrokeCap
+	join     StrokeJoin
+	miter    float32
+	dashes   []float32
+	phase    float32
 }
 
 // Stack represents an Op pushed on the clip stack.
@@ -41,6 +47,11 @@ func init() {
 // Push saves the current clip state on the stack and updates the current
 // state to the intersection of the current p.
 func (p Op) Push(o *op.Ops) Stack {
+	if p.fillRule == EvenOdd && p.path.hasSegments {
+		// The renderers fill by the non-zero rule. Convert the path to
+		// non-overlapping contours, which fill the same by either rule.
+		p.path = Combine(o, PathUnion, Outline{Path: p.path, FillRule: EvenOdd}, Outline{})
+	}
 	id, macroID := ops.PushOp(&o.Internal, ops.ClipStack)
 	p.add(o)
 	return Stack{ops: &o.Internal, id: id, macroID: macroID}
@@ -77,16 +88,32 @@ func (p Op) add(o *op.Ops) {
 
 	bounds := path.bounds
 	if p.width > 0 {
-		// Expand bounds to cover stroke.
-		half := int(p.width*.5 + .5)
+		// Expand bounds to cover stroke, including square caps and
+		// miter joins.
+		ext := float32(1)
+		if p.cap == SquareCap {
+			ext = math.Sqrt2
+		}
+		if p.join == MiterJoin && p.miter > ext {
+			ext = p.miter
+		}
+		half := int(math.Ceil(float64(p.width * .5 * ext)))
 		bounds.Min.X -= half
 		bounds.Min.Y -= half
 		bounds.Max.X += half
 		bounds.Max.Y += half
-		data := ops.Write(&o.Internal, ops.TypeStrokeLen)
+		var dashes interface{}
+		if len(p.dashes) > 0 {
+			dashes = p.dashes
+		}
+		data := ops.Write1(&o.Internal, ops.TypeStrokeLen, dashes)
 		data[0] = byte(ops.TypeStroke)
 		bo := binary.LittleEndian
 		bo.PutUint32(data[1:], math.Float32bits(p.width))
+		bo.PutUint32(data[5:], math.Float32bits(p.miter))
+		data[9] = byte(p.cap)
+		data[10] = byte(p.join)
+		bo.PutUint32(data[11:], math.Float32bits(p.phase))
 	}
 
 	data := ops.Write(&o.Internal, ops.TypeClipLen)
@@ -118,8 +145,8 @@ type PathSpec struct {
 
 // Path constructs a Op clip path described by lines and
 // Bézier curves, where drawing outside the Path is discarded.
-// The inside-ness of a pixel is determines by the non-zero winding rule,
-// similar to the SVG rule of the same name.
+// The inside-ness of a pixel is determined by the fill rule of the
+// Outline of the path, the non-zero winding rule by default.
 //
 // Path generates no garbage and can be used for dynamic paths; path
 // data is stored directly in the Ops list supplied to Begin.
@@ -132,6 +159,8 @@ type Path struct {
 	hasSegments bool
 	bounds      f32internal.Rectangle
 	hash        maphash.Hash
+	// dataStart is the offset of the path data in ops.
+	dataStart int
 }
 
 // Pos returns the current pen position.
@@ -148,6 +177,8 @@ func (p *Path) Begin(o *op.Ops) {
 	ops.BeginMulti(p.ops)
 	data := ops.WriteMulti(p.ops, ops.TypeAuxLen)
 	data[0] = byte(ops.TypeAux)
+	data, _ = ops.Contents(p.ops)
+	p.dataStart = len(data)
 }
 
 // End returns a PathSpec ready to use in clipping operations.
@@ -317,26 +348,97 @@ type Stroke struct {
 	Path PathSpec
 	// Width of the stroked path.
 	Width float32
+	// Cap is the style of the open ends of the path, including the
+	// ends of dashes.
+	Cap StrokeCap
+	// Join is the style of the corners between path segments.
+	Join StrokeJoin
+	// MiterLimit is the maximum ratio of the length of a MiterJoin to
+	// Width. Corners exceeding the limit are joined with BevelJoin. The
+	// zero value means a limit of 4.
+	MiterLimit float32
+	// Dashes is the pattern of alternating dash and gap lengths along
+	// the path. An odd number of lengths is repeated to yield an even
+	// number. A nil or invalid pattern strokes a solid line.
+	Dashes []float32
+	// DashOffset is the distance into the dash pattern at which the
+	// stroke starts. The pattern restarts at every contour of Path.
+	DashOffset float32
 }
 
+// StrokeCap describes the head or tail of a stroked path.
+type StrokeCap uint8
+
+// StrokeJoin describes how stroked path segments are joined.
+type StrokeJoin uint8
+
+const (
+	// RoundCap caps the stroke with a half-circle centered at the
+	// path endpoint.
+	RoundCap StrokeCap = iota
+	// ButtCap ends the stroke squarely at the path endpoint.
+	ButtCap
+	// SquareCap caps the stroke with a half-square extending half the
+	// stroke width beyond the path endpoint.
+	SquareCap
+)
+
+const (
+	// RoundJoin joins path segments with a circular arc.
+	RoundJoin StrokeJoin = iota
+	// MiterJoin joins path segments by extending their outer edges
+	// until they meet.
+	MiterJoin
+	// BevelJoin joins path segments by connecting their outer corners
+	// with a straight line.
+	BevelJoin
+)
+
+// defaultMiterLimit is the miter limit of a zero Stroke.MiterLimit,
+// matching the SVG default.
+const defaultMiterLimit = 4
+
 // Op returns a clip operation representing the stroke.
 func (s Stroke) Op() Op {
+	miter := s.MiterLimit
+	if miter <= 0 {
+		miter = defaultMiterLimit
+	}
+	var dashes []float32
+	if len(s.Dashes) > 0 {
+		// Copy the pattern, because the operation may be
+		// executed after s.Dashes is modified.
+		dashes = append(dashes, s.Dashes...)
+	}
 	return Op{
-		path:  s.Path,
-		width: s.Width,
+		path:   s.Path,
+		width:  s.Width,
+		cap:    s.Cap,
+		join:   s.Join,
+		miter:  miter,
+		dashes: dashes,
+		phase:  s.DashOffset,
 	}
 }
 
-// Outline represents the area inside of a path, according to the
-// non-zero winding rule.
+// Outline represents the area inside of a path, according to a fill
+// rule.
 type Outline struct {
 	Path PathSpec
+	// FillRule determines the area inside the path. The zero value is
+	// the non-zero winding rule.
+	//
+	// The even-odd rule is implemented by converting the path with
+	// Combine when the clip operation is pushed, which is expensive for
+	// complex paths.
+	FillRule FillRule
 }
 
 // Op returns a clip operation representing the outline.
 func (o Outline) Op() Op {
 	return Op{
-		path:    o.Path,
-		outline: true,
+		path:     o.Path,
+		outline:  true,
+		fillRule: o.FillRule,
 	}
 }
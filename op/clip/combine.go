@@ -0,0 +1,515 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package clip
+
+import (
+	"math"
+	"sort"
+
+	"gioui.org/f32"
+	"gioui.org/internal/scene"
+	"gioui.org/internal/stroke"
+	"gioui.org/op"
+)
+
+// FillRule determines the area inside a path.
+type FillRule uint8
+
+// PathOp is a boolean operation on the areas of two outlines.
+type PathOp uint8
+
+const (
+	// NonZero fills the areas that a path winds around a non-zero
+	// number of times, like the SVG fill rule of the same name.
+	NonZero FillRule = iota
+	// EvenOdd fills the areas that a path winds around an odd number
+	// of times, like the SVG fill rule of the same name. Overlapping
+	// contours cut holes regardless of their directions.
+	EvenOdd
+)
+
+const (
+	// PathUnion is the area inside either outline.
+	PathUnion PathOp = iota
+	// PathIntersect is the area inside both outlines.
+	PathIntersect
+	// PathDifference is the area inside the first outline but not
+	// the second.
+	PathDifference
+	// PathXor is the area inside exactly one of the outlines.
+	PathXor
+)
+
+// gridScale is the number of fixed-point grid steps per unit of the
+// coordinates of combined paths.
+const gridScale = 256
+
+// gridMax is the largest coordinate of the grid. It bounds the
+// coordinates so that the exact orientation tests don't overflow.
+const gridMax = 1 << 20 * gridScale
+
+// combineFlatness is the maximum distance between the curves of
+// combined paths and the lines that approximate them.
+const combineFlatness = 1.0 / 32
+
+// gridPt is a point of the fixed-point grid.
+type gridPt struct {
+	X, Y int64
+}
+
+// edge is a directed line of an operand of Combine.
+type edge struct {
+	from, to gridPt
+	// operand is 0 for edges of the first outline, 1 for the second.
+	operand int
+}
+
+// segment is a line between two points of the arrangement of the
+// edges, where p is less than q.
+type segment struct {
+	p, q gridPt
+	// wind is the number of times each operand traverses the segment
+	// from p to q, minus the times from q to p.
+	wind [2]int
+}
+
+// Combine returns the path of the area that results from applying
+// mode to the areas of a and b.
+//
+// Curves are approximated by lines and coordinates are rounded to
+// 1/256 units. The contours of the returned path don't cross each
+// other, and wind once around the area, so the path fills the same
+// area by either fill rule.
+//
+// Combine is computed on the CPU and is expensive for complex paths.
+// Combine paths once, not every frame.
+func Combine(o *op.Ops, mode PathOp, a, b Outline) PathSpec {
+	edges := a.Path.appendEdges(nil, 0)
+	edges = b.Path.appendEdges(edges, 1)
+	edges = splitEdges(edges)
+	segs := mergeEdges(edges)
+	rules := [2]FillRule{a.FillRule, b.FillRule}
+	inside := func(w [2]int) bool {
+		in := [2]bool{}
+		for i, r := range rules {
+			if r == EvenOdd {
+				in[i] = w[i]%2 != 0
+			} else {
+				in[i] = w[i] != 0
+			}
+		}
+		switch mode {
+		case PathIntersect:
+			return in[0] && in[1]
+		case PathDifference:
+			return in[0] && !in[1]
+		case PathXor:
+			return in[0] != in[1]
+		default:
+			return in[0] || in[1]
+		}
+	}
+	idx := [2]*segmentIndex{newSegmentIndex(segs, false), newSegmentIndex(segs, true)}
+	var boundary []edge
+	for _, s := range segs {
+		// Determine the winding numbers on either side of the
+		// segment with a ray along the x axis, or the y axis for
+		// horizontal segments. Swapping the axes reflects the plane
+		// and negates the winding numbers.
+		swap := s.p.Y == s.q.Y
+		sign := 1
+		p, q := s.p, s.q
+		if swap {
+			sign = -1
+			p, q = p.swap(), q.swap()
+		}
+		dir := 1
+		if q.Y < p.Y {
+			dir = -1
+		}
+		right := idx[0]
+		if swap {
+			right = idx[1]
+		}
+		wr := right.winding(gridPt{X: p.X + q.X, Y: p.Y + q.Y})
+		var wl [2]int
+		for i := range wl {
+			wl[i] = sign * (wr[i] + dir*s.wind[i])
+			wr[i] *= sign
+		}
+		inL, inR := inside(wl), inside(wr)
+		if inL == inR {
+			continue
+		}
+		// Orient the segment so the area winds once around it: an
+		// area to the left of the segment, in the ray direction, is
+		// crossed by its rays.
+		want := sign
+		if inR {
+			want = -sign
+		}
+		e := edge{from: s.p, to: s.q}
+		if dir != want {
+			e.from, e.to = e.to, e.from
+		}
+		boundary = append(boundary, e)
+	}
+	return buildContours(o, boundary)
+}
+
+// appendEdges appends the edges of p, with curves approximated by
+// lines, to edges.
+func (p PathSpec) appendEdges(edges []edge, operand int) []edge {
+	add := func(from, to f32.Point) {
+		e := edge{from: toGrid(from), to: toGrid(to), operand: operand}
+		if e.from != e.to {
+			edges = append(edges, e)
+		}
+	}
+	p.decode(func(cmd scene.Command) {
+		switch cmd.Op() {
+		case scene.OpLine:
+			add(scene.DecodeLine(cmd))
+		case scene.OpGap:
+			add(scene.DecodeGap(cmd))
+		case scene.OpQuad:
+			from, ctrl, to := scene.DecodeQuad(cmd)
+			flattenQuad(add, stroke.QuadSegment{From: from, Ctrl: ctrl, To: to})
+		case scene.OpCubic:
+			for _, q := range stroke.SplitCubic(scene.DecodeCubic(cmd)) {
+				flattenQuad(add, q)
+			}
+		}
+	})
+	return edges
+}
+
+// flattenQuad approximates the quadratic Bézier curve q by lines.
+func flattenQuad(line func(from, to f32.Point), q stroke.QuadSegment) {
+	if q.From == q.To && q.Ctrl == q.To {
+		return
+	}
+	// The distance between the curve and n lines is bounded by an
+	// eighth of its second derivative divided by n².
+	dd := q.From.Sub(q.Ctrl.Mul(2)).Add(q.To)
+	l := 2 * math.Hypot(float64(dd.X), float64(dd.Y))
+	n := int(math.Ceil(math.Sqrt(l / (8 * combineFlatness))))
+	if n < 1 {
+		n = 1
+	}
+	from := q.From
+	for i := 1; i <= n; i++ {
+		t := float32(i) / float32(n)
+		to := q.To
+		if i < n {
+			a := q.From.Add(q.Ctrl.Sub(q.From).Mul(t))
+			b := q.Ctrl.Add(q.To.Sub(q.Ctrl).Mul(t))
+			to = a.Add(b.Sub(a).Mul(t))
+		}
+		line(from, to)
+		from = to
+	}
+}
+
+// splitEdges splits the edges at the points where they cross or touch
+// each other, so that edges meet only at their end points.
+func splitEdges(edges []edge) []edge {
+	// Rounding the split points may create new crossings; repeat
+	// until there are none.
+	for iter := 0; iter < 16; iter++ {
+		sort.Slice(edges, func(i, j int) bool {
+			return edges[i].minX() < edges[j].minX()
+		})
+		splits := make(map[int][]gridPt)
+		for i := range edges {
+			e := edges[i]
+			emaxX := max64(e.from.X, e.to.X)
+			for j := i + 1; j < len(edges) && edges[j].minX() <= emaxX; j++ {
+				f := edges[j]
+				if max64(e.from.Y, e.to.Y) < min64(f.from.Y, f.to.Y) || max64(f.from.Y, f.to.Y) < min64(e.from.Y, e.to.Y) {
+					continue
+				}
+				pe, pf := intersect(e, f)
+				if len(pe) > 0 {
+					splits[i] = append(splits[i], pe...)
+				}
+				if len(pf) > 0 {
+					splits[j] = append(splits[j], pf...)
+				}
+			}
+		}
+		if len(splits) == 0 {
+			break
+		}
+		var split []edge
+		for i, e := range edges {
+			pts, ok := splits[i]
+			if !ok {
+				split = append(split, e)
+				continue
+			}
+			d := gridPt{X: e.to.X - e.from.X, Y: e.to.Y - e.from.Y}
+			sort.Slice(pts, func(i, j int) bool {
+				return d.dot(pts[i].sub(e.from)) < d.dot(pts[j].sub(e.from))
+			})
+			from := e.from
+			for _, p := range append(pts, e.to) {
+				if p != from {
+					split = append(split, edge{from: from, to: p, operand: e.operand})
+					from = p
+				}
+			}
+		}
+		edges = split
+	}
+	return edges
+}
+
+// intersect returns the points where e and f cross or touch, as the
+// points to split e and f at.
+func intersect(e, f edge) (pe, pf []gridPt) {
+	a, b, c, d := e.from, e.to, f.from, f.to
+	o1, o2 := orient(a, b, c), orient(a, b, d)
+	o3, o4 := orient(c, d, a), orient(c, d, b)
+	if (o1 > 0 && o2 < 0 || o1 < 0 && o2 > 0) && (o3 > 0 && o4 < 0 || o3 < 0 && o4 > 0) {
+		t := float64(o3) / (float64(o3) - float64(o4))
+		p := gridPt{
+			X: a.X + int64(math.Round(t*float64(b.X-a.X))),
+			Y: a.Y + int64(math.Round(t*float64(b.Y-a.Y))),
+		}
+		if p != a && p != b {
+			pe = append(pe, p)
+		}
+		if p != c && p != d {
+			pf = append(pf, p)
+		}
+		return pe, pf
+	}
+	// Touching and overlapping edges.
+	if o1 == 0 && between(a, b, c) {
+		pe = append(pe, c)
+	}
+	if o2 == 0 && between(a, b, d) {
+		pe = append(pe, d)
+	}
+	if o3 == 0 && between(c, d, a) {
+		pf = append(pf, a)
+	}
+	if o4 == 0 && between(c, d, b) {
+		pf = append(pf, b)
+	}
+	return pe, pf
+}
+
+// mergeEdges merges the edges between the same points into segments.
+func mergeEdges(edges []edge) []segment {
+	index := make(map[[2]gridPt]int)
+	var segs []segment
+	for _, e := range edges {
+		p, q, w := e.from, e.to, 1
+		if q.less(p) {
+			p, q, w = q, p, -1
+		}
+		k := [2]gridPt{p, q}
+		i, ok := index[k]
+		if !ok {
+			i = len(segs)
+			index[k] = i
+			segs = append(segs, segment{p: p, q: q})
+		}
+		segs[i].wind[e.operand] += w
+	}
+	return segs
+}
+
+// segmentIndex is an index of the segments that span ranges of y
+// coordinates, or x coordinates if the axes are swapped.
+type segmentIndex struct {
+	segs    []segment
+	min     int64
+	size    int64
+	buckets [][]int
+}
+
+func newSegmentIndex(segs []segment, swap bool) *segmentIndex {
+	idx := &segmentIndex{segs: make([]segment, len(segs))}
+	if len(segs) == 0 {
+		return idx
+	}
+	lo, hi := int64(math.MaxInt64), int64(math.MinInt64)
+	for i, s := range segs {
+		if swap {
+			s.p, s.q = s.p.swap(), s.q.swap()
+		}
+		idx.segs[i] = s
+		lo = min64(lo, min64(s.p.Y, s.q.Y))
+		hi = max64(hi, max64(s.p.Y, s.q.Y))
+	}
+	n := int64(len(segs))
+	idx.min = lo
+	idx.size = (hi-lo)/n + 1
+	idx.buckets = make([][]int, (hi-lo)/idx.size+1)
+	for i, s := range idx.segs {
+		first := (min64(s.p.Y, s.q.Y) - lo) / idx.size
+		last := (max64(s.p.Y, s.q.Y) - lo) / idx.size
+		for b := first; b <= last; b++ {
+			idx.buckets[b] = append(idx.buckets[b], i)
+		}
+	}
+	return idx
+}
+
+// winding returns the winding numbers of the operands at m, in doubled
+// grid coordinates, from the segments crossed by a ray from m in the
+// direction of the x axis. Segments through m are not counted.
+func (idx *segmentIndex) winding(m gridPt) [2]int {
+	var w [2]int
+	b := (m.Y/2 - idx.min) / idx.size
+	if b < 0 || b >= int64(len(idx.buckets)) {
+		return w
+	}
+	for _, i := range idx.buckets[b] {
+		s := idx.segs[i]
+		p := gridPt{X: 2 * s.p.X, Y: 2 * s.p.Y}
+		q := gridPt{X: 2 * s.q.X, Y: 2 * s.q.Y}
+		if (p.Y <= m.Y) == (q.Y <= m.Y) {
+			continue
+		}
+		dir := int64(1)
+		if q.Y < p.Y {
+			dir = -1
+		}
+		// The segment crosses the ray if m is on its left, in the
+		// direction of increasing y.
+		if o := orient(p, q, m); o*dir <= 0 {
+			continue
+		}
+		for j := range w {
+			w[j] += int(dir) * s.wind[j]
+		}
+	}
+	return w
+}
+
+// buildContours links the edges into closed contours and returns them
+// as a path.
+func buildContours(o *op.Ops, edges []edge) PathSpec {
+	outgoing := make(map[gridPt][]int)
+	for i, e := range edges {
+		outgoing[e.from] = append(outgoing[e.from], i)
+	}
+	used := make([]bool, len(edges))
+	var p Path
+	p.Begin(o)
+	var pts []gridPt
+	for i := range edges {
+		if used[i] {
+			continue
+		}
+		start := edges[i].from
+		pts = append(pts[:0], start)
+		for e := i; e != -1; {
+			used[e] = true
+			v := edges[e].to
+			if v == start {
+				break
+			}
+			// Merge collinear edges.
+			if n := len(pts); n >= 2 && orient(pts[n-2], pts[n-1], v) == 0 {
+				pts = pts[:n-1]
+			}
+			pts = append(pts, v)
+			e = -1
+			for out := outgoing[v]; len(out) > 0; {
+				next := out[len(out)-1]
+				out = out[:len(out)-1]
+				outgoing[v] = out
+				if !used[next] {
+					e = next
+					break
+				}
+			}
+		}
+		if len(pts) < 3 {
+			continue
+		}
+		p.MoveTo(pts[0].f32())
+		for _, pt := range pts[1:] {
+			p.LineTo(pt.f32())
+		}
+		p.Close()
+	}
+	return p.End()
+}
+
+func toGrid(p f32.Point) gridPt {
+	return gridPt{X: toGridCoord(p.X), Y: toGridCoord(p.Y)}
+}
+
+func toGridCoord(v float32) int64 {
+	c := math.Round(float64(v) * gridScale)
+	switch {
+	case c > gridMax:
+		c = gridMax
+	case c < -gridMax:
+		c = -gridMax
+	case c != c:
+		c = 0
+	}
+	return int64(c)
+}
+
+func (p gridPt) f32() f32.Point {
+	return f32.Pt(float32(p.X)/gridScale, float32(p.Y)/gridScale)
+}
+
+func (p gridPt) swap() gridPt {
+	return gridPt{X: p.Y, Y: p.X}
+}
+
+func (p gridPt) sub(q gridPt) gridPt {
+	return gridPt{X: p.X - q.X, Y: p.Y - q.Y}
+}
+
+func (p gridPt) dot(q gridPt) int64 {
+	return p.X*q.X + p.Y*q.Y
+}
+
+func (p gridPt) less(q gridPt) bool {
+	return p.X < q.X || p.X == q.X && p.Y < q.Y
+}
+
+func (e edge) minX() int64 {
+	return min64(e.from.X, e.to.X)
+}
+
+// orient returns a positive value if c is to the left of the line
+// from a to b, negative if c is to the right, and zero if the points
+// are collinear.
+func orient(a, b, c gridPt) int64 {
+	return (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+}
+
+// between reports whether c, collinear with a and b, is strictly
+// between them.
+func between(a, b, c gridPt) bool {
+	if c == a || c == b {
+		return false
+	}
+	return min64(a.X, b.X) <= c.X && c.X <= max64(a.X, b.X) &&
+		min64(a.Y, b.Y) <= c.Y && c.Y <= max64(a.Y, b.Y)
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
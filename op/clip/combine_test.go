@@ -0,0 +1,159 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package clip
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+)
+
+func TestCombine(t *testing.T) {
+	ops := new(op.Ops)
+	square := func(min, max float32, ccw bool) PathSpec {
+		var p Path
+		p.Begin(ops)
+		p.MoveTo(f32.Pt(min, min))
+		if ccw {
+			p.LineTo(f32.Pt(min, max))
+			p.LineTo(f32.Pt(max, max))
+			p.LineTo(f32.Pt(max, min))
+		} else {
+			p.LineTo(f32.Pt(max, min))
+			p.LineTo(f32.Pt(max, max))
+			p.LineTo(f32.Pt(min, max))
+		}
+		p.Close()
+		return p.End()
+	}
+	a := Outline{Path: square(0, 20, false)}
+	b := Outline{Path: Rect(image.Rect(10, 10, 30, 30)).Path()}
+	in := func(x, y float32) func(f32.Point) bool {
+		return func(p f32.Point) bool { return p.X > x && p.X < x+20 && p.Y > y && p.Y < y+20 }
+	}
+	inA, inB := in(0, 0), in(10, 10)
+	tests := []struct {
+		name   string
+		mode   PathOp
+		a, b   Outline
+		inside func(p f32.Point) bool
+		area   float32
+	}{
+		{"union", PathUnion, a, b, func(p f32.Point) bool { return inA(p) || inB(p) }, 700},
+		{"intersect", PathIntersect, a, b, func(p f32.Point) bool { return inA(p) && inB(p) }, 100},
+		{"difference", PathDifference, a, b, func(p f32.Point) bool { return inA(p) && !inB(p) }, 300},
+		{"xor", PathXor, a, b, func(p f32.Point) bool { return inA(p) != inB(p) }, 600},
+		{"empty", PathUnion, a, Outline{}, inA, 400},
+		{"disjoint", PathIntersect, a, Outline{Path: square(25, 30, false)}, func(f32.Point) bool { return false }, 0},
+		{
+			// The operands fill separately, so the opposite direction
+			// of the inner square doesn't cut a hole.
+			"nested", PathUnion,
+			Outline{Path: square(0, 20, false)}, Outline{Path: square(5, 15, true)},
+			func(p f32.Point) bool { return inA(p) }, 400,
+		},
+	}
+	for _, test := range tests {
+		res := Combine(ops, test.mode, test.a, test.b)
+		for y := float32(-1.5); y < 32; y += 2 {
+			for x := float32(-1.5); x < 32; x += 2 {
+				p := f32.Pt(x, y)
+				w := windingAt(res, p)
+				if exp := test.inside(p); (w != 0) != exp || w != 0 && w != 1 && w != -1 {
+					t.Errorf("%s: winding %d at %v, expected inside %v", test.name, w, p, exp)
+				}
+			}
+		}
+		if area := pathArea(res); math.Abs(float64(area-test.area)) > 1e-3 {
+			t.Errorf("%s: area %v, expected %v", test.name, area, test.area)
+		}
+	}
+}
+
+func TestCombineCurves(t *testing.T) {
+	ops := new(op.Ops)
+	a := Outline{Path: Ellipse(image.Rect(0, 0, 40, 40)).Path(ops)}
+	b := Outline{Path: Ellipse(image.Rect(20, 0, 60, 40)).Path(ops)}
+	res := Combine(ops, PathIntersect, a, b)
+	// The lens of two circles of radius r with centers r apart.
+	r := 20.0
+	exp := 2*r*r*math.Acos(0.5) - r*r*math.Sqrt(3)/2
+	// Flattening the curves loses up to about 2/3 of the flatness
+	// times the perimeter.
+	if area := float64(pathArea(res)); math.Abs(area-exp) > 2 {
+		t.Errorf("area %v, expected %v", area, exp)
+	}
+	if w := windingAt(res, f32.Pt(30, 20)); w == 0 {
+		t.Errorf("center of the lens is outside")
+	}
+	if w := windingAt(res, f32.Pt(10, 20)); w != 0 {
+		t.Errorf("center of a circle is inside")
+	}
+}
+
+func TestEvenOdd(t *testing.T) {
+	ops := new(op.Ops)
+	// Two contours in the same direction, overlapping in a square
+	// they wind around twice.
+	var p Path
+	p.Begin(ops)
+	for _, off := range []float32{0, 10} {
+		p.MoveTo(f32.Pt(off, off))
+		p.LineTo(f32.Pt(off+20, off))
+		p.LineTo(f32.Pt(off+20, off+20))
+		p.LineTo(f32.Pt(off, off+20))
+		p.Close()
+	}
+	spec := p.End()
+	if w := windingAt(spec, f32.Pt(15, 15)); w != 2 && w != -2 {
+		t.Fatalf("winding %d in the overlap, expected 2", w)
+	}
+	nonZero := Combine(ops, PathUnion, Outline{Path: spec}, Outline{})
+	if w := windingAt(nonZero, f32.Pt(15, 15)); w == 0 {
+		t.Errorf("overlap is outside by the non-zero rule")
+	}
+	evenOdd := Combine(ops, PathUnion, Outline{Path: spec, FillRule: EvenOdd}, Outline{})
+	if w := windingAt(evenOdd, f32.Pt(15, 15)); w != 0 {
+		t.Errorf("overlap is inside by the even-odd rule")
+	}
+	if w := windingAt(evenOdd, f32.Pt(5, 5)); w == 0 {
+		t.Errorf("(5,5) is outside by the even-odd rule")
+	}
+	if area := pathArea(evenOdd); area != 600 {
+		t.Errorf("even-odd area %v, expected 600", area)
+	}
+}
+
+// windingAt returns the winding number of spec around p.
+func windingAt(spec PathSpec, pt f32.Point) int {
+	w := 0
+	for _, e := range spec.appendEdges(nil, 0) {
+		a, b := e.from.f32(), e.to.f32()
+		if (a.Y <= pt.Y) == (b.Y <= pt.Y) {
+			continue
+		}
+		x := a.X + (pt.Y-a.Y)/(b.Y-a.Y)*(b.X-a.X)
+		if x <= pt.X {
+			continue
+		}
+		if b.Y > a.Y {
+			w++
+		} else {
+			w--
+		}
+	}
+	return w
+}
+
+// pathArea returns the absolute area enclosed by the contours of spec.
+func pathArea(spec PathSpec) float32 {
+	var area float64
+	for _, e := range spec.appendEdges(nil, 0) {
+		a, b := e.from.f32(), e.to.f32()
+		area += float64(a.X*b.Y - b.X*a.Y)
+	}
+	return float32(math.Abs(area / 2))
+}
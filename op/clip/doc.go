@@ -9,6 +9,7 @@ to the intersection of the current clip and pushed clip area. Popping the
 area restores the clip to its state before pushing.
 
 General clipping areas are constructed with Path. Common cases such as
-rectangular clip areas also exist as convenient constructors.
+rectangular clip areas also exist as convenient constructors. Paths in the
+path data format of SVG are parsed by Path.AppendSVG and SVGPath.
 */
 package clip
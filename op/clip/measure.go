@@ -0,0 +1,489 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package clip
+
+import (
+	"math"
+	"sort"
+
+	"gioui.org/f32"
+	"gioui.org/internal/ops"
+	"gioui.org/internal/scene"
+	"gioui.org/op"
+)
+
+// PathMeasure answers geometric queries about a path, for hit testing
+// and for placing content along the path. The queries are computed on
+// the CPU, in the coordinates of the path.
+//
+// Measuring a path is expensive compared to building it. Measure
+// paths once and reuse the PathMeasure for repeated queries.
+type PathMeasure struct {
+	segs []measureSeg
+	// lengths are the accumulated lengths of the segments, excluding
+	// gaps.
+	lengths []float64
+}
+
+// measureSeg is a segment of a measured path, represented by the
+// control points of a cubic Bézier curve.
+type measureSeg struct {
+	p [4]vec2
+	// gap marks the implicit segments that close contours. Gaps
+	// bound the area of a path but are not part of its outline.
+	gap  bool
+	line bool
+}
+
+type vec2 struct {
+	X, Y float64
+}
+
+// Measure returns a PathMeasure for p.
+func (p PathSpec) Measure() PathMeasure {
+	var m PathMeasure
+	p.decode(m.add)
+	m.accumulate()
+	return m
+}
+
+// Measure returns a PathMeasure for the path constructed so far. The
+// current contour is measured as if it were ended by End.
+func (p *Path) Measure() PathMeasure {
+	var m PathMeasure
+	if p.ops == nil {
+		return m
+	}
+	data, _ := ops.Contents(p.ops)
+	decodeCommands(data[p.dataStart:], m.add)
+	if p.pen != p.start {
+		m.add(scene.Gap(p.pen, p.start))
+	}
+	m.accumulate()
+	return m
+}
+
+// decode calls f with each command of p.
+func (p PathSpec) decode(f func(cmd scene.Command)) {
+	if !p.hasSegments {
+		if p.shape != ops.Rect || p.bounds.Empty() {
+			return
+		}
+		b := p.bounds
+		corners := [...]f32.Point{
+			f32.Pt(float32(b.Min.X), float32(b.Min.Y)),
+			f32.Pt(float32(b.Max.X), float32(b.Min.Y)),
+			f32.Pt(float32(b.Max.X), float32(b.Max.Y)),
+			f32.Pt(float32(b.Min.X), float32(b.Max.Y)),
+		}
+		for i, c := range corners {
+			f(scene.Line(c, corners[(i+1)%len(corners)]))
+		}
+		return
+	}
+	var o op.Ops
+	p.spec.Add(&o)
+	var r ops.Reader
+	r.Reset(&o.Internal)
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		if ops.OpType(encOp.Data[0]) != ops.TypeAux {
+			continue
+		}
+		decodeCommands(encOp.Data[ops.TypeAuxLen:], f)
+	}
+}
+
+// decodeCommands calls f with each command of the path data in data.
+func decodeCommands(data []byte, f func(cmd scene.Command)) {
+	for len(data) >= scene.CommandSize+4 {
+		f(ops.DecodeCommand(data[4:]))
+		data = data[scene.CommandSize+4:]
+	}
+}
+
+func (m *PathMeasure) add(cmd scene.Command) {
+	var s measureSeg
+	switch cmd.Op() {
+	case scene.OpLine, scene.OpGap:
+		var from, to f32.Point
+		if cmd.Op() == scene.OpGap {
+			from, to = scene.DecodeGap(cmd)
+			s.gap = true
+		} else {
+			from, to = scene.DecodeLine(cmd)
+		}
+		a, b := toVec2(from), toVec2(to)
+		s.p = [4]vec2{a, a.lerp(b, 1.0/3), a.lerp(b, 2.0/3), b}
+		s.line = true
+	case scene.OpQuad:
+		from, ctrl, to := scene.DecodeQuad(cmd)
+		a, c, b := toVec2(from), toVec2(ctrl), toVec2(to)
+		s.p = [4]vec2{a, a.lerp(c, 2.0/3), b.lerp(c, 2.0/3), b}
+	case scene.OpCubic:
+		from, ctrl0, ctrl1, to := scene.DecodeCubic(cmd)
+		s.p = [4]vec2{toVec2(from), toVec2(ctrl0), toVec2(ctrl1), toVec2(to)}
+	default:
+		return
+	}
+	if s.gap && s.p[0] == s.p[3] {
+		return
+	}
+	m.segs = append(m.segs, s)
+}
+
+func (m *PathMeasure) accumulate() {
+	m.lengths = make([]float64, len(m.segs))
+	l := 0.0
+	for i, s := range m.segs {
+		if !s.gap {
+			l += s.length(1)
+		}
+		m.lengths[i] = l
+	}
+}
+
+// Bounds returns the smallest rectangle that contains the path,
+// including the curves between the control points. The rectangle is
+// represented by its minimum and maximum points, and is empty for
+// paths without segments.
+func (m PathMeasure) Bounds() (min, max f32.Point) {
+	if len(m.segs) == 0 {
+		return
+	}
+	lo := vec2{math.Inf(1), math.Inf(1)}
+	hi := vec2{math.Inf(-1), math.Inf(-1)}
+	expand := func(p vec2) {
+		lo.X, lo.Y = math.Min(lo.X, p.X), math.Min(lo.Y, p.Y)
+		hi.X, hi.Y = math.Max(hi.X, p.X), math.Max(hi.Y, p.Y)
+	}
+	for _, s := range m.segs {
+		expand(s.p[0])
+		expand(s.p[3])
+		if s.line {
+			continue
+		}
+		// The curve is extreme where its derivative is zero.
+		for axis := 0; axis < 2; axis++ {
+			for _, t := range s.extrema(axis) {
+				expand(s.point(t))
+			}
+		}
+	}
+	return lo.f32(), hi.f32()
+}
+
+// Contains reports whether pt is inside the path according to rule.
+func (m PathMeasure) Contains(pt f32.Point, rule FillRule) bool {
+	w := m.winding(toVec2(pt))
+	if rule == EvenOdd {
+		return w%2 != 0
+	}
+	return w != 0
+}
+
+// winding returns the winding number of the path around p, from the
+// segments crossed by a ray from p in the direction of the x axis.
+func (m PathMeasure) winding(p vec2) int {
+	w := 0
+	for _, s := range m.segs {
+		if math.Max(math.Max(s.p[0].X, s.p[1].X), math.Max(s.p[2].X, s.p[3].X)) <= p.X {
+			continue
+		}
+		// Split the segment into parts monotonic in y, and find the
+		// crossing of each part by bisection.
+		ts := append([]float64{0}, s.extrema(1)...)
+		ts = append(ts, 1)
+		for i := 0; i < len(ts)-1; i++ {
+			t0, t1 := ts[i], ts[i+1]
+			a, b := s.point(t0), s.point(t1)
+			if (a.Y <= p.Y) == (b.Y <= p.Y) {
+				continue
+			}
+			up := b.Y > a.Y
+			for j := 0; j < 50; j++ {
+				t := (t0 + t1) / 2
+				if (s.point(t).Y <= p.Y) == up {
+					t0 = t
+				} else {
+					t1 = t
+				}
+			}
+			if s.point((t0+t1)/2).X <= p.X {
+				continue
+			}
+			if up {
+				w++
+			} else {
+				w--
+			}
+		}
+	}
+	return w
+}
+
+// Distance returns the distance from pt to the nearest point on the
+// outline of the path, the line that a stroke of the path follows.
+func (m PathMeasure) Distance(pt f32.Point) float32 {
+	p := toVec2(pt)
+	d := math.Inf(1)
+	for _, s := range m.segs {
+		if s.gap {
+			continue
+		}
+		d = math.Min(d, s.distance(p))
+	}
+	return float32(d)
+}
+
+// Length returns the length of the outline of the path.
+func (m PathMeasure) Length() float32 {
+	if len(m.lengths) == 0 {
+		return 0
+	}
+	return float32(m.lengths[len(m.lengths)-1])
+}
+
+// PointAt returns the point at the distance length along the outline
+// of the path, and the unit tangent in the direction of the path at
+// that point. Lengths outside the outline are clamped to its ends.
+func (m PathMeasure) PointAt(length float32) (pt, tangent f32.Point) {
+	if len(m.segs) == 0 {
+		return
+	}
+	l := float64(length)
+	i := sort.Search(len(m.segs), func(i int) bool {
+		return m.lengths[i] >= l
+	})
+	for i < len(m.segs) && m.segs[i].gap {
+		i++
+	}
+	if i == len(m.segs) {
+		// Clamp to the end of the last segment of the outline.
+		for i = len(m.segs) - 1; i > 0 && m.segs[i].gap; i-- {
+		}
+		s := m.segs[i]
+		return s.p[3].f32(), s.tangent(1).f32()
+	}
+	s := m.segs[i]
+	rem := l
+	if i > 0 {
+		rem -= m.lengths[i-1]
+	}
+	// Find the parameter at the remaining length by bisection.
+	t0, t1 := 0.0, 1.0
+	for j := 0; j < 40 && rem > 0; j++ {
+		t := (t0 + t1) / 2
+		if s.length(t) < rem {
+			t0 = t
+		} else {
+			t1 = t
+		}
+	}
+	t := (t0 + t1) / 2
+	if rem <= 0 {
+		t = 0
+	}
+	return s.point(t).f32(), s.tangent(t).f32()
+}
+
+// point returns the point of s at t.
+func (s measureSeg) point(t float64) vec2 {
+	mt := 1 - t
+	a, b, c, d := mt*mt*mt, 3*mt*mt*t, 3*mt*t*t, t*t*t
+	return vec2{
+		X: a*s.p[0].X + b*s.p[1].X + c*s.p[2].X + d*s.p[3].X,
+		Y: a*s.p[0].Y + b*s.p[1].Y + c*s.p[2].Y + d*s.p[3].Y,
+	}
+}
+
+// deriv returns the derivative of s at t.
+func (s measureSeg) deriv(t float64) vec2 {
+	mt := 1 - t
+	a, b, c := 3*mt*mt, 6*mt*t, 3*t*t
+	return vec2{
+		X: a*(s.p[1].X-s.p[0].X) + b*(s.p[2].X-s.p[1].X) + c*(s.p[3].X-s.p[2].X),
+		Y: a*(s.p[1].Y-s.p[0].Y) + b*(s.p[2].Y-s.p[1].Y) + c*(s.p[3].Y-s.p[2].Y),
+	}
+}
+
+// tangent returns the unit tangent of s at t.
+func (s measureSeg) tangent(t float64) vec2 {
+	d := s.deriv(t)
+	if d.len() < 1e-9 {
+		// The derivative vanishes at end points that coincide with
+		// their control points. Use the direction to the nearest
+		// distinct control point instead.
+		from, to := s.p[0], s.p[3]
+		if t < .5 {
+			for _, c := range s.p[1:] {
+				if to = c; c != from {
+					break
+				}
+			}
+		} else {
+			for i := 2; i >= 0; i-- {
+				if from = s.p[i]; from != to {
+					break
+				}
+			}
+		}
+		d = to.sub(from)
+	}
+	l := d.len()
+	if l == 0 {
+		return vec2{}
+	}
+	return vec2{d.X / l, d.Y / l}
+}
+
+// extrema returns the parameters in (0, 1) where the derivative of s
+// along the axis is zero, in increasing order.
+func (s measureSeg) extrema(axis int) []float64 {
+	c := func(p vec2) float64 {
+		if axis == 0 {
+			return p.X
+		}
+		return p.Y
+	}
+	p0, p1, p2, p3 := c(s.p[0]), c(s.p[1]), c(s.p[2]), c(s.p[3])
+	// The derivative is the quadratic a*t² + b*t + c.
+	a := 3 * (-p0 + 3*p1 - 3*p2 + p3)
+	b := 6 * (p0 - 2*p1 + p2)
+	cc := 3 * (p1 - p0)
+	var roots []float64
+	add := func(t float64) {
+		if t > 0 && t < 1 {
+			roots = append(roots, t)
+		}
+	}
+	const eps = 1e-12
+	if math.Abs(a) < eps {
+		if math.Abs(b) > eps {
+			add(-cc / b)
+		}
+		return roots
+	}
+	disc := b*b - 4*a*cc
+	if disc < 0 {
+		return nil
+	}
+	sq := math.Sqrt(disc)
+	t0, t1 := (-b-sq)/(2*a), (-b+sq)/(2*a)
+	if t0 > t1 {
+		t0, t1 = t1, t0
+	}
+	add(t0)
+	if t1 != t0 {
+		add(t1)
+	}
+	return roots
+}
+
+// gaussLegendre are the nodes and weights of the 5 point
+// Gauss-Legendre quadrature on [-1, 1].
+var gaussLegendre = [5][2]float64{
+	{0, 0.5688888888888889},
+	{-0.5384693101056831, 0.4786286704993665},
+	{0.5384693101056831, 0.4786286704993665},
+	{-0.9061798459386640, 0.2369268850561891},
+	{0.9061798459386640, 0.2369268850561891},
+}
+
+// length returns the length of s from 0 to t.
+func (s measureSeg) length(t float64) float64 {
+	if s.line {
+		return s.p[3].sub(s.p[0]).len() * t
+	}
+	// Integrate the speed of the curve in intervals short enough for
+	// the quadrature.
+	n := int(math.Ceil(s.p[1].sub(s.p[0]).len()+s.p[2].sub(s.p[1]).len()+s.p[3].sub(s.p[2]).len()) / 4)
+	if n < 4 {
+		n = 4
+	}
+	if n > 64 {
+		n = 64
+	}
+	l := 0.0
+	h := t / float64(n)
+	for i := 0; i < n; i++ {
+		mid := (float64(i) + .5) * h
+		for _, g := range gaussLegendre {
+			l += g[1] * s.deriv(mid+g[0]*h/2).len()
+		}
+	}
+	return l * h / 2
+}
+
+// distance returns the distance from p to s.
+func (s measureSeg) distance(p vec2) float64 {
+	if s.line {
+		a, b := s.p[0], s.p[3]
+		d := b.sub(a)
+		t := 0.0
+		if l2 := d.dot(d); l2 > 0 {
+			t = math.Max(0, math.Min(1, p.sub(a).dot(d)/l2))
+		}
+		return a.lerp(b, t).sub(p).len()
+	}
+	// Sample the curve for the nearest point and refine the sample
+	// with Newton's method.
+	const samples = 32
+	best, bestT := math.Inf(1), 0.0
+	for i := 0; i <= samples; i++ {
+		t := float64(i) / samples
+		if d := s.point(t).sub(p).len(); d < best {
+			best, bestT = d, t
+		}
+	}
+	t := bestT
+	for i := 0; i < 8; i++ {
+		// Minimize |B(t)-p|² by finding the zero of its derivative,
+		// (B(t)-p)·B'(t).
+		q := s.point(t).sub(p)
+		d1 := s.deriv(t)
+		d2 := s.deriv2(t)
+		f := q.dot(d1)
+		df := d1.dot(d1) + q.dot(d2)
+		if df == 0 {
+			break
+		}
+		t = math.Max(0, math.Min(1, t-f/df))
+		if d := s.point(t).sub(p).len(); d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// deriv2 returns the second derivative of s at t.
+func (s measureSeg) deriv2(t float64) vec2 {
+	mt := 1 - t
+	return vec2{
+		X: 6 * (mt*(s.p[2].X-2*s.p[1].X+s.p[0].X) + t*(s.p[3].X-2*s.p[2].X+s.p[1].X)),
+		Y: 6 * (mt*(s.p[2].Y-2*s.p[1].Y+s.p[0].Y) + t*(s.p[3].Y-2*s.p[2].Y+s.p[1].Y)),
+	}
+}
+
+func toVec2(p f32.Point) vec2 {
+	return vec2{float64(p.X), float64(p.Y)}
+}
+
+func (v vec2) f32() f32.Point {
+	return f32.Pt(float32(v.X), float32(v.Y))
+}
+
+func (v vec2) sub(w vec2) vec2 {
+	return vec2{v.X - w.X, v.Y - w.Y}
+}
+
+func (v vec2) dot(w vec2) float64 {
+	return v.X*w.X + v.Y*w.Y
+}
+
+func (v vec2) len() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+func (v vec2) lerp(w vec2, t float64) vec2 {
+	return vec2{v.X + (w.X-v.X)*t, v.Y + (w.Y-v.Y)*t}
+}
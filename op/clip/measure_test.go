@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package clip
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+)
+
+func TestMeasureBounds(t *testing.T) {
+	ops := new(op.Ops)
+	var p Path
+	p.Begin(ops)
+	p.MoveTo(f32.Pt(0, 0))
+	// The control point is far outside the curve, which peaks at y=5.
+	p.QuadTo(f32.Pt(5, 10), f32.Pt(10, 0))
+	p.CubeTo(f32.Pt(10, -8), f32.Pt(0, -8), f32.Pt(0, 0))
+	m := p.End().Measure()
+	min, max := m.Bounds()
+	if !approx(min, f32.Pt(0, -6)) || !approx(max, f32.Pt(10, 5)) {
+		t.Errorf("got bounds %v-%v, expected (0,-6)-(10,5)", min, max)
+	}
+	min, max = Rect(image.Rect(1, 2, 3, 4)).Path().Measure().Bounds()
+	if min != f32.Pt(1, 2) || max != f32.Pt(3, 4) {
+		t.Errorf("got rectangle bounds %v-%v", min, max)
+	}
+	var empty PathSpec
+	if min, max := empty.Measure().Bounds(); min != (f32.Point{}) || max != (f32.Point{}) {
+		t.Errorf("got empty bounds %v-%v", min, max)
+	}
+}
+
+func TestMeasureContains(t *testing.T) {
+	ops := new(op.Ops)
+	m := Ellipse(image.Rect(0, 0, 20, 20)).Path(ops).Measure()
+	tests := []struct {
+		pt     f32.Point
+		inside bool
+	}{
+		{f32.Pt(10, 10), true},
+		{f32.Pt(1, 10), true},
+		{f32.Pt(2, 2), false},
+		{f32.Pt(-1, 10), false},
+		{f32.Pt(10, 19.5), true},
+		{f32.Pt(10, 20.5), false},
+	}
+	for _, test := range tests {
+		if got := m.Contains(test.pt, NonZero); got != test.inside {
+			t.Errorf("Contains(%v) = %v, expected %v", test.pt, got, test.inside)
+		}
+	}
+
+	// Two overlapping contours in the same direction.
+	var p Path
+	p.Begin(ops)
+	for _, off := range []float32{0, 10} {
+		p.MoveTo(f32.Pt(off, off))
+		p.LineTo(f32.Pt(off+20, off))
+		p.LineTo(f32.Pt(off+20, off+20))
+		p.LineTo(f32.Pt(off, off+20))
+	}
+	// Measure the path under construction, with its last contour open.
+	m = p.Measure()
+	overlap := f32.Pt(15, 15)
+	if !m.Contains(overlap, NonZero) || m.Contains(overlap, EvenOdd) {
+		t.Errorf("overlap of contours: got non-zero %v, even-odd %v", m.Contains(overlap, NonZero), m.Contains(overlap, EvenOdd))
+	}
+	if !m.Contains(f32.Pt(25, 25), EvenOdd) {
+		t.Errorf("open contour doesn't contain (25,25)")
+	}
+}
+
+func TestMeasureLength(t *testing.T) {
+	ops := new(op.Ops)
+	var p Path
+	p.Begin(ops)
+	p.MoveTo(f32.Pt(0, 0))
+	p.LineTo(f32.Pt(10, 0))
+	p.LineTo(f32.Pt(10, 10))
+	// The gap back to the start is not part of the outline.
+	p.MoveTo(f32.Pt(20, 0))
+	p.Line(f32.Pt(0, 5))
+	m := p.End().Measure()
+	if l := m.Length(); l != 25 {
+		t.Errorf("got length %v, expected 25", l)
+	}
+	tests := []struct {
+		length      float32
+		pt, tangent f32.Point
+	}{
+		{-1, f32.Pt(0, 0), f32.Pt(1, 0)},
+		{5, f32.Pt(5, 0), f32.Pt(1, 0)},
+		{15, f32.Pt(10, 5), f32.Pt(0, 1)},
+		{20, f32.Pt(10, 10), f32.Pt(0, 1)},
+		{22, f32.Pt(20, 2), f32.Pt(0, 1)},
+		{30, f32.Pt(20, 5), f32.Pt(0, 1)},
+	}
+	for _, test := range tests {
+		pt, tan := m.PointAt(test.length)
+		if !approx(pt, test.pt) || !approx(tan, test.tangent) {
+			t.Errorf("PointAt(%v) = %v, %v, expected %v, %v", test.length, pt, tan, test.pt, test.tangent)
+		}
+	}
+
+	// The circumference of a circle.
+	m = Ellipse(image.Rect(0, 0, 20, 20)).Path(ops).Measure()
+	if l, exp := m.Length(), float32(20*math.Pi); math.Abs(float64(l-exp)) > .05 {
+		t.Errorf("got circle length %v, expected %v", l, exp)
+	}
+	// Half way around the circle is the opposite point.
+	start, tan0 := m.PointAt(0)
+	half, tan1 := m.PointAt(m.Length() / 2)
+	if !approx(half, f32.Pt(20-start.X, 20-start.Y)) || !approx(tan1, tan0.Mul(-1)) {
+		t.Errorf("got half way point %v, %v, expected opposite of %v, %v", half, tan1, start, tan0)
+	}
+}
+
+func TestMeasureDistance(t *testing.T) {
+	ops := new(op.Ops)
+	m := Ellipse(image.Rect(0, 0, 20, 20)).Path(ops).Measure()
+	tests := []struct {
+		pt   f32.Point
+		dist float32
+	}{
+		{f32.Pt(10, 10), 10},
+		{f32.Pt(10, -5), 5},
+		{f32.Pt(10+6, 10+8), 0},
+		{f32.Pt(30, 10), 10},
+	}
+	for _, test := range tests {
+		if d := m.Distance(test.pt); math.Abs(float64(d-test.dist)) > .01 {
+			t.Errorf("Distance(%v) = %v, expected %v", test.pt, d, test.dist)
+		}
+	}
+	// The gap closing a contour is not part of the outline.
+	var p Path
+	p.Begin(ops)
+	p.MoveTo(f32.Pt(0, 0))
+	p.LineTo(f32.Pt(10, 0))
+	p.LineTo(f32.Pt(10, 10))
+	m = p.End().Measure()
+	if d := m.Distance(f32.Pt(5, 5)); math.Abs(float64(d-5)) > 1e-4 {
+		t.Errorf("got distance %v to open contour, expected 5", d)
+	}
+}
+
+func approx(p, q f32.Point) bool {
+	const eps = 1e-2
+	return math.Abs(float64(p.X-q.X)) < eps && math.Abs(float64(p.Y-q.Y)) < eps
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package clip
+
+import (
+	"fmt"
+
+	"gioui.org/internal/svgpath"
+	"gioui.org/op"
+)
+
+// SVGPathError describes a syntax error in SVG path data.
+type SVGPathError struct {
+	// Offset is the byte offset of the error in the path data.
+	Offset int
+	Msg    string
+}
+
+func (e *SVGPathError) Error() string {
+	return fmt.Sprintf("clip: invalid SVG path data at offset %d: %s", e.Offset, e.Msg)
+}
+
+// SVGPath is a convenience for constructing a path from the SVG path data
+// in d with AppendSVG.
+func SVGPath(o *op.Ops, d string) (PathSpec, error) {
+	var p Path
+	p.Begin(o)
+	err := p.AppendSVG(d)
+	return p.End(), err
+}
+
+// AppendSVG parses the SVG path data d, such as the d attribute of an SVG
+// path element, and appends its segments to the path. All commands are
+// supported, including relative commands, smooth curves and elliptical
+// arcs.
+//
+// Like SVG renderers, AppendSVG appends the segments up to the first
+// error, which is returned as an *SVGPathError.
+func (p *Path) AppendSVG(d string) error {
+	if err := svgpath.Parse(p, d); err != nil {
+		err := err.(*svgpath.Error)
+		return &SVGPathError{Offset: err.Offset, Msg: err.Msg}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package clip
+
+import (
+	"errors"
+	"image"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/op"
+)
+
+func TestSVGPath(t *testing.T) {
+	tests := []struct {
+		d    string
+		want func(p *Path)
+	}{
+		{"M10 20 L30 40 H50 V60 Z", func(p *Path) {
+			p.MoveTo(f32.Pt(10, 20))
+			p.LineTo(f32.Pt(30, 40))
+			p.LineTo(f32.Pt(50, 40))
+			p.LineTo(f32.Pt(50, 60))
+			p.Close()
+		}},
+		// Relative commands, implicit lineto and compact numbers.
+		{"m10,20 20 20h20v20l-20.5.5e1z", func(p *Path) {
+			p.MoveTo(f32.Pt(10, 20))
+			p.LineTo(f32.Pt(30, 40))
+			p.LineTo(f32.Pt(50, 40))
+			p.LineTo(f32.Pt(50, 60))
+			p.LineTo(f32.Pt(29.5, 65))
+			p.Close()
+		}},
+		// Smooth curves reflect the previous control point.
+		{"M0 0 C10 0 20 10 20 20 S30 40 40 40 s10 10 20 0", func(p *Path) {
+			p.MoveTo(f32.Pt(0, 0))
+			p.CubeTo(f32.Pt(10, 0), f32.Pt(20, 10), f32.Pt(20, 20))
+			p.CubeTo(f32.Pt(20, 30), f32.Pt(30, 40), f32.Pt(40, 40))
+			p.CubeTo(f32.Pt(50, 40), f32.Pt(50, 50), f32.Pt(60, 40))
+		}},
+		{"M0 0 Q10 0 10 10 T20 20 t10 10", func(p *Path) {
+			p.MoveTo(f32.Pt(0, 0))
+			p.QuadTo(f32.Pt(10, 0), f32.Pt(10, 10))
+			p.QuadTo(f32.Pt(10, 20), f32.Pt(20, 20))
+			p.QuadTo(f32.Pt(30, 20), f32.Pt(30, 30))
+		}},
+		// Smooth curves without a preceding curve use the pen as control
+		// point.
+		{"M0 0 L10 0 S20 10 20 20 T30 30", func(p *Path) {
+			p.MoveTo(f32.Pt(0, 0))
+			p.LineTo(f32.Pt(10, 0))
+			p.CubeTo(f32.Pt(10, 0), f32.Pt(20, 10), f32.Pt(20, 20))
+			p.QuadTo(f32.Pt(20, 20), f32.Pt(30, 30))
+		}},
+		// Subpaths after closepath start at the closed subpath.
+		{"M10 10 h10 v10 z l5 5", func(p *Path) {
+			p.MoveTo(f32.Pt(10, 10))
+			p.LineTo(f32.Pt(20, 10))
+			p.LineTo(f32.Pt(20, 20))
+			p.Close()
+			p.LineTo(f32.Pt(15, 15))
+		}},
+		// Arcs with a zero radius are lines.
+		{"M0 0 A0 10 0 0 1 10 10", func(p *Path) {
+			p.MoveTo(f32.Pt(0, 0))
+			p.LineTo(f32.Pt(10, 10))
+		}},
+	}
+	for _, test := range tests {
+		o := new(op.Ops)
+		got, err := SVGPath(o, test.d)
+		if err != nil {
+			t.Errorf("%q: %v", test.d, err)
+			continue
+		}
+		var p Path
+		p.Begin(o)
+		test.want(&p)
+		want := p.End()
+		if got.hash != want.hash || got.bounds != want.bounds {
+			t.Errorf("%q: path doesn't match the equivalent Path commands", test.d)
+		}
+	}
+}
+
+func TestSVGPathArc(t *testing.T) {
+	tests := []struct {
+		d      string
+		end    f32.Point
+		bounds image.Rectangle
+	}{
+		{"M0 0 A10 10 0 0 1 20 0", f32.Pt(20, 0), image.Rect(0, -10, 20, 0)},
+		{"M0 0 A10 10 0 0 0 20 0", f32.Pt(20, 0), image.Rect(0, 0, 20, 10)},
+		// The large arc of a circle through the end points.
+		{"M0 0 a10 10 0 1 1 10 -10", f32.Pt(10, -10), image.Rect(-10, -20, 10, 0)},
+		// Radii too small to reach the end point are scaled up.
+		{"M0 0 A1 1 0 0 1 20 0", f32.Pt(20, 0), image.Rect(0, -10, 20, 0)},
+		// A rotated ellipse.
+		{"M0 0 A20 10 90 0 1 0 40", f32.Pt(0, 40), image.Rect(0, 0, 10, 40)},
+	}
+	for _, test := range tests {
+		o := new(op.Ops)
+		var p Path
+		p.Begin(o)
+		if err := p.AppendSVG(test.d); err != nil {
+			t.Errorf("%q: %v", test.d, err)
+			continue
+		}
+		pos := p.Pos()
+		spec := p.End()
+		// Allow for rounding of the bounds.
+		if !near(spec.bounds, test.bounds) {
+			t.Errorf("%q: got bounds %v, expected %v", test.d, spec.bounds, test.bounds)
+		}
+		if pos != test.end {
+			t.Errorf("%q: got end point %v, expected %v", test.d, pos, test.end)
+		}
+	}
+}
+
+func near(r1, r2 image.Rectangle) bool {
+	d := func(a, b int) bool { return a-b <= 1 && b-a <= 1 }
+	return d(r1.Min.X, r2.Min.X) && d(r1.Min.Y, r2.Min.Y) && d(r1.Max.X, r2.Max.X) && d(r1.Max.Y, r2.Max.Y)
+}
+
+func TestSVGPathError(t *testing.T) {
+	tests := []struct {
+		d      string
+		offset int
+	}{
+		{"L10 10", 0},
+		{"M10", 3},
+		{"M10 10 L20 x", 11},
+		{"M10 10 Z 5", 9},
+		{"M0 0 A10 10 0 2 1 20 0", 14},
+		{"M0 0 C1 2 3 4 5", 15},
+	}
+	for _, test := range tests {
+		_, err := SVGPath(new(op.Ops), test.d)
+		var perr *SVGPathError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: got error %v, expected an SVGPathError", test.d, err)
+			continue
+		}
+		if perr.Offset != test.offset {
+			t.Errorf("%q: got error at offset %d, expected %d: %v", test.d, perr.Offset, test.offset, err)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package dump serializes frames of operations to files, for attaching
+the output of a program to bug reports and replaying it in tests.
+
+A frame is captured by encoding the operation list before passing it
+to the window:
+
+	case system.FrameEvent:
+		gtx := layout.NewContext(&ops, e)
+		...
+		if capture {
+			dump.Encode(f, dump.Frame{Size: e.Size, Ops: gtx.Ops})
+		}
+		e.Frame(gtx.Ops)
+
+The encoding preserves the operations along with the images, path
+data and macros they refer to. Decode rebuilds an equivalent operation
+list that renders identically to the original.
+
+Input operations refer to program values such as event handler tags
+that can't be encoded. Decode replaces them with placeholder values, so
+decoded frames are meant for rendering, not for processing input.
+Shader programs are replaced likewise, and decoded ShaderOps paint
+nothing.
+
+The command gioui.org/cmd/renderframe renders a dump file to a PNG
+image.
+*/
+package dump
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"reflect"
+	"sort"
+
+	"gioui.org/internal/ops"
+	"gioui.org/op"
+)
+
+// Frame is a frame of operations.
+type Frame struct {
+	// Size is the size of the window that displayed the frame.
+	Size image.Point
+	Ops  *op.Ops
+}
+
+// magic identifies dump files.
+const magic = "gioframe"
+
+// Version is the version of the encoding. Files written by a different
+// version are rejected by Decode.
+//
+// The version must be incremented whenever the encoding of operations
+// changes.
+const Version = 5
+
+// file is the encoded form of a Frame.
+type file struct {
+	Size image.Point
+	// Ops are the operation lists of the frame. The first list is the
+	// root list, the others are the targets of macros.
+	Ops    []opList
+	Images []imageData
+}
+
+type opList struct {
+	Data []byte
+	Refs []ref
+}
+
+// ref is an encoded operation reference.
+type ref struct {
+	Kind refKind
+	// Index is the index of the operation list, image or value of
+	// kinds refOps, refImage, refHandle and refPlaceholder.
+	Index  int
+	String string
+	Floats []float32
+}
+
+type imageData struct {
+	Rect image.Rectangle
+	// Pix are the pixels of the image, without padding between rows.
+	Pix []byte
+}
+
+type refKind uint8
+
+const (
+	refNil refKind = iota
+	refOps
+	refImage
+	refHandle
+	refString
+	refFloats
+	refPlaceholder
+)
+
+// placeholder replaces references that can't be encoded.
+type placeholder struct {
+	// index makes every placeholder distinct.
+	index int
+}
+
+type encoder struct {
+	f       file
+	ops     map[*ops.Ops]int
+	images  map[*image.RGBA]int
+	values  map[interface{}]int
+	nvalues int
+}
+
+// Encode writes the frame f to w.
+func Encode(w io.Writer, f Frame) error {
+	if f.Ops == nil {
+		return errors.New("dump: nil operation list")
+	}
+	e := &encoder{
+		f:      file{Size: f.Size},
+		ops:    make(map[*ops.Ops]int),
+		images: make(map[*image.RGBA]int),
+		values: make(map[interface{}]int),
+	}
+	e.addOps(&f.Ops.Internal)
+	bw := bufio.NewWriter(w)
+	if _, err := io.WriteString(bw, magic); err != nil {
+		return err
+	}
+	if err := binary.Write(bw, binary.LittleEndian, uint32(Version)); err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(bw).Encode(&e.f); err != nil {
+		return fmt.Errorf("dump: %w", err)
+	}
+	return bw.Flush()
+}
+
+// addOps adds the operation list o and the lists it refers to, and
+// returns its index.
+func (e *encoder) addOps(o *ops.Ops) int {
+	if idx, ok := e.ops[o]; ok {
+		return idx
+	}
+	idx := len(e.f.Ops)
+	e.ops[o] = idx
+	e.f.Ops = append(e.f.Ops, opList{})
+	data, refs := ops.Contents(o)
+	list := opList{
+		Data: append([]byte(nil), data...),
+		Refs: make([]ref, len(refs)),
+	}
+	for i, r := range refs {
+		list.Refs[i] = e.ref(r)
+	}
+	e.f.Ops[idx] = list
+	return idx
+}
+
+func (e *encoder) ref(r interface{}) ref {
+	switch r := r.(type) {
+	case nil:
+		return ref{Kind: refNil}
+	case *ops.Ops:
+		return ref{Kind: refOps, Index: e.addOps(r)}
+	case *image.RGBA:
+		if r == nil {
+			return ref{Kind: refNil}
+		}
+		return ref{Kind: refImage, Index: e.addImage(r)}
+	case string:
+		return ref{Kind: refString, String: r}
+	case []float32:
+		return ref{Kind: refFloats, Floats: r}
+	case *int:
+		// Image handles are *int values.
+		return ref{Kind: refHandle, Index: e.value(r)}
+	default:
+		return ref{Kind: refPlaceholder, Index: e.value(r)}
+	}
+}
+
+// value returns an index that is equal for equal values of v.
+func (e *encoder) value(v interface{}) int {
+	if !reflect.TypeOf(v).Comparable() {
+		e.nvalues++
+		return e.nvalues - 1
+	}
+	idx, ok := e.values[v]
+	if !ok {
+		idx = e.nvalues
+		e.nvalues++
+		e.values[v] = idx
+	}
+	return idx
+}
+
+func (e *encoder) addImage(img *image.RGBA) int {
+	if idx, ok := e.images[img]; ok {
+		return idx
+	}
+	b := img.Bounds()
+	d := imageData{
+		Rect: b,
+		Pix:  make([]byte, 0, 4*b.Dx()*b.Dy()),
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(b.Min.X, y):]
+		d.Pix = append(d.Pix, row[:4*b.Dx()]...)
+	}
+	idx := len(e.f.Images)
+	e.images[img] = idx
+	e.f.Images = append(e.f.Images, d)
+	return idx
+}
+
+// Decode reads a frame written by Encode.
+func Decode(r io.Reader) (Frame, error) {
+	br := bufio.NewReader(r)
+	var hdr [len(magic)]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil || string(hdr[:]) != magic {
+		return Frame{}, errors.New("dump: not a frame dump")
+	}
+	var version uint32
+	if err := binary.Read(br, binary.LittleEndian, &version); err != nil {
+		return Frame{}, fmt.Errorf("dump: %w", err)
+	}
+	if version != Version {
+		return Frame{}, fmt.Errorf("dump: unsupported version %d, expected %d", version, Version)
+	}
+	var f file
+	if err := gob.NewDecoder(br).Decode(&f); err != nil {
+		return Frame{}, fmt.Errorf("dump: %w", err)
+	}
+	if len(f.Ops) == 0 {
+		return Frame{}, errors.New("dump: missing operations")
+	}
+	images := make([]*image.RGBA, len(f.Images))
+	for i, d := range f.Images {
+		b := d.Rect
+		if b.Dx() < 0 || b.Dy() < 0 || len(d.Pix) != 4*b.Dx()*b.Dy() {
+			return Frame{}, fmt.Errorf("dump: invalid image %d", i)
+		}
+		images[i] = &image.RGBA{Pix: d.Pix, Stride: 4 * b.Dx(), Rect: b}
+	}
+	lists := make([]*op.Ops, len(f.Ops))
+	for i := range lists {
+		lists[i] = new(op.Ops)
+	}
+	values := make(map[int]interface{})
+	value := func(idx int, v func(idx int) interface{}) interface{} {
+		val, ok := values[idx]
+		if !ok {
+			val = v(idx)
+			values[idx] = val
+		}
+		return val
+	}
+	if err := validate(f.Ops); err != nil {
+		return Frame{}, err
+	}
+	for i, l := range f.Ops {
+		refs := make([]interface{}, len(l.Refs))
+		for j, r := range l.Refs {
+			var v interface{}
+			switch r.Kind {
+			case refNil:
+			case refOps:
+				if r.Index < 0 || r.Index >= len(lists) {
+					return Frame{}, fmt.Errorf("dump: invalid operation list reference %d", r.Index)
+				}
+				v = &lists[r.Index].Internal
+			case refImage:
+				if r.Index < 0 || r.Index >= len(images) {
+					return Frame{}, fmt.Errorf("dump: invalid image reference %d", r.Index)
+				}
+				v = images[r.Index]
+			case refHandle:
+				v = value(r.Index, func(int) interface{} { return new(int) })
+			case refString:
+				v = r.String
+			case refFloats:
+				v = r.Floats
+			case refPlaceholder:
+				v = value(r.Index, func(idx int) interface{} { return &placeholder{index: idx} })
+			default:
+				return Frame{}, fmt.Errorf("dump: invalid reference kind %d", r.Kind)
+			}
+			refs[j] = v
+		}
+		ops.SetContents(&lists[i].Internal, l.Data, refs)
+	}
+	return Frame{Size: f.Size, Ops: lists[0]}, nil
+}
+
+// call is a call operation to be validated, or an image rendered
+// from the whole of its operation list.
+type call struct {
+	list, pc, target int
+	// start and end are the data and reference indices of the
+	// called operations.
+	start, end [2]int
+	// macro is the position of the innermost macro enclosing the
+	// call, or -1. The call is skipped by calls that start after
+	// the macro.
+	macro int
+	image bool
+}
+
+// validate checks the structure of the operation lists, so that the
+// decoded lists are safe to execute.
+func validate(lists []opList) error {
+	// pcs maps the start of every operation, and the end of every list,
+	// to the reference index at that point.
+	pcs := make([]map[int]int, len(lists))
+	var calls []call
+	for i := range lists {
+		var err error
+		pcs[i], calls, err = validateList(lists, i, calls)
+		if err != nil {
+			return err
+		}
+	}
+	for _, c := range calls {
+		target := pcs[c.target]
+		startRefs, ok1 := target[c.start[0]]
+		endRefs, ok2 := target[c.end[0]]
+		if !ok1 || !ok2 || startRefs != c.start[1] || endRefs != c.end[1] || c.start[0] > c.end[0] {
+			return fmt.Errorf("dump: list %d: invalid call at %d", c.list, c.pc)
+		}
+	}
+	return checkCycles(calls)
+}
+
+// checkCycles rejects calls and rendered images that execute
+// themselves, which would execute forever. The calls are ordered by
+// list and position.
+func checkCycles(calls []call) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]uint8, len(calls))
+	var visit func(i int) error
+	visit = func(i int) error {
+		c := calls[i]
+		state[i] = visiting
+		j := sort.Search(len(calls), func(j int) bool {
+			d := calls[j]
+			return d.list > c.target || d.list == c.target && d.pc >= c.start[0]
+		})
+		for ; j < len(calls) && calls[j].list == c.target && calls[j].pc < c.end[0]; j++ {
+			if calls[j].macro >= c.start[0] {
+				// Macros inside the called operations are skipped.
+				continue
+			}
+			switch state[j] {
+			case visiting:
+				if c.image {
+					return fmt.Errorf("dump: list %d: recursive image at %d", c.list, c.pc)
+				}
+				return fmt.Errorf("dump: list %d: recursive call at %d", c.list, c.pc)
+			case unvisited:
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+	for i := range calls {
+		if state[i] == unvisited {
+			if err := visit(i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateList(lists []opList, idx int, calls []call) (map[int]int, []call, error) {
+	bo := binary.LittleEndian
+	l := lists[idx]
+	data := l.Data
+	pcs := make(map[int]int)
+	pc, nrefs := 0, 0
+	// starts and ends track the positions of the enclosing macros.
+	var starts, ends []int
+	for pc < len(data) {
+		for len(ends) > 0 && ends[len(ends)-1] == pc {
+			starts, ends = starts[:len(starts)-1], ends[:len(ends)-1]
+		}
+		macro := -1
+		if len(starts) > 0 {
+			macro = starts[len(starts)-1]
+		}
+		pcs[pc] = nrefs
+		t := ops.OpType(data[pc])
+		size := t.Size()
+		if size == 0 {
+			return nil, nil, fmt.Errorf("dump: list %d: unknown operation %d at %d", idx, t, pc)
+		}
+		if t == ops.TypeAux {
+			// Aux operations extend to the end of their macro.
+			if len(ends) == 0 {
+				return nil, nil, fmt.Errorf("dump: list %d: aux operation outside macro at %d", idx, pc)
+			}
+			size = ends[len(ends)-1] - pc
+		}
+		if pc+size > len(data) || nrefs+t.NumRefs() > len(l.Refs) {
+			return nil, nil, fmt.Errorf("dump: list %d: truncated operation at %d", idx, pc)
+		}
+		enc := data[pc : pc+size]
+		refs := l.Refs[nrefs : nrefs+t.NumRefs()]
+		switch t {
+		case ops.TypeMacro:
+			end := int(bo.Uint32(enc[1:]))
+			if end == 0 {
+				// An incomplete macro.
+				end = len(data)
+			}
+			if end < pc+size || end > len(data) || len(ends) > 0 && end > ends[len(ends)-1] {
+				return nil, nil, fmt.Errorf("dump: list %d: invalid macro at %d", idx, pc)
+			}
+			starts, ends = append(starts, pc), append(ends, end)
+		case ops.TypeCall:
+			r := refs[0]
+			if r.Kind != refOps || r.Index < 0 || r.Index >= len(lists) {
+				return nil, nil, fmt.Errorf("dump: list %d: invalid call at %d", idx, pc)
+			}
+			calls = append(calls, call{
+				list:   idx,
+				pc:     pc,
+				target: r.Index,
+				start:  [2]int{int(bo.Uint32(enc[1:])), int(bo.Uint32(enc[5:]))},
+				end:    [2]int{int(bo.Uint32(enc[9:])), int(bo.Uint32(enc[13:]))},
+				macro:  macro,
+			})
+		case ops.TypeImage:
+			// Images are either pixels or rendered from an operation list.
+			src := refs[0]
+			validSrc := src.Kind == refImage || src.Kind == refOps && src.Index >= 0 && src.Index < len(lists)
+			if k := refs[1].Kind; !validSrc || k != refHandle && k != refNil {
+				return nil, nil, fmt.Errorf("dump: list %d: invalid image at %d", idx, pc)
+			}
+			if src.Kind == refOps {
+				target := lists[src.Index]
+				calls = append(calls, call{
+					list:   idx,
+					pc:     pc,
+					target: src.Index,
+					end:    [2]int{len(target.Data), len(target.Refs)},
+					macro:  macro,
+					image:  true,
+				})
+			}
+		case ops.TypeGradient, ops.TypeSemanticLabel, ops.TypeSemanticDesc:
+			if refs[0].Kind != refString {
+				return nil, nil, fmt.Errorf("dump: list %d: invalid reference at %d", idx, pc)
+			}
+		case ops.TypeShader:
+			if refs[1].Kind != refString {
+				return nil, nil, fmt.Errorf("dump: list %d: invalid reference at %d", idx, pc)
+			}
+		}
+		pc += size
+		nrefs += t.NumRefs()
+	}
+	if nrefs != len(l.Refs) {
+		return nil, nil, fmt.Errorf("dump: list %d: %d references, expected %d", idx, len(l.Refs), nrefs)
+	}
+	pcs[pc] = nrefs
+	return pcs, calls, nil
+}
@@ -0,0 +1,223 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package dump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/gpu/software"
+	"gioui.org/internal/ops"
+	"gioui.org/io/pointer"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/shader"
+)
+
+func frame() *op.Ops {
+	o := new(op.Ops)
+	red := color.NRGBA{R: 0xff, A: 0xff}
+
+	// A macro, called twice.
+	m := op.Record(o)
+	paint.FillShape(o, red, clip.Ellipse{Max: image.Pt(20, 20)}.Op(o))
+	c := m.Stop()
+	c.Add(o)
+	t := op.Offset(image.Pt(30, 0)).Push(o)
+	c.Add(o)
+	t.Pop()
+
+	// An image.
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 4)
+	}
+	t = op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(5, 5)).Offset(f32.Pt(60, 0))).Push(o)
+	paint.NewImageOp(img).Add(o)
+	paint.PaintOp{}.Add(o)
+	t.Pop()
+
+	// An image rendered from the macro.
+	t = op.Offset(image.Pt(70, 40)).Push(o)
+	paint.NewRenderedImageOp(c, image.Pt(20, 20)).Add(o)
+	paint.PaintOp{}.Add(o)
+	t.Pop()
+
+	// A dashed stroke.
+	var p clip.Path
+	p.Begin(o)
+	p.MoveTo(f32.Pt(10, 40))
+	p.QuadTo(f32.Pt(50, 80), f32.Pt(90, 40))
+	paint.FillShape(o, color.NRGBA{B: 0xff, A: 0xff}, clip.Stroke{
+		Path:   p.End(),
+		Width:  4,
+		Dashes: []float32{5, 3},
+	}.Op())
+
+	// A gradient.
+	st := clip.Rect(image.Rect(0, 70, 100, 100)).Push(o)
+	paint.LinearGradientOp{
+		Stop1:  f32.Pt(0, 0),
+		Color1: red,
+		Stop2:  f32.Pt(100, 0),
+		Color2: color.NRGBA{G: 0xff, A: 0xff},
+	}.Add(o)
+	paint.PaintOp{}.Add(o)
+
+	// Input operations.
+	pointer.InputOp{Tag: o, Types: pointer.Press}.Add(o)
+	st.Pop()
+	return o
+}
+
+func TestRoundTrip(t *testing.T) {
+	o := frame()
+	var buf bytes.Buffer
+	if err := Encode(&buf, Frame{Size: image.Pt(100, 100), Ops: o}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Size != image.Pt(100, 100) {
+		t.Errorf("got size %v, expected (100,100)", f.Size)
+	}
+	want, got := render(t, o), render(t, f.Ops)
+	if !bytes.Equal(want.Pix, got.Pix) {
+		t.Error("decoded frame renders differently from the original")
+	}
+	if bytes.Equal(want.Pix, make([]byte, len(want.Pix))) {
+		t.Error("frame rendered nothing")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	var valid bytes.Buffer
+	if err := Encode(&valid, Frame{Ops: frame()}); err != nil {
+		t.Fatal(err)
+	}
+
+	badVersion := append([]byte(nil), valid.Bytes()...)
+	binary.LittleEndian.PutUint32(badVersion[len(magic):], Version+1)
+
+	// A call to operations outside the operation list.
+	o := new(op.Ops)
+	m := op.Record(o)
+	paint.ColorOp{}.Add(o)
+	m.Stop().Add(o)
+	data, refs := ops.Contents(&o.Internal)
+	data = append([]byte(nil), data...)
+	for pc := 0; pc < len(data); pc += ops.OpType(data[pc]).Size() {
+		if ops.OpType(data[pc]) == ops.TypeCall {
+			binary.LittleEndian.PutUint32(data[pc+9:], 1000)
+		}
+	}
+	ops.SetContents(&o.Internal, data, refs)
+	var badCall bytes.Buffer
+	if err := Encode(&badCall, Frame{Ops: o}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A call to the whole list, including the call.
+	o = new(op.Ops)
+	m = op.Record(o)
+	paint.ColorOp{}.Add(o)
+	m.Stop().Add(o)
+	data, refs = ops.Contents(&o.Internal)
+	data, refs = append([]byte(nil), data...), append([]interface{}(nil), refs...)
+	for pc := 0; pc < len(data); pc += ops.OpType(data[pc]).Size() {
+		if ops.OpType(data[pc]) == ops.TypeCall {
+			binary.LittleEndian.PutUint32(data[pc+1:], 0)
+			binary.LittleEndian.PutUint32(data[pc+5:], 0)
+			binary.LittleEndian.PutUint32(data[pc+9:], uint32(len(data)))
+			binary.LittleEndian.PutUint32(data[pc+13:], uint32(len(refs)))
+		}
+	}
+	ops.SetContents(&o.Internal, data, refs)
+	var recursiveCall bytes.Buffer
+	if err := Encode(&recursiveCall, Frame{Ops: o}); err != nil {
+		t.Fatal(err)
+	}
+
+	// An image rendered from the list that draws it.
+	o = new(op.Ops)
+	m = op.Record(o)
+	paint.ColorOp{}.Add(o)
+	paint.NewRenderedImageOp(m.Stop(), image.Pt(10, 10)).Add(o)
+	data, refs = ops.Contents(&o.Internal)
+	refs = append([]interface{}(nil), refs...)
+	for i, r := range refs {
+		if _, ok := r.(*ops.Ops); ok {
+			refs[i] = &o.Internal
+		}
+	}
+	ops.SetContents(&o.Internal, append([]byte(nil), data...), refs)
+	var recursiveImage bytes.Buffer
+	if err := Encode(&recursiveImage, Frame{Ops: o}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"magic", []byte("not a dump"), "not a frame dump"},
+		{"version", badVersion, "unsupported version"},
+		{"truncated", valid.Bytes()[:valid.Len()/2], "dump:"},
+		{"call", badCall.Bytes(), "invalid call"},
+		{"recursive call", recursiveCall.Bytes(), "recursive call"},
+		{"recursive image", recursiveImage.Bytes(), "recursive image"},
+	}
+	for _, test := range tests {
+		_, err := Decode(bytes.NewReader(test.data))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, expected %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestShader(t *testing.T) {
+	s := paint.NewShader(shader.Sources{}, func(f32.Point, []byte) color.NRGBA {
+		return color.NRGBA{R: 0xff, A: 0xff}
+	})
+	o := new(op.Ops)
+	paint.ShaderOp{Shader: s, Uniforms: []byte{1, 2, 3, 4}}.Add(o)
+	paint.PaintOp{}.Add(o)
+	var buf bytes.Buffer
+	if err := Encode(&buf, Frame{Ops: o}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Shader programs are not encoded.
+	if img := render(t, f.Ops); !bytes.Equal(img.Pix, make([]byte, len(img.Pix))) {
+		t.Error("decoded shader painted")
+	}
+}
+
+func render(t *testing.T, o *op.Ops) *image.RGBA {
+	t.Helper()
+	w, err := software.NewWindow(100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Release()
+	if err := w.Frame(o); err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	if err := w.Screenshot(img); err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
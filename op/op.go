@@ -102,6 +102,27 @@ type InvalidateOp struct {
 	At time.Time
 }
 
+// CacheOp invokes the operations recorded in Call, and hints to
+// renderers that they draw the same graphics as long as Key doesn't
+// change. Renderers may then reuse work from previous frames, such as
+// the encoded form of paths and strokes, instead of repeating it. Use
+// CacheOp for large subtrees that rarely change, such as maps and
+// documents, and change Key whenever their operations would draw
+// differently. Key must be comparable, and should be distinct from
+// the keys of other CacheOps that draw differently. A nil Key
+// disables the hint.
+//
+// Key need not change when the CacheOp is drawn with a different
+// transformation. Only drawing is affected by the hint; input
+// operations in Call are processed as usual.
+//
+// To reuse rasterized pixels, draw an image from
+// paint.NewRenderedImageOp instead.
+type CacheOp struct {
+	Key  interface{}
+	Call CallOp
+}
+
 // TransformOp represents a transformation that can be pushed on the
 // transformation stack.
 type TransformOp struct {
@@ -181,6 +202,21 @@ func (c CallOp) Add(o *Ops) {
 	ops.AddCall(&o.Internal, c.ops, c.start, c.end)
 }
 
+func (c CacheOp) Add(o *Ops) {
+	if c.Call.ops == nil {
+		return
+	}
+	if c.Key == nil {
+		c.Call.Add(o)
+		return
+	}
+	data := ops.Write1(&o.Internal, ops.TypeCacheLen, c.Key)
+	data[0] = byte(ops.TypeCache)
+	c.Call.Add(o)
+	data = ops.Write(&o.Internal, ops.TypePopCacheLen)
+	data[0] = byte(ops.TypePopCache)
+}
+
 func (r InvalidateOp) Add(o *Ops) {
 	data := ops.Write(&o.Internal, ops.TypeRedrawLen)
 	data[0] = byte(ops.TypeInvalidate)
@@ -34,3 +34,34 @@ func TestIncompleteMacroReader(t *testing.T) {
 		t.Error("decoded an operation from a semantically empty Ops")
 	}
 }
+
+func TestCacheOp(t *testing.T) {
+	var o Ops
+	m := Record(&o)
+	InvalidateOp{}.Add(&o)
+	call := m.Stop()
+	CacheOp{Key: "key", Call: call}.Add(&o)
+	// A nil key adds only the call.
+	CacheOp{Call: call}.Add(&o)
+
+	var r ops.Reader
+	r.Reset(&o.Internal)
+	var types []ops.OpType
+	for encOp, ok := r.Decode(); ok; encOp, ok = r.Decode() {
+		typ := ops.OpType(encOp.Data[0])
+		if typ == ops.TypeCache && encOp.Refs[0] != "key" {
+			t.Errorf("got cache key %v, expected %q", encOp.Refs[0], "key")
+		}
+		types = append(types, typ)
+	}
+	exp := []ops.OpType{ops.TypeCache, ops.TypeInvalidate, ops.TypePopCache, ops.TypeInvalidate}
+	if len(types) != len(exp) {
+		t.Fatalf("got operations %v, expected %v", types, exp)
+	}
+	for i := range types {
+		if types[i] != exp[i] {
+			t.Errorf("got operations %v, expected %v", types, exp)
+			break
+		}
+	}
+}
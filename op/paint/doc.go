@@ -8,7 +8,15 @@ current transformation into account. Drawing outside the current clip area is
 ignored.
 
 The current brush is set by either a ColorOp for a constant color, or
-ImageOp for an image, or LinearGradientOp for gradients.
+ImageOp for an image, or LinearGradientOp, RadialGradientOp or
+ConicGradientOp for gradients. GradientOp describes gradients with any
+number of color stops. ShaderOp paints with a custom fragment program.
+
+OpacityOp fades a group of operations as a whole: the operations are
+drawn into an offscreen layer which is then blended with the layer
+opacity. BlendOp similarly draws a group of operations into a layer, which
+is combined with the content below it by a blend mode such as
+BlendMultiply or BlendDifference.
 
 All color.NRGBA values are in the sRGB color space.
 */
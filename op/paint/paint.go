@@ -8,6 +8,7 @@ import (
 	"image/color"
 	"image/draw"
 	"math"
+	"sort"
 
 	"gioui.org/f32"
 	"gioui.org/internal/ops"
@@ -17,15 +18,26 @@ import (
 
 // ImageOp sets the brush to an image.
 type ImageOp struct {
+	// Filter is the filter for sampling the image when it is drawn
+	// at a different scale than its size.
+	Filter ImageFilter
+
 	uniform bool
 	color   color.NRGBA
 	src     *image.RGBA
+	// render is the operation list of images rendered from
+	// operations, and size is their size.
+	render *op.Ops
+	size   image.Point
 
 	// handle is a key to uniquely identify this ImageOp
 	// in a map of cached textures.
 	handle interface{}
 }
 
+// ImageFilter is a filter for sampling scaled images.
+type ImageFilter uint8
+
 // ColorOp sets the brush to a constant color.
 type ColorOp struct {
 	Color color.NRGBA
@@ -40,10 +52,194 @@ type LinearGradientOp struct {
 	Color2 color.NRGBA
 }
 
+// RadialGradientOp sets the brush to a circular gradient centered at
+// Center with Color1, fading to Color2 at distance Radius. Points
+// beyond Radius are painted with Color2.
+type RadialGradientOp struct {
+	Center f32.Point
+	Radius float32
+	Color1 color.NRGBA
+	Color2 color.NRGBA
+}
+
+// ConicGradientOp sets the brush to a gradient that sweeps clockwise
+// around Center, from Color1 at Angle to Color2 at Angle + 2π. Angle is
+// measured in radians from the positive X axis.
+type ConicGradientOp struct {
+	Center f32.Point
+	Angle  float32
+	Color1 color.NRGBA
+	Color2 color.NRGBA
+}
+
+// GradientOp sets the brush to a gradient with any number of color
+// stops. The geometry of the gradient is determined by Kind:
+//
+// For LinearGradient, offset 0 is at Start and offset 1 is at End.
+//
+// For RadialGradient, Start is the center and the distance from Start to
+// End is the radius of offset 1.
+//
+// For ConicGradient, Start is the center and the direction from Start to
+// End is the angle of offset 0. Offsets increase clockwise and reach 1
+// after a full turn.
+//
+// Colors between stops are interpolated in linear color space. Spread
+// determines the color of points outside the [0, 1] offset range.
+//
+// Add panics if Kind or Spread is not one of the constants of its type.
+type GradientOp struct {
+	Kind   GradientKind
+	Start  f32.Point
+	End    f32.Point
+	Stops  []GradientStop
+	Spread Spread
+}
+
+// GradientKind is the geometry of a GradientOp.
+type GradientKind uint8
+
+// GradientStop is a color stop of a GradientOp.
+type GradientStop struct {
+	// Offset is the position of the stop along the gradient, in the
+	// range [0, 1].
+	Offset float32
+	Color  color.NRGBA
+}
+
+// Spread determines how a GradientOp extends beyond its [0, 1]
+// offset range.
+type Spread uint8
+
 // PaintOp fills the current clip area with the current brush.
 type PaintOp struct {
 }
 
+// OpacityOp renders the operations that follow it into an offscreen
+// layer, which is blended into the parent layer with Opacity when the
+// layer is popped. Overlapping drawing operations within the layer
+// blend with each other before the opacity is applied.
+//
+// Opacity is in the range [0, 1].
+type OpacityOp struct {
+	Opacity float32
+}
+
+// OpacityStack represents an OpacityOp pushed on the opacity stack.
+type OpacityStack struct {
+	ops     *ops.Ops
+	id      ops.StackID
+	macroID int
+}
+
+// BlendOp renders the operations that follow it into an offscreen
+// layer, which is blended into the parent layer with Mode when the
+// layer is popped. Operations within the layer use regular source-over
+// blending among themselves.
+//
+// Blend modes other than BlendSourceOver require the renderer to read
+// back the area below the layer, which is significantly more expensive
+// than an OpacityOp.
+type BlendOp struct {
+	Mode BlendMode
+}
+
+// BlendMode determines how the colors of a layer combine with the
+// colors below it. The modes follow the separable blend modes of the
+// W3C Compositing and Blending specification, computed in linear
+// color space.
+type BlendMode uint8
+
+// BlendStack represents a BlendOp pushed on the blend stack.
+type BlendStack struct {
+	ops     *ops.Ops
+	id      ops.StackID
+	macroID int
+}
+
+// ShadowOp sets the brush to the shadow of a rectangle with rounded
+// corners: the rectangle filled with Color and blurred, like the box
+// shadows of CSS. Use FillShadow to paint a shadow within its bounds.
+type ShadowOp struct {
+	// Rect is the rectangle that casts the shadow, and Radius is the
+	// radius of its corners.
+	Rect   image.Rectangle
+	Radius int
+	// Offset moves the shadow relative to Rect.
+	Offset image.Point
+	// Blur is the blur radius. The edges of the shadow fade out over
+	// a distance of Blur on either side.
+	Blur int
+	// Spread grows the shadow, or shrinks it if negative, before it
+	// is blurred.
+	Spread int
+	Color  color.NRGBA
+}
+
+// BlurOp blurs the content below the current clip area by a Gaussian
+// blur, for frosted glass effects behind dialogs and menus.
+//
+// Blurring requires the renderer to read back the area below the clip
+// area, which is significantly more expensive than painting it.
+type BlurOp struct {
+	// Radius is the blur radius in pixels, unaffected by
+	// transformations. Colors spread over a distance of about Radius.
+	Radius float32
+}
+
+const (
+	LinearGradient GradientKind = iota
+	RadialGradient
+	ConicGradient
+)
+
+const (
+	// SpreadPad extends the colors of the first and last stops.
+	SpreadPad Spread = iota
+	// SpreadRepeat repeats the gradient.
+	SpreadRepeat
+	// SpreadReflect repeats the gradient, reversing every other
+	// repetition.
+	SpreadReflect
+)
+
+const (
+	// FilterLinearMipmapLinear interpolates linearly between the
+	// nearest pixels. Images drawn smaller than their size are sampled
+	// from their mipmaps, halved copies generated on demand, to avoid
+	// the aliasing and shimmering of downscaled images.
+	FilterLinearMipmapLinear ImageFilter = iota
+	// FilterLinear interpolates linearly between the nearest pixels,
+	// without mipmaps.
+	FilterLinear
+	// FilterNearest samples the nearest pixel. It preserves the
+	// hard edges of upscaled pixel art.
+	FilterNearest
+)
+
+const (
+	// BlendSourceOver draws the layer over its backdrop.
+	BlendSourceOver BlendMode = iota
+	// BlendMultiply multiplies the layer and backdrop colors. The
+	// result is never lighter than either color.
+	BlendMultiply
+	// BlendScreen multiplies the complements of the layer and
+	// backdrop colors. The result is never darker than either color.
+	BlendScreen
+	// BlendOverlay multiplies or screens the colors depending on the
+	// backdrop color, preserving its highlights and shadows.
+	BlendOverlay
+	// BlendDarken selects the darker of the layer and backdrop colors.
+	BlendDarken
+	// BlendLighten selects the lighter of the layer and backdrop colors.
+	BlendLighten
+	// BlendDifference subtracts the darker of the layer and backdrop
+	// colors from the lighter.
+	BlendDifference
+	// BlendPlus adds the layer and backdrop colors.
+	BlendPlus
+)
+
 // NewImageOp creates an ImageOp backed by src.
 //
 // NewImageOp assumes the backing image is immutable, and may cache a
@@ -77,7 +273,33 @@ func NewImageOp(src image.Image) ImageOp {
 	}
 }
 
+// NewRenderedImageOp creates an ImageOp of an image of size pixels,
+// rendered from the operations of call, for thumbnails, drag previews
+// and caching static content that is expensive to draw. The image is
+// transparent where the operations don't draw.
+//
+// The image is rendered by the renderer that draws the ImageOp, when
+// it is first drawn. GPU renderers keep the image in a texture for as
+// long as the ImageOp is drawn in every frame, and render it again if
+// the texture is discarded. The operation list of call must therefore
+// not be changed or reset while the ImageOp is in use. Create a new
+// ImageOp to render changed operations.
+//
+// Images rendered from operations are sampled without mipmaps.
+func NewRenderedImageOp(call op.CallOp, size image.Point) ImageOp {
+	o := new(op.Ops)
+	call.Add(o)
+	return ImageOp{
+		render: o,
+		size:   size,
+		handle: new(int),
+	}
+}
+
 func (i ImageOp) Size() image.Point {
+	if i.render != nil {
+		return i.size
+	}
 	if i.src == nil {
 		return image.Point{}
 	}
@@ -90,11 +312,25 @@ func (i ImageOp) Add(o *op.Ops) {
 			Color: i.color,
 		}.Add(o)
 		return
-	} else if i.src == nil || i.src.Bounds().Empty() {
+	}
+	if i.render != nil {
+		if i.size.X <= 0 || i.size.Y <= 0 {
+			return
+		}
+		data := ops.Write2(&o.Internal, ops.TypeImageLen, &i.render.Internal, i.handle)
+		data[0] = byte(ops.TypeImage)
+		data[1] = byte(i.Filter)
+		bo := binary.LittleEndian
+		bo.PutUint32(data[2:], uint32(i.size.X))
+		bo.PutUint32(data[6:], uint32(i.size.Y))
+		return
+	}
+	if i.src == nil || i.src.Bounds().Empty() {
 		return
 	}
 	data := ops.Write2(&o.Internal, ops.TypeImageLen, i.src, i.handle)
 	data[0] = byte(ops.TypeImage)
+	data[1] = byte(i.Filter)
 }
 
 func (c ColorOp) Add(o *op.Ops) {
@@ -126,17 +362,217 @@ func (c LinearGradientOp) Add(o *op.Ops) {
 	data[21+3] = c.Color2.A
 }
 
+func (c RadialGradientOp) Add(o *op.Ops) {
+	data := ops.Write(&o.Internal, ops.TypeRadialGradientLen)
+	data[0] = byte(ops.TypeRadialGradient)
+
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(c.Center.X))
+	bo.PutUint32(data[5:], math.Float32bits(c.Center.Y))
+	bo.PutUint32(data[9:], math.Float32bits(c.Radius))
+
+	data[13+0] = c.Color1.R
+	data[13+1] = c.Color1.G
+	data[13+2] = c.Color1.B
+	data[13+3] = c.Color1.A
+	data[17+0] = c.Color2.R
+	data[17+1] = c.Color2.G
+	data[17+2] = c.Color2.B
+	data[17+3] = c.Color2.A
+}
+
+func (c ConicGradientOp) Add(o *op.Ops) {
+	data := ops.Write(&o.Internal, ops.TypeConicGradientLen)
+	data[0] = byte(ops.TypeConicGradient)
+
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(c.Center.X))
+	bo.PutUint32(data[5:], math.Float32bits(c.Center.Y))
+	bo.PutUint32(data[9:], math.Float32bits(c.Angle))
+
+	data[13+0] = c.Color1.R
+	data[13+1] = c.Color1.G
+	data[13+2] = c.Color1.B
+	data[13+3] = c.Color1.A
+	data[17+0] = c.Color2.R
+	data[17+1] = c.Color2.G
+	data[17+2] = c.Color2.B
+	data[17+3] = c.Color2.A
+}
+
+func (g GradientOp) Add(o *op.Ops) {
+	if g.Kind > ConicGradient {
+		panic("paint: invalid gradient kind")
+	}
+	if g.Spread > SpreadReflect {
+		panic("paint: invalid gradient spread")
+	}
+	// Encode the stops sorted by offset. A string is immutable and
+	// can be safely referenced until the ops are reset.
+	stops := make([]GradientStop, len(g.Stops))
+	copy(stops, g.Stops)
+	for i := range stops {
+		off := &stops[i].Offset
+		switch {
+		case *off > 1:
+			*off = 1
+		case !(*off >= 0):
+			// Clamp NaN offsets too.
+			*off = 0
+		}
+	}
+	sort.SliceStable(stops, func(i, j int) bool {
+		return stops[i].Offset < stops[j].Offset
+	})
+	bo := binary.LittleEndian
+	enc := make([]byte, len(stops)*8)
+	for i, s := range stops {
+		stop := enc[i*8:]
+		bo.PutUint32(stop, math.Float32bits(s.Offset))
+		stop[4+0] = s.Color.R
+		stop[4+1] = s.Color.G
+		stop[4+2] = s.Color.B
+		stop[4+3] = s.Color.A
+	}
+
+	data := ops.Write1(&o.Internal, ops.TypeGradientLen, string(enc))
+	data[0] = byte(ops.TypeGradient)
+	data[1] = byte(g.Kind)
+	data[2] = byte(g.Spread)
+	bo.PutUint32(data[3:], math.Float32bits(g.Start.X))
+	bo.PutUint32(data[7:], math.Float32bits(g.Start.Y))
+	bo.PutUint32(data[11:], math.Float32bits(g.End.X))
+	bo.PutUint32(data[15:], math.Float32bits(g.End.Y))
+}
+
+func (s ShadowOp) Add(o *op.Ops) {
+	r := s.shape()
+	data := ops.Write(&o.Internal, ops.TypeShadowLen)
+	data[0] = byte(ops.TypeShadow)
+
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(float32(r.Min.X)))
+	bo.PutUint32(data[5:], math.Float32bits(float32(r.Min.Y)))
+	bo.PutUint32(data[9:], math.Float32bits(float32(r.Max.X)))
+	bo.PutUint32(data[13:], math.Float32bits(float32(r.Max.Y)))
+	bo.PutUint32(data[17:], math.Float32bits(float32(s.radius())))
+	// The standard deviation of the Gaussian is half the blur radius.
+	bo.PutUint32(data[21:], math.Float32bits(float32(s.Blur)/2))
+
+	data[25+0] = s.Color.R
+	data[25+1] = s.Color.G
+	data[25+2] = s.Color.B
+	data[25+3] = s.Color.A
+}
+
+// Bounds returns the area covered by the shadow.
+func (s ShadowOp) Bounds() image.Rectangle {
+	// The Gaussian is negligible beyond 3 standard deviations.
+	b := s.Blur
+	if b < 0 {
+		b = 0
+	}
+	return s.shape().Inset(-(3*b + 1) / 2)
+}
+
+// shape returns the rectangle that is blurred.
+func (s ShadowOp) shape() image.Rectangle {
+	r := s.Rect.Canon().Add(s.Offset).Inset(-s.Spread)
+	if r.Empty() {
+		return image.Rectangle{Min: r.Min, Max: r.Min}
+	}
+	return r
+}
+
+// radius returns the corner radius of the shape.
+func (s ShadowOp) radius() int {
+	if s.Radius <= 0 {
+		return 0
+	}
+	r := s.Radius + s.Spread
+	if r < 0 {
+		r = 0
+	}
+	return r
+}
+
+func (b BlurOp) Add(o *op.Ops) {
+	r := b.Radius
+	if r < 0 || r != r {
+		r = 0
+	}
+	data := ops.Write(&o.Internal, ops.TypeBlurLen)
+	data[0] = byte(ops.TypeBlur)
+	bo := binary.LittleEndian
+	// The standard deviation of the Gaussian is half the blur radius.
+	bo.PutUint32(data[1:], math.Float32bits(r/2))
+}
+
 func (d PaintOp) Add(o *op.Ops) {
 	data := ops.Write(&o.Internal, ops.TypePaintLen)
 	data[0] = byte(ops.TypePaint)
 }
 
+// Push starts a layer with the opacity of p. The layer extends until
+// the returned OpacityStack is popped.
+func (p OpacityOp) Push(o *op.Ops) OpacityStack {
+	id, macroID := ops.PushOp(&o.Internal, ops.OpacityStack)
+	opacity := p.Opacity
+	switch {
+	case opacity < 0 || opacity != opacity:
+		opacity = 0
+	case opacity > 1:
+		opacity = 1
+	}
+	data := ops.Write(&o.Internal, ops.TypeOpacityLen)
+	data[0] = byte(ops.TypeOpacity)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[1:], math.Float32bits(opacity))
+	return OpacityStack{ops: &o.Internal, id: id, macroID: macroID}
+}
+
+// Pop ends the layer and blends it into its parent layer.
+func (s OpacityStack) Pop() {
+	ops.PopOp(s.ops, ops.OpacityStack, s.id, s.macroID)
+	data := ops.Write(s.ops, ops.TypePopOpacityLen)
+	data[0] = byte(ops.TypePopOpacity)
+}
+
+// Push starts a layer blended with the mode of b. The layer extends
+// until the returned BlendStack is popped.
+func (b BlendOp) Push(o *op.Ops) BlendStack {
+	id, macroID := ops.PushOp(&o.Internal, ops.BlendStack)
+	data := ops.Write(&o.Internal, ops.TypeBlendLen)
+	data[0] = byte(ops.TypeBlend)
+	data[1] = byte(b.Mode)
+	return BlendStack{ops: &o.Internal, id: id, macroID: macroID}
+}
+
+// Pop ends the layer and blends it into its parent layer.
+func (s BlendStack) Pop() {
+	ops.PopOp(s.ops, ops.BlendStack, s.id, s.macroID)
+	data := ops.Write(s.ops, ops.TypePopBlendLen)
+	data[0] = byte(ops.TypePopBlend)
+}
+
 // FillShape fills the clip shape with a color.
 func FillShape(ops *op.Ops, c color.NRGBA, shape clip.Op) {
 	defer shape.Push(ops).Pop()
 	Fill(ops, c)
 }
 
+// FillShadow paints the shadow s within its bounds.
+func FillShadow(ops *op.Ops, s ShadowOp) {
+	if s.Blur <= 0 {
+		// A sharp shadow is the rounded rectangle.
+		FillShape(ops, s.Color, clip.UniformRRect(s.shape(), s.radius()).Op(ops))
+		return
+	}
+	defer clip.Rect(s.Bounds()).Push(ops).Pop()
+	s.Add(ops)
+	PaintOp{}.Add(ops)
+}
+
 // Fill paints an infinitely large plane with the provided color. It
 // is intended to be used with a clip.Op already in place to limit
 // the painted area. Use FillShape unless you need to paint several
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package paint
+
+import (
+	"image/color"
+
+	"gioui.org/f32"
+	"gioui.org/internal/ops"
+	"gioui.org/op"
+	"gioui.org/shader"
+)
+
+// Shader is a fragment program for painting with ShaderOp.
+//
+// The program is written in the Vulkan flavor of GLSL and compiled to
+// the shader formats of the GPU backends by the convertshaders command
+// of the gioui.org/shader module, like the programs of Gio itself. It
+// receives the position in the coordinate space of its ShaderOp as the
+// input
+//
+//	layout(location = 0) in highp vec2 vUV;
+//
+// and outputs linear, premultiplied colors. Its uniforms are declared
+// in a push constant block whose first member is at offset
+// ShaderUniformsOffset:
+//
+//	layout(push_constant) uniform Params {
+//		layout(offset = 48) vec4 color;
+//		float time;
+//	} _params;
+type Shader struct {
+	src      shader.Sources
+	fallback func(p f32.Point, uniforms []byte) color.NRGBA
+}
+
+// ShaderOp sets the brush to a Shader program.
+//
+// Renderers that can't run the program call the fallback of the
+// shader for every pixel instead. The software and compute renderers
+// never run shader programs, nor do GPU backends that fail to compile
+// them.
+type ShaderOp struct {
+	Shader *Shader
+	// Uniforms are the values of the uniforms of the program, in the
+	// layout of its uniform block starting at ShaderUniformsOffset.
+	Uniforms []byte
+}
+
+const (
+	// ShaderUniformsOffset is the offset of the uniforms of shader
+	// programs in their push constant block. The block starts with
+	// the uniforms of the vertex program provided by Gio.
+	ShaderUniformsOffset = 48
+	// MaxShaderUniforms is the maximum size of the uniforms of a
+	// shader program, in bytes.
+	MaxShaderUniforms = 128 - ShaderUniformsOffset
+)
+
+// NewShader creates a shader from the sources of its fragment program.
+// Fallback computes the sRGB color of the shader at position p for
+// renderers that can't run the program. It must not retain uniforms.
+// A nil fallback paints nothing.
+//
+// Renderers cache the resources of a shader by its identity, so a
+// program should be created once and reused for every frame.
+func NewShader(src shader.Sources, fallback func(p f32.Point, uniforms []byte) color.NRGBA) *Shader {
+	return &Shader{src: src, fallback: fallback}
+}
+
+// Sources returns the sources of the fragment program.
+func (s *Shader) Sources() shader.Sources {
+	return s.src
+}
+
+// Fallback returns the color of the shader at position p computed
+// by its fallback function, or the transparent color if it has none.
+func (s *Shader) Fallback(p f32.Point, uniforms []byte) color.NRGBA {
+	if s.fallback == nil {
+		return color.NRGBA{}
+	}
+	return s.fallback(p, uniforms)
+}
+
+func (s ShaderOp) Add(o *op.Ops) {
+	if s.Shader == nil {
+		panic("paint: nil shader")
+	}
+	if len(s.Uniforms) > MaxShaderUniforms {
+		panic("paint: shader uniforms exceed MaxShaderUniforms")
+	}
+	// A string is immutable and can be safely referenced until the ops
+	// are reset.
+	data := ops.Write2(&o.Internal, ops.TypeShaderLen, s.Shader, string(s.Uniforms))
+	data[0] = byte(ops.TypeShader)
+}
@@ -7,6 +7,7 @@ import (
 	"image"
 	"io"
 	"sort"
+	"unicode"
 
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
@@ -31,6 +32,8 @@ type document struct {
 	alignment Alignment
 	// alignWidth is the width used when aligning text.
 	alignWidth int
+	// paragraphSpacing is the extra space between paragraphs.
+	paragraphSpacing fixed.Int26_6
 }
 
 // append adds the lines of other to the end of l and ensures they
@@ -38,7 +41,7 @@ type document struct {
 func (l *document) append(other document) {
 	l.lines = append(l.lines, other.lines...)
 	l.alignWidth = max(l.alignWidth, other.alignWidth)
-	calculateYOffsets(l.lines)
+	calculateYOffsets(l.lines, l.paragraphSpacing)
 }
 
 // reset empties the document in preparation to reuse its memory.
@@ -46,6 +49,7 @@ func (l *document) reset() {
 	l.lines = l.lines[:0]
 	l.alignment = Start
 	l.alignWidth = 0
+	l.paragraphSpacing = 0
 }
 
 func max(a, b int) int {
@@ -55,6 +59,13 @@ func max(a, b int) int {
 	return b
 }
 
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
 // A line contains the measurements of a line of text.
 type line struct {
 	// runs contains sequences of shaped glyphs with common attributes. The order
@@ -82,6 +93,21 @@ type line struct {
 	direction system.TextDirection
 	// runeCount is the number of text runes represented by this line's runs.
 	runeCount int
+	// span is the index of the first span of the paragraph of the line,
+	// for text shaped from spans.
+	span int
+	// newlineSpan is the index of the span of the paragraph containing
+	// its trailing newline, relative to span. It is only meaningful for
+	// the line ending with the newline.
+	newlineSpan int
+	// paragraphStart is set for the first line of a paragraph.
+	paragraphStart bool
+	// spaces is the number of glyphs between words, which are stretched
+	// by justification.
+	spaces int
+	// trailing is the advance of the whitespace at the end of the line,
+	// which is collapsed by justification.
+	trailing fixed.Int26_6
 
 	yOffset int
 }
@@ -118,6 +144,10 @@ type glyph struct {
 	// bounds describes the visual bounding box of the glyph relative to
 	// its dot.
 	bounds fixed.Rectangle26_6
+	// space is set for whitespace glyphs between words.
+	space bool
+	// trailing is set for whitespace glyphs at the end of a line.
+	trailing bool
 }
 
 type runLayout struct {
@@ -146,6 +176,14 @@ type runLayout struct {
 	// truncator indicates that this run is a text truncator standing in for remaining
 	// text.
 	truncator bool
+	// span is the index of the span of the paragraph that the run belongs to.
+	span int
+	// spaces and trailing are like their line counterparts, for the
+	// glyphs of the run.
+	spaces   int
+	trailing fixed.Int26_6
+	// decorations are the decoration metrics of face at PPEM.
+	decorations Decorations
 }
 
 // faceOrderer chooses the order in which faces should be applied to text.
@@ -250,10 +288,25 @@ func (f *faceOrderer) sorted(primary Font) []font.Face {
 	return f.faceScratch
 }
 
+// spanStyle is the style of a range of runes in a paragraph.
+type spanStyle struct {
+	font Font
+	ppem fixed.Int26_6
+	// runes is the number of runes in the span.
+	runes int
+}
+
 // shaperImpl implements the shaping and line-wrapping of opentype fonts.
 type shaperImpl struct {
 	// Fields for tracking fonts/faces.
 	orderer faceOrderer
+	// sources supply faces for runes not covered by the loaded faces.
+	sources []FontSource
+	// searched records the runes the sources have been searched for, by
+	// the font they were searched for.
+	searched map[fontBlock]*runeBits
+	// coverage caches the runes covered by each face.
+	coverage map[faceBlock]*faceCoverage
 
 	// Shaping and wrapping state.
 	shaper        shaping.HarfbuzzShaper
@@ -265,6 +318,7 @@ type shaperImpl struct {
 	splitScratch1, splitScratch2 []shaping.Input
 	outScratchBuf                []shaping.Output
 	scratchRunes                 []rune
+	spanScratch                  []spanStyle
 
 	// bitmapGlyphCache caches extracted bitmap glyph images.
 	bitmapGlyphCache bitmapCache
@@ -277,6 +331,119 @@ func (s *shaperImpl) Load(f FontFace) {
 	s.orderer.insert(f.Font, f.Face.Face())
 }
 
+// AddSource adds a source of fallback faces.
+func (s *shaperImpl) AddSource(src FontSource) {
+	s.sources = append(s.sources, src)
+	s.searched = nil
+}
+
+// runeBlockSize is the number of runes in the blocks of runes of the
+// coverage and search caches.
+const runeBlockSize = 256
+
+// runeBits is a set of the runes of a block.
+type runeBits [runeBlockSize / 64]uint64
+
+func (b *runeBits) has(r rune) bool {
+	i := r % runeBlockSize
+	return b[i/64]&(1<<(i%64)) != 0
+}
+
+func (b *runeBits) add(r rune) {
+	i := r % runeBlockSize
+	b[i/64] |= 1 << (i % 64)
+}
+
+// fontBlock identifies the searches for the runes of a block in a style.
+type fontBlock struct {
+	font  Font
+	block rune
+}
+
+// faceBlock identifies the coverage of the runes of a block by a face.
+type faceBlock struct {
+	face  font.Face
+	block rune
+}
+
+// faceCoverage records which of the runes of a block are known to be
+// covered by a face.
+type faceCoverage struct {
+	known, covered runeBits
+}
+
+// loadFallbacks loads faces from the sources for the runes not covered by
+// faces, in a style close to fnt. It reports whether any face was loaded.
+func (s *shaperImpl) loadFallbacks(fnt Font, faces []font.Face, runes []rune) bool {
+	if len(s.sources) == 0 {
+		return false
+	}
+	loaded := false
+	for _, r := range runes {
+		// Control characters such as newlines are not drawn.
+		if unicode.IsControl(r) {
+			continue
+		}
+		key := fontBlock{font: fnt, block: r / runeBlockSize}
+		searched := s.searched[key]
+		if searched != nil && searched.has(r) || s.covers(faces, r) {
+			continue
+		}
+		if searched == nil {
+			if s.searched == nil {
+				s.searched = make(map[fontBlock]*runeBits)
+			}
+			searched = new(runeBits)
+			s.searched[key] = searched
+		}
+		searched.add(r)
+		for _, src := range s.sources {
+			f, ok := src.Fallback(fnt, r)
+			if !ok {
+				continue
+			}
+			if existing, exists := s.orderer.faces[f.Font]; exists {
+				if existing == f.Face.Face() {
+					// Reuse the loaded face.
+					break
+				}
+				// The Font of the fallback already identifies another
+				// face; ask the remaining sources.
+				continue
+			}
+			s.Load(f)
+			loaded = true
+			break
+		}
+	}
+	return loaded
+}
+
+// covers reports whether any of faces has a glyph for r.
+func (s *shaperImpl) covers(faces []font.Face, r rune) bool {
+	for _, f := range faces {
+		key := faceBlock{face: f, block: r / runeBlockSize}
+		c := s.coverage[key]
+		if c == nil {
+			if s.coverage == nil {
+				s.coverage = make(map[faceBlock]*faceCoverage)
+			}
+			c = new(faceCoverage)
+			s.coverage[key] = c
+		}
+		if !c.known.has(r) {
+			c.known.add(r)
+			if _, ok := f.NominalGlyph(r); ok {
+				c.covered.add(r)
+			}
+		}
+		if c.covered.has(r) {
+			return true
+		}
+	}
+	return false
+}
+
 // splitByScript divides the inputs into new, smaller inputs on script boundaries
 // and correctly sets the text direction per-script. It will
 // use buf as the backing memory for the returned slice if buf is non-nil.
@@ -358,36 +525,52 @@ func (s *shaperImpl) splitBidi(input shaping.Input) []shaping.Input {
 	return splitInputs
 }
 
-// splitByFaces divides the inputs by font coverage in the provided faces. It will use the slice provided in buf
-// as the backing storage of the returned slice if buf is non-nil.
-func (s *shaperImpl) splitByFaces(inputs []shaping.Input, faces []font.Face, buf []shaping.Input) []shaping.Input {
-	var split []shaping.Input
-	if buf == nil {
-		split = make([]shaping.Input, 0, len(inputs))
-	} else {
-		split = buf
-	}
-	for _, input := range inputs {
-		split = append(split, shaping.SplitByFontGlyphs(input, faces)...)
-	}
-	return split
-}
-
 // shapeText invokes the text shaper and returns the raw text data in the shaper's native
-// format. It does not wrap lines.
-func (s *shaperImpl) shapeText(faces []font.Face, ppem fixed.Int26_6, lc system.Locale, txt []rune) []shaping.Output {
-	if len(faces) < 1 {
-		return nil
-	}
+// format. The runes of txt are styled by spans, in order. It does not wrap lines.
+func (s *shaperImpl) shapeText(spans []spanStyle, params Parameters, txt []rune) []shaping.Output {
 	lcfg := langConfig{
-		Language:  language.NewLanguage(lc.Language),
-		Direction: mapDirection(lc.Direction),
-	}
-	// Create an initial input.
-	input := toInput(faces[0], ppem, lcfg, txt)
-	// Break input on font glyph coverage.
-	inputs := s.splitBidi(input)
-	inputs = s.splitByFaces(inputs, faces, s.splitScratch1[:0])
+		Language:  language.NewLanguage(params.Locale.Language),
+		Direction: mapDirection(params.Locale.Direction),
+	}
+	// Create an initial input, and split it by direction for the
+	// paragraph as a whole.
+	input := toInput(nil, 0, lcfg, txt)
+	bidiInputs := s.splitBidi(input)
+	// Break the inputs on span boundaries and then on font glyph coverage
+	// by the faces of each span.
+	inputs := s.splitScratch1[:0]
+	start := 0
+	for i, sp := range spans {
+		end := start + sp.runes
+		// Empty text is shaped with the style of the first span.
+		empty := len(txt) == 0 && i == 0
+		if start == end && !empty {
+			continue
+		}
+		faces := s.orderer.sortedFacesForStyle(sp.font)
+		if len(faces) < 1 {
+			return nil
+		}
+		if s.loadFallbacks(sp.font, faces, txt[start:end]) {
+			faces = s.orderer.sortedFacesForStyle(sp.font)
+		}
+		for _, in := range bidiInputs {
+			if in.RunStart < start {
+				in.RunStart = start
+			}
+			if in.RunEnd > end {
+				in.RunEnd = end
+			}
+			if in.RunStart >= in.RunEnd && !empty {
+				continue
+			}
+			in.Face = faces[0]
+			in.Size = sp.ppem
+			inputs = append(inputs, shaping.SplitByFontGlyphs(in, faces)...)
+		}
+		start = end
+	}
+	s.splitScratch1 = inputs
 	inputs = splitByScript(inputs, lcfg.Direction, s.splitScratch2[:0])
 	// Shape all inputs.
 	if needed := len(inputs) - len(s.outScratchBuf); needed > 0 {
@@ -396,12 +579,16 @@ func (s *shaperImpl) shapeText(faces []font.Face, ppem fixed.Int26_6, lc system.
 	s.outScratchBuf = s.outScratchBuf[:len(inputs)]
 	for i := range inputs {
 		s.outScratchBuf[i] = s.shaper.Shape(inputs[i])
+		if params.LetterSpacing != 0 {
+			addLetterSpacing(&s.outScratchBuf[i], params.LetterSpacing)
+		}
 	}
 	return s.outScratchBuf
 }
 
 // shapeAndWrapText invokes the text shaper and returns wrapped lines in the shaper's native format.
-func (s *shaperImpl) shapeAndWrapText(faces []font.Face, params Parameters, txt []rune) (_ []shaping.Line, truncated int) {
+// The truncator is styled like the last span.
+func (s *shaperImpl) shapeAndWrapText(spans []spanStyle, params Parameters, txt []rune) (_ []shaping.Line, truncated int) {
 	wc := shaping.WrapConfig{
 		TruncateAfterLines: params.MaxLines,
 		TextContinues:      params.forceTruncate,
@@ -412,10 +599,13 @@ func (s *shaperImpl) shapeAndWrapText(faces []font.Face, params Parameters, txt
 		}
 		// We only permit a single run as the truncator, regardless of whether more were generated.
 		// Just use the first one.
-		wc.Truncator = s.shapeText(faces, params.PxPerEm, params.Locale, []rune(params.Truncator))[0]
+		trunc := []rune(params.Truncator)
+		last := spans[len(spans)-1]
+		style := []spanStyle{{font: last.font, ppem: last.ppem, runes: len(trunc)}}
+		wc.Truncator = s.shapeText(style, params, trunc)[0]
 	}
 	// Wrap outputs into lines.
-	return s.wrapper.WrapParagraph(wc, params.MaxWidth, txt, s.shapeText(faces, params.PxPerEm, params.Locale, txt)...)
+	return s.wrapper.WrapParagraph(wc, params.MaxWidth, txt, s.shapeText(spans, params, txt)...)
 }
 
 // replaceControlCharacters replaces problematic unicode
@@ -457,11 +647,44 @@ func (s *shaperImpl) Layout(params Parameters, txt io.RuneReader) document {
 	return s.LayoutRunes(params, s.scratchRunes)
 }
 
-func calculateYOffsets(lines []line) {
+// addLetterSpacing adds spacing to the advance of every glyph cluster of o.
+func addLetterSpacing(o *shaping.Output, spacing fixed.Int26_6) {
+	for i := range o.Glyphs {
+		// Space the last glyph of the cluster, which is the last in
+		// visual order.
+		if i == len(o.Glyphs)-1 || o.Glyphs[i+1].ClusterIndex != o.Glyphs[i].ClusterIndex {
+			o.Glyphs[i].XAdvance += spacing
+		}
+	}
+	o.RecomputeAdvance()
+}
+
+// setLineHeight distributes the difference between the line height of
+// params and the height of l evenly above and below its glyphs.
+func setLineHeight(l *line, params Parameters) {
+	height := params.LineHeight
+	if height == 0 {
+		height = l.ascent + l.descent
+	}
+	if params.LineHeightScale != 0 {
+		height = fixed.Int26_6(float32(height) * params.LineHeightScale)
+	}
+	gap := height - (l.ascent + l.descent)
+	above := gap / 2
+	l.ascent += above
+	l.descent += gap - above
+}
+
+// calculateYOffsets computes the baselines of lines, separating paragraphs
+// by paragraphSpacing.
+func calculateYOffsets(lines []line, paragraphSpacing fixed.Int26_6) {
 	currentY := 0
 	prevDesc := fixed.I(0)
 	for i := range lines {
 		ascent, descent := lines[i].ascent, lines[i].descent
+		if i > 0 && lines[i].paragraphStart {
+			ascent += paragraphSpacing
+		}
 		currentY += (prevDesc + ascent).Ceil()
 		lines[i].yOffset = currentY
 		prevDesc = descent
@@ -470,11 +693,30 @@ func calculateYOffsets(lines []line) {
 
 // LayoutRunes shapes and wraps the text, and returns the result in Gio's shaped text format.
 func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
+	span := spanStyle{font: params.Font, ppem: params.PxPerEm, runes: len(txt)}
+	return s.LayoutSpans(params, []spanStyle{span}, txt)
+}
+
+// LayoutSpans is like LayoutRunes, but styles the runes of txt by spans
+// instead of by the font and size of params. The spans must cover txt.
+func (s *shaperImpl) LayoutSpans(params Parameters, spans []spanStyle, txt []rune) document {
 	hasNewline := len(txt) > 0 && txt[len(txt)-1] == '\n'
+	var newlineSpan int
 	if hasNewline {
+		// The newline keeps its own span even though it isn't shaped.
+		newlineSpan = spanFor(spans, len(txt)-1)
 		txt = txt[:len(txt)-1]
+		// Exclude the newline from the last span that covers it.
+		spans = append(s.spanScratch[:0], spans...)
+		for i := len(spans) - 1; i >= 0; i-- {
+			if spans[i].runes > 0 {
+				spans[i].runes--
+				break
+			}
+		}
+		s.spanScratch = spans
 	}
-	ls, truncated := s.shapeAndWrapText(s.orderer.sortedFacesForStyle(params.Font), params, replaceControlCharacters(txt))
+	ls, truncated := s.shapeAndWrapText(spans, params, replaceControlCharacters(txt))
 
 	didTruncate := truncated > 0 || (params.forceTruncate && params.MaxLines == len(ls))
 
@@ -488,6 +730,13 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 	textLines := make([]line, len(ls))
 	for i := range ls {
 		otLine := toLine(&s.orderer, ls[i], params.Locale.Direction)
+		for j := range otLine.runs {
+			otLine.runs[j].span = spanFor(spans, ls[i][j].Runes.Offset)
+		}
+		otLine.paragraphStart = i == 0
+		if params.LineHeight != 0 || params.LineHeightScale != 0 {
+			setLineHeight(&otLine, params)
+		}
 		isFinalLine := i == len(ls)-1
 		if isFinalLine && hasNewline {
 			// If there was a trailing newline update the rune counts to include
@@ -495,6 +744,7 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 			finalRunIdx := len(otLine.runs) - 1
 			otLine.runeCount += 1
 			otLine.runs[finalRunIdx].Runes.Count += 1
+			otLine.newlineSpan = newlineSpan
 
 			syntheticGlyph := glyph{
 				id:           0,
@@ -521,6 +771,7 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 			// truncator to make it represent the truncated text.
 			finalRunIdx := len(otLine.runs) - 1
 			otLine.runs[finalRunIdx].truncator = true
+			otLine.runs[finalRunIdx].span = len(spans) - 1
 			finalGlyphIdx := len(otLine.runs[finalRunIdx].Glyphs) - 1
 			// The run represents all of the truncated text.
 			otLine.runs[finalRunIdx].Runes.Count = truncated
@@ -533,9 +784,10 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 				}
 			}
 		}
+		markSpaces(&otLine, txt)
 		textLines[i] = otLine
 	}
-	calculateYOffsets(textLines)
+	calculateYOffsets(textLines, 0)
 	return document{
 		lines:      textLines,
 		alignment:  params.Alignment,
@@ -543,6 +795,57 @@ func (s *shaperImpl) LayoutRunes(params Parameters, txt []rune) document {
 	}
 }
 
+// markSpaces marks the whitespace glyphs of l for justification. Glyph
+// clusters index txt.
+func markSpaces(l *line, txt []rune) {
+	for i := range l.runs {
+		run := &l.runs[i]
+		if run.truncator {
+			continue
+		}
+		for j := range run.Glyphs {
+			g := &run.Glyphs[j]
+			if g.glyphCount > 0 && g.clusterIndex < len(txt) && unicode.IsSpace(txt[g.clusterIndex]) {
+				g.space = true
+				run.spaces++
+				l.spaces++
+			}
+		}
+	}
+	// Collapse the whitespace at the logical end of the line.
+	for i := len(l.runs) - 1; i >= 0; i-- {
+		run := &l.runs[i]
+		rtl := run.Direction.Progression() == system.TowardOrigin
+		for j := range run.Glyphs {
+			idx := len(run.Glyphs) - 1 - j
+			if rtl {
+				idx = j
+			}
+			g := &run.Glyphs[idx]
+			if !g.space {
+				return
+			}
+			g.space, g.trailing = false, true
+			run.spaces--
+			l.spaces--
+			run.trailing += g.xAdvance
+			l.trailing += g.xAdvance
+		}
+	}
+}
+
+// spanFor returns the index of the span containing the rune at offset.
+func spanFor(spans []spanStyle, offset int) int {
+	end := 0
+	for i, sp := range spans {
+		end += sp.runes
+		if offset < end {
+			return i
+		}
+	}
+	return len(spans) - 1
+}
+
 func alignWidth(minWidth int, lines []line) int {
 	for _, l := range lines {
 		minWidth = max(minWidth, l.width.Ceil())
@@ -776,10 +1079,11 @@ func toLine(orderer *faceOrderer, o shaping.Line, dir system.TextDirection) line
 				Count:  run.Runes.Count,
 				Offset: line.runeCount,
 			},
-			Direction: unmapDirection(run.Direction),
-			face:      run.Face,
-			Advance:   run.Advance,
-			PPEM:      run.Size,
+			Direction:   unmapDirection(run.Direction),
+			face:        run.Face,
+			Advance:     run.Advance,
+			PPEM:        run.Size,
+			decorations: decorationsFor(run),
 		}
 		line.runeCount += run.Runes.Count
 		if line.bounds.Min.Y > -run.LineBounds.Ascent {
@@ -815,6 +1119,33 @@ func toLine(orderer *faceOrderer, o shaping.Line, dir system.TextDirection) line
 	return line
 }
 
+// decorationsFor computes the decoration metrics of the face of o. Metrics
+// missing from the face are estimated from the size of the text.
+func decorationsFor(o shaping.Output) Decorations {
+	d := Decorations{OverlineOffset: -o.LineBounds.Ascent}
+	if o.Face == nil || o.Face.Upem() == 0 {
+		return d
+	}
+	scale := float32(o.Size) / float32(o.Face.Upem())
+	metric := func(m api.LineMetric) fixed.Int26_6 {
+		return fixed.Int26_6(o.Face.LineMetric(m) * scale)
+	}
+	d.UnderlineOffset = -metric(api.UnderlinePosition)
+	d.UnderlineThickness = metric(api.UnderlineThickness)
+	d.StrikethroughOffset = -metric(api.StrikethroughPosition)
+	d.StrikethroughThickness = metric(api.StrikethroughThickness)
+	if d.UnderlineThickness <= 0 {
+		d.UnderlineThickness = o.Size / 16
+		d.UnderlineOffset = o.Size / 8
+	}
+	if d.StrikethroughThickness <= 0 {
+		d.StrikethroughThickness = d.UnderlineThickness
+		// Strike through the middle of lowercase letters.
+		d.StrikethroughOffset = -o.Size / 4
+	}
+	return d
+}
+
 // computeVisualOrder will populate the Line's VisualOrder field and the
 // VisualPosition field of each element in Runs.
 func computeVisualOrder(l *line) {
@@ -268,16 +268,18 @@ func makeTestText(shaper *shaperImpl, primaryDir system.TextDirection, fontSize,
 			rtlSource = string(complexRunes[:runeLimit])
 		}
 	}
-	simpleText, _ := shaper.shapeAndWrapText(shaper.orderer.sortedFacesForStyle(Font{}), Parameters{
+	simpleRunes := []rune(simpleSource)
+	simpleText, _ := shaper.shapeAndWrapText([]spanStyle{{ppem: fixed.I(fontSize), runes: len(simpleRunes)}}, Parameters{
 		PxPerEm:  fixed.I(fontSize),
 		MaxWidth: lineWidth,
 		Locale:   locale,
-	}, []rune(simpleSource))
-	complexText, _ := shaper.shapeAndWrapText(shaper.orderer.sortedFacesForStyle(Font{}), Parameters{
+	}, simpleRunes)
+	complexRunes := []rune(complexSource)
+	complexText, _ := shaper.shapeAndWrapText([]spanStyle{{ppem: fixed.I(fontSize), runes: len(complexRunes)}}, Parameters{
 		PxPerEm:  fixed.I(fontSize),
 		MaxWidth: lineWidth,
 		Locale:   locale,
-	}, []rune(complexSource))
+	}, complexRunes)
 	testShaper(rtlFace, ltrFace)
 	return simpleText, complexText
 }
@@ -157,7 +157,12 @@ type layoutKey struct {
 	truncator          string
 	locale             system.Locale
 	font               Font
-	forceTruncate      bool
+	// spans encodes the styles of text laid out from spans.
+	spans         string
+	forceTruncate bool
+	lineHeight    fixed.Int26_6
+	lineScale     float32
+	letterSpacing fixed.Int26_6
 }
 
 type pathKey struct {
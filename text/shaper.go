@@ -6,6 +6,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -38,18 +39,49 @@ type Parameters struct {
 	// Locale provides primary direction and language information for the shaped text.
 	Locale system.Locale
 
+	// LineHeight is the distance between the baselines of consecutive lines
+	// of a paragraph. If zero, it is the distance given by the ascent and
+	// descent of the fonts of the lines. The difference in height is shared
+	// evenly by the space above and below each line, and reflected in the
+	// Ascent and Descent of its glyphs.
+	LineHeight fixed.Int26_6
+	// LineHeightScale multiplies the line height. Zero means no scaling.
+	LineHeightScale float32
+	// LetterSpacing is added to the advance of every glyph cluster.
+	LetterSpacing fixed.Int26_6
+	// ParagraphSpacing is added to the distance between paragraphs.
+	ParagraphSpacing fixed.Int26_6
+
 	// forceTruncate controls whether the truncator string is inserted on the final line of
 	// text with a MaxLines. It is unexported because this behavior only makes sense for the
 	// shaper to control when it iterates paragraphs of text.
 	forceTruncate bool
 }
 
+// Span is a run of text with its own font and size, for LayoutSpans.
+type Span struct {
+	// Font describes the preferred typeface of the span.
+	Font Font
+	// PxPerEm is the pixels-per-em to shape the span with.
+	PxPerEm fixed.Int26_6
+	Text    string
+}
+
 // A FontFace is a Font and a matching Face.
 type FontFace struct {
 	Font Font
 	Face Face
 }
 
+// A FontSource supplies faces for the runes that the faces of a Shaper
+// don't cover, such as the fonts installed on the system.
+type FontSource interface {
+	// Fallback returns a face that covers r, in a style as close as
+	// possible to font. The Font of the result identifies the face, and
+	// must differ from the Fonts of other faces.
+	Fallback(font Font, r rune) (FontFace, bool)
+}
+
 // Glyph describes a shaped font glyph. Many fields are distances relative
 // to the "dot", which is a point on the baseline (the line upon which glyphs
 // visually rest) for the line of text containing the glyph.
@@ -94,6 +126,29 @@ type Glyph struct {
 	Runes int
 	// Flags encode special properties of this glyph.
 	Flags Flags
+	// Span is the index of the span containing the glyph, for text
+	// laid out by LayoutSpans. It is zero for other text.
+	Span int
+	// Decorations are the metrics of lines decorating the glyph, as
+	// suggested by its font.
+	Decorations Decorations
+}
+
+// Decorations describes the placement of lines that decorate text. Offsets
+// are from the dot to the top of the lines, in the same direction as
+// Glyph.Bounds. Lines above the baseline have negative offsets.
+type Decorations struct {
+	// UnderlineOffset is the offset of underlines.
+	UnderlineOffset fixed.Int26_6
+	// UnderlineThickness is the thickness of underlines and overlines.
+	UnderlineThickness fixed.Int26_6
+	// StrikethroughOffset is the offset of lines through the text.
+	StrikethroughOffset fixed.Int26_6
+	// StrikethroughThickness is the thickness of lines through the text.
+	StrikethroughThickness fixed.Int26_6
+	// OverlineOffset is the offset of overlines, which is the ascent of
+	// the font.
+	OverlineOffset fixed.Int26_6
 }
 
 type Flags uint16
@@ -177,6 +232,12 @@ type Shaper struct {
 	layoutCache      layoutCache
 	paragraph        []rune
 
+	// Scratch space for LayoutSpans.
+	spanRunes  []rune
+	spanEnds   []int
+	spanStyles []spanStyle
+	spanKey    []byte
+
 	reader strings.Reader
 
 	// Iterator state.
@@ -204,6 +265,17 @@ func NewShaper(collection []FontFace) *Shaper {
 	return l
 }
 
+// AddSource adds a source of fallback faces. The sources are searched, in
+// the order they were added, for the runes that no face of the shaper
+// covers. A face from a source is loaded the first time it is needed, in
+// the style of the text needing it, and stays loaded for the lifetime of
+// the shaper.
+func (l *Shaper) AddSource(src FontSource) {
+	l.shaper.AddSource(src)
+	// Discard layouts shaped without the faces of src.
+	l.layoutCache = layoutCache{}
+}
+
 // Layout text from an io.Reader according to a set of options. Results can be retrieved by
 // iteratively calling NextGlyph.
 func (l *Shaper) Layout(params Parameters, txt io.Reader) {
@@ -215,20 +287,21 @@ func (l *Shaper) LayoutString(params Parameters, str string) {
 	l.layoutText(params, nil, str)
 }
 
-func (l *Shaper) reset(align Alignment) {
+func (l *Shaper) reset(params Parameters) {
 	l.line, l.run, l.glyph, l.advance = 0, 0, 0, 0
 	l.done = false
 	l.txt.reset()
-	l.txt.alignment = align
+	l.txt.alignment = params.Alignment
+	l.txt.paragraphSpacing = params.ParagraphSpacing
 }
 
 // layoutText lays out a large text document by breaking it into paragraphs and laying
 // out each of them separately. This allows the shaping results to be cached independently
 // by paragraph. Only one of txt and str should be provided.
 func (l *Shaper) layoutText(params Parameters, txt *bufio.Reader, str string) {
-	l.reset(params.Alignment)
+	l.reset(params)
 	if txt == nil && len(str) == 0 {
-		l.txt.append(l.layoutParagraph(params, "", nil))
+		l.txt.append(l.layoutParagraph(params, nil, "", nil))
 		return
 	}
 	truncating := params.MaxLines > 0
@@ -266,7 +339,7 @@ func (l *Shaper) layoutText(params Parameters, txt *bufio.Reader, str string) {
 		}
 		if startByte != endByte || (len(l.paragraph) > 0 || len(l.txt.lines) == 0) {
 			params.forceTruncate = truncating && !done
-			lines := l.layoutParagraph(params, str[startByte:endByte], l.paragraph)
+			lines := l.layoutParagraph(params, nil, str[startByte:endByte], l.paragraph)
 			if truncating {
 				params.MaxLines -= len(lines.lines)
 				if params.MaxLines == 0 {
@@ -301,10 +374,87 @@ func (l *Shaper) layoutText(params Parameters, txt *bufio.Reader, str string) {
 	}
 }
 
+// LayoutSpans lays out text made of spans of different fonts and sizes.
+// The spans are wrapped together as if they were a single string, and
+// NextGlyph reports the index of the span of every glyph in Glyph.Span.
+// The Font and PxPerEm of params are ignored in favor of the style of
+// each span. A truncator is styled like the last span of the truncated
+// paragraph.
+func (l *Shaper) LayoutSpans(params Parameters, spans []Span) {
+	if len(spans) == 0 {
+		l.LayoutString(params, "")
+		return
+	}
+	l.reset(params)
+	runes := l.spanRunes[:0]
+	ends := l.spanEnds[:0]
+	for _, sp := range spans {
+		for _, r := range sp.Text {
+			runes = append(runes, r)
+		}
+		ends = append(ends, len(runes))
+	}
+	l.spanRunes, l.spanEnds = runes, ends
+	truncating := params.MaxLines > 0
+	// first is the index of the first span of the current paragraph.
+	first := 0
+	for start, done := 0, false; !done; {
+		end := start
+		for end < len(runes) {
+			end++
+			if runes[end-1] == '\n' {
+				break
+			}
+		}
+		done = end == len(runes)
+		for first < len(spans)-1 && ends[first] <= start {
+			first++
+		}
+		// Clip the spans to the paragraph.
+		styles := l.spanStyles[:0]
+		for i := first; i < len(spans); i++ {
+			spanStart := 0
+			if i > 0 {
+				spanStart = ends[i-1]
+			}
+			n := min(end, ends[i]) - max(start, spanStart)
+			styles = append(styles, spanStyle{font: spans[i].Font, ppem: spans[i].PxPerEm, runes: max(n, 0)})
+			if ends[i] >= end {
+				break
+			}
+		}
+		l.spanStyles = styles
+		if start != end || len(l.txt.lines) == 0 {
+			params.forceTruncate = truncating && !done
+			lines := l.layoutParagraph(params, styles, "", runes[start:end])
+			if truncating {
+				params.MaxLines -= len(lines.lines)
+				if params.MaxLines == 0 {
+					done = true
+					// Account for the runes after the paragraph in the truncator.
+					unreadRunes := len(runes) - end
+					lastLineIdx := len(lines.lines) - 1
+					lastRunIdx := len(lines.lines[lastLineIdx].runs) - 1
+					lastGlyphIdx := len(lines.lines[lastLineIdx].runs[lastRunIdx].Glyphs) - 1
+					lines.lines[lastLineIdx].runs[lastRunIdx].Runes.Count += unreadRunes
+					lines.lines[lastLineIdx].runs[lastRunIdx].Glyphs[lastGlyphIdx].runeCount += unreadRunes
+				}
+			}
+			n := len(l.txt.lines)
+			l.txt.append(lines)
+			for i := n; i < len(l.txt.lines); i++ {
+				l.txt.lines[i].span = first
+			}
+		}
+		start = end
+	}
+}
+
 // layoutParagraph shapes and wraps a paragraph using the provided parameters.
 // It accepts the paragraph data in either string or rune format, preferring the
-// string in order to hit the shaper cache more quickly.
-func (l *Shaper) layoutParagraph(params Parameters, asStr string, asRunes []rune) document {
+// string in order to hit the shaper cache more quickly. If spans is non-nil, it
+// styles the paragraph instead of the font and size of params.
+func (l *Shaper) layoutParagraph(params Parameters, spans []spanStyle, asStr string, asRunes []rune) document {
 	if l == nil {
 		return document{}
 	}
@@ -322,6 +472,13 @@ func (l *Shaper) layoutParagraph(params Parameters, asStr string, asRunes []rune
 		font:          params.Font,
 		forceTruncate: params.forceTruncate,
 		str:           asStr,
+		lineHeight:    params.LineHeight,
+		lineScale:     params.LineHeightScale,
+		letterSpacing: params.LetterSpacing,
+	}
+	if spans != nil {
+		lk.ppem, lk.font = 0, Font{}
+		lk.spans = l.spansKey(spans)
 	}
 	if l, ok := l.layoutCache.Get(lk); ok {
 		return l
@@ -329,11 +486,38 @@ func (l *Shaper) layoutParagraph(params Parameters, asStr string, asRunes []rune
 	if len(asRunes) == 0 && len(asStr) > 0 {
 		asRunes = []rune(asStr)
 	}
-	lines := l.shaper.LayoutRunes(params, asRunes)
+	var lines document
+	if spans != nil {
+		lines = l.shaper.LayoutSpans(params, spans, asRunes)
+	} else {
+		lines = l.shaper.LayoutRunes(params, asRunes)
+	}
 	l.layoutCache.Put(lk, lines)
 	return lines
 }
 
+// spansKey encodes the styles of spans for use in a layoutKey.
+func (l *Shaper) spansKey(spans []spanStyle) string {
+	b := l.spanKey[:0]
+	for _, sp := range spans {
+		f := sp.font
+		b = append(b, f.Typeface...)
+		b = append(b, 0)
+		b = append(b, f.Variant...)
+		b = append(b, 0)
+		b = strconv.AppendInt(b, int64(f.Style), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(f.Weight), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(sp.ppem), 10)
+		b = append(b, ',')
+		b = strconv.AppendInt(b, int64(sp.runes), 10)
+		b = append(b, ';')
+	}
+	l.spanKey = b
+	return string(b)
+}
+
 // NextGlyph returns the next glyph from the most recent shaping operation, if
 // any. If there are no more glyphs, ok will be false.
 func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
@@ -358,7 +542,24 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 			continue
 		}
 		run := line.runs[l.run]
-		align := l.txt.alignment.Align(line.direction, line.width, l.txt.alignWidth)
+		// stretch is the space added to every glyph between words of a
+		// justified line.
+		var stretch fixed.Int26_6
+		justify := l.justified()
+		width, runX, runAdvance := line.width, run.X, run.Advance
+		if justify {
+			stretch = (fixed.I(l.txt.alignWidth) - (line.width - line.trailing)) / fixed.Int26_6(line.spaces)
+			if stretch < 0 {
+				stretch = 0
+			}
+			width += stretch*fixed.Int26_6(line.spaces) - line.trailing
+			for _, idx := range line.visualOrder[:run.VisualPosition] {
+				prev := line.runs[idx]
+				runX += stretch*fixed.Int26_6(prev.spaces) - prev.trailing
+			}
+			runAdvance += stretch*fixed.Int26_6(run.spaces) - run.trailing
+		}
+		align := l.txt.alignment.Align(line.direction, width, l.txt.alignWidth)
 		if l.line == 0 && l.run == 0 && len(run.Glyphs) == 0 {
 			// The very first run is empty, which will only happen when the
 			// entire text is a shaped empty string. Return a single synthetic
@@ -386,36 +587,47 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 			glyphIdx = len(run.Glyphs) - 1 - glyphIdx
 		}
 		g := run.Glyphs[glyphIdx]
+		advance := g.xAdvance
+		if justify {
+			switch {
+			case g.space:
+				advance += stretch
+			case g.trailing:
+				advance = 0
+			}
+		}
 		if rtl {
 			// Modify the advance prior to computing runOffset to ensure that the
 			// current glyph's width is subtracted in RTL.
-			l.advance += g.xAdvance
+			l.advance += advance
 		}
 		// runOffset computes how far into the run the dot should be positioned.
 		runOffset := l.advance
 		if rtl {
-			runOffset = run.Advance - l.advance
+			runOffset = runAdvance - l.advance
 		}
 		glyph := Glyph{
 			ID:      g.id,
-			X:       align + run.X + runOffset,
+			X:       align + runX + runOffset,
 			Y:       int32(line.yOffset),
 			Ascent:  line.ascent,
 			Descent: line.descent,
-			Advance: g.xAdvance,
+			Advance: advance,
 			Runes:   g.runeCount,
 			Offset: fixed.Point26_6{
 				X: g.xOffset,
 				Y: g.yOffset,
 			},
-			Bounds: g.bounds,
+			Bounds:      g.bounds,
+			Span:        line.span + run.span,
+			Decorations: run.decorations,
 		}
 		if run.truncator {
 			glyph.Flags |= FlagTruncator
 		}
 		l.glyph++
 		if !rtl {
-			l.advance += g.xAdvance
+			l.advance += advance
 		}
 
 		endOfRun := l.glyph == len(run.Glyphs)
@@ -449,12 +661,16 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 			l.brokeParagraph = false
 		}
 		if g.glyphCount == 0 {
+			// The synthetic newline glyph belongs to the span of the
+			// newline, not to the span of its run.
+			glyph.Span = line.span + line.newlineSpan
 			glyph.Flags |= FlagParagraphBreak
 			l.brokeParagraph = true
 			if endOfText {
 				l.pararagraphStart = Glyph{
 					Ascent:  glyph.Ascent,
 					Descent: glyph.Descent,
+					Span:    glyph.Span,
 					Flags:   FlagParagraphStart | FlagLineBreak | FlagRunBreak | FlagClusterBreak,
 				}
 				// If a glyph is both a paragraph break and the final glyph, it's a newline
@@ -462,13 +678,24 @@ func (l *Shaper) NextGlyph() (_ Glyph, ok bool) {
 				// of a valid cursor position they can use for "after" such a newline,
 				// taking text alignment into account.
 				l.pararagraphStart.X = l.txt.alignment.Align(line.direction, 0, l.txt.alignWidth)
-				l.pararagraphStart.Y = glyph.Y + int32((glyph.Ascent + glyph.Descent).Ceil())
+				l.pararagraphStart.Y = glyph.Y + int32((glyph.Ascent + glyph.Descent + l.txt.paragraphSpacing).Ceil())
 			}
 		}
 		return glyph, true
 	}
 }
 
+// justified reports whether the current line is stretched to the
+// alignment width.
+func (l *Shaper) justified() bool {
+	if l.txt.alignment != Justify || l.txt.lines[l.line].spaces == 0 {
+		return false
+	}
+	// The last line of a paragraph is not justified.
+	next := l.line + 1
+	return next < len(l.txt.lines) && !l.txt.lines[next].paragraphStart
+}
+
 const (
 	facebits = 16
 	sizebits = 16
@@ -393,6 +393,386 @@ func TestCacheGlyphConverstion(t *testing.T) {
 	}
 }
 
+// TestLayoutSpans ensures that spans are wrapped as a single paragraph and
+// that glyphs report the span they belong to.
+func TestLayoutSpans(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	collection := []FontFace{{Face: ltrFace}}
+	cache := NewShaper(collection)
+	params := Parameters{
+		MaxWidth: 100,
+		Locale:   english,
+	}
+	spans := []Span{
+		{PxPerEm: fixed.I(10), Text: "Lorem ipsum "},
+		{PxPerEm: fixed.I(10), Text: "dolor sit amet, consectetur"},
+		{PxPerEm: fixed.I(10), Text: " adipiscing elit,\nsed do eiusmod"},
+	}
+	var text string
+	for _, sp := range spans {
+		text += sp.Text
+	}
+	// Spans of the same style must lay out like the concatenated text.
+	strParams := params
+	strParams.PxPerEm = fixed.I(10)
+	cache.LayoutString(strParams, text)
+	var expected []Glyph
+	for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+		expected = append(expected, g)
+	}
+	cache.LayoutSpans(params, spans)
+	var actual []Glyph
+	for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+		actual = append(actual, g)
+	}
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d glyphs, got %d", len(expected), len(actual))
+	}
+	// ends are the rune offsets of the ends of the spans.
+	var ends []int
+	runes := 0
+	for _, sp := range spans {
+		runes += len([]rune(sp.Text))
+		ends = append(ends, runes)
+	}
+	runes = 0
+	for i, g := range actual {
+		span := g.Span
+		// Spans break runs.
+		g.Span, g.Flags = 0, g.Flags&^FlagRunBreak
+		expected[i].Flags &^= FlagRunBreak
+		if g != expected[i] {
+			t.Errorf("glyph %d: expected %+v, got %+v", i, expected[i], g)
+		}
+		if g.Runes == 0 {
+			continue
+		}
+		// Latin glyphs each represent a single rune.
+		if runes >= ends[span] || (span > 0 && runes < ends[span-1]) {
+			t.Errorf("glyph %d: rune %d reported in span %d", i, runes, span)
+		}
+		runes += g.Runes
+	}
+
+	// A larger span must enlarge its line, but not the others.
+	spans[1].PxPerEm = fixed.I(20)
+	cache.LayoutSpans(params, spans)
+	// spanLines are the baselines of the lines of span 1.
+	spanLines := make(map[int32]bool)
+	var a fixed.Int26_6
+	for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+		if g.Span == 1 && g.Runes > 0 {
+			spanLines[g.Y] = true
+			a = g.Ascent
+		}
+	}
+	if len(spanLines) == 0 {
+		t.Fatal("no glyphs in span 1")
+	}
+	cache.LayoutSpans(params, spans)
+	for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+		if !spanLines[g.Y] && g.Ascent >= a {
+			t.Errorf("line at %d without span 1 has ascent %v, expected less than %v", g.Y, g.Ascent, a)
+		}
+	}
+}
+
+// TestLayoutSpansNewline ensures that a newline starting a span is reported
+// in that span rather than in the span before it.
+func TestLayoutSpansNewline(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	collection := []FontFace{{Face: ltrFace}}
+	cache := NewShaper(collection)
+	spans := []Span{
+		{PxPerEm: fixed.I(10), Text: "a"},
+		{PxPerEm: fixed.I(10), Text: "\nb"},
+	}
+	cache.LayoutSpans(Parameters{MaxWidth: 100, Locale: english}, spans)
+	var got []int
+	for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+		if g.Runes > 0 {
+			got = append(got, g.Span)
+		}
+	}
+	// The spans of the glyphs of "a", "\n" and "b".
+	if want := []int{0, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("got spans %v, want %v", got, want)
+	}
+}
+
+// TestLayoutSpansTruncation ensures that truncated spans account for every
+// rune of the text.
+func TestLayoutSpansTruncation(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	collection := []FontFace{{Face: ltrFace}}
+	cache := NewShaper(collection)
+	spans := []Span{
+		{PxPerEm: fixed.I(10), Text: "Lorem ipsum dolor sit amet,"},
+		{PxPerEm: fixed.I(16), Text: " consectetur\nadipiscing elit"},
+		{PxPerEm: fixed.I(10), Text: ", sed do eiusmod"},
+	}
+	total := 0
+	for _, sp := range spans {
+		total += len([]rune(sp.Text))
+	}
+	cache.LayoutSpans(Parameters{
+		MaxWidth: 100,
+		MaxLines: 2,
+		Locale:   english,
+	}, spans)
+	runes, lines := 0, 0
+	truncated := false
+	for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+		runes += g.Runes
+		if g.Flags&FlagLineBreak != 0 {
+			lines++
+		}
+		if g.Flags&FlagTruncator != 0 {
+			truncated = true
+		}
+	}
+	if !truncated {
+		t.Errorf("expected truncated text")
+	}
+	if lines != 2 {
+		t.Errorf("expected %d lines, got %d", 2, lines)
+	}
+	if runes != total {
+		t.Errorf("expected %d runes, got %d", total, runes)
+	}
+}
+
+// TestSpacing ensures that line height, letter spacing and paragraph spacing
+// position glyphs as expected.
+func TestSpacing(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	collection := []FontFace{{Face: ltrFace}}
+	cache := NewShaper(collection)
+	layout := func(params Parameters, txt string) []Glyph {
+		params.PxPerEm = fixed.I(10)
+		params.MaxWidth = 100
+		params.Locale = english
+		cache.LayoutString(params, txt)
+		var glyphs []Glyph
+		for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+			glyphs = append(glyphs, g)
+		}
+		return glyphs
+	}
+	// baselines returns the distinct baselines of glyphs.
+	baselines := func(glyphs []Glyph) []int32 {
+		var ys []int32
+		for _, g := range glyphs {
+			if len(ys) == 0 || ys[len(ys)-1] != g.Y {
+				ys = append(ys, g.Y)
+			}
+		}
+		return ys
+	}
+	const txt = "Lorem ipsum dolor sit amet, consectetur adipiscing elit."
+	natural := baselines(layout(Parameters{}, txt))
+	if len(natural) < 3 {
+		t.Fatalf("expected several lines, got %d", len(natural))
+	}
+	naturalHeight := natural[1] - natural[0]
+
+	ys := baselines(layout(Parameters{LineHeight: fixed.I(30)}, txt))
+	for i := 1; i < len(ys); i++ {
+		if d := ys[i] - ys[i-1]; d != 30 {
+			t.Errorf("line %d: expected line height %d, got %d", i, 30, d)
+		}
+	}
+	ys = baselines(layout(Parameters{LineHeightScale: 2}, txt))
+	for i := 1; i < len(ys); i++ {
+		if d := ys[i] - ys[i-1]; d < 2*naturalHeight-1 || d > 2*naturalHeight+1 {
+			t.Errorf("line %d: expected line height %d, got %d", i, 2*naturalHeight, d)
+		}
+	}
+
+	plain := layout(Parameters{}, "Lorem")
+	spaced := layout(Parameters{LetterSpacing: fixed.I(3)}, "Lorem")
+	for i := range spaced {
+		if exp := plain[i].Advance + fixed.I(3); spaced[i].Advance != exp {
+			t.Errorf("glyph %d: expected advance %v, got %v", i, exp, spaced[i].Advance)
+		}
+		if i > 0 && spaced[i].X != spaced[i-1].X+spaced[i-1].Advance {
+			t.Errorf("glyph %d: expected x %v, got %v", i, spaced[i-1].X+spaced[i-1].Advance, spaced[i].X)
+		}
+	}
+
+	ys = baselines(layout(Parameters{ParagraphSpacing: fixed.I(7)}, "Lorem\nipsum\n"))
+	if len(ys) != 3 {
+		t.Fatalf("expected %d baselines, got %d", 3, len(ys))
+	}
+	for i := 1; i < len(ys); i++ {
+		if d := ys[i] - ys[i-1]; d != naturalHeight+7 {
+			t.Errorf("paragraph %d: expected spacing %d, got %d", i, naturalHeight+7, d)
+		}
+	}
+}
+
+// TestJustify ensures that justified lines are stretched to the alignment
+// width, except for the last line of every paragraph.
+func TestJustify(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	collection := []FontFace{{Face: ltrFace}}
+	cache := NewShaper(collection)
+	layout := func(align Alignment) []Glyph {
+		cache.LayoutString(Parameters{
+			Alignment: align,
+			PxPerEm:   fixed.I(10),
+			MinWidth:  100,
+			MaxWidth:  100,
+			Locale:    english,
+		}, "Lorem ipsum dolor sit amet, consectetur adipiscing elit.\nSed do eiusmod tempor.")
+		var glyphs []Glyph
+		for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+			glyphs = append(glyphs, g)
+		}
+		return glyphs
+	}
+	start, justified := layout(Start), layout(Justify)
+	if len(start) != len(justified) {
+		t.Fatalf("expected %d glyphs, got %d", len(start), len(justified))
+	}
+	lines := 0
+	lineStart := 0
+	for i, g := range justified {
+		if g.ID != start[i].ID || g.Y != start[i].Y {
+			t.Errorf("glyph %d: expected %v at %d, got %v at %d", i, start[i].ID, start[i].Y, g.ID, g.Y)
+		}
+		if i > lineStart && g.X != justified[i-1].X+justified[i-1].Advance {
+			t.Errorf("glyph %d: expected x %v, got %v", i, justified[i-1].X+justified[i-1].Advance, g.X)
+		}
+		if g.Flags&FlagLineBreak == 0 {
+			continue
+		}
+		last := g.Flags&FlagParagraphBreak != 0 || i == len(justified)-1
+		if end := g.X + g.Advance; !last && (end < fixed.I(99) || end > fixed.I(100)) {
+			t.Errorf("line %d: ends at %v, expected %d", lines, end, 100)
+		}
+		if last {
+			for j := lineStart; j <= i; j++ {
+				if justified[j] != start[j] {
+					t.Errorf("glyph %d of last line: expected %+v, got %+v", j, start[j], justified[j])
+				}
+			}
+		}
+		lines++
+		lineStart = i + 1
+	}
+	if lines < 4 {
+		t.Errorf("expected several lines, got %d", lines)
+	}
+}
+
+// TestDecorations ensures that glyphs report decoration metrics in the order
+// of the lines from the top.
+func TestDecorations(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	collection := []FontFace{{Face: ltrFace}}
+	cache := NewShaper(collection)
+	for _, size := range []int{10, 20} {
+		cache.LayoutString(Parameters{
+			PxPerEm:  fixed.I(size),
+			MaxWidth: 100,
+			Locale:   english,
+		}, "Lorem")
+		g, _ := cache.NextGlyph()
+		d := g.Decorations
+		if !(d.OverlineOffset < d.StrikethroughOffset && d.StrikethroughOffset < 0 && 0 < d.UnderlineOffset) {
+			t.Errorf("size %d: decoration offsets out of order: %+v", size, d)
+		}
+		if d.UnderlineThickness <= 0 || d.StrikethroughThickness <= 0 {
+			t.Errorf("size %d: expected positive thicknesses: %+v", size, d)
+		}
+		if d.UnderlineOffset+d.UnderlineThickness > g.Descent {
+			t.Errorf("size %d: underline below descent %v: %+v", size, g.Descent, d)
+		}
+	}
+}
+
+// testSource is a FontSource of a single face.
+type testSource struct {
+	face     FontFace
+	requests []rune
+}
+
+func (s *testSource) Fallback(font Font, r rune) (FontFace, bool) {
+	s.requests = append(s.requests, r)
+	if _, ok := s.face.Face.Face().NominalGlyph(r); !ok {
+		return FontFace{}, false
+	}
+	return s.face, true
+}
+
+// TestFontSource checks that faces are loaded from a source for the runes not
+// covered by the faces of a shaper, and only once per style.
+func TestFontSource(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	rtlFace, _ := opentype.Parse(nsareg.TTF)
+	layout := func(cache *Shaper, font Font, txt string) (faces map[int]bool, notdef int) {
+		cache.LayoutString(Parameters{
+			Font:     font,
+			PxPerEm:  fixed.I(10),
+			MaxWidth: 1000,
+			Locale:   english,
+		}, txt)
+		faces = make(map[int]bool)
+		for g, ok := cache.NextGlyph(); ok; g, ok = cache.NextGlyph() {
+			_, face, gid := splitGlyphID(g.ID)
+			faces[face] = true
+			// Ignore the synthetic glyphs of newlines and empty lines.
+			if gid == 0 && g.Runes > 0 && g.Flags&FlagParagraphBreak == 0 {
+				notdef++
+			}
+		}
+		return faces, notdef
+	}
+	cache := NewShaper([]FontFace{{Face: ltrFace}})
+	src := &testSource{face: FontFace{Font: Font{Typeface: "Noto"}, Face: rtlFace}}
+	cache.AddSource(src)
+	faces, notdef := layout(cache, Font{}, "Hello سماء\n")
+	if notdef != 0 {
+		t.Errorf("found %d notdef glyphs", notdef)
+	}
+	if !faces[0] || !faces[1] {
+		t.Errorf("expected glyphs from both faces, got faces %v", faces)
+	}
+	if len(src.requests) == 0 {
+		t.Fatalf("the source was not searched")
+	}
+	for _, r := range src.requests {
+		if r < 0x600 {
+			t.Errorf("the source was searched for covered rune %q", r)
+		}
+	}
+	// Runes covered by the loaded face or already searched for must not be
+	// searched for again.
+	n := len(src.requests)
+	layout(cache, Font{}, "لا سماء ✓")
+	layout(cache, Font{}, "✓ سماء")
+	if got := len(src.requests); got != n+1 {
+		t.Errorf("expected 1 new search, got %d: %q", got-n, src.requests[n:])
+	}
+	// Another style may have another fallback.
+	n = len(src.requests)
+	layout(cache, Font{Weight: Bold}, "✓")
+	if got := len(src.requests); got != n+1 {
+		t.Errorf("expected 1 new search for bold text, got %d: %q", got-n, src.requests[n:])
+	}
+
+	// A fallback identified by the Font of another face must not stop the
+	// search.
+	cache = NewShaper([]FontFace{{Face: ltrFace}})
+	clash := &testSource{face: FontFace{Face: rtlFace}}
+	cache.AddSource(clash)
+	cache.AddSource(src)
+	if _, notdef := layout(cache, Font{}, "سماء"); notdef != 0 {
+		t.Errorf("found %d notdef glyphs with a clashing source", notdef)
+	}
+}
+
 func checkFlag(t *testing.T, shouldHave bool, flag Flags, actual Glyph, glyphCursor int) {
 	t.Helper()
 	if shouldHave && actual.Flags&flag == 0 {
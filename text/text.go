@@ -45,6 +45,9 @@ const (
 	Start Alignment = iota
 	End
 	Middle
+	// Justify stretches the space between words to fill every line
+	// except the last line of a paragraph, which is aligned like Start.
+	Justify
 )
 
 const (
@@ -80,6 +83,8 @@ func (a Alignment) String() string {
 		return "End"
 	case Middle:
 		return "Middle"
+	case Justify:
+		return "Justify"
 	default:
 		panic("invalid Alignment")
 	}
@@ -92,7 +97,7 @@ func (a Alignment) Align(dir system.TextDirection, width fixed.Int26_6, maxWidth
 	mw := fixed.I(maxWidth)
 	if dir.Progression() == system.TowardOrigin {
 		switch a {
-		case Start:
+		case Start, Justify:
 			a = End
 		case End:
 			a = Start
@@ -103,7 +108,7 @@ func (a Alignment) Align(dir system.TextDirection, width fixed.Int26_6, maxWidth
 		return (mw - width) / 2
 	case End:
 		return (mw - width)
-	case Start:
+	case Start, Justify:
 		return 0
 	default:
 		panic(fmt.Errorf("unknown alignment %v", a))
@@ -49,6 +49,17 @@ type Editor struct {
 	// Newline characters are not masked. When non-zero, the unmasked contents
 	// are accessed by Len, Text, and SetText.
 	Mask rune
+	// LineHeight is the distance between the baselines of lines of text.
+	// If zero, it is determined by the font.
+	LineHeight unit.Sp
+	// LineHeightScale multiplies the line height. Zero means no scaling.
+	LineHeightScale float32
+	// LetterSpacing is extra space after every character.
+	LetterSpacing unit.Sp
+	// ParagraphSpacing is extra space between paragraphs.
+	ParagraphSpacing unit.Sp
+	// Decorations are lines to draw along ranges of the text.
+	Decorations []TextDecoration
 	// InputHint specifies the type of on-screen keyboard to be displayed.
 	InputHint key.InputHint
 	// MaxLen limits the editor content to a maximum length. Zero means no limit.
@@ -503,6 +514,11 @@ func (e *Editor) initBuffer() {
 	e.text.Alignment = e.Alignment
 	e.text.SingleLine = e.SingleLine
 	e.text.Mask = e.Mask
+	e.text.LineHeight = e.LineHeight
+	e.text.LineHeightScale = e.LineHeightScale
+	e.text.LetterSpacing = e.LetterSpacing
+	e.text.ParagraphSpacing = e.ParagraphSpacing
+	e.text.Decorations = e.Decorations
 }
 
 // Layout lays out the editor using the provided textMaterial as the paint material
@@ -547,6 +547,38 @@ func TestEditorDimensions(t *testing.T) {
 	}
 }
 
+// TestEditorSpacing ensures that the caret follows the line height and
+// letter spacing of the editor.
+func TestEditorSpacing(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(100, 100)},
+		Locale:      english,
+	}
+	cache := text.NewShaper(gofont.Collection())
+	fontSize := unit.Sp(10)
+	font := text.Font{}
+	caret := func(e *Editor, start int) f32.Point {
+		e.SetCaret(start, start)
+		e.Layout(gtx, cache, font, fontSize, op.CallOp{}, op.CallOp{})
+		return e.CaretCoords()
+	}
+	plain := new(Editor)
+	plain.SetText("ab\ncd")
+	spaced := &Editor{
+		LineHeight:       30,
+		LetterSpacing:    4,
+		ParagraphSpacing: 5,
+	}
+	spaced.SetText("ab\ncd")
+	if got, exp := caret(spaced, 4).Y-caret(spaced, 0).Y, float32(35); got != exp {
+		t.Errorf("caret moved %v between paragraphs, expected %v", got, exp)
+	}
+	if got, exp := caret(spaced, 2).X, caret(plain, 2).X+8; got != exp {
+		t.Errorf("caret at %v after two letters, expected %v", got, exp)
+	}
+}
+
 // assertCaret asserts that the editor caret is at a particular line
 // and column, and that the byte position matches as well.
 func assertCaret(t *testing.T, e *Editor, line, col, bytes int) {
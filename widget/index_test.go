@@ -7,6 +7,7 @@ import (
 
 	nsareg "eliasnaur.com/font/noto/sans/arabic/regular"
 	"gioui.org/font/opentype"
+	"gioui.org/io/system"
 	"gioui.org/text"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/math/fixed"
@@ -465,6 +466,61 @@ func TestIndexPositionLines(t *testing.T) {
 	}
 }
 
+// TestIndexPositionJustify checks that the index lines of justified text
+// span the full width, and that the closest position to the coordinates of
+// every position is at the same place.
+func TestIndexPositionJustify(t *testing.T) {
+	ltrFace, _ := opentype.Parse(goregular.TTF)
+	rtlFace, _ := opentype.Parse(nsareg.TTF)
+	shaper := text.NewShaper([]text.FontFace{
+		{Font: text.Font{Typeface: "LTR"}, Face: ltrFace},
+		{Font: text.Font{Typeface: "RTL"}, Face: rtlFace},
+	})
+	const lineWidth = 160
+	source := "The quick سماء שלום لا fox تمط שלום غير the lazy dog.\nThe quick brown fox jumps over the lazy dog."
+	for _, locale := range []system.Locale{english, arabic} {
+		t.Run(locale.Direction.String(), func(t *testing.T) {
+			shaper.LayoutString(text.Parameters{
+				Alignment: text.Justify,
+				PxPerEm:   fixed.I(16),
+				MinWidth:  lineWidth,
+				MaxWidth:  lineWidth,
+				Locale:    locale,
+			}, source)
+			var gi glyphIndex
+			gi.reset()
+			// paragraphEnds are the indices of the last lines of paragraphs.
+			paragraphEnds := make(map[int]bool)
+			for g, ok := shaper.NextGlyph(); ok; g, ok = shaper.NextGlyph() {
+				if g.Flags&text.FlagParagraphBreak != 0 {
+					paragraphEnds[len(gi.lines)] = true
+				}
+				gi.Glyph(g)
+			}
+			justified := 0
+			for i, l := range gi.lines {
+				if paragraphEnds[i] || i == len(gi.lines)-1 {
+					continue
+				}
+				justified++
+				// Allow for the rounding of the stretch of every space.
+				if d := l.xOff + l.width - fixed.I(lineWidth); l.xOff < 0 || l.xOff > fixed.I(1) || d < -fixed.I(1) || d > 0 {
+					t.Errorf("line %d spans [%v, %v], expected [0, %d]", i, l.xOff, l.xOff+l.width, lineWidth)
+				}
+			}
+			if justified < 2 {
+				t.Errorf("expected several justified lines, got %d", justified)
+			}
+			for i, pos := range gi.positions {
+				closest := gi.closestToXY(pos.x, pos.y)
+				if closest.x != pos.x || closest.lineCol.line != pos.lineCol.line {
+					t.Errorf("position %d: closest to (%v, %d) is %+v, expected %+v", i, pos.x, pos.y, closest, pos)
+				}
+			}
+		})
+	}
+}
+
 // TestIndexPositionRunes checks for rune accounting errors in positions
 // generated by the index.
 func TestIndexPositionRunes(t *testing.T) {
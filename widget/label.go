@@ -4,6 +4,7 @@ package widget
 
 import (
 	"image"
+	"math"
 
 	"gioui.org/f32"
 	"gioui.org/io/semantic"
@@ -27,6 +28,17 @@ type Label struct {
 	// Truncator is the text that will be shown at the end of the final
 	// line if MaxLines is exceeded. Defaults to "…" if empty.
 	Truncator string
+	// LineHeight is the distance between the baselines of lines of text.
+	// If zero, it is determined by the font.
+	LineHeight unit.Sp
+	// LineHeightScale multiplies the line height. Zero means no scaling.
+	LineHeightScale float32
+	// LetterSpacing is extra space after every character.
+	LetterSpacing unit.Sp
+	// ParagraphSpacing is extra space between paragraphs.
+	ParagraphSpacing unit.Sp
+	// Decorations are lines to draw along ranges of the text.
+	Decorations []TextDecoration
 }
 
 // Layout the label with the given shaper, font, size, text, and material.
@@ -34,14 +46,18 @@ func (l Label) Layout(gtx layout.Context, lt *text.Shaper, font text.Font, size
 	cs := gtx.Constraints
 	textSize := fixed.I(gtx.Sp(size))
 	lt.LayoutString(text.Parameters{
-		Font:      font,
-		PxPerEm:   textSize,
-		MaxLines:  l.MaxLines,
-		Truncator: l.Truncator,
-		Alignment: l.Alignment,
-		MaxWidth:  cs.Max.X,
-		MinWidth:  cs.Min.X,
-		Locale:    gtx.Locale,
+		Font:             font,
+		PxPerEm:          textSize,
+		MaxLines:         l.MaxLines,
+		Truncator:        l.Truncator,
+		Alignment:        l.Alignment,
+		MaxWidth:         cs.Max.X,
+		MinWidth:         cs.Min.X,
+		Locale:           gtx.Locale,
+		LineHeight:       spToFixed(gtx.Metric, l.LineHeight),
+		LineHeightScale:  l.LineHeightScale,
+		LetterSpacing:    spToFixed(gtx.Metric, l.LetterSpacing),
+		ParagraphSpacing: spToFixed(gtx.Metric, l.ParagraphSpacing),
 	}, txt)
 	m := op.Record(gtx.Ops)
 	viewport := image.Rectangle{Max: cs.Max}
@@ -51,14 +67,18 @@ func (l Label) Layout(gtx layout.Context, lt *text.Shaper, font text.Font, size
 		material: textMaterial,
 	}
 	semantic.LabelOp(txt).Add(gtx.Ops)
+	var dec decorationPainter
+	dec.reset(l.Decorations, 0)
 	var glyphs [32]text.Glyph
 	line := glyphs[:0]
 	for g, ok := lt.NextGlyph(); ok; g, ok = lt.NextGlyph() {
+		dec.glyph(g)
 		var ok bool
 		if line, ok = it.paintGlyph(gtx, lt, g, line); !ok {
 			break
 		}
 	}
+	dec.paint(gtx, image.Point{}, textMaterial)
 	call := m.Stop()
 	viewport.Min = viewport.Min.Add(it.padding.Min)
 	viewport.Max = viewport.Max.Add(it.padding.Max)
@@ -85,6 +105,9 @@ type textIterator struct {
 	// material sets the paint material for the text glyphs. If none is provided
 	// the glyphs will be invisible.
 	material op.CallOp
+	// materials, if set, are the paint materials of the spans of the text,
+	// indexed by text.Glyph.Span. They override material.
+	materials []op.CallOp
 
 	// linesSeen tracks the quantity of line endings this iterator has seen.
 	linesSeen int
@@ -151,6 +174,20 @@ func fixedToFloat(i fixed.Int26_6) float32 {
 	return float32(i) / 64.0
 }
 
+func floatToFixed(f float32) fixed.Int26_6 {
+	return fixed.Int26_6(math.Round(float64(f) * 64))
+}
+
+// spToFixed converts v to pixels like Metric.Sp, but keeps the fractional
+// pixels.
+func spToFixed(m unit.Metric, v unit.Sp) fixed.Int26_6 {
+	pxPerSp := m.PxPerSp
+	if pxPerSp == 0 {
+		pxPerSp = 1
+	}
+	return floatToFixed(pxPerSp * float32(v))
+}
+
 // paintGlyph buffers up and paints text glyphs. It should be invoked iteratively upon each glyph
 // until it returns false. The line parameter should be a slice with
 // a backing array of sufficient size to buffer multiple glyphs.
@@ -161,23 +198,36 @@ func fixedToFloat(i fixed.Int26_6) float32 {
 func (it *textIterator) paintGlyph(gtx layout.Context, shaper *text.Shaper, glyph text.Glyph, line []text.Glyph) ([]text.Glyph, bool) {
 	_, visibleOrBefore := it.processGlyph(glyph, true)
 	if it.visible {
+		if len(line) > 0 && line[0].Span != glyph.Span {
+			// Paint the glyphs of every span with its own material.
+			line = it.paintLine(gtx, shaper, line)
+		}
 		if len(line) == 0 {
 			it.lineOff = f32.Point{X: fixedToFloat(glyph.X), Y: float32(glyph.Y)}.Sub(layout.FPt(it.viewport.Min))
 		}
 		line = append(line, glyph)
 	}
 	if glyph.Flags&text.FlagLineBreak != 0 || cap(line)-len(line) == 0 || !visibleOrBefore {
-		t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
-		path := shaper.Shape(line)
-		outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
-		it.material.Add(gtx.Ops)
-		paint.PaintOp{}.Add(gtx.Ops)
-		outline.Pop()
-		if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
-			call.Add(gtx.Ops)
-		}
-		t.Pop()
-		line = line[:0]
+		line = it.paintLine(gtx, shaper, line)
 	}
 	return line, visibleOrBefore
 }
+
+// paintLine paints the buffered glyphs of line and returns it emptied.
+func (it *textIterator) paintLine(gtx layout.Context, shaper *text.Shaper, line []text.Glyph) []text.Glyph {
+	material := it.material
+	if len(line) > 0 && line[0].Span < len(it.materials) {
+		material = it.materials[line[0].Span]
+	}
+	t := op.Affine(f32.Affine2D{}.Offset(it.lineOff)).Push(gtx.Ops)
+	path := shaper.Shape(line)
+	outline := clip.Outline{Path: path}.Op().Push(gtx.Ops)
+	material.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+	outline.Pop()
+	if call := shaper.Bitmaps(line); call != (op.CallOp{}) {
+		call.Add(gtx.Ops)
+	}
+	t.Pop()
+	return line[:0]
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"gioui.org/text"
+	"gioui.org/unit"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -166,3 +167,21 @@ func TestGlyphIterator(t *testing.T) {
 		})
 	}
 }
+
+// TestSpToFixed ensures that text spacing keeps its fractional pixels.
+func TestSpToFixed(t *testing.T) {
+	for _, tc := range []struct {
+		metric unit.Metric
+		v      unit.Sp
+		want   fixed.Int26_6
+	}{
+		{metric: unit.Metric{PxPerSp: 1.5}, v: 1, want: fixed.I(3) / 2},
+		{metric: unit.Metric{PxPerSp: 1.25}, v: 2, want: fixed.I(5) / 2},
+		// A zero metric is one pixel per sp.
+		{metric: unit.Metric{}, v: 0.5, want: fixed.I(1) / 2},
+	} {
+		if got := spToFixed(tc.metric, tc.v); got != tc.want {
+			t.Errorf("spToFixed(%+v, %v) = %v, want %v", tc.metric, tc.v, got, tc.want)
+		}
+	}
+}
@@ -60,7 +60,14 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 	}
 
 	macro := op.Record(gtx.Ops)
-	tl := widget.Label{Alignment: e.Editor.Alignment, MaxLines: maxlines}
+	tl := widget.Label{
+		Alignment:        e.Editor.Alignment,
+		MaxLines:         maxlines,
+		LineHeight:       e.Editor.LineHeight,
+		LineHeightScale:  e.Editor.LineHeightScale,
+		LetterSpacing:    e.Editor.LetterSpacing,
+		ParagraphSpacing: e.Editor.ParagraphSpacing,
+	}
 	dims := tl.Layout(gtx, e.shaper, e.Font, e.TextSize, e.Hint, hintColor)
 	call := macro.Stop()
 
@@ -31,6 +31,17 @@ type LabelStyle struct {
 	// Truncator is the text that will be shown at the end of the final
 	// line if MaxLines is exceeded. Defaults to "…" if empty.
 	Truncator string
+	// LineHeight is the distance between the baselines of lines of text.
+	// If zero, it is determined by the font.
+	LineHeight unit.Sp
+	// LineHeightScale multiplies the line height. Zero means no scaling.
+	LineHeightScale float32
+	// LetterSpacing is extra space after every character.
+	LetterSpacing unit.Sp
+	// ParagraphSpacing is extra space between paragraphs.
+	ParagraphSpacing unit.Sp
+	// Decorations are lines to draw along ranges of the text.
+	Decorations []widget.TextDecoration
 	// Text is the content displayed by the label.
 	Text string
 	// TextSize determines the size of the text glyphs.
@@ -126,12 +137,22 @@ func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 		l.State.Alignment = l.Alignment
 		l.State.MaxLines = l.MaxLines
 		l.State.Truncator = l.Truncator
+		l.State.LineHeight = l.LineHeight
+		l.State.LineHeightScale = l.LineHeightScale
+		l.State.LetterSpacing = l.LetterSpacing
+		l.State.ParagraphSpacing = l.ParagraphSpacing
+		l.State.Decorations = l.Decorations
 		return l.State.Layout(gtx, l.Shaper, l.Font, l.TextSize, textColor, selectColor)
 	}
 	tl := widget.Label{
-		Alignment: l.Alignment,
-		MaxLines:  l.MaxLines,
-		Truncator: l.Truncator,
+		Alignment:        l.Alignment,
+		MaxLines:         l.MaxLines,
+		Truncator:        l.Truncator,
+		LineHeight:       l.LineHeight,
+		LineHeightScale:  l.LineHeightScale,
+		LetterSpacing:    l.LetterSpacing,
+		ParagraphSpacing: l.ParagraphSpacing,
+		Decorations:      l.Decorations,
 	}
 	return tl.Layout(gtx, l.Shaper, l.Font, l.TextSize, l.Text, textColor)
 }
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"errors"
+	"image"
+	"image/draw"
+	"math"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+)
+
+// NinePatch is a widget that draws an image scaled by regions behind
+// a widget, as a skin for panels, bubbles and buttons of any size.
+//
+// The image is divided into nine regions by Inset. The corners are
+// drawn unscaled, the top and bottom edges are scaled horizontally,
+// the left and right edges vertically, and the center in both
+// directions. If the area is too small for the corners, the corners
+// are scaled down to fit.
+type NinePatch struct {
+	// Src is the image to display.
+	Src paint.ImageOp
+	// Inset is the size of the borders of Src that are drawn unscaled.
+	Inset PatchInset
+	// Padding is the space between the borders of the image and the
+	// content widget.
+	Padding PatchInset
+	// Modes specifies how the scaled regions fill their areas.
+	Modes PatchModes
+	// Scale is the factor used for converting image pixels to dp.
+	// If Scale is zero it defaults to 1.
+	Scale float32
+}
+
+// PatchInset is the size of the borders of a nine-patch image, in
+// image pixels.
+type PatchInset struct {
+	Top, Right, Bottom, Left int
+}
+
+// PatchModes are the fill modes of the scaled regions of a nine-patch
+// image.
+type PatchModes struct {
+	Top, Right, Bottom, Left PatchMode
+	Center                   PatchMode
+}
+
+// PatchMode specifies how a region of a nine-patch image fills its
+// area.
+type PatchMode uint8
+
+const (
+	// PatchStretch scales the region to fill its area.
+	PatchStretch PatchMode = iota
+	// PatchTile repeats the region unscaled to fill its area.
+	PatchTile
+)
+
+// DecodeNinePatch converts an image in the Android nine-patch format,
+// usually stored in files ending in ".9.png", to a NinePatch.
+//
+// The outermost pixels of such images are markers, not image content.
+// Black pixels in the top and left rows mark the scaled regions, and
+// black pixels in the bottom and right rows mark the content area. If
+// the content area is not marked, it equals the scaled center.
+// Images with more than one scaled region in a row are not supported.
+func DecodeNinePatch(img image.Image) (NinePatch, error) {
+	b := img.Bounds()
+	if b.Dx() < 3 || b.Dy() < 3 {
+		return NinePatch{}, errors.New("widget: nine-patch image too small")
+	}
+	inner := b.Inset(1)
+	var n NinePatch
+	// marks returns the extent of the marker pixels at the points
+	// from p in the direction d.
+	marks := func(p, d image.Point, length int) (start, end int, err error) {
+		start, end = -1, -1
+		for i := 0; i < length; i++ {
+			q := p.Add(d.Mul(i))
+			r, g, b, a := img.At(q.X, q.Y).RGBA()
+			if a != 0xffff || r != 0 || g != 0 || b != 0 {
+				continue
+			}
+			if end != -1 && end != i {
+				return 0, 0, errors.New("widget: nine-patch image has more than one scaled region")
+			}
+			if start == -1 {
+				start = i
+			}
+			end = i + 1
+		}
+		return start, end, nil
+	}
+	w, h := inner.Dx(), inner.Dy()
+	left, right, err := marks(image.Pt(inner.Min.X, b.Min.Y), image.Pt(1, 0), w)
+	if err != nil {
+		return NinePatch{}, err
+	}
+	top, bottom, err := marks(image.Pt(b.Min.X, inner.Min.Y), image.Pt(0, 1), h)
+	if err != nil {
+		return NinePatch{}, err
+	}
+	if left == -1 || top == -1 {
+		return NinePatch{}, errors.New("widget: nine-patch image has no scaled region")
+	}
+	n.Inset = PatchInset{Top: top, Right: w - right, Bottom: h - bottom, Left: left}
+	n.Padding = n.Inset
+	left, right, err = marks(image.Pt(inner.Min.X, b.Max.Y-1), image.Pt(1, 0), w)
+	if err != nil {
+		return NinePatch{}, err
+	}
+	if left != -1 {
+		n.Padding.Left, n.Padding.Right = left, w-right
+	}
+	top, bottom, err = marks(image.Pt(b.Max.X-1, inner.Min.Y), image.Pt(0, 1), h)
+	if err != nil {
+		return NinePatch{}, err
+	}
+	if top != -1 {
+		n.Padding.Top, n.Padding.Bottom = top, h-bottom
+	}
+	src := image.NewRGBA(image.Rectangle{Max: inner.Size()})
+	draw.Draw(src, src.Bounds(), img, inner.Min, draw.Src)
+	n.Src = paint.NewImageOp(src)
+	return n, nil
+}
+
+// Layout lays out w inset by the padding and draws the image behind
+// it, scaled to the size of the padded widget.
+func (n NinePatch) Layout(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	scale := n.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	p := n.Padding
+	inset := layout.Inset{
+		Top:    unit.Dp(float32(p.Top) * scale),
+		Right:  unit.Dp(float32(p.Right) * scale),
+		Bottom: unit.Dp(float32(p.Bottom) * scale),
+		Left:   unit.Dp(float32(p.Left) * scale),
+	}
+	macro := op.Record(gtx.Ops)
+	dims := inset.Layout(gtx, w)
+	call := macro.Stop()
+	n.paint(gtx.Ops, dims.Size, scale*gtx.Metric.PxPerDp)
+	call.Add(gtx.Ops)
+	return dims
+}
+
+// paint draws the image in an area of size sz. The unscaled regions
+// are scaled by pxScale, the number of pixels per image pixel.
+func (n NinePatch) paint(ops *op.Ops, sz image.Point, pxScale float32) {
+	in := n.Inset
+	isz := n.Src.Size()
+	if in.Left < 0 || in.Right < 0 || in.Left+in.Right > isz.X ||
+		in.Top < 0 || in.Bottom < 0 || in.Top+in.Bottom > isz.Y || pxScale <= 0 {
+		return
+	}
+	// The columns and rows of the regions in the image and in the
+	// area.
+	srcX := [4]int{0, in.Left, isz.X - in.Right, isz.X}
+	srcY := [4]int{0, in.Top, isz.Y - in.Bottom, isz.Y}
+	dstX := patchSplit(sz.X, in.Left, in.Right, pxScale)
+	dstY := patchSplit(sz.Y, in.Top, in.Bottom, pxScale)
+	m := n.Modes
+	modes := [3][3]PatchMode{
+		{PatchStretch, m.Top, PatchStretch},
+		{m.Left, m.Center, m.Right},
+		{PatchStretch, m.Bottom, PatchStretch},
+	}
+	n.Src.Add(ops)
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			src := image.Rect(srcX[col], srcY[row], srcX[col+1], srcY[row+1])
+			dst := image.Rect(dstX[col], dstY[row], dstX[col+1], dstY[row+1])
+			if src.Empty() || dst.Empty() {
+				continue
+			}
+			// Tiled regions repeat along the scaled directions.
+			tile := modes[row][col] == PatchTile
+			drawPatch(ops, src, dst, tile && col == 1, tile && row == 1, pxScale)
+		}
+	}
+}
+
+// drawPatch draws the src region of the current image brush in dst,
+// scaled to fill dst or, along the tiled directions, repeated at the
+// scale pxScale.
+func drawPatch(ops *op.Ops, src, dst image.Rectangle, tileX, tileY bool, pxScale float32) {
+	defer clip.Rect(dst).Push(ops).Pop()
+	scale := f32.Pt(float32(dst.Dx())/float32(src.Dx()), float32(dst.Dy())/float32(src.Dy()))
+	nx, ny := 1, 1
+	if tileX {
+		scale.X = pxScale
+		nx = int(math.Ceil(float64(float32(dst.Dx()) / (float32(src.Dx()) * scale.X))))
+	}
+	if tileY {
+		scale.Y = pxScale
+		ny = int(math.Ceil(float64(float32(dst.Dy()) / (float32(src.Dy()) * scale.Y))))
+	}
+	size := f32.Pt(float32(src.Dx())*scale.X, float32(src.Dy())*scale.Y)
+	for y := 0; y < ny; y++ {
+		for x := 0; x < nx; x++ {
+			off := f32.Pt(float32(dst.Min.X)+float32(x)*size.X, float32(dst.Min.Y)+float32(y)*size.Y)
+			t := f32.Affine2D{}.
+				Offset(f32.Pt(-float32(src.Min.X), -float32(src.Min.Y))).
+				Scale(f32.Point{}, scale).
+				Offset(off)
+			st := op.Affine(t).Push(ops)
+			// Clip to the region, so its neighbours in the image don't
+			// show in the tiles.
+			cl := clip.Rect(src).Push(ops)
+			paint.PaintOp{}.Add(ops)
+			cl.Pop()
+			st.Pop()
+		}
+	}
+}
+
+// patchSplit returns the edges of the regions along an axis of length
+// size, where the borders of the image are start and end image pixels.
+// The borders are scaled down to fit in size.
+func patchSplit(size, start, end int, pxScale float32) [4]int {
+	s := float32(start) * pxScale
+	e := float32(end) * pxScale
+	if sz := float32(size); s+e > sz {
+		f := sz / (s + e)
+		s, e = s*f, e*f
+	}
+	s0 := int(s + .5)
+	e0 := size - int(e+.5)
+	if e0 < s0 {
+		e0 = s0
+	}
+	return [4]int{0, s0, e0, size}
+}
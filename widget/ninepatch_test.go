@@ -0,0 +1,175 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"gioui.org/golden"
+	"gioui.org/internal/f32color"
+	"gioui.org/layout"
+	"gioui.org/op/paint"
+)
+
+var (
+	patchRed   = color.NRGBA{R: 0xff, A: 0xff}
+	patchGreen = color.NRGBA{G: 0xff, A: 0xff}
+	patchBlue  = color.NRGBA{B: 0xff, A: 0xff}
+	patchWhite = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+func TestNinePatch(t *testing.T) {
+	// A 4x3 image with red corners, green edges, and a center of a
+	// blue and a white pixel.
+	src := image.NewNRGBA(image.Rect(0, 0, 4, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 4; x++ {
+			c := patchGreen
+			switch {
+			case (x == 0 || x == 3) && (y == 0 || y == 2):
+				c = patchRed
+			case y == 1 && x == 1:
+				c = patchBlue
+			case y == 1 && x == 2:
+				c = patchWhite
+			}
+			src.SetNRGBA(x, y, c)
+		}
+	}
+	im := paint.NewImageOp(src)
+	im.Filter = paint.FilterNearest
+	n := NinePatch{
+		Src:     im,
+		Inset:   PatchInset{Top: 1, Right: 1, Bottom: 1, Left: 1},
+		Padding: PatchInset{Top: 1, Right: 1, Bottom: 1, Left: 1},
+		Scale:   10,
+	}
+	content := func(gtx layout.Context) layout.Dimensions {
+		return layout.Dimensions{Size: image.Pt(60, 40)}
+	}
+	opts := golden.Options{Size: image.Pt(100, 100)}
+	draw := func(n NinePatch) *image.RGBA {
+		img, err := golden.Render(opts, func(gtx layout.Context) layout.Dimensions {
+			gtx.Constraints.Min = image.Point{}
+			dims := n.Layout(gtx, content)
+			if exp := image.Pt(80, 60); dims.Size != exp {
+				t.Errorf("got size %v, expected %v", dims.Size, exp)
+			}
+			return dims
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+	type pixel struct {
+		x, y int
+		c    color.NRGBA
+	}
+	check := func(img *image.RGBA, pixels []pixel) {
+		t.Helper()
+		for _, p := range pixels {
+			if got, exp := img.RGBAAt(p.x, p.y), f32color.NRGBAToRGBA(p.c); got != exp {
+				t.Errorf("(%d,%d): got %v, expected %v", p.x, p.y, got, exp)
+			}
+		}
+	}
+	img := draw(n)
+	check(img, []pixel{
+		// Unscaled corners.
+		{0, 0, patchRed}, {9, 9, patchRed}, {10, 0, patchGreen},
+		{79, 59, patchRed}, {70, 50, patchRed}, {69, 50, patchGreen},
+		// Stretched center: 2 pixels over 60.
+		{10, 30, patchBlue}, {39, 30, patchBlue}, {40, 30, patchWhite}, {69, 30, patchWhite},
+		// Outside.
+		{80, 30, color.NRGBA{}},
+	})
+
+	n.Modes.Center = PatchTile
+	img = draw(n)
+	check(img, []pixel{
+		{10, 20, patchBlue}, {19, 20, patchBlue}, {20, 20, patchWhite},
+		{29, 20, patchWhite}, {30, 20, patchBlue}, {69, 20, patchWhite},
+		// The center stretches vertically.
+		{10, 49, patchBlue},
+	})
+}
+
+func TestNinePatchSmall(t *testing.T) {
+	// The corners are scaled down to fit.
+	got := patchSplit(10, 2, 3, 4)
+	if exp := [4]int{0, 4, 4, 10}; got != exp {
+		t.Errorf("got %v, expected %v", got, exp)
+	}
+	got = patchSplit(100, 2, 3, 4)
+	if exp := [4]int{0, 8, 88, 100}; got != exp {
+		t.Errorf("got %v, expected %v", got, exp)
+	}
+}
+
+func TestDecodeNinePatch(t *testing.T) {
+	black := color.NRGBA{A: 0xff}
+	img := image.NewNRGBA(image.Rect(0, 0, 7, 6))
+	// Scaled regions: columns 2-3 and row 1 of the content.
+	img.SetNRGBA(3, 0, black)
+	img.SetNRGBA(4, 0, black)
+	img.SetNRGBA(0, 2, black)
+	// Content area: columns 1-4 and rows 0-3.
+	for x := 2; x <= 5; x++ {
+		img.SetNRGBA(x, 5, black)
+	}
+	for y := 1; y <= 4; y++ {
+		img.SetNRGBA(6, y, black)
+	}
+	img.SetNRGBA(1, 1, patchRed)
+	n, err := DecodeNinePatch(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (PatchInset{Top: 1, Right: 1, Bottom: 2, Left: 2}); n.Inset != exp {
+		t.Errorf("got inset %+v, expected %+v", n.Inset, exp)
+	}
+	if exp := (PatchInset{Top: 0, Right: 0, Bottom: 0, Left: 1}); n.Padding != exp {
+		t.Errorf("got padding %+v, expected %+v", n.Padding, exp)
+	}
+	if exp := image.Pt(5, 4); n.Src.Size() != exp {
+		t.Errorf("got image size %v, expected %v", n.Src.Size(), exp)
+	}
+
+	// Without content markers, the content area is the scaled region.
+	for x := 2; x <= 5; x++ {
+		img.SetNRGBA(x, 5, color.NRGBA{})
+	}
+	n, err = DecodeNinePatch(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := (PatchInset{Top: 0, Right: 1, Bottom: 0, Left: 2}); n.Padding != exp {
+		t.Errorf("got padding %+v, expected %+v", n.Padding, exp)
+	}
+
+	tests := []struct {
+		name string
+		img  func() image.Image
+		err  string
+	}{
+		{"small", func() image.Image { return image.NewNRGBA(image.Rect(0, 0, 2, 5)) }, "too small"},
+		{"unmarked", func() image.Image { return image.NewNRGBA(image.Rect(0, 0, 5, 5)) }, "no scaled region"},
+		{"multiple", func() image.Image {
+			img := image.NewNRGBA(image.Rect(0, 0, 6, 6))
+			img.SetNRGBA(0, 2, black)
+			img.SetNRGBA(1, 0, black)
+			img.SetNRGBA(3, 0, black)
+			return img
+		}, "more than one"},
+	}
+	for _, test := range tests {
+		_, err := DecodeNinePatch(test.img())
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, expected %q", test.name, err, test.err)
+		}
+	}
+}
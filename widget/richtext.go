@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"image/color"
+	"strings"
+
+	"gioui.org/io/semantic"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+	"gioui.org/unit"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// Span is a run of text with its own font, size and color, for
+// RichText.
+type Span struct {
+	Font  text.Font
+	Size  unit.Sp
+	Color color.NRGBA
+	Text  string
+}
+
+// RichText is a widget for laying out and drawing text made of spans of
+// different styles. The spans wrap together as a single text. Like
+// Label, RichText is always non-interactive.
+type RichText struct {
+	// Alignment specifies the text alignment.
+	Alignment text.Alignment
+	// MaxLines limits the number of lines. Zero means no limit.
+	MaxLines int
+	// Truncator is the text that will be shown at the end of the final
+	// line if MaxLines is exceeded. Defaults to "…" if empty.
+	Truncator string
+	// LineHeight is the distance between the baselines of lines of text.
+	// If zero, it is determined by the font.
+	LineHeight unit.Sp
+	// LineHeightScale multiplies the line height. Zero means no scaling.
+	LineHeightScale float32
+	// LetterSpacing is extra space after every character.
+	LetterSpacing unit.Sp
+	// ParagraphSpacing is extra space between paragraphs.
+	ParagraphSpacing unit.Sp
+}
+
+// Layout the spans with the given shaper.
+func (r RichText) Layout(gtx layout.Context, lt *text.Shaper, spans ...Span) layout.Dimensions {
+	cs := gtx.Constraints
+	textSpans := make([]text.Span, len(spans))
+	materials := make([]op.CallOp, len(spans))
+	var label strings.Builder
+	for i, s := range spans {
+		textSpans[i] = text.Span{
+			Font:    s.Font,
+			PxPerEm: fixed.I(gtx.Sp(s.Size)),
+			Text:    s.Text,
+		}
+		m := op.Record(gtx.Ops)
+		paint.ColorOp{Color: s.Color}.Add(gtx.Ops)
+		materials[i] = m.Stop()
+		label.WriteString(s.Text)
+	}
+	lt.LayoutSpans(text.Parameters{
+		MaxLines:         r.MaxLines,
+		Truncator:        r.Truncator,
+		Alignment:        r.Alignment,
+		MaxWidth:         cs.Max.X,
+		MinWidth:         cs.Min.X,
+		Locale:           gtx.Locale,
+		LineHeight:       spToFixed(gtx.Metric, r.LineHeight),
+		LineHeightScale:  r.LineHeightScale,
+		LetterSpacing:    spToFixed(gtx.Metric, r.LetterSpacing),
+		ParagraphSpacing: spToFixed(gtx.Metric, r.ParagraphSpacing),
+	}, textSpans)
+	m := op.Record(gtx.Ops)
+	viewport := image.Rectangle{Max: cs.Max}
+	it := textIterator{
+		viewport:  viewport,
+		maxLines:  r.MaxLines,
+		materials: materials,
+	}
+	semantic.LabelOp(label.String()).Add(gtx.Ops)
+	var glyphs [32]text.Glyph
+	line := glyphs[:0]
+	for g, ok := lt.NextGlyph(); ok; g, ok = lt.NextGlyph() {
+		var ok bool
+		if line, ok = it.paintGlyph(gtx, lt, g, line); !ok {
+			break
+		}
+	}
+	call := m.Stop()
+	viewport.Min = viewport.Min.Add(it.padding.Min)
+	viewport.Max = viewport.Max.Add(it.padding.Max)
+	clipStack := clip.Rect(viewport).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	dims := layout.Dimensions{Size: it.bounds.Size()}
+	dims.Size = cs.Constrain(dims.Size)
+	dims.Baseline = dims.Size.Y - it.baseline
+	clipStack.Pop()
+	return dims
+}
@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"gioui.org/font/gofont"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/text"
+)
+
+func TestRichText(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Constraints{Max: image.Pt(1000, 1000)},
+		Locale:      english,
+	}
+	cache := text.NewShaper(gofont.Collection())
+	label := Label{}.Layout(gtx, cache, text.Font{}, 10, "Hello world", op.CallOp{})
+	same := RichText{}.Layout(gtx, cache,
+		Span{Size: 10, Text: "Hello"},
+		Span{Size: 10, Text: " world"},
+	)
+	if same != label {
+		t.Errorf("spans of the same style laid out as %+v, expected %+v", same, label)
+	}
+	larger := RichText{}.Layout(gtx, cache,
+		Span{Size: 10, Text: "Hello"},
+		Span{Size: 20, Text: " world"},
+	)
+	if larger.Size.X <= label.Size.X || larger.Size.Y <= label.Size.Y {
+		t.Errorf("larger span laid out as %v, expected larger than %v", larger.Size, label.Size)
+	}
+	gtx.Constraints.Max.X = 50
+	wrapped := RichText{MaxLines: 1}.Layout(gtx, cache,
+		Span{Size: 10, Text: "Hello"},
+		Span{Size: 20, Text: " world"},
+	)
+	if wrapped.Size.X > gtx.Constraints.Max.X || wrapped.Size.Y != larger.Size.Y {
+		t.Errorf("truncated spans laid out as %v, expected a single line within %d", wrapped.Size, gtx.Constraints.Max.X)
+	}
+}
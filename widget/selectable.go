@@ -56,7 +56,18 @@ type Selectable struct {
 	MaxLines int
 	// Truncator is the symbol to use at the end of the final line of text
 	// if text was cut off. Defaults to "…" if left empty.
-	Truncator   string
+	Truncator string
+	// LineHeight is the distance between the baselines of lines of text.
+	// If zero, it is determined by the font.
+	LineHeight unit.Sp
+	// LineHeightScale multiplies the line height. Zero means no scaling.
+	LineHeightScale float32
+	// LetterSpacing is extra space after every character.
+	LetterSpacing unit.Sp
+	// ParagraphSpacing is extra space between paragraphs.
+	ParagraphSpacing unit.Sp
+	// Decorations are lines to draw along ranges of the text.
+	Decorations []TextDecoration
 	initialized bool
 	source      stringSource
 	// scratch is a buffer reused to efficiently read text out of the
@@ -181,6 +192,11 @@ func (l *Selectable) Layout(gtx layout.Context, lt *text.Shaper, font text.Font,
 	l.text.Alignment = l.Alignment
 	l.text.MaxLines = l.MaxLines
 	l.text.Truncator = l.Truncator
+	l.text.LineHeight = l.LineHeight
+	l.text.LineHeightScale = l.LineHeightScale
+	l.text.LetterSpacing = l.LetterSpacing
+	l.text.ParagraphSpacing = l.ParagraphSpacing
+	l.text.Decorations = l.Decorations
 	l.text.Update(gtx, lt, font, size, l.handleEvents)
 	dims := l.text.Dimensions()
 	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
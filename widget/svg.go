@@ -0,0 +1,1100 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"gioui.org/f32"
+	"gioui.org/internal/svgpath"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/unit"
+
+	"golang.org/x/image/colornames"
+)
+
+// SVG is a vector image decoded from a subset of SVG 1.1. The subset
+// covers the path, rect, circle, ellipse, line, polyline and polygon
+// shapes, groups with transforms, solid and gradient fills and strokes,
+// opacity and the view box of the root element.
+//
+// SVG images are decoded once by NewSVG and drawn with operations that
+// are recorded on first use and cached for subsequent layouts.
+type SVG struct {
+	root *svgElement
+	// viewBox is the area of the image in user units.
+	viewBox svgRect
+	// width and height are the intrinsic size of the image.
+	width, height unit.Dp
+
+	// Cached values.
+	ops  op.Ops
+	call op.CallOp
+	// recorded tracks whether call is valid.
+	recorded bool
+}
+
+// svgElement is a decoded group or shape.
+type svgElement struct {
+	transform f32.Affine2D
+	opacity   float32
+	// children of a group.
+	children []*svgElement
+	// path of a shape. Groups have a nil path.
+	path   *svgPath
+	fill   svgPaint
+	stroke svgPaint
+	style  svgStyle
+}
+
+// svgStyle is the set of inherited properties of an element.
+type svgStyle struct {
+	color         color.NRGBA
+	fill          svgPaint
+	fillOpacity   float32
+	fillRule      clip.FillRule
+	stroke        svgPaint
+	strokeOpacity float32
+	strokeWidth   float32
+	cap           clip.StrokeCap
+	join          clip.StrokeJoin
+	miterLimit    float32
+	dashes        []float32
+	dashOffset    float32
+}
+
+// svgPaint is the fill or stroke of a shape.
+type svgPaint struct {
+	// color is used when grad is nil.
+	color color.NRGBA
+	grad  *svgGradient
+	// none is set for the "none" paint.
+	none bool
+}
+
+type svgGradient struct {
+	radial bool
+	// userSpace is set when the gradient coordinates are in user space
+	// rather than relative to the bounding box of the shape.
+	userSpace bool
+	transform f32.Affine2D
+	// start and end are the end points of a linear gradient. For radial
+	// gradients, start is the center and end is on the circle.
+	start, end f32.Point
+	spread     paint.Spread
+	stops      []paint.GradientStop
+}
+
+type svgRect struct {
+	Min, Max f32.Point
+}
+
+// svgNode is an element of the SVG document tree.
+type svgNode struct {
+	name     string
+	attrs    map[string]string
+	children []*svgNode
+}
+
+// svgDecoder converts the document tree to elements.
+type svgDecoder struct {
+	viewBox svgRect
+	ids     map[string]*svgNode
+	grads   map[string]*svgGradient
+}
+
+const (
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+)
+
+// NewSVG decodes an SVG image. It returns an error if the image contains
+// elements or features outside the supported subset.
+func NewSVG(data []byte) (*SVG, error) {
+	root, err := parseSVGTree(data)
+	if err != nil {
+		return nil, err
+	}
+	s := new(SVG)
+	vb, hasViewBox, err := svgViewBox(root.attrs["viewBox"])
+	if err != nil {
+		return nil, err
+	}
+	// Relative sizes such as percentages are ignored.
+	w, errw := svgLength(root.attrs["width"])
+	h, errh := svgLength(root.attrs["height"])
+	hasSize := errw == nil && errh == nil && w > 0 && h > 0
+	switch {
+	case hasViewBox && hasSize:
+		s.width, s.height = unit.Dp(w), unit.Dp(h)
+	case hasViewBox:
+		s.width, s.height = unit.Dp(vb.Max.X-vb.Min.X), unit.Dp(vb.Max.Y-vb.Min.Y)
+	case hasSize:
+		vb = svgRect{Max: f32.Pt(w, h)}
+		s.width, s.height = unit.Dp(w), unit.Dp(h)
+	default:
+		return nil, errors.New("widget: SVG image has neither a view box nor a size")
+	}
+	s.viewBox = vb
+	d := &svgDecoder{
+		viewBox: vb,
+		ids:     make(map[string]*svgNode),
+		grads:   make(map[string]*svgGradient),
+	}
+	d.collectIDs(root)
+	def := svgStyle{
+		color:         color.NRGBA{A: 0xff},
+		fill:          svgPaint{color: color.NRGBA{A: 0xff}},
+		fillOpacity:   1,
+		stroke:        svgPaint{none: true},
+		strokeOpacity: 1,
+		strokeWidth:   1,
+		cap:           clip.ButtCap,
+		join:          clip.MiterJoin,
+		miterLimit:    4,
+	}
+	s.root, err = d.element(root, def)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Layout displays the image with its width set to the X minimum
+// constraint, or its intrinsic width if the constraint is zero. The
+// height follows from the aspect ratio of the image, and the view box is
+// scaled uniformly to fit and centered.
+func (s *SVG) Layout(gtx layout.Context) layout.Dimensions {
+	vsz := s.viewBox.Max.Sub(s.viewBox.Min)
+	w := gtx.Constraints.Min.X
+	if w == 0 {
+		w = gtx.Dp(s.width)
+	}
+	h := int(float32(w)*float32(s.height)/float32(s.width) + .5)
+	size := gtx.Constraints.Constrain(image.Pt(w, h))
+	defer clip.Rect{Max: size}.Push(gtx.Ops).Pop()
+
+	if !s.recorded {
+		s.record()
+	}
+	scale := float32(size.X) / vsz.X
+	if sy := float32(size.Y) / vsz.Y; sy < scale {
+		scale = sy
+	}
+	off := layout.FPt(size).Sub(vsz.Mul(scale)).Mul(.5)
+	tr := f32.Affine2D{}.Offset(s.viewBox.Min.Mul(-1)).Scale(f32.Point{}, f32.Pt(scale, scale)).Offset(off)
+	defer op.Affine(tr).Push(gtx.Ops).Pop()
+	s.call.Add(gtx.Ops)
+	return layout.Dimensions{Size: size}
+}
+
+// record the operations for drawing the image in user units.
+func (s *SVG) record() {
+	m := op.Record(&s.ops)
+	s.root.add(&s.ops)
+	s.call = m.Stop()
+	s.recorded = true
+}
+
+func (e *svgElement) add(ops *op.Ops) {
+	if e.transform != (f32.Affine2D{}) {
+		defer op.Affine(e.transform).Push(ops).Pop()
+	}
+	opacity := e.opacity
+	fill := e.fill.visible() && e.path != nil
+	stroke := e.stroke.visible() && e.path != nil && e.style.strokeWidth > 0
+	if opacity < 1 && (e.path == nil || fill && stroke) {
+		// Opacity applies to the element as a whole, which requires a
+		// layer unless it can be folded into a single paint.
+		defer paint.OpacityOp{Opacity: opacity}.Push(ops).Pop()
+		opacity = 1
+	}
+	for _, c := range e.children {
+		c.add(ops)
+	}
+	if !fill && !stroke {
+		return
+	}
+	spec := e.path.spec(ops)
+	if fill {
+		cl := clip.Outline{Path: spec, FillRule: e.style.fillRule}.Op().Push(ops)
+		e.fill.add(ops, e.path.bounds, e.style.fillOpacity*opacity)
+		cl.Pop()
+	}
+	if stroke {
+		cl := clip.Stroke{
+			Path:       spec,
+			Width:      e.style.strokeWidth,
+			Cap:        e.style.cap,
+			Join:       e.style.join,
+			MiterLimit: e.style.miterLimit,
+			Dashes:     e.style.dashes,
+			DashOffset: e.style.dashOffset,
+		}.Op().Push(ops)
+		e.stroke.add(ops, e.path.bounds, e.style.strokeOpacity*opacity)
+		cl.Pop()
+	}
+}
+
+func (p svgPaint) visible() bool {
+	return !p.none && (p.grad != nil || p.color.A > 0)
+}
+
+// add the paint to the current clip area, with its alpha scaled by
+// opacity. The bounds of the shape locate gradients in bounding box
+// units.
+func (p svgPaint) add(ops *op.Ops, bounds svgRect, opacity float32) {
+	g := p.grad
+	if g == nil {
+		paint.ColorOp{Color: scaleAlpha(p.color, opacity)}.Add(ops)
+		paint.PaintOp{}.Add(ops)
+		return
+	}
+	switch len(g.stops) {
+	case 0:
+		return
+	case 1:
+		paint.ColorOp{Color: scaleAlpha(g.stops[0].Color, opacity)}.Add(ops)
+		paint.PaintOp{}.Add(ops)
+		return
+	}
+	tr := g.transform
+	if !g.userSpace {
+		sz := bounds.Max.Sub(bounds.Min)
+		if sz.X <= 0 || sz.Y <= 0 {
+			// Bounding box units are undefined for shapes without area.
+			return
+		}
+		tr = f32.Affine2D{}.Scale(f32.Point{}, sz).Offset(bounds.Min).Mul(tr)
+	}
+	stops := make([]paint.GradientStop, len(g.stops))
+	for i, s := range g.stops {
+		s.Color = scaleAlpha(s.Color, opacity)
+		stops[i] = s
+	}
+	kind := paint.LinearGradient
+	if g.radial {
+		kind = paint.RadialGradient
+	}
+	// Non-uniform transforms of radial gradients are approximated by
+	// transforming the center and a point on the circle.
+	paint.GradientOp{
+		Kind:   kind,
+		Start:  tr.Transform(g.start),
+		End:    tr.Transform(g.end),
+		Stops:  stops,
+		Spread: g.spread,
+	}.Add(ops)
+	paint.PaintOp{}.Add(ops)
+}
+
+func scaleAlpha(c color.NRGBA, alpha float32) color.NRGBA {
+	c.A = uint8(float32(c.A)*alpha + .5)
+	return c
+}
+
+// parseSVGTree parses an SVG document into a tree of nodes. Elements and
+// attributes in foreign namespaces, such as those of editors, are
+// ignored.
+func parseSVGTree(data []byte) (*svgNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var (
+		root  *svgNode
+		stack []*svgNode
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("widget: invalid SVG: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != "" && t.Name.Space != svgNamespace {
+				if err := dec.Skip(); err != nil {
+					return nil, fmt.Errorf("widget: invalid SVG: %w", err)
+				}
+				continue
+			}
+			n := &svgNode{name: t.Name.Local, attrs: make(map[string]string)}
+			for _, a := range t.Attr {
+				switch a.Name.Space {
+				case "", svgNamespace:
+					n.attrs[a.Name.Local] = a.Value
+				case xlinkNamespace:
+					if a.Name.Local == "href" {
+						n.attrs["href"] = a.Value
+					}
+				}
+			}
+			// Style declarations override presentation attributes.
+			for _, decl := range strings.Split(n.attrs["style"], ";") {
+				if k, v, ok := strings.Cut(decl, ":"); ok {
+					n.attrs[strings.TrimSpace(k)] = strings.TrimSpace(v)
+				}
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if root == nil || root.name != "svg" {
+		return nil, errors.New("widget: missing SVG root element")
+	}
+	return root, nil
+}
+
+func (d *svgDecoder) collectIDs(n *svgNode) {
+	if id := n.attrs["id"]; id != "" {
+		d.ids[id] = n
+	}
+	for _, c := range n.children {
+		d.collectIDs(c)
+	}
+}
+
+// element decodes the node n, whose parent has the style parent. It
+// returns nil for nodes that are not drawn.
+func (d *svgDecoder) element(n *svgNode, parent svgStyle) (*svgElement, error) {
+	switch n.name {
+	case "svg", "g", "path", "rect", "circle", "ellipse", "line", "polyline", "polygon":
+	case "defs", "linearGradient", "radialGradient", "stop":
+		// Gradients are decoded when referenced.
+		return nil, d.checkDefs(n)
+	case "title", "desc", "metadata":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("widget: unsupported SVG element <%s>", n.name)
+	}
+	for _, attr := range []string{"clip-path", "mask", "filter"} {
+		if v := n.attrs[attr]; v != "" && v != "none" {
+			return nil, fmt.Errorf("widget: unsupported SVG attribute %s of <%s>", attr, n.name)
+		}
+	}
+	if n.attrs["display"] == "none" {
+		return nil, nil
+	}
+	style, err := d.style(n, parent)
+	if err != nil {
+		return nil, fmt.Errorf("widget: SVG element <%s>: %w", n.name, err)
+	}
+	e := &svgElement{
+		opacity: 1,
+		style:   style,
+		fill:    style.fill,
+		stroke:  style.stroke,
+	}
+	if v, ok := n.attrs["opacity"]; ok {
+		if e.opacity, err = svgOpacity(v); err != nil {
+			return nil, fmt.Errorf("widget: SVG element <%s>: %w", n.name, err)
+		}
+	}
+	if v, ok := n.attrs["transform"]; ok && n.name != "svg" {
+		if e.transform, err = parseSVGTransform(v); err != nil {
+			return nil, fmt.Errorf("widget: SVG element <%s>: %w", n.name, err)
+		}
+	}
+	switch n.name {
+	case "svg", "g":
+		for _, c := range n.children {
+			if c.name == "svg" {
+				// Nested svg elements establish new viewports.
+				return nil, errors.New("widget: unsupported nested SVG element <svg>")
+			}
+			ce, err := d.element(c, style)
+			if err != nil {
+				return nil, err
+			}
+			if ce != nil {
+				e.children = append(e.children, ce)
+			}
+		}
+		return e, nil
+	}
+	if !style.fill.visible() && !style.stroke.visible() {
+		return nil, nil
+	}
+	e.path, err = d.shape(n)
+	if err != nil {
+		return nil, fmt.Errorf("widget: SVG element <%s>: %w", n.name, err)
+	}
+	if e.path == nil {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// checkDefs reports an error for unsupported elements below n.
+func (d *svgDecoder) checkDefs(n *svgNode) error {
+	for _, c := range n.children {
+		switch c.name {
+		case "linearGradient", "radialGradient", "stop", "title", "desc":
+		default:
+			if n.name == "defs" {
+				// Shapes in definitions are not drawn, but their
+				// features must be supported.
+				if _, err := d.element(c, svgStyle{}); err != nil {
+					return err
+				}
+				continue
+			}
+			return fmt.Errorf("widget: unsupported SVG element <%s> in <%s>", c.name, n.name)
+		}
+		if err := d.checkDefs(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// style returns the style of n, given the style of its parent.
+func (d *svgDecoder) style(n *svgNode, s svgStyle) (svgStyle, error) {
+	var err error
+	attr := func(name string, f func(v string) error) {
+		if v, ok := n.attrs[name]; ok && err == nil && v != "inherit" {
+			if e := f(v); e != nil {
+				err = fmt.Errorf("invalid %s: %w", name, e)
+			}
+		}
+	}
+	attr("color", func(v string) (err error) {
+		s.color, err = svgColor(v, s.color)
+		return
+	})
+	attr("fill", func(v string) (err error) {
+		s.fill, err = d.paint(v, s.color)
+		return
+	})
+	attr("stroke", func(v string) (err error) {
+		s.stroke, err = d.paint(v, s.color)
+		return
+	})
+	attr("fill-opacity", func(v string) (err error) {
+		s.fillOpacity, err = svgOpacity(v)
+		return
+	})
+	attr("stroke-opacity", func(v string) (err error) {
+		s.strokeOpacity, err = svgOpacity(v)
+		return
+	})
+	attr("fill-rule", func(v string) error {
+		switch v {
+		case "nonzero":
+			s.fillRule = clip.NonZero
+		case "evenodd":
+			s.fillRule = clip.EvenOdd
+		default:
+			return fmt.Errorf("unknown value %q", v)
+		}
+		return nil
+	})
+	attr("stroke-width", func(v string) (err error) {
+		s.strokeWidth, err = svgLength(v)
+		return
+	})
+	attr("stroke-linecap", func(v string) error {
+		switch v {
+		case "butt":
+			s.cap = clip.ButtCap
+		case "round":
+			s.cap = clip.RoundCap
+		case "square":
+			s.cap = clip.SquareCap
+		default:
+			return fmt.Errorf("unknown value %q", v)
+		}
+		return nil
+	})
+	attr("stroke-linejoin", func(v string) error {
+		switch v {
+		case "miter", "miter-clip", "arcs":
+			s.join = clip.MiterJoin
+		case "round":
+			s.join = clip.RoundJoin
+		case "bevel":
+			s.join = clip.BevelJoin
+		default:
+			return fmt.Errorf("unknown value %q", v)
+		}
+		return nil
+	})
+	attr("stroke-miterlimit", func(v string) (err error) {
+		s.miterLimit, err = svgNumber(v)
+		return
+	})
+	attr("stroke-dasharray", func(v string) (err error) {
+		s.dashes = nil
+		if v != "none" {
+			s.dashes, err = svgpath.Numbers(v)
+		}
+		return
+	})
+	attr("stroke-dashoffset", func(v string) (err error) {
+		s.dashOffset, err = svgLength(v)
+		return
+	})
+	return s, err
+}
+
+// paint decodes a fill or stroke value.
+func (d *svgDecoder) paint(v string, current color.NRGBA) (svgPaint, error) {
+	if !strings.HasPrefix(v, "url(") {
+		if v == "none" {
+			return svgPaint{none: true}, nil
+		}
+		c, err := svgColor(v, current)
+		return svgPaint{color: c}, err
+	}
+	end := strings.IndexByte(v, ')')
+	if end == -1 {
+		return svgPaint{}, fmt.Errorf("invalid paint %q", v)
+	}
+	ref := strings.TrimSpace(v[len("url("):end])
+	id := strings.TrimPrefix(ref, "#")
+	if id == ref {
+		return svgPaint{}, fmt.Errorf("unsupported paint reference %q", ref)
+	}
+	if n, ok := d.ids[id]; ok {
+		g, err := d.gradient(n, 0)
+		return svgPaint{grad: g}, err
+	}
+	// Use the fallback value of missing references.
+	if fallback := strings.TrimSpace(v[end+1:]); fallback != "" {
+		return d.paint(fallback, current)
+	}
+	return svgPaint{none: true}, nil
+}
+
+// gradient decodes the gradient node n. Depth limits the chain of
+// gradients that inherit from other gradients.
+func (d *svgDecoder) gradient(n *svgNode, depth int) (*svgGradient, error) {
+	if g, ok := d.grads[n.attrs["id"]]; ok && depth == 0 {
+		return g, nil
+	}
+	if n.name != "linearGradient" && n.name != "radialGradient" {
+		return nil, fmt.Errorf("unsupported paint server <%s>", n.name)
+	}
+	if depth > 10 {
+		return nil, errors.New("too many gradient references")
+	}
+	// Gradients inherit attributes and stops from the gradient
+	// referenced by href.
+	attrs := make(map[string]string)
+	var stops []paint.GradientStop
+	if href := n.attrs["href"]; href != "" {
+		base, ok := d.ids[strings.TrimPrefix(href, "#")]
+		if !ok {
+			return nil, fmt.Errorf("missing gradient %q", href)
+		}
+		bg, err := d.gradient(base, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range base.attrs {
+			attrs[k] = v
+		}
+		stops = bg.stops
+	}
+	for k, v := range n.attrs {
+		attrs[k] = v
+	}
+	g := &svgGradient{radial: n.name == "radialGradient"}
+	switch u := attrs["gradientUnits"]; u {
+	case "", "objectBoundingBox":
+	case "userSpaceOnUse":
+		g.userSpace = true
+	default:
+		return nil, fmt.Errorf("invalid gradientUnits %q", u)
+	}
+	switch s := attrs["spreadMethod"]; s {
+	case "", "pad":
+		g.spread = paint.SpreadPad
+	case "repeat":
+		g.spread = paint.SpreadRepeat
+	case "reflect":
+		g.spread = paint.SpreadReflect
+	default:
+		return nil, fmt.Errorf("invalid spreadMethod %q", s)
+	}
+	if v, ok := attrs["gradientTransform"]; ok {
+		t, err := parseSVGTransform(v)
+		if err != nil {
+			return nil, err
+		}
+		g.transform = t
+	}
+	vsz := d.viewBox.Max.Sub(d.viewBox.Min)
+	// coord decodes a gradient coordinate, where percentages are
+	// relative to the bounding box or the view box.
+	coord := func(name, def string, size float32) (float32, error) {
+		v, ok := attrs[name]
+		if !ok {
+			v = def
+		}
+		if strings.HasSuffix(v, "%") {
+			f, err := svgNumber(strings.TrimSuffix(v, "%"))
+			if !g.userSpace {
+				size = 1
+			}
+			return f / 100 * size, err
+		}
+		return svgLength(v)
+	}
+	var (
+		coords [4]float32
+		err    error
+	)
+	names := [...]string{"x1", "y1", "x2", "y2"}
+	defs := [...]string{"0%", "0%", "100%", "0%"}
+	if g.radial {
+		names = [...]string{"cx", "cy", "r", ""}
+		defs = [...]string{"50%", "50%", "50%", ""}
+	}
+	diag := float32(math.Sqrt(float64(vsz.X*vsz.X+vsz.Y*vsz.Y) / 2))
+	sizes := [...]float32{vsz.X, vsz.Y, vsz.X, vsz.Y}
+	if g.radial {
+		sizes[2] = diag
+	}
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		if coords[i], err = coord(name, defs[i], sizes[i]); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	if g.radial {
+		g.start = f32.Pt(coords[0], coords[1])
+		g.end = g.start.Add(f32.Pt(coords[2], 0))
+	} else {
+		g.start = f32.Pt(coords[0], coords[1])
+		g.end = f32.Pt(coords[2], coords[3])
+	}
+	var own []paint.GradientStop
+	for _, c := range n.children {
+		if c.name != "stop" {
+			continue
+		}
+		s, err := svgStop(c)
+		if err != nil {
+			return nil, err
+		}
+		// Offsets never decrease.
+		if len(own) > 0 && s.Offset < own[len(own)-1].Offset {
+			s.Offset = own[len(own)-1].Offset
+		}
+		own = append(own, s)
+	}
+	if len(own) > 0 {
+		stops = own
+	}
+	g.stops = stops
+	if depth == 0 {
+		d.grads[n.attrs["id"]] = g
+	}
+	return g, nil
+}
+
+func svgStop(n *svgNode) (paint.GradientStop, error) {
+	var s paint.GradientStop
+	off := n.attrs["offset"]
+	var err error
+	if strings.HasSuffix(off, "%") {
+		s.Offset, err = svgNumber(strings.TrimSuffix(off, "%"))
+		s.Offset /= 100
+	} else if off != "" {
+		s.Offset, err = svgNumber(off)
+	}
+	if err != nil {
+		return s, fmt.Errorf("invalid stop offset: %w", err)
+	}
+	s.Offset = clamp1(s.Offset)
+	s.Color = color.NRGBA{A: 0xff}
+	if v, ok := n.attrs["stop-color"]; ok {
+		if s.Color, err = svgColor(v, color.NRGBA{A: 0xff}); err != nil {
+			return s, fmt.Errorf("invalid stop-color: %w", err)
+		}
+	}
+	if v, ok := n.attrs["stop-opacity"]; ok {
+		o, err := svgOpacity(v)
+		if err != nil {
+			return s, fmt.Errorf("invalid stop-opacity: %w", err)
+		}
+		s.Color = scaleAlpha(s.Color, o)
+	}
+	return s, nil
+}
+
+// shape decodes the geometry of a shape element. It returns nil for
+// shapes that are not rendered, such as rectangles without area.
+func (d *svgDecoder) shape(n *svgNode) (*svgPath, error) {
+	var err error
+	num := func(name string) float32 {
+		v, ok := n.attrs[name]
+		if !ok || err != nil {
+			return 0
+		}
+		f, e := svgLength(v)
+		if e != nil {
+			err = fmt.Errorf("invalid %s: %w", name, e)
+		}
+		return f
+	}
+	p := new(svgPath)
+	switch n.name {
+	case "path":
+		if e := svgpath.Parse(p, n.attrs["d"]); e != nil {
+			return nil, e
+		}
+	case "rect":
+		x, y, w, h := num("x"), num("y"), num("width"), num("height")
+		rx, ry := num("rx"), num("ry")
+		if err != nil || w <= 0 || h <= 0 {
+			return nil, err
+		}
+		// A missing radius defaults to the other radius.
+		if _, ok := n.attrs["rx"]; !ok {
+			rx = ry
+		}
+		if _, ok := n.attrs["ry"]; !ok {
+			ry = rx
+		}
+		rx = float32(math.Min(float64(rx), float64(w/2)))
+		ry = float32(math.Min(float64(ry), float64(h/2)))
+		if rx <= 0 || ry <= 0 {
+			p.MoveTo(f32.Pt(x, y))
+			p.LineTo(f32.Pt(x+w, y))
+			p.LineTo(f32.Pt(x+w, y+h))
+			p.LineTo(f32.Pt(x, y+h))
+			p.Close()
+			break
+		}
+		p.MoveTo(f32.Pt(x+rx, y))
+		p.LineTo(f32.Pt(x+w-rx, y))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(x+w, y+ry))
+		p.LineTo(f32.Pt(x+w, y+h-ry))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(x+w-rx, y+h))
+		p.LineTo(f32.Pt(x+rx, y+h))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(x, y+h-ry))
+		p.LineTo(f32.Pt(x, y+ry))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(x+rx, y))
+		p.Close()
+	case "circle", "ellipse":
+		cx, cy := num("cx"), num("cy")
+		rx, ry := num("rx"), num("ry")
+		if n.name == "circle" {
+			rx = num("r")
+			ry = rx
+		}
+		if err != nil || rx <= 0 || ry <= 0 {
+			return nil, err
+		}
+		p.MoveTo(f32.Pt(cx+rx, cy))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(cx, cy+ry))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(cx-rx, cy))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(cx, cy-ry))
+		svgpath.ArcTo(p, rx, ry, 0, false, true, f32.Pt(cx+rx, cy))
+		p.Close()
+	case "line":
+		x1, y1, x2, y2 := num("x1"), num("y1"), num("x2"), num("y2")
+		if err != nil {
+			return nil, err
+		}
+		p.MoveTo(f32.Pt(x1, y1))
+		p.LineTo(f32.Pt(x2, y2))
+	case "polyline", "polygon":
+		pts, err := svgpath.Numbers(n.attrs["points"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid points: %w", err)
+		}
+		if len(pts)%2 == 1 {
+			return nil, errors.New("odd number of coordinates in points")
+		}
+		for i := 0; i < len(pts); i += 2 {
+			pt := f32.Pt(pts[i], pts[i+1])
+			if i == 0 {
+				p.MoveTo(pt)
+			} else {
+				p.LineTo(pt)
+			}
+		}
+		if n.name == "polygon" {
+			p.Close()
+		}
+	}
+	if len(p.segs) == 0 {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// svgPath records the segments of a shape and their bounds.
+type svgPath struct {
+	segs       []svgSegment
+	pen, start f32.Point
+	bounds     svgRect
+	hasBounds  bool
+}
+
+type svgSegment struct {
+	// op is one of 'M', 'L', 'Q', 'C' or 'Z'.
+	op  byte
+	pts [3]f32.Point
+}
+
+func (p *svgPath) Pos() f32.Point { return p.pen }
+
+func (p *svgPath) MoveTo(to f32.Point) {
+	p.segs = append(p.segs, svgSegment{op: 'M', pts: [3]f32.Point{to}})
+	p.pen, p.start = to, to
+}
+
+func (p *svgPath) LineTo(to f32.Point) {
+	p.segs = append(p.segs, svgSegment{op: 'L', pts: [3]f32.Point{to}})
+	p.expand(p.pen)
+	p.expand(to)
+	p.pen = to
+}
+
+func (p *svgPath) QuadTo(ctrl, to f32.Point) {
+	p.segs = append(p.segs, svgSegment{op: 'Q', pts: [3]f32.Point{ctrl, to}})
+	// Elevate the curve to a cubic to find its extrema.
+	c0 := p.pen.Add(ctrl.Sub(p.pen).Mul(2. / 3))
+	c1 := to.Add(ctrl.Sub(to).Mul(2. / 3))
+	p.expandCubic(p.pen, c0, c1, to)
+	p.pen = to
+}
+
+func (p *svgPath) CubeTo(ctrl0, ctrl1, to f32.Point) {
+	p.segs = append(p.segs, svgSegment{op: 'C', pts: [3]f32.Point{ctrl0, ctrl1, to}})
+	p.expandCubic(p.pen, ctrl0, ctrl1, to)
+	p.pen = to
+}
+
+func (p *svgPath) Close() {
+	p.segs = append(p.segs, svgSegment{op: 'Z'})
+	p.pen = p.start
+}
+
+// expandCubic expands the bounds to include the end points and extrema of
+// a cubic Bézier curve.
+func (p *svgPath) expandCubic(p0, p1, p2, p3 f32.Point) {
+	p.expand(p0)
+	p.expand(p3)
+	extrema := func(v0, v1, v2, v3 float32) []float64 {
+		// The roots of the derivative a*t² + b*t + c.
+		a := float64(3 * (-v0 + 3*v1 - 3*v2 + v3))
+		b := float64(6 * (v0 - 2*v1 + v2))
+		c := float64(3 * (v1 - v0))
+		if math.Abs(a) < 1e-12 {
+			if b == 0 {
+				return nil
+			}
+			return []float64{-c / b}
+		}
+		disc := b*b - 4*a*c
+		if disc < 0 {
+			return nil
+		}
+		sq := math.Sqrt(disc)
+		return []float64{(-b + sq) / (2 * a), (-b - sq) / (2 * a)}
+	}
+	ts := append(extrema(p0.X, p1.X, p2.X, p3.X), extrema(p0.Y, p1.Y, p2.Y, p3.Y)...)
+	for _, t := range ts {
+		if t <= 0 || t >= 1 {
+			continue
+		}
+		t := float32(t)
+		mt := 1 - t
+		pt := p0.Mul(mt * mt * mt).Add(p1.Mul(3 * mt * mt * t)).Add(p2.Mul(3 * mt * t * t)).Add(p3.Mul(t * t * t))
+		p.expand(pt)
+	}
+}
+
+func (p *svgPath) expand(pt f32.Point) {
+	if !p.hasBounds {
+		p.bounds = svgRect{Min: pt, Max: pt}
+		p.hasBounds = true
+		return
+	}
+	b := &p.bounds
+	b.Min.X = float32(math.Min(float64(b.Min.X), float64(pt.X)))
+	b.Min.Y = float32(math.Min(float64(b.Min.Y), float64(pt.Y)))
+	b.Max.X = float32(math.Max(float64(b.Max.X), float64(pt.X)))
+	b.Max.Y = float32(math.Max(float64(b.Max.Y), float64(pt.Y)))
+}
+
+// spec records the path in ops.
+func (p *svgPath) spec(ops *op.Ops) clip.PathSpec {
+	var cp clip.Path
+	cp.Begin(ops)
+	for _, s := range p.segs {
+		switch s.op {
+		case 'M':
+			cp.MoveTo(s.pts[0])
+		case 'L':
+			cp.LineTo(s.pts[0])
+		case 'Q':
+			cp.QuadTo(s.pts[0], s.pts[1])
+		case 'C':
+			cp.CubeTo(s.pts[0], s.pts[1], s.pts[2])
+		case 'Z':
+			cp.Close()
+		}
+	}
+	return cp.End()
+}
+
+// parseSVGTransform decodes the value of a transform attribute.
+func parseSVGTransform(v string) (f32.Affine2D, error) {
+	var t f32.Affine2D
+	s := strings.TrimSpace(v)
+	for s != "" {
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open == -1 || end < open {
+			return t, fmt.Errorf("invalid transform %q", v)
+		}
+		name := strings.TrimSpace(s[:open])
+		args, err := svgpath.Numbers(s[open+1 : end])
+		if err != nil {
+			return t, fmt.Errorf("invalid transform %q: %w", v, err)
+		}
+		s = strings.TrimLeft(s[end+1:], " \t\r\n,")
+		n := len(args)
+		arg := func(i int, def float32) float32 {
+			if i < n {
+				return args[i]
+			}
+			return def
+		}
+		const rad = math.Pi / 180
+		var m f32.Affine2D
+		switch {
+		case name == "matrix" && n == 6:
+			m = f32.NewAffine2D(args[0], args[2], args[4], args[1], args[3], args[5])
+		case name == "translate" && (n == 1 || n == 2):
+			m = m.Offset(f32.Pt(args[0], arg(1, 0)))
+		case name == "scale" && (n == 1 || n == 2):
+			m = m.Scale(f32.Point{}, f32.Pt(args[0], arg(1, args[0])))
+		case name == "rotate" && (n == 1 || n == 3):
+			m = m.Rotate(f32.Pt(arg(1, 0), arg(2, 0)), args[0]*rad)
+		case name == "skewX" && n == 1:
+			m = f32.NewAffine2D(1, float32(math.Tan(float64(args[0]*rad))), 0, 0, 1, 0)
+		case name == "skewY" && n == 1:
+			m = f32.NewAffine2D(1, 0, 0, float32(math.Tan(float64(args[0]*rad))), 1, 0)
+		default:
+			return t, fmt.Errorf("invalid transform %q", v)
+		}
+		// Transforms apply from right to left.
+		t = t.Mul(m)
+	}
+	return t, nil
+}
+
+func svgViewBox(v string) (svgRect, bool, error) {
+	if v == "" {
+		return svgRect{}, false, nil
+	}
+	nums, err := svgpath.Numbers(v)
+	if err != nil {
+		return svgRect{}, false, fmt.Errorf("widget: invalid SVG viewBox: %w", err)
+	}
+	if len(nums) != 4 || nums[2] <= 0 || nums[3] <= 0 {
+		return svgRect{}, false, fmt.Errorf("widget: invalid SVG viewBox %q", v)
+	}
+	return svgRect{Min: f32.Pt(nums[0], nums[1]), Max: f32.Pt(nums[0]+nums[2], nums[1]+nums[3])}, true, nil
+}
+
+// svgLength decodes a length in user units.
+func svgLength(v string) (float32, error) {
+	return svgNumber(strings.TrimSuffix(strings.TrimSpace(v), "px"))
+}
+
+func svgNumber(v string) (float32, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", v)
+	}
+	return float32(f), nil
+}
+
+// svgOpacity decodes an opacity, clamped to [0, 1].
+func svgOpacity(v string) (float32, error) {
+	o, err := svgNumber(v)
+	return clamp1(o), err
+}
+
+// svgColor decodes a color. The current color is used for the
+// "currentColor" keyword.
+func svgColor(v string, current color.NRGBA) (color.NRGBA, error) {
+	v = strings.TrimSpace(v)
+	switch {
+	case v == "currentColor":
+		return current, nil
+	case strings.HasPrefix(v, "#"):
+		hex := v[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		c, err := strconv.ParseUint(hex, 16, 32)
+		if len(hex) != 6 || err != nil {
+			return color.NRGBA{}, fmt.Errorf("invalid color %q", v)
+		}
+		return color.NRGBA{R: uint8(c >> 16), G: uint8(c >> 8), B: uint8(c), A: 0xff}, nil
+	case strings.HasPrefix(v, "rgb(") && strings.HasSuffix(v, ")"):
+		parts := strings.Split(v[len("rgb("):len(v)-1], ",")
+		if len(parts) != 3 {
+			return color.NRGBA{}, fmt.Errorf("invalid color %q", v)
+		}
+		var rgb [3]uint8
+		for i, p := range parts {
+			p = strings.TrimSpace(p)
+			scale := float32(1)
+			if strings.HasSuffix(p, "%") {
+				p = strings.TrimSuffix(p, "%")
+				scale = 255. / 100
+			}
+			f, err := svgNumber(p)
+			if err != nil {
+				return color.NRGBA{}, fmt.Errorf("invalid color %q", v)
+			}
+			rgb[i] = uint8(clamp1(f*scale/255)*255 + .5)
+		}
+		return color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 0xff}, nil
+	}
+	if c, ok := colornames.Map[strings.ToLower(v)]; ok {
+		return color.NRGBA(c), nil
+	}
+	return color.NRGBA{}, fmt.Errorf("invalid color %q", v)
+}
+
+func clamp1(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
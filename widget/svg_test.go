@@ -0,0 +1,162 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"gioui.org/f32"
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/unit"
+)
+
+const testSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"
+     xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape"
+     width="48" height="24" viewBox="0 0 96 48">
+  <title>Test</title>
+  <inkscape:perspective id="perspective"/>
+  <defs>
+    <linearGradient id="base">
+      <stop offset="0" stop-color="red"/>
+      <stop offset="100%" style="stop-color:#00f;stop-opacity:.5"/>
+    </linearGradient>
+    <linearGradient id="lin" xlink:href="#base" x2="0" y2="1" spreadMethod="reflect"/>
+    <radialGradient id="rad" cx="24" cy="24" r="10" gradientUnits="userSpaceOnUse">
+      <stop offset=".2" stop-color="rgb(255, 128, 0)"/>
+      <stop offset=".1" stop-color="rgb(0%, 50%, 100%)"/>
+    </radialGradient>
+  </defs>
+  <g transform="translate(2 2) scale(.5)" opacity=".5" color="green" fill="currentColor">
+    <path d="M0 0 h20 v20 z" stroke="black" stroke-width="2" stroke-dasharray="2 1"/>
+    <rect x="4" y="4" width="10" height="8" rx="2" fill="url(#lin)"/>
+    <circle cx="24" cy="24" r="10" fill="url(#rad)" style="opacity: .5"/>
+  </g>
+  <ellipse cx="60" cy="20" rx="10" ry="5" fill="none" stroke="#abc" stroke-linejoin="round" stroke-linecap="square"/>
+  <line x1="0" y1="40" x2="96" y2="40" stroke="blue"/>
+  <polyline points="0,44 10,46 20,44" fill="none" stroke="blue"/>
+  <polygon points="80 0 96 0 96 16" fill="url(#missing) yellow" fill-rule="evenodd" display="inline"/>
+  <rect width="0" height="10"/>
+</svg>
+`
+
+func TestSVG(t *testing.T) {
+	s, err := NewSVG([]byte(testSVG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.width != 48 || s.height != 24 {
+		t.Errorf("got size %vx%v, expected 48x24", s.width, s.height)
+	}
+	root := s.root
+	if n := len(root.children); n != 5 {
+		t.Fatalf("got %d elements, expected 5", n)
+	}
+	g := root.children[0]
+	if g.opacity != .5 || len(g.children) != 3 {
+		t.Errorf("unexpected group %+v", g)
+	}
+	if got, want := g.transform.Transform(f32.Pt(4, 4)), f32.Pt(4, 4); got != want {
+		t.Errorf("group transformed (4,4) to %v, expected %v", got, want)
+	}
+	if c := g.children[0].fill.color; c != (color.NRGBA{G: 0x80, A: 0xff}) {
+		t.Errorf("got currentColor fill %v, expected green", c)
+	}
+	lin := g.children[1].fill.grad
+	if lin == nil || len(lin.stops) != 2 || lin.spread != 2 || lin.end != f32.Pt(0, 1) {
+		t.Errorf("unexpected inherited gradient %+v", lin)
+	} else if c := lin.stops[1].Color; c != (color.NRGBA{B: 0xff, A: 0x80}) {
+		t.Errorf("got stop color %v, expected semi-transparent blue", c)
+	}
+	rad := g.children[2].fill.grad
+	if rad == nil || !rad.userSpace || rad.start != f32.Pt(24, 24) || rad.end != f32.Pt(34, 24) {
+		t.Errorf("unexpected radial gradient %+v", rad)
+	} else if off := rad.stops[1].Offset; off != .2 {
+		t.Errorf("got decreasing stop offset %v, expected .2", off)
+	}
+	if b := g.children[2].path.bounds; b != (svgRect{Min: f32.Pt(14, 14), Max: f32.Pt(34, 34)}) {
+		t.Errorf("got circle bounds %v", b)
+	}
+	ell := root.children[1]
+	if ell.style.join != clip.RoundJoin || ell.style.cap != clip.SquareCap || ell.fill.visible() {
+		t.Errorf("unexpected ellipse style %+v", ell.style)
+	}
+	if c := root.children[4].fill.color; c != (color.NRGBA{R: 0xff, G: 0xff, A: 0xff}) {
+		t.Errorf("got fallback color %v, expected yellow", c)
+	}
+	if r := root.children[4].style.fillRule; r != clip.EvenOdd {
+		t.Errorf("got fill rule %v, expected even-odd", r)
+	}
+
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Metric:      unit.Metric{PxPerDp: 2},
+		Constraints: layout.Constraints{Max: image.Pt(1000, 1000)},
+	}
+	if dims := s.Layout(gtx); dims.Size != image.Pt(96, 48) {
+		t.Errorf("got intrinsic size %v, expected (96,48)", dims.Size)
+	}
+	gtx.Constraints.Min.X = 200
+	if dims := s.Layout(gtx); dims.Size != image.Pt(200, 100) {
+		t.Errorf("got size %v, expected (200,100)", dims.Size)
+	}
+}
+
+func TestSVGErrors(t *testing.T) {
+	tests := []struct {
+		svg string
+		err string
+	}{
+		{`<svg viewBox="0 0 10 10"><text>Hi</text></svg>`, "unsupported SVG element <text>"},
+		{`<svg viewBox="0 0 10 10"><defs><clipPath id="c"/></defs></svg>`, "unsupported SVG element <clipPath>"},
+		{`<svg viewBox="0 0 10 10"><svg/></svg>`, "nested"},
+		{`<svg viewBox="0 0 10 10"><path d="M0 0" clip-path="url(#c)"/></svg>`, "clip-path"},
+		{`<svg viewBox="0 0 10 10"><path d="M0 0 L1 x"/></svg>`, "offset 8"},
+		{`<svg viewBox="0 0 10 10"><path d="M0 0" fill-rule="alternate"/></svg>`, "invalid fill-rule"},
+		{`<svg viewBox="0 0 10 10"><path d="M0 0" fill="#12"/></svg>`, "invalid color"},
+		{`<svg viewBox="0 0 10 10"><rect width="50%" height="10"/></svg>`, "invalid width"},
+		{`<svg><path d="M0 0"/></svg>`, "neither a view box nor a size"},
+		{`<html/>`, "missing SVG root"},
+		{`<svg viewBox="0 0 10 10">`, "invalid SVG"},
+	}
+	for _, test := range tests {
+		_, err := NewSVG([]byte(test.svg))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, expected %q", test.svg, err, test.err)
+		}
+	}
+}
+
+func TestSVGTransform(t *testing.T) {
+	tests := []struct {
+		transform string
+		want      f32.Point
+	}{
+		{"translate(1)", f32.Pt(2, 1)},
+		{"scale(2, 3)", f32.Pt(2, 3)},
+		{"rotate(90)", f32.Pt(-1, 1)},
+		{"rotate(90 1 0)", f32.Pt(0, 0)},
+		{"matrix(1 0 0 1 5 6)", f32.Pt(6, 7)},
+		{"translate(1,2) scale(2)", f32.Pt(3, 4)},
+		{"skewX(45)", f32.Pt(2, 1)},
+	}
+	for _, test := range tests {
+		tr, err := parseSVGTransform(test.transform)
+		if err != nil {
+			t.Errorf("%s: %v", test.transform, err)
+			continue
+		}
+		got := tr.Transform(f32.Pt(1, 1))
+		if d := got.Sub(test.want); d.X*d.X+d.Y*d.Y > 1e-6 {
+			t.Errorf("%s: transformed (1,1) to %v, expected %v", test.transform, got, test.want)
+		}
+	}
+	if _, err := parseSVGTransform("rotate(1 2)"); err == nil {
+		t.Error("invalid transform succeeded")
+	}
+}
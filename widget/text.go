@@ -52,6 +52,17 @@ type textView struct {
 	// Truncator is the text that will be shown at the end of the final
 	// line if MaxLines is exceeded. Defaults to "…" if empty.
 	Truncator string
+	// LineHeight is the distance between the baselines of lines of text.
+	// If zero, it is determined by the font.
+	LineHeight unit.Sp
+	// LineHeightScale multiplies the line height. Zero means no scaling.
+	LineHeightScale float32
+	// LetterSpacing is extra space after every character.
+	LetterSpacing unit.Sp
+	// ParagraphSpacing is extra space between paragraphs.
+	ParagraphSpacing unit.Sp
+	// Decorations are lines to draw along ranges of the text.
+	Decorations []TextDecoration
 	// Mask replaces the visual display of each rune in the contents with the given rune.
 	// Newline characters are not masked. When non-zero, the unmasked contents
 	// are accessed by Len, Text, and SetText.
@@ -67,6 +78,7 @@ type textView struct {
 	// paragraphReader is used to populate graphemes.
 	paragraphReader graphemeReader
 	lastMask        rune
+	decorator       decorationPainter
 	viewSize        image.Point
 	valid           bool
 	regions         []Region
@@ -266,6 +278,19 @@ func (e *textView) Update(gtx layout.Context, lt *text.Shaper, font text.Font, s
 		e.params.MaxLines = e.MaxLines
 		e.invalidate()
 	}
+	lineHeight := spToFixed(gtx.Metric, e.LineHeight)
+	letterSpacing := spToFixed(gtx.Metric, e.LetterSpacing)
+	paragraphSpacing := spToFixed(gtx.Metric, e.ParagraphSpacing)
+	if lineHeight != e.params.LineHeight || e.LineHeightScale != e.params.LineHeightScale {
+		e.params.LineHeight = lineHeight
+		e.params.LineHeightScale = e.LineHeightScale
+		e.invalidate()
+	}
+	if letterSpacing != e.params.LetterSpacing || paragraphSpacing != e.params.ParagraphSpacing {
+		e.params.LetterSpacing = letterSpacing
+		e.params.ParagraphSpacing = paragraphSpacing
+		e.invalidate()
+	}
 
 	e.makeValid()
 	if eventHandling != nil {
@@ -315,14 +340,23 @@ func (e *textView) PaintText(gtx layout.Context, material op.CallOp) {
 		}
 		startGlyph += line.glyphs
 	}
+	runes := 0
+	if len(e.Decorations) > 0 {
+		for _, g := range e.index.glyphs[:startGlyph] {
+			runes += g.Runes
+		}
+	}
+	e.decorator.reset(e.Decorations, runes)
 	var glyphs [32]text.Glyph
 	line := glyphs[:0]
 	for _, g := range e.index.glyphs[startGlyph:] {
+		e.decorator.glyph(g)
 		var ok bool
 		if line, ok = it.paintGlyph(gtx, e.shaper, g, line); !ok {
 			break
 		}
 	}
+	e.decorator.paint(gtx, e.scrollOff, material)
 
 	call := m.Stop()
 	viewport.Min = viewport.Min.Add(it.padding.Min)
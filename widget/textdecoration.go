@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+
+	"gioui.org/layout"
+	"gioui.org/op"
+	"gioui.org/op/clip"
+	"gioui.org/op/paint"
+	"gioui.org/text"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// DecorationLine is a set of lines that decorate text.
+type DecorationLine uint8
+
+const (
+	// Underline draws a line below the baseline.
+	Underline DecorationLine = 1 << iota
+	// Strikethrough draws a line through the text.
+	Strikethrough
+	// Overline draws a line above the text.
+	Overline
+)
+
+// TextDecoration decorates the runes [Start, End) of a text with lines. The
+// lines are placed according to the metrics of the fonts of the text, and
+// follow the text across line wraps and changes of direction.
+type TextDecoration struct {
+	Start, End int
+	Lines      DecorationLine
+	// Material is the paint material of the lines. If unset, the material
+	// of the text is used.
+	Material op.CallOp
+}
+
+// decorationPainter collects the line segments of text decorations from
+// the glyphs of a text.
+type decorationPainter struct {
+	decorations []TextDecoration
+	// runes is the rune offset of the current glyph cluster.
+	runes int
+	// clusterMin and clusterMax track the horizontal extent of the
+	// current glyph cluster.
+	clusterMin, clusterMax fixed.Int26_6
+	inCluster              bool
+	segments               []decorationSegment
+}
+
+// decorationSegment is a horizontal line of a decoration.
+type decorationSegment struct {
+	// decoration is the index of the decoration of the segment.
+	decoration int
+	line       DecorationLine
+	// y is the baseline of the segment.
+	y              int32
+	offset, height fixed.Int26_6
+	minX, maxX     fixed.Int26_6
+}
+
+// reset prepares the painter for the glyphs of a text, starting at
+// rune offset runes.
+func (d *decorationPainter) reset(decorations []TextDecoration, runes int) {
+	d.decorations = decorations
+	d.runes = runes
+	d.inCluster = false
+	d.segments = d.segments[:0]
+}
+
+// glyph adds the decoration segments covering g.
+func (d *decorationPainter) glyph(g text.Glyph) {
+	if len(d.decorations) == 0 {
+		return
+	}
+	if !d.inCluster {
+		d.inCluster = true
+		d.clusterMin, d.clusterMax = g.X, g.X
+	}
+	if g.X < d.clusterMin {
+		d.clusterMin = g.X
+	}
+	if end := g.X + g.Advance; end > d.clusterMax {
+		d.clusterMax = end
+	}
+	if g.Flags&text.FlagClusterBreak == 0 {
+		return
+	}
+	d.inCluster = false
+	start, n := d.runes, g.Runes
+	d.runes += n
+	width := d.clusterMax - d.clusterMin
+	if n == 0 || width == 0 {
+		return
+	}
+	for i, deco := range d.decorations {
+		from, to := max(deco.Start, start), min(deco.End, start+n)
+		if from >= to {
+			continue
+		}
+		minX, maxX := d.clusterMin, d.clusterMax
+		if g.Flags&text.FlagTruncator == 0 {
+			// Divide the cluster evenly among its runes, like the
+			// cursor positions of the glyph index.
+			at := func(runes int) fixed.Int26_6 {
+				return width * fixed.Int26_6(runes-start) / fixed.Int26_6(n)
+			}
+			if g.Flags&text.FlagTowardOrigin != 0 {
+				minX, maxX = d.clusterMax-at(to), d.clusterMax-at(from)
+			} else {
+				minX, maxX = d.clusterMin+at(from), d.clusterMin+at(to)
+			}
+		}
+		m := g.Decorations
+		if deco.Lines&Underline != 0 {
+			d.add(decorationSegment{decoration: i, line: Underline, y: g.Y, offset: m.UnderlineOffset, height: m.UnderlineThickness, minX: minX, maxX: maxX})
+		}
+		if deco.Lines&Strikethrough != 0 {
+			d.add(decorationSegment{decoration: i, line: Strikethrough, y: g.Y, offset: m.StrikethroughOffset, height: m.StrikethroughThickness, minX: minX, maxX: maxX})
+		}
+		if deco.Lines&Overline != 0 {
+			d.add(decorationSegment{decoration: i, line: Overline, y: g.Y, offset: m.OverlineOffset, height: m.UnderlineThickness, minX: minX, maxX: maxX})
+		}
+	}
+}
+
+// add a segment, extending the previous segment of the same line if
+// they touch.
+func (d *decorationPainter) add(s decorationSegment) {
+	for i := len(d.segments) - 1; i >= 0; i-- {
+		p := &d.segments[i]
+		if p.decoration != s.decoration || p.line != s.line {
+			continue
+		}
+		if p.y == s.y && p.offset == s.offset && p.height == s.height {
+			switch {
+			case p.maxX == s.minX:
+				p.maxX = s.maxX
+				return
+			case p.minX == s.maxX:
+				p.minX = s.minX
+				return
+			}
+		}
+		break
+	}
+	d.segments = append(d.segments, s)
+}
+
+// paint the segments offset by off, with the material of their
+// decoration or the text material.
+func (d *decorationPainter) paint(gtx layout.Context, off image.Point, textMaterial op.CallOp) {
+	for _, s := range d.segments {
+		top := (fixed.I(int(s.y)) + s.offset).Round()
+		r := image.Rectangle{
+			Min: image.Pt(s.minX.Round(), top),
+			Max: image.Pt(s.maxX.Round(), top+max(s.height.Round(), 1)),
+		}
+		material := d.decorations[s.decoration].Material
+		if material == (op.CallOp{}) {
+			material = textMaterial
+		}
+		area := clip.Rect(r.Sub(off)).Push(gtx.Ops)
+		material.Add(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		area.Pop()
+	}
+}
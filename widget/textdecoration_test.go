@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"testing"
+
+	"gioui.org/text"
+	"golang.org/x/image/math/fixed"
+)
+
+// TestTextDecorations checks that decoration lines cover exactly the glyphs of
+// the decorated runes, across line wraps and runs of either direction.
+func TestTextDecorations(t *testing.T) {
+	type testcase struct {
+		name       string
+		str        string
+		start, end int
+		minLines   int
+	}
+	for _, tc := range []testcase{
+		{name: "wrapped", str: "Lorem ipsum dolor sit amet, consectetur", start: 3, end: 30, minLines: 2},
+		{name: "rtl run", str: "abc שלום def", start: 4, end: 8, minLines: 1},
+		{name: "bidi", str: "abc שלום def", start: 2, end: 10, minLines: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			glyphs := getGlyphs(16, 0, 100, text.Start, tc.str)
+			var dec decorationPainter
+			dec.reset([]TextDecoration{{Start: tc.start, End: tc.end, Lines: Underline | Overline}}, 0)
+			// Compute the expected width of the lines, line by line.
+			expected := make(map[int32]fixed.Int26_6)
+			runes := 0
+			for _, g := range glyphs {
+				dec.glyph(g)
+				if runes >= tc.start && runes < tc.end {
+					expected[g.Y] += g.Advance
+				}
+				runes += g.Runes
+			}
+			if len(expected) < tc.minLines {
+				t.Fatalf("expected at least %d lines, got %d", tc.minLines, len(expected))
+			}
+			for _, line := range []DecorationLine{Underline, Overline} {
+				actual := make(map[int32]fixed.Int26_6)
+				for _, s := range dec.segments {
+					if s.line != line {
+						continue
+					}
+					actual[s.y] += s.maxX - s.minX
+					if s.height <= 0 {
+						t.Errorf("line %v at %d: segment has height %v", line, s.y, s.height)
+					}
+				}
+				for y, w := range expected {
+					if actual[y] != w {
+						t.Errorf("line %v at %d: expected width %v, got %v", line, y, w, actual[y])
+					}
+				}
+				if len(actual) != len(expected) {
+					t.Errorf("line %v: expected %d lines, got %d", line, len(expected), len(actual))
+				}
+			}
+		})
+	}
+}